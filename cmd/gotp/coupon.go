@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/models"
+)
+
+var cmdCoupon = cli.Command{
+	Name:   "coupon",
+	Usage:  "Print vouchers with unique checked codes (coupon --count 10 --title \"-10%\")",
+	Action: runCoupon,
+	Flags: append([]cli.Flag{
+		cli.IntFlag{
+			Name:  "count",
+			Usage: "number of vouchers",
+			Value: 1,
+		},
+		cli.BoolFlag{
+			Name:  "random",
+			Usage: "random codes instead of numbers of the seq counter",
+		},
+		cli.StringFlag{
+			Name:  "prefix",
+			Usage: "first characters of the codes (0-9 and A-Z without I, L, O and U)",
+		},
+		cli.IntFlag{
+			Name:  "length",
+			Usage: "digits of the numbers, random characters with --random",
+			Value: 8,
+		},
+		cli.StringFlag{
+			Name:  "seq",
+			Usage: "counter of the numbers, see gotp seq",
+			Value: "coupon",
+		},
+		cli.StringFlag{
+			Name:  "code",
+			Usage: "qrcode or a bar code type",
+			Value: "CODE128",
+		},
+		cli.StringFlag{
+			Name:  "title",
+			Usage: "large line on top of the vouchers",
+		},
+		cli.StringFlag{
+			Name:  "text",
+			Usage: "text of the vouchers",
+		},
+		cli.StringFlag{
+			Name:  "expires",
+			Usage: "line under the code, \"Valid until 31.12.2026\"",
+		},
+		cli.BoolFlag{
+			Name:  "codes",
+			Usage: "write the codes to stdout instead of printing them",
+		},
+	}, copyFlags...),
+}
+
+// couponCodes - count codes of the coupon flags, numbers from the seq counter
+// or random ones
+func couponCodes(c *cli.Context, count int) ([]string, error) {
+	if c.Bool("random") {
+		return models.RandomCoupons(c.String("prefix"), c.Int("length"), count)
+	}
+	store := counters(c)
+	codes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		n, err := store.Next(c.String("seq"))
+		if err != nil {
+			return nil, err
+		}
+		code, _ := models.CouponCode(c.String("prefix"), n, c.Int("length"))
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func runCoupon(c *cli.Context) {
+	count := c.Int("count")
+	if count < 1 || c.Int("length") < 1 {
+		usage(c, "coupon --count N --length N")
+		return
+	}
+	// a bad prefix before any number of the counter is taken
+	if _, err := models.CouponCode(c.String("prefix"), 0, 1); err != nil {
+		fail(c, exitUsage, err)
+		return
+	}
+	codes, err := couponCodes(c, count)
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	if c.Bool("codes") {
+		if jsonOutput(c) {
+			printJSON(codes)
+			return
+		}
+		for _, code := range codes {
+			fmt.Fprintln(stdout, code)
+		}
+		return
+	}
+	res := models.CouponModel(models.Coupon{Title: c.String("title"), Text: c.String("text"),
+		Code: c.String("code"), Expires: c.String("expires")}, codes)
+	p := printer(c)
+	begin(c, p)
+	p.PrintCopies(&res, copies(c), c.String("banner"))
+	checkPrinted(c, p)
+	saveJob(c, res)
+	writeOutput(c)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/models"
+)
+
+var cmdCups = cli.Command{
+	Name:   "cups",
+	Usage:  "CUPS backend / filter: cups <job> <user> <title> <copies> <options> [file]",
+	Action: runCups,
+}
+
+// cupsMode - when gotp is installed as a CUPS backend or filter, run the
+// cups command with the CUPS arguments. A backend prints on DEVICE_URI
+// (gotp:/dev/ttyAMA0, gotp:file:/tmp/job.bin), a filter writes the
+// ESC/POS stream to stdout
+func cupsMode() {
+	switch cupsRole() {
+	case "backend":
+		if uri := os.Getenv("DEVICE_URI"); strings.HasPrefix(uri, "gotp:") {
+			os.Setenv("GOTP_PRINTER", strings.TrimPrefix(uri, "gotp:"))
+		}
+	case "filter":
+		os.Setenv("GOTP_PRINTER", "-")
+	default:
+		return
+	}
+	os.Args = append([]string{os.Args[0], "cups"}, os.Args[1:]...)
+}
+
+// cupsRole - "backend" or "filter" when CUPS runs gotp, empty otherwise.
+// CUPS passes the device URI (backend) or the queue name (filter) as
+// argv[0], so the role is the directory of the executable (a copy
+// installed there), or else the CUPS environment with the job arguments
+// (job user title copies options [file]) for one linked there
+func cupsRole() string {
+	if path, err := os.Executable(); err == nil {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			path = real
+		}
+		dir := filepath.Dir(path)
+		switch {
+		case strings.HasSuffix(dir, filepath.Join("cups", "backend")):
+			return "backend"
+		case strings.HasSuffix(dir, filepath.Join("cups", "filter")):
+			return "filter"
+		}
+	}
+	if n := len(os.Args) - 1; n != 5 && n != 6 {
+		return ""
+	}
+	switch {
+	case len(os.Getenv("DEVICE_URI")) > 0 && os.Args[0] == os.Getenv("DEVICE_URI"):
+		return "backend"
+	case len(os.Getenv("CONTENT_TYPE")) > 0 && len(os.Getenv("PRINTER")) > 0 && os.Args[0] == os.Getenv("PRINTER"):
+		return "filter"
+	}
+	return ""
+}
+
+// cupsError - report err to the CUPS log and fail the job
+func cupsError(err error) {
+	fmt.Fprintln(os.Stderr, "ERROR:", err)
+	endJobs()
+	os.Exit(1)
+}
+
+func runCups(c *cli.Context) {
+	args := c.Args()
+	if len(args) == 0 {
+		// device discovery
+		fmt.Println(`direct gotp "Unknown" "gotp ESC/POS thermal printer"`)
+		return
+	}
+	if len(args) < 5 {
+		cupsError(fmt.Errorf("Usage: cups <job> <user> <title> <copies> <options> [file]"))
+	}
+	n, err := strconv.Atoi(args[3])
+	if err != nil || n < 1 {
+		n = 1
+	}
+	var data []byte
+	if len(args) > 5 {
+		data, err = ioutil.ReadFile(args[5])
+	} else {
+		data, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		cupsError(err)
+	}
+
+	seq := counters(c).Job()
+	var res models.PrinterLine
+	if text := bytes.TrimSpace(data); len(text) > 0 && text[0] == '{' {
+		if res, err = models.ReadPrintModel(bytes.NewReader(text), "."); err == nil {
+			err = res.RenderFuncs(template.FuncMap{"seq": seq.Seq})
+		}
+	} else {
+		text := strings.Replace(string(data), "\r\n", "\n", -1)
+		lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+		res = models.TextModel(lines, "left")
+	}
+	if err != nil {
+		seq.Done(err)
+		cupsError(err)
+	}
+	p := printer(c)
+	begin(c, p)
+	p.PrintCopies(&res, n, "")
+	seq.Done(p.Err())
+	if err := p.Err(); err != nil {
+		cupsError(err)
+	}
+	saveJob(c, res)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/emulator"
+	"github.com/grengojbo/gotp/render"
+)
+
+var cmdEmulate = cli.Command{
+	Name:   "emulate",
+	Usage:  "Virtual printer: accept raw ESC/POS jobs of other software on a TCP port, log the decoded commands and render the receipts",
+	Action: runEmulate,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "listen, l",
+			Usage: "listen address, host:port",
+			Value: ":9100",
+		},
+		cli.StringFlag{
+			Name:  "dir",
+			Usage: "directory of a PNG and the raw stream (for gotp raw --file) of every job (empty - none)",
+		},
+		cli.BoolFlag{
+			Name:  "terminal",
+			Usage: "draw the receipts on the terminal",
+		},
+		cli.IntFlag{
+			Name:  "columns",
+			Usage: "width of the receipts on the terminal",
+			Value: 96,
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "don't log the commands",
+		},
+		cli.IntFlag{
+			Name:  "idle",
+			Usage: "seconds without data which end a job of a connection kept open (0 - jobs end with the connection)",
+			Value: 2,
+		},
+		cli.StringFlag{
+			Name:  "firmware",
+			Usage: "firmware version reported to GS I 65",
+			Value: "2.68",
+		},
+		cli.BoolFlag{
+			Name:  "paper-out",
+			Usage: "report the paper out to the status queries",
+		},
+	},
+}
+
+func runEmulate(c *cli.Context) {
+	if dir := c.String("dir"); len(dir) > 0 {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			printError(c, err)
+			return
+		}
+	}
+	em := &emulator.Emulator{
+		Idle:     time.Duration(c.Int("idle")) * time.Second,
+		Firmware: c.String("firmware"),
+		PaperOut: c.Bool("paper-out"),
+	}
+	if !c.Bool("quiet") && !jsonOutput(c) {
+		em.Log = os.Stdout
+	}
+	em.Job = func(j emulator.Job) {
+		files, err := saveEmulated(c.String("dir"), j)
+		if err != nil {
+			printError(c, err)
+		}
+		if c.Bool("terminal") && !jsonOutput(c) {
+			if err := render.WriteText(os.Stdout, j.Pages, c.Int("columns")); err != nil {
+				printError(c, err)
+			}
+		}
+		if jsonOutput(c) {
+			printJSON(map[string]interface{}{"job": j.ID, "remote": j.Remote, "bytes": len(j.Data),
+				"pages": len(j.Pages), "files": files})
+		} else if !c.Bool("quiet") || verbose(c) {
+			fmt.Printf("Job %d from %s: %d bytes, %d pages\n", j.ID, j.Remote, len(j.Data), len(j.Pages))
+		}
+	}
+	if verbose(c) {
+		fmt.Println("Listen", c.String("listen"))
+	}
+	if err := em.ListenAndServe(c.String("listen")); err != nil {
+		printError(c, err)
+	}
+}
+
+// saveEmulated - j as <dir>/job-<time>-<id>.png and .bin, nothing without
+// dir
+func saveEmulated(dir string, j emulator.Job) ([]string, error) {
+	if len(dir) == 0 {
+		return nil, nil
+	}
+	name := filepath.Join(dir, fmt.Sprintf("job-%s-%d", j.Start.Format("20060102-150405"), j.ID))
+	if err := ioutil.WriteFile(name+".bin", j.Data, 0644); err != nil {
+		return nil, err
+	}
+	files := []string{name + ".bin"}
+	if len(j.Pages) == 0 {
+		return files, nil
+	}
+	f, err := os.Create(name + ".png")
+	if err != nil {
+		return files, err
+	}
+	defer f.Close()
+	if err := render.WritePNG(f, j.Pages); err != nil {
+		return files, err
+	}
+	return append(files, name+".png"), nil
+}
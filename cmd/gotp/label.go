@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/escpos"
+	"github.com/grengojbo/gotp/models"
+	"github.com/grengojbo/gotp/render"
+)
+
+var cmdLabel = cli.Command{
+	Name:   "label",
+	Usage:  "Print a shelf label or a name badge (label badge name=\"Anna Smith\" company=ACME), or a label model (label tag.json)",
+	Action: runLabel,
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "width",
+			Usage: "label width, \"40mm\" or dots (empty - the print head)",
+		},
+		cli.StringFlag{
+			Name:  "height",
+			Usage: "label height, \"30mm\" or dots (empty - the one of the layout or model)",
+		},
+		cli.BoolFlag{
+			Name:  "raster",
+			Usage: "print the label as an image also on printers with page mode",
+		},
+	}, copyFlags[:1]...),
+}
+
+// labelFirst - field of a value given without key=, label badge "Anna Smith"
+var labelFirst = map[string]string{
+	"shelf": "name",
+	"badge": "name",
+}
+
+// labelModel - label of the arguments, a layout with key=value fields or
+// a model file
+func labelModel(c *cli.Context) (models.PrinterLine, error) {
+	kind := c.Args().First()
+	if _, ok := labelFirst[kind]; ok {
+		fields, err := keyValues(c.Args().Tail(), labelFirst[kind])
+		if err != nil {
+			return models.PrinterLine{}, err
+		}
+		return models.LabelModel(kind, fields, c.String("width"), c.String("height"))
+	}
+	res, err := models.LoadPrintModel(kind)
+	if err != nil {
+		return res, err
+	}
+	if err := res.Render(); err != nil {
+		return res, err
+	}
+	if res.Label == nil {
+		res.Label = &models.Label{}
+	}
+	if len(c.String("width")) > 0 {
+		res.Label.Width = c.String("width")
+	}
+	if len(c.String("height")) > 0 {
+		res.Label.Height = c.String("height")
+	}
+	if len(res.Label.Height) == 0 {
+		return res, fmt.Errorf("Label %s: no height, see --height", kind)
+	}
+	return res, nil
+}
+
+// labelImage - m as a page mode printer of the resolution of p prints it,
+// white to the label size
+func labelImage(p *escpos.Escpos, m *models.PrinterLine) (image.Image, error) {
+	width, height, err := p.LabelSize(m.Label)
+	if err != nil {
+		return nil, err
+	}
+	if width > render.Width {
+		return nil, fmt.Errorf("Label width %d dots: images of labels up to %d", width, render.Width)
+	}
+	var b bytes.Buffer
+	page := escpos.NewWriter(&b)
+	if err := page.SetProfile(escpos.Profile{Name: "label", Firmware: escpos.FirmwareDefault,
+		DPI: p.Profile().DPI, Width: width}); err != nil {
+		return nil, err
+	}
+	page.Init()
+	page.PrintModel(m)
+	if err := page.Err(); err != nil {
+		return nil, err
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if pages := render.Render(b.Bytes()); len(pages) > 0 {
+		draw.Draw(img, img.Bounds(), pages[0], pages[0].Bounds().Min, draw.Src)
+	}
+	return img, nil
+}
+
+func runLabel(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "label "+strings.Join(models.LabelKinds, "|")+" key=value... | label <model.json>")
+		return
+	}
+	res, err := labelModel(c)
+	if err != nil {
+		fail(c, exitUsage, err)
+		return
+	}
+	p := printer(c)
+	begin(c, p)
+	if err := printLabel(p, &res, copies(c), c.Bool("raster")); err != nil {
+		fail(c, exitUsage, err)
+		return
+	}
+	checkPrinted(c, p)
+	saveJob(c, res)
+	writeOutput(c)
+}
+
+// printLabel - n copies of the label m in page mode, as an image on
+// printers without it or with raster
+func printLabel(p *escpos.Escpos, m *models.PrinterLine, n int, raster bool) error {
+	if p.HasPageMode() && !raster {
+		p.PrintCopies(m, n, "")
+		return nil
+	}
+	img, err := labelImage(p, m)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		p.PrintImage(img, img.Bounds().Dx(), "threshold", "left")
+		if p.Profile().Media == "label" {
+			p.FeedLabel()
+		}
+	}
+	return nil
+}
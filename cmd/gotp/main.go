@@ -0,0 +1,1480 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/counter"
+	"github.com/grengojbo/gotp/escpos"
+	"github.com/grengojbo/gotp/fetch"
+	"github.com/grengojbo/gotp/history"
+	"github.com/grengojbo/gotp/models"
+	"github.com/grengojbo/gotp/render"
+	"github.com/grengojbo/gotp/server"
+)
+
+// output - copy of the ESC/POS stream for --output
+var output bytes.Buffer
+
+var (
+	// Version - current version
+	Version   = "0.1.0"
+	BuildTime = "2015-09-20 UTC"
+	GitHash   = "c00"
+)
+
+// Commands - list command
+var Commands = []cli.Command{
+	cmdTest,
+	cmdDemo,
+	cmdEncodings,
+	cmdPrinters,
+	cmdText,
+	cmdFile,
+	cmdCat,
+	cmdQr,
+	cmdCoupon,
+	cmdLabel,
+	cmdZpl,
+	cmdModel,
+	cmdTemplate,
+	cmdReprint,
+	cmdSeq,
+	cmdRaw,
+	cmdFeed,
+	cmdBanner,
+	cmdTicket,
+	cmdStatus,
+	cmdSelftest,
+	cmdCalibrate,
+	cmdServe,
+	cmdEmulate,
+	cmdTop,
+	cmdService,
+	cmdCups,
+}
+
+var cmdTest = cli.Command{
+	Name:   "test",
+	Usage:  "Print Test Page",
+	Action: runTest,
+}
+
+// copyFlags - flags of commands printing jobs
+var copyFlags = []cli.Flag{
+	cli.IntFlag{
+		Name:  "copies, n",
+		Usage: "number of copies",
+		Value: 1,
+	},
+	cli.StringFlag{
+		Name:  "banner",
+		Usage: "banner printed before each copy after the first (empty - none)",
+		Value: "*** COPY ***",
+	},
+}
+
+var cmdDemo = cli.Command{
+	Name:   "demo",
+	Usage:  "Print fonts, styles, alignment, bar codes, QR code and image",
+	Action: runDemo,
+}
+
+var cmdEncodings = cli.Command{
+	Name:   "encodings",
+	Usage:  "List supported code pages (--encode values), --print prints a sample of each",
+	Action: runEncodings,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "print",
+			Usage: "print the upper half of every code page",
+		},
+	},
+}
+
+var cmdPrinters = cli.Command{
+	Name:   "printers",
+	Usage:  "List the printers of the --config file (--printer values)",
+	Action: runPrinters,
+}
+
+var cmdStatus = cli.Command{
+	Name:   "status",
+	Usage:  "Show printer status: online, paper, cover, drawer, errors, head temperature and voltage (csn-a2)",
+	Action: runStatus,
+}
+
+var cmdSelftest = cli.Command{
+	Name:   "selftest",
+	Usage:  "Check port, status and firmware, print a calibration block and report pass/fail as JSON",
+	Action: runSelftest,
+}
+
+var cmdCalibrate = cli.Command{
+	Name:   "calibrate",
+	Usage:  "Measure the time the printer takes to print and feed (about 60 mm of paper), pacing follows it",
+	Action: runCalibrate,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "save",
+			Usage: "write the timing into the --printer of the config file",
+		},
+	},
+}
+
+var cmdServe = cli.Command{
+	Name:   "serve",
+	Usage:  "Print models posted to /print (IPP text and images on /ipp/print) over HTTP, a unix socket or written to a named pipe",
+	Action: runServe,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "listen, l",
+			Usage: "listen address: host:port, unix:///run/gotp.sock, systemd:// (a socket of socket activation, systemd://<FileDescriptorName>) or fifo:///run/gotp.fifo",
+			Value: ":8080",
+		},
+		cli.IntFlag{
+			Name:  "idle-sleep",
+			Usage: "seconds without jobs before the printer is put to sleep (0 - never)",
+		},
+		cli.IntFlag{
+			Name:  "requeue",
+			Usage: "times a job past --timeout is queued again before it fails",
+		},
+		cli.IntFlag{
+			Name:  "idempotency-window",
+			Usage: "seconds a job with the Idempotency-Key (idempotencyKey) of a printed one is answered without printing",
+			Value: 600,
+		},
+		cli.IntFlag{
+			Name:  "cache",
+			Usage: "jobs of models with \"cache\": true kept rendered, the same model and data again prints without rendering (0 - none)",
+			Value: 32,
+		},
+		cli.StringFlag{
+			Name:  "pool",
+			Usage: "more printers sharing the jobs of --printer: ports or --config printers separated by commas, a job of a failed one prints on another",
+		},
+		cli.StringFlag{
+			Name:  "balance",
+			Usage: "printer of the pool a job goes to: least-busy or round-robin",
+			Value: "least-busy",
+		},
+		cli.IntFlag{
+			Name:  "status-interval",
+			Usage: "seconds between status reads of the idle printers for /status and top (0 - never)",
+			Value: 10,
+		},
+	},
+}
+
+var cmdCat = cli.Command{
+	Name:   "cat",
+	Usage:  "Print text files wrapped to the paper width (cat notes.txt, - for stdin)",
+	Action: runCat,
+	Flags: append([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "number",
+			Usage: "number the lines",
+		},
+		cli.IntFlag{
+			Name:  "page",
+			Usage: "lines of a page, each page starts with a header (0 - no pages)",
+		},
+		cli.StringFlag{
+			Name:  "header",
+			Usage: "title of the page header, default the file name",
+		},
+		cli.StringFlag{
+			Name:  "font",
+			Usage: "font: A or B (small)",
+			Value: "A",
+		},
+		cli.StringFlag{
+			Name:  "cut",
+			Usage: "cut the paper after the file: full, partial or none",
+			Value: "full",
+		},
+	}, copyFlags...),
+}
+
+var cmdService = cli.Command{
+	Name:  "service",
+	Usage: "Windows service running serve",
+	Subcommands: []cli.Command{
+		{
+			Name:            "install",
+			Usage:           "Install the service running serve with these global and serve flags (gotp --printer COM3 service install --listen :8080)",
+			Action:          runServiceInstall,
+			SkipFlagParsing: true,
+		},
+		{
+			Name:   "remove",
+			Usage:  "Stop and remove the service",
+			Action: runServiceRemove,
+		},
+	},
+}
+
+var cmdFile = cli.Command{
+	Name:   "file",
+	Usage:  "Print from file",
+	Action: runFile,
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "data, d",
+			Usage: "JSON file (http(s) URL or - for stdin) of the template data, merged over the data of the model",
+		},
+		cli.StringSliceFlag{
+			Name:  "var",
+			Usage: "template data key=value (table=12, customer.name=Anna), over the data of the model, --data and the GOTP_VAR_<KEY> variables",
+			Value: &cli.StringSlice{},
+		},
+	}, copyFlags...),
+}
+
+var cmdReprint = cli.Command{
+	Name:   "reprint",
+	Usage:  "Print a job from the history again (reprint <job-id|last|list>)",
+	Action: runReprint,
+	Flags:  copyFlags,
+}
+
+var cmdModel = cli.Command{
+	Name:  "model",
+	Usage: "Model file tools",
+	Subcommands: []cli.Command{
+		{
+			Name:   "upgrade",
+			Usage:  "Rewrite model file in the current version format (model upgrade old.json [new.json])",
+			Action: runModelUpgrade,
+		},
+	},
+}
+
+var cmdTemplate = cli.Command{
+	Name:  "template",
+	Usage: "Built-in starter templates",
+	Subcommands: []cli.Command{
+		{
+			Name:   "list",
+			Usage:  "List the built-in templates",
+			Action: runTemplateList,
+		},
+		{
+			Name:   "init",
+			Usage:  "Write a copy of a template to customize (template init retail > receipt.json)",
+			Action: runTemplateInit,
+		},
+	},
+}
+
+var cmdRaw = cli.Command{
+	Name:   "raw",
+	Usage:  "Send raw bytes: raw \"1B 40 1D 56 41 00\" or raw --file cmd.bin",
+	Action: runRaw,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "file, f",
+			Usage: "binary file with the bytes to send",
+		},
+	},
+}
+
+var cmdFeed = cli.Command{
+	Name:   "feed",
+	Usage:  "Feed paper N lines (feed N) or M dots (feed --dots M)",
+	Action: runFeed,
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "dots",
+			Usage: "feed dot rows instead of lines",
+		},
+	},
+}
+
+var cmdBanner = cli.Command{
+	Name:   "banner",
+	Usage:  "Print a pickup number or short text in the largest characters (banner 42)",
+	Action: runBanner,
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "feed",
+			Usage: "lines fed after the banner",
+			Value: 3,
+		},
+	},
+}
+
+var cmdSeq = cli.Command{
+	Name:  "seq",
+	Usage: "Receipt number sequences",
+	Subcommands: []cli.Command{
+		{
+			Name:   "show",
+			Usage:  "Show sequences and their gaps (seq show [name])",
+			Action: runSeqShow,
+		},
+		{
+			Name:   "reset",
+			Usage:  "Reset sequence, the next number is value+1 (seq reset <name> [value])",
+			Action: runSeqReset,
+		},
+	},
+}
+
+var cmdText = cli.Command{
+	Name:   "text",
+	Usage:  "Print text",
+	Action: runText,
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "align, a",
+			Usage: "text align (L,C,R)",
+			Value: "left",
+		},
+		cli.BoolFlag{
+			Name:  "bold, b",
+			Usage: "bold text",
+		},
+		cli.BoolFlag{
+			Name:  "underline, u",
+			Usage: "underlined text",
+		},
+		cli.StringFlag{
+			Name:  "size, s",
+			Usage: "character size: normal, medium (double height) or large",
+			Value: "normal",
+		},
+		cli.StringFlag{
+			Name:  "font",
+			Usage: "font: A or B (small)",
+			Value: "A",
+		},
+		cli.BoolFlag{
+			Name:  "wrap, w",
+			Usage: "break the lines at spaces to the paper width",
+		},
+		cli.IntFlag{
+			Name:  "feed",
+			Usage: "lines fed after the text",
+			Value: 2,
+		},
+		cli.StringFlag{
+			Name:  "cut",
+			Usage: "cut the paper after the text: full or partial",
+		},
+	}, copyFlags...),
+}
+
+func runTest(c *cli.Context) {
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Print test page")
+	}
+	p := printer(c)
+
+	begin(c, p)
+	p.TestPage()
+	checkPrinted(c, p)
+	writeOutput(c)
+
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Finish :)")
+	}
+}
+
+func runDemo(c *cli.Context) {
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Print demo page")
+	}
+	p := printer(c)
+	begin(c, p)
+	p.Demo()
+	checkPrinted(c, p)
+	writeOutput(c)
+}
+
+func runEncodings(c *cli.Context) {
+	if jsonOutput(c) {
+		printJSON(escpos.CodePages)
+	} else {
+		for _, cp := range escpos.CodePages {
+			fmt.Fprintf(stdout, "%-11s ESC t %-3d %s\n", cp.Name, cp.Number, cp.Description)
+		}
+	}
+	if !c.Bool("print") {
+		return
+	}
+	p := printer(c)
+	p.Begin()
+	for _, cp := range escpos.CodePages {
+		p.PrintCodePageSample(cp)
+	}
+	p.Feed(3)
+	checkPrinted(c, p)
+	writeOutput(c)
+}
+
+func runPrinters(c *cli.Context) {
+	printers := loadConfig(c)
+	names := make([]string, 0, len(printers))
+	for name := range printers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if jsonOutput(c) {
+		res := make([]escpos.Profile, len(names))
+		for i, name := range names {
+			res[i] = printers[name]
+		}
+		printJSON(res)
+		return
+	}
+	for _, name := range names {
+		p := printers[name]
+		fmt.Fprintf(stdout, "%-12s %-24s %s\n", name, p.Port, p.Description)
+	}
+}
+
+func runFile(c *cli.Context) {
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Print from file")
+	}
+	if !c.Args().Present() {
+		usage(c, "file <model.json>")
+		return
+	}
+	seq := counters(c).Job()
+	res, err := models.LoadPrintModel(c.Args().First())
+	if err == nil && len(c.String("data")) > 0 {
+		var data map[string]interface{}
+		if data, err = models.LoadData(c.String("data")); err == nil {
+			res.MergeData(data)
+		}
+	}
+	if err == nil {
+		if verr := setVars(c, &res); verr != nil {
+			usage(c, verr.Error())
+			return
+		}
+	}
+	if err == nil {
+		err = res.RenderFuncs(template.FuncMap{"seq": seq.Seq})
+	}
+	if err != nil {
+		fail(c, exitTemplate, err)
+		seq.Done(err)
+	} else {
+		p := printer(c)
+
+		begin(c, p)
+		p.PrintCopies(&res, copies(c), c.String("banner"))
+		if err := seq.Done(p.Err()); err != nil {
+			printError(c, err)
+		}
+		checkPrinted(c, p)
+		saveJob(c, res)
+		writeOutput(c)
+	}
+
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Finish :)")
+	}
+}
+
+func runCat(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "cat <file.txt>...")
+		return
+	}
+	font := strings.ToUpper(c.String("font"))
+	if font != "A" && font != "B" {
+		usage(c, "cat --font A|B")
+		return
+	}
+	cut := c.String("cut")
+	if cut != "full" && cut != "partial" && cut != "none" {
+		usage(c, "cat --cut full|partial|none")
+		return
+	}
+	res := models.PrinterLine{Version: models.ModelVersion}
+	for _, name := range c.Args() {
+		var b []byte
+		var err error
+		if name == "-" {
+			b, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			b, err = ioutil.ReadFile(name)
+		}
+		if err != nil {
+			printError(c, err)
+			return
+		}
+		title := c.String("header")
+		if len(title) == 0 {
+			title = filepath.Base(name)
+		}
+		lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+		m := models.CatModel(title, lines, c.Bool("number"), c.Int("page"))
+		res.Sections = append(res.Sections, m.Sections...)
+	}
+	for i := range res.Sections {
+		for j, row := range res.Sections[i].Rows {
+			if row.Wrap && font == "B" {
+				res.Sections[i].Rows[j].Style = "small"
+			}
+		}
+	}
+	if cut != "none" {
+		res.Sections = append(res.Sections, models.Section{Name: "cut",
+			Rows: []models.Printer{{Type: "cut", Cut: cut}}})
+	}
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Print", strings.Join(c.Args(), " "))
+	}
+	p := printer(c)
+	begin(c, p)
+	p.PrintCopies(&res, copies(c), c.String("banner"))
+	checkPrinted(c, p)
+	saveJob(c, res)
+	writeOutput(c)
+}
+
+// varPrefix - environment variables of the template data, GOTP_VAR_TABLE
+// is .table
+const varPrefix = "GOTP_VAR_"
+
+// setVars - the GOTP_VAR_<KEY> variables and --var flags in the data of m
+func setVars(c *cli.Context, m *models.PrinterLine) error {
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, varPrefix) {
+			kv := strings.SplitN(strings.TrimPrefix(env, varPrefix), "=", 2)
+			if len(kv[0]) > 0 {
+				m.SetVar(strings.ToLower(kv[0]), kv[1])
+			}
+		}
+	}
+	for _, v := range c.StringSlice("var") {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return fmt.Errorf("file --var key=value")
+		}
+		m.SetVar(kv[0], kv[1])
+	}
+	return nil
+}
+
+func runModelUpgrade(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "model upgrade old.json [new.json]")
+		return
+	}
+	src := c.Args().First()
+	dst := src
+	if len(c.Args()) > 1 {
+		dst = c.Args().Get(1)
+	}
+	if err := models.UpgradeModel(src, dst); err != nil {
+		printError(c, err)
+	} else if verbose(c) {
+		fmt.Fprintf(stdout, "Upgrade %s -> %s\n", src, dst)
+	}
+}
+
+func runTemplateList(c *cli.Context) {
+	if jsonOutput(c) {
+		var list []map[string]string
+		for _, name := range models.Templates() {
+			list = append(list, map[string]string{"name": name, "description": models.TemplateInfo[name]})
+		}
+		printJSON(list)
+		return
+	}
+	for _, name := range models.Templates() {
+		fmt.Fprintf(stdout, "%-10s %s\n", name, models.TemplateInfo[name])
+	}
+}
+
+func runTemplateInit(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "template init <name> > model.json, one of: "+strings.Join(models.Templates(), ", "))
+		return
+	}
+	b, err := models.Template(c.Args().First())
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	os.Stdout.Write(b)
+}
+
+func runText(c *cli.Context) {
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Print text")
+	}
+	if c.Args().Present() {
+		p := printer(c)
+
+		if verbose(c) {
+			fmt.Fprintln(stdout, "---------------------------------")
+			fmt.Fprintln(stdout, c.Args())
+			fmt.Fprintln(stdout, "---------------------------------")
+		}
+		res := models.TextModel(c.Args(), c.String("align"))
+		if err := textFormat(c, &res); err != nil {
+			usage(c, err.Error())
+			return
+		}
+		begin(c, p)
+		p.PrintCopies(&res, copies(c), c.String("banner"))
+		checkPrinted(c, p)
+		saveJob(c, res)
+		writeOutput(c)
+	} else {
+		usage(c, "text <line>...")
+	}
+
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Finish :)")
+	}
+}
+
+// textFormat - the formatting flags of the text command on the rows of
+// m, --feed and --cut after them
+func textFormat(c *cli.Context, m *models.PrinterLine) error {
+	var style []string
+	if c.Bool("bold") {
+		style = append(style, "bold")
+	}
+	switch strings.ToUpper(c.String("font")) {
+	case "A":
+	case "B":
+		style = append(style, "small")
+	default:
+		return fmt.Errorf("text --font A|B")
+	}
+	size := c.String("size")
+	if size != "normal" && size != "medium" && size != "large" {
+		return fmt.Errorf("text --size normal|medium|large")
+	}
+	if cut := c.String("cut"); len(cut) > 0 && cut != "full" && cut != "partial" {
+		return fmt.Errorf("text --cut full|partial")
+	}
+	if c.Int("feed") < 0 || c.Int("feed") > 255 {
+		return fmt.Errorf("text --feed 0..255")
+	}
+	s := &m.Sections[0]
+	for i := range s.Rows {
+		s.Rows[i].Style = strings.Join(style, " ")
+		s.Rows[i].Size = size
+		s.Rows[i].Wrap = c.Bool("wrap")
+		if c.Bool("underline") {
+			s.Rows[i].Underline = 1
+		}
+	}
+	s.Feed = uint8(c.Int("feed"))
+	if cut := c.String("cut"); len(cut) > 0 {
+		m.Sections = append(m.Sections, models.Section{Name: "cut",
+			Rows: []models.Printer{{Type: "cut", Cut: cut}}})
+	}
+	return nil
+}
+
+func runReprint(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "reprint <job-id|last|list>")
+		return
+	}
+	if c.Args().First() == "list" {
+		jobs, err := jobHistory(c).List()
+		if err != nil {
+			printError(c, err)
+		}
+		if jsonOutput(c) {
+			printJSON(jobs)
+			return
+		}
+		for _, job := range jobs {
+			fmt.Fprintf(stdout, "%s  %s  %s\n", job.ID, job.Time.Format("2006-01-02 15:04:05"), job.Title)
+		}
+		return
+	}
+	res, err := jobHistory(c).Get(c.Args().First())
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	p := printer(c)
+
+	begin(c, p)
+	banner := c.String("banner")
+	if len(banner) > 0 {
+		p.PrintBanner(banner)
+	}
+	p.PrintCopies(&res, copies(c), banner)
+	checkPrinted(c, p)
+	writeOutput(c)
+}
+
+func runRaw(c *cli.Context) {
+	var data []byte
+	var err error
+	if len(c.String("file")) > 0 {
+		data, err = ioutil.ReadFile(c.String("file"))
+	} else if c.Args().Present() {
+		data, err = escpos.ParseHex(strings.Join(c.Args(), " "))
+	} else {
+		usage(c, "raw <hex bytes> | raw --file cmd.bin")
+		return
+	}
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	// the bytes go out as they are, without the ESC @ of Begin
+	p := printer(c)
+	if _, err := p.WriteStream(data); err != nil {
+		printError(c, err)
+	} else if verbose(c) {
+		fmt.Fprintf(stdout, "Sent %d bytes\n", len(data))
+	}
+	writeOutput(c)
+}
+
+func runFeed(c *cli.Context) {
+	dots := c.Int("dots")
+	lines := 1
+	if c.Args().Present() {
+		n, err := strconv.Atoi(c.Args().First())
+		if err != nil || n < 0 || n > 255 {
+			printError(c, fmt.Errorf("Invalid number of lines: %s", c.Args().First()))
+			return
+		}
+		lines = n
+	}
+	if dots < 0 || dots > 255 {
+		printError(c, fmt.Errorf("Invalid number of dots: %d", dots))
+		return
+	}
+	p := printer(c)
+	p.Begin()
+	if dots > 0 {
+		p.FeedDots(uint8(dots))
+	} else {
+		p.Feed(lines)
+	}
+	checkPrinted(c, p)
+	writeOutput(c)
+}
+
+func runBanner(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "banner <text>")
+		return
+	}
+	p := printer(c)
+	begin(c, p)
+	if err := p.Banner(strings.Join(c.Args(), " ")); err != nil {
+		printError(c, err)
+		return
+	}
+	p.Feed(c.Int("feed"))
+	checkPrinted(c, p)
+	writeOutput(c)
+}
+
+func runStatus(c *cli.Context) {
+	p := printer(c)
+	s, err := p.Status()
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	if s.PaperOut {
+		setExit(exitPaperOut)
+	}
+	if jsonOutput(c) {
+		printJSON(s)
+		return
+	}
+	if len(s.Model) > 0 {
+		fmt.Fprintln(stdout, "Model:         ", s.Model)
+	}
+	if len(s.Serial) > 0 {
+		fmt.Fprintln(stdout, "Serial number: ", s.Serial)
+	}
+	fmt.Fprintln(stdout, "Online:        ", s.Online)
+	fmt.Fprintln(stdout, "Paper out:     ", s.PaperOut)
+	fmt.Fprintln(stdout, "Paper near end:", s.PaperNearEnd)
+	fmt.Fprintln(stdout, "Cover open:    ", s.CoverOpen)
+	fmt.Fprintln(stdout, "Drawer open:   ", s.DrawerOpen)
+	fmt.Fprintln(stdout, "Cutter error:  ", s.CutterError)
+	fmt.Fprintln(stdout, "Error:         ", s.Error)
+	if s.Temperature != nil {
+		fmt.Fprintf(stdout, "Temperature:    %.0f °C\n", *s.Temperature)
+	}
+	if s.Voltage != nil {
+		fmt.Fprintf(stdout, "Voltage:        %.1f V", *s.Voltage)
+		if s.LowVoltage {
+			fmt.Fprint(stdout, " (low, prints fade)")
+		}
+		fmt.Fprintln(stdout)
+	}
+}
+
+func runSelftest(c *cli.Context) {
+	p := printer(c)
+	res := p.SelfTest()
+	printJSON(res)
+	if res.Status != nil && res.Status.PaperOut {
+		setExit(exitPaperOut)
+	} else if !res.Pass {
+		setExit(exitError)
+	}
+}
+
+func runCalibrate(c *cli.Context) {
+	p := printer(c)
+	p.Begin()
+	t, err := p.Calibrate()
+	p.Feed(3)
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	printJSON(t)
+	if !c.Bool("save") {
+		return
+	}
+	name := c.GlobalString("printer")
+	if _, ok := loadConfig(c)[name]; !ok {
+		usage(c, "calibrate --save with a --printer of --config")
+		return
+	}
+	if err := escpos.SaveTiming(configPath(c), name, t); err != nil {
+		printError(c, err)
+	}
+}
+
+func runServe(c *cli.Context) {
+	if b := c.String("balance"); b != "least-busy" && b != "round-robin" {
+		usage(c, "serve --balance least-busy|round-robin")
+		return
+	}
+	pool := []*escpos.Escpos{printer(c)}
+	for _, target := range strings.Split(c.String("pool"), ",") {
+		if target = strings.TrimSpace(target); len(target) > 0 {
+			pool = append(pool, printerAt(c, target))
+		}
+	}
+	for _, p := range pool {
+		// every job of the server runs the hooks
+		setup(c, p)
+		// the deadline is per job
+		p.SetDeadline(time.Time{})
+	}
+	store := counters(c)
+	srv := server.NewPool(pool...)
+	srv.Balance = c.String("balance")
+	// the numbers of a failed job are gaps, the ones of the others stay
+	srv.JobFuncs = func() (template.FuncMap, func(error)) {
+		seq := store.Job()
+		return template.FuncMap{"seq": seq.Seq}, func(err error) {
+			if err := seq.Done(err); err != nil {
+				printError(c, err)
+			}
+		}
+	}
+	if path := configPath(c); len(path) > 0 {
+		schedule, err := server.LoadSchedule(path)
+		if err != nil {
+			printError(c, err)
+		}
+		for _, sc := range schedule {
+			srv.AddSchedule(sc)
+			if verbose(c) {
+				fmt.Fprintln(stdout, "Schedule", sc.Name, sc.Cron, sc.Model)
+			}
+		}
+	}
+	srv.IdleSleep = time.Duration(c.Int("idle-sleep")) * time.Second
+	srv.Timeout = timeout(c)
+	srv.Requeue = c.Int("requeue")
+	srv.StatusInterval = time.Duration(c.Int("status-interval")) * time.Second
+	srv.Idempotency = time.Duration(c.Int("idempotency-window")) * time.Second
+	srv.Cache = c.Int("cache")
+	srv.Spool = filepath.Join(c.GlobalString("state"), "spool")
+	srv.Printed = func(m models.PrinterLine, err error) {
+		if err != nil {
+			printError(c, err)
+		} else {
+			saveJob(c, m)
+		}
+	}
+	listen := func() {
+		if verbose(c) {
+			fmt.Fprintln(stdout, "Listen", c.String("listen"))
+		}
+		if err := srv.ListenAndServe(c.String("listen")); err != nil {
+			printError(c, err)
+		}
+	}
+	if !runAsService(c, srv, listen) {
+		// SIGTERM and ^C finish the receipt printing, the queued jobs
+		// print after the restart
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+		go func() {
+			<-stop
+			if verbose(c) {
+				fmt.Fprintln(stdout, "Shutdown")
+			}
+			if err := srv.Shutdown(context.Background()); err != nil {
+				printError(c, err)
+			}
+		}()
+		listen()
+	}
+	for _, p := range pool {
+		p.Close()
+	}
+}
+
+func runServiceInstall(c *cli.Context) {
+	// the global flags before "service install" and the serve flags after
+	var args []string
+	for i, arg := range os.Args[1:] {
+		if arg == "service" && i+2 < len(os.Args) && os.Args[i+2] == "install" {
+			args = append(args, os.Args[1:i+1]...)
+			break
+		}
+	}
+	// the service account has another home
+	if !c.GlobalIsSet("state") {
+		args = append(args, "--state", c.GlobalString("state"))
+	}
+	args = append(append(args, "serve"), c.Args()...)
+	if err := installService(args); err != nil {
+		printError(c, err)
+		return
+	}
+	if verbose(c) {
+		fmt.Fprintln(stdout, "Installed", strings.Join(args, " "))
+	}
+}
+
+func runServiceRemove(c *cli.Context) {
+	if err := removeService(); err != nil {
+		printError(c, err)
+	}
+}
+
+func runSeqShow(c *cli.Context) {
+	seq, err := counters(c).Get()
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	if jsonOutput(c) {
+		if c.Args().Present() {
+			printJSON(map[string]*counter.Sequence{c.Args().First(): seq[c.Args().First()]})
+		} else {
+			printJSON(seq)
+		}
+		return
+	}
+	for name, s := range seq {
+		if c.Args().Present() && c.Args().First() != name {
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: %d", name, s.Last)
+		if len(s.Gaps) > 0 {
+			fmt.Fprintf(stdout, " gaps: %v", s.Gaps)
+		}
+		fmt.Fprintln(stdout)
+	}
+}
+
+func runSeqReset(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "seq reset <name> [value]")
+		return
+	}
+	var value int64
+	if len(c.Args()) > 1 {
+		v, err := strconv.ParseInt(c.Args().Get(1), 10, 64)
+		if err != nil {
+			printError(c, err)
+			return
+		}
+		value = v
+	}
+	if err := counters(c).Reset(c.Args().First(), value); err != nil {
+		printError(c, err)
+	}
+}
+
+// counters - receipt number sequences in the state directory
+func counters(c *cli.Context) *counter.Store {
+	if err := os.MkdirAll(c.GlobalString("state"), 0755); err != nil {
+		printError(c, err)
+	}
+	return counter.Open(filepath.Join(c.GlobalString("state"), "seq.json"))
+}
+
+// printer - printer from the global flags
+func printer(c *cli.Context) *escpos.Escpos {
+	return printerAt(c, c.GlobalString("printer"))
+}
+
+// printerAt - printer of the global flags on target, a port or a printer
+// of the config file
+func printerAt(c *cli.Context, target string) *escpos.Escpos {
+	profile := findPrinter(c, target)
+	if len(c.GlobalString("serial")) > 0 {
+		profile.Frame = c.GlobalString("serial")
+	}
+	if len(c.GlobalString("image")) > 0 {
+		profile.Image = c.GlobalString("image")
+	}
+	if len(c.GlobalString("media")) > 0 {
+		profile.Media = c.GlobalString("media")
+	}
+	if len(c.GlobalString("page-length")) > 0 {
+		profile.PageLength = c.GlobalString("page-length")
+	}
+	if len(c.GlobalString("feed-mode")) > 0 {
+		profile.FeedMode = c.GlobalString("feed-mode")
+	}
+	if len(c.GlobalString("cut-mode")) > 0 {
+		profile.CutMode = c.GlobalString("cut-mode")
+	}
+	if c.GlobalBool("no-cut") {
+		profile.CutMode = "none"
+	}
+	if c.GlobalInt("band-height") > 0 {
+		profile.BandHeight = c.GlobalInt("band-height")
+	}
+	if len(c.GlobalString("band-wait")) > 0 {
+		profile.BandWait = c.GlobalString("band-wait")
+	}
+	if len(c.GlobalString("before-job")) > 0 {
+		profile.BeforeJob = c.GlobalString("before-job")
+	}
+	if len(c.GlobalString("after-job")) > 0 {
+		profile.AfterJob = c.GlobalString("after-job")
+	}
+	if c.GlobalInt("columns") > 0 {
+		profile.Columns = c.GlobalInt("columns")
+	}
+	if c.GlobalInt("read-timeout") > 0 {
+		profile.ReadTimeout = time.Duration(c.GlobalInt("read-timeout")) * time.Millisecond
+	}
+	p := open(c, profile)
+	p.Verbose = verbose(c)
+	if t := timeout(c); t > 0 {
+		p.SetDeadline(time.Now().Add(t))
+	}
+	p.SetEntities(c.GlobalBool("entities"))
+	if len(c.GlobalString("output")) > 0 {
+		p.Tee(&output)
+	}
+	if err := p.SetProfile(profile); err != nil {
+		printError(c, err)
+	}
+	return p
+}
+
+// findPrinter - profile and port of target: a printer of the config
+// file by name, else the --profile one on the target port
+func findPrinter(c *cli.Context, target string) escpos.Profile {
+	if p, ok := loadConfig(c)[target]; ok {
+		return p
+	}
+	profile, ok := escpos.FindProfile(c.GlobalString("profile"))
+	if !ok {
+		printError(c, fmt.Errorf("Invalid profile: %s", c.GlobalString("profile")))
+		profile, _ = escpos.FindProfile("adafruit")
+	}
+	profile.Port = target
+	return profile
+}
+
+// homeDir - home of the user, the default --state is in it
+func homeDir() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return os.Getenv("HOME")
+}
+
+// configPath - the --config file, <state>/printers.json when it exists,
+// empty without one
+func configPath(c *cli.Context) string {
+	if len(c.GlobalString("config")) > 0 {
+		return c.GlobalString("config")
+	}
+	path := filepath.Join(c.GlobalString("state"), "printers.json")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// loadConfig - printers of the config file, nil without one
+func loadConfig(c *cli.Context) map[string]escpos.Profile {
+	path := configPath(c)
+	if len(path) == 0 {
+		return nil
+	}
+	printers, err := escpos.LoadConfig(path)
+	if err != nil {
+		printError(c, err)
+	}
+	return printers
+}
+
+// open - printer on the port of profile: "-" is stdout, "file:<path>" a
+// file, "tcp:<host[:port]>" an Ethernet printer, anything else
+// ("serial:<port>" or "<port>") a serial port
+func open(c *cli.Context, profile escpos.Profile) *escpos.Escpos {
+	target := profile.Port
+	if target == "-" {
+		streamToStdout()
+		p := escpos.NewWriter(os.Stdout)
+		p.Debug = c.GlobalBool("debug")
+		p.Log = os.Stderr
+		return p
+	}
+	if strings.HasPrefix(target, "file:") {
+		f, err := os.Create(strings.TrimPrefix(target, "file:"))
+		if err != nil {
+			printError(c, err)
+			return escpos.NewWriter(nil)
+		}
+		p := escpos.NewWriter(f)
+		p.Debug = c.GlobalBool("debug")
+		return p
+	}
+	if strings.HasPrefix(target, "tcp:") {
+		p := escpos.NewTCP(c.GlobalBool("debug"), strings.TrimPrefix(target, "tcp:"))
+		p.SetSNMP(c.GlobalString("snmp"))
+		return p
+	}
+	port := strings.TrimPrefix(target, "serial:")
+	rate := baud(c)
+	if profile.Baud > 0 && !c.GlobalIsSet("baud") {
+		rate = profile.Baud
+	}
+	config, err := profile.SerialConfig(port, rate)
+	if err != nil {
+		printError(c, err)
+		config, _ = escpos.Profile{}.SerialConfig(port, rate)
+	}
+	if c.GlobalString("baud") == "auto" && !c.GlobalBool("debug") {
+		if config.Baud, err = escpos.ProbeBaud(*config); err != nil {
+			printError(c, err)
+			config.Baud = escpos.BAUDRATE
+		} else if verbose(c) {
+			fmt.Fprintln(stdout, "Baud:", config.Baud)
+		}
+	}
+	return escpos.NewConfig(c.GlobalBool("debug"), config)
+}
+
+// timeout - --timeout flag, deadline of the command or of each job of
+// serve
+func timeout(c *cli.Context) time.Duration {
+	return time.Duration(c.GlobalInt("timeout")) * time.Second
+}
+
+// baud - --baud flag, BAUDRATE for "auto" until probed
+func baud(c *cli.Context) int {
+	if c.GlobalString("baud") == "auto" {
+		return escpos.BAUDRATE
+	}
+	n, err := strconv.Atoi(c.GlobalString("baud"))
+	if err != nil || n <= 0 {
+		printError(c, fmt.Errorf("Invalid baud: %s", c.GlobalString("baud")))
+		return escpos.BAUDRATE
+	}
+	return n
+}
+
+// jobs - printers of begin, their jobs end after the command
+var jobs []*escpos.Escpos
+
+// begin - start the job of the command on p (the before hooks, a refused
+// job prints nothing) and set it up
+func begin(c *cli.Context, p *escpos.Escpos) {
+	if err := p.StartJob(c.Command.Name); err != nil {
+		printError(c, err)
+	}
+	jobs = append(jobs, p)
+	setup(c, p)
+}
+
+// endJobs - end the jobs of begin, the after hooks get their errors
+func endJobs() {
+	for _, p := range jobs {
+		p.EndJob()
+	}
+	jobs = nil
+}
+
+// setup - initialize printer, select the --encode code page (unless the
+// printer config has one) and the --quality preset, lock the panel
+// buttons with --lock-buttons
+func setup(c *cli.Context, p *escpos.Escpos) {
+	p.Begin()
+	if c.GlobalIsSet("encode") || len(p.Profile().CodePage) == 0 {
+		if err := p.SetCodePage(c.GlobalString("encode")); err != nil {
+			printError(c, err)
+		}
+	}
+	if len(c.GlobalString("quality")) > 0 {
+		if err := p.SetQuality(c.GlobalString("quality")); err != nil {
+			printError(c, err)
+		}
+	}
+	if c.GlobalBool("lock-buttons") {
+		p.SetPanelButtons(false)
+	}
+}
+
+// writeOutput - render the printed stream to the --output file,
+// "pdf:<path>" or "png:<path>"
+func writeOutput(c *cli.Context) {
+	target := c.GlobalString("output")
+	if len(target) == 0 {
+		return
+	}
+	i := strings.Index(target, ":")
+	if i < 0 {
+		printError(c, fmt.Errorf("Invalid output: %s", target))
+		return
+	}
+	write := render.WritePDF
+	switch target[:i] {
+	case "pdf":
+	case "png":
+		write = render.WritePNG
+	default:
+		printError(c, fmt.Errorf("Invalid output: %s", target))
+		return
+	}
+	f, err := os.Create(target[i+1:])
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	defer f.Close()
+	if err := write(f, render.Render(output.Bytes())); err != nil {
+		printError(c, err)
+	} else if verbose(c) {
+		fmt.Fprintln(stdout, "Output:", target[i+1:])
+	}
+}
+
+// copies - --copies flag, at least 1
+func copies(c *cli.Context) int {
+	if n := c.Int("copies"); n > 1 {
+		return n
+	}
+	return 1
+}
+
+// jobHistory - last printed jobs in the state directory
+func jobHistory(c *cli.Context) *history.Store {
+	return history.Open(filepath.Join(c.GlobalString("state"), "jobs"), c.GlobalInt("history"))
+}
+
+// saveJob - keep rendered job for reprint
+func saveJob(c *cli.Context, res models.PrinterLine) {
+	id, err := jobHistory(c).Add(res)
+	if err != nil {
+		printError(c, err)
+	} else if jsonOutput(c) {
+		printJSON(map[string]string{"job": id})
+	} else if verbose(c) {
+		fmt.Fprintln(stdout, "Job:", id)
+	}
+}
+
+func main() {
+	runtime.GOMAXPROCS(1)
+	cupsMode()
+
+	app := cli.NewApp()
+	app.Name = "print-pos"
+	app.Version = Version
+	app.Usage = "Mini Thermal Printer cli print"
+	app.Author = "Oleg Dolya"
+	app.Email = "oleg.dolya@gmail.com"
+	app.EnableBashCompletion = true
+	app.Commands = Commands
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Verbose mode",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "Results and errors as JSON for scripts",
+		},
+		cli.BoolFlag{
+			Name:  "debug",
+			Usage: "Debug mode, hexdump of the commands instead of printing",
+		},
+		cli.StringFlag{
+			Name:  "encode",
+			Usage: "Setting Code page (see encodings)",
+			Value: "PC437",
+		},
+		cli.BoolFlag{
+			Name:  "entities",
+			Usage: "decode all HTML entities in text (&eacute;, &#8364;), not only &amp; &lt; &gt; &quot; &apos;",
+		},
+		cli.BoolFlag{
+			Name:  "lock-buttons",
+			Usage: "Disable the printer feed button",
+		},
+		cli.StringFlag{
+			Name:  "profile",
+			Usage: "Printer profile: adafruit, adafruit-old, adafruit-label, csn-a2, star or auto (detect firmware)",
+			Value: "adafruit",
+		},
+		cli.StringFlag{
+			Name:   "printer",
+			Usage:  "Serial port ([serial:]/dev/ttyUSB0, COM3), tcp:<host[:9100]>, file:<path>, - for stdout or a printer of --config",
+			Value:  escpos.DefaultSerialPort,
+			EnvVar: "GOTP_PRINTER",
+		},
+		cli.StringFlag{
+			Name:   "config",
+			Usage:  "JSON file of named printers with their port, profile and overrides, default <state>/printers.json",
+			EnvVar: "GOTP_CONFIG",
+		},
+		cli.StringFlag{
+			Name:  "snmp",
+			Usage: "SNMP community (public) to read the status of a tcp: printer from its printer MIB",
+		},
+		cli.StringFlag{
+			Name:  "baud",
+			Usage: "Serial baud rate, auto probes 19200, 9600 and other common rates",
+			Value: "19200",
+		},
+		cli.StringFlag{
+			Name:  "serial",
+			Usage: "Serial frame: data bits, parity, stop bits (8N1, 7E1), default from profile",
+		},
+		cli.StringFlag{
+			Name:  "image",
+			Usage: "Image command: bitmap (DC2 *) or raster (GS v 0), default from profile",
+		},
+		cli.StringFlag{
+			Name:  "quality",
+			Usage: "Print quality: draft (fast, light), normal or dark (slow), default normal",
+		},
+		cli.StringFlag{
+			Name:  "media",
+			Usage: "Paper: receipt or label (gap / black mark stock), default from profile",
+		},
+		cli.StringFlag{
+			Name:  "page-length",
+			Usage: "Page of pre-printed roll stock a form feed feeds to: 70mm, 24lines or dots, default from profile",
+		},
+		cli.StringFlag{
+			Name:  "feed-mode",
+			Usage: "Line feeds: lf, lines (ESC d) or dots (ESC J) for clones which misfeed, default auto (ESC d on firmware 2.64+) or from profile",
+		},
+		cli.StringFlag{
+			Name:  "cut-mode",
+			Usage: "Cuts: full, partial or none (feed to the tear bar), default auto (as the job asks) or from profile",
+		},
+		cli.BoolFlag{
+			Name:  "no-cut",
+			Usage: "Printer with a tear bar, feed the paper to it instead of cutting (--cut-mode none)",
+		},
+		cli.IntFlag{
+			Name:  "band-height",
+			Usage: "Dot rows of the image bands, default the ones which fit in the printer buffer or from profile",
+		},
+		cli.StringFlag{
+			Name:  "band-wait",
+			Usage: "Wait between image bands: status (GS r reply), delay (print time) or none, default from profile",
+		},
+		cli.StringFlag{
+			Name:  "before-job",
+			Usage: "Shell command before every job, exiting with an error refuses the job (the till is closed), default from config",
+		},
+		cli.StringFlag{
+			Name:  "after-job",
+			Usage: "Shell command after every job, $GOTP_ERROR is the error of a failed one, default from config",
+		},
+		cli.IntFlag{
+			Name:  "columns",
+			Usage: "Characters per line of font A at normal size (32 on 58 mm, 42 or 48 on 80 mm), default from profile and width",
+		},
+		cli.IntFlag{
+			Name:  "read-timeout",
+			Usage: "Serial read timeout in milliseconds for status queries, default from profile",
+		},
+		cli.IntFlag{
+			Name:  "timeout",
+			Usage: "Seconds a command (each job of serve) may take, a blocked printer port fails it (0 - no limit)",
+		},
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "Also render the printed receipt: pdf:receipt.pdf or png:receipt.png",
+		},
+		cli.StringFlag{
+			Name:   "state",
+			Usage:  "Directory for the last job and other state",
+			Value:  filepath.Join(homeDir(), ".gotp"),
+			EnvVar: "GOTP_STATE",
+		},
+		cli.IntFlag{
+			Name:  "history",
+			Usage: "Number of printed jobs kept for reprint",
+			Value: 20,
+		},
+		cli.IntFlag{
+			Name:  "fetch-limit",
+			Usage: "KiB a model or image from an http(s) URL may have",
+			Value: 4096,
+		},
+		cli.IntFlag{
+			Name:  "fetch-age",
+			Usage: "Seconds a model or image from an http(s) URL is printed from <state>/cache before it is checked again",
+			Value: 300,
+		},
+	}
+	app.Before = func(c *cli.Context) error {
+		if c.GlobalString("printer") == "-" {
+			streamToStdout()
+		}
+		fetch.Default.Dir = filepath.Join(c.GlobalString("state"), "cache")
+		fetch.Default.MaxSize = int64(c.GlobalInt("fetch-limit")) << 10
+		fetch.Default.MaxAge = time.Duration(c.GlobalInt("fetch-age")) * time.Second
+		return nil
+	}
+
+	app.Run(os.Args)
+	endJobs()
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/escpos"
+)
+
+// exit codes
+const (
+	exitError    = 1
+	exitUsage    = 2
+	exitNoDevice = 3
+	exitEncode   = 4
+	exitPaperOut = 5
+	exitTemplate = 6
+	exitTimeout  = 7
+	exitRefused  = 8
+)
+
+// exitCode - code of the first error reported, the process exits with it
+var exitCode int
+
+// stdout - results, messages and errors of the commands, stderr when the
+// ESC/POS stream of --printer - goes to stdout
+var stdout io.Writer = os.Stdout
+
+// streamToStdout - the printer stream goes to stdout, everything else to
+// stderr
+func streamToStdout() {
+	stdout = os.Stderr
+}
+
+// errorCode - exit code of err
+func errorCode(err error) int {
+	switch {
+	case errors.Is(err, escpos.ErrNoDevice):
+		return exitNoDevice
+	case errors.Is(err, escpos.ErrEncode):
+		return exitEncode
+	case errors.Is(err, escpos.ErrPaperOut):
+		return exitPaperOut
+	case errors.Is(err, escpos.ErrTimeout):
+		return exitTimeout
+	case errors.Is(err, escpos.ErrRefused):
+		return exitRefused
+	}
+	return exitError
+}
+
+// jsonOutput - --json flag, results and errors as JSON on stdout
+func jsonOutput(c *cli.Context) bool {
+	return c.GlobalBool("json")
+}
+
+// verbose - --verbose flag, off with --json so stdout stays JSON
+func verbose(c *cli.Context) bool {
+	return c.GlobalBool("verbose") && !jsonOutput(c)
+}
+
+// printJSON - v as indented JSON on stdout, see stdout
+func printJSON(v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		setExit(exitError)
+		return
+	}
+	fmt.Fprintln(stdout, string(b))
+}
+
+// setExit - exit with code unless an earlier error set one
+func setExit(code int) {
+	if exitCode == 0 {
+		exitCode = code
+	}
+}
+
+// printError - report err, {"error": "...", "code": n} with --json; the
+// process exits with the code of err after the command
+func printError(c *cli.Context, err error) {
+	fail(c, errorCode(err), err)
+}
+
+// fail - report err and exit with code after the command
+func fail(c *cli.Context, code int, err error) {
+	setExit(code)
+	if jsonOutput(c) {
+		printJSON(map[string]interface{}{"error": err.Error(), "code": code})
+		return
+	}
+	fmt.Fprintln(stdout, err)
+}
+
+// usage - report wrong arguments of a command
+func usage(c *cli.Context, text string) {
+	fail(c, exitUsage, fmt.Errorf("Usage: %s", text))
+}
+
+// checkPrinted - report the error of a job printed on p
+func checkPrinted(c *cli.Context, p *escpos.Escpos) {
+	if err := p.Err(); err != nil {
+		printError(c, err)
+	}
+}
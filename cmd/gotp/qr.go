@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/models"
+)
+
+var cmdQr = cli.Command{
+	Name:   "qr",
+	Usage:  "Print a QR code of a WiFi network, contact, link or payment (qr wifi ssid=Cafe password=secret)",
+	Action: runQr,
+	Flags: append([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "payload",
+			Usage: "write the QR code text to stdout instead of printing it",
+		},
+		cli.IntFlag{
+			Name:  "size",
+			Usage: "module size 1..16",
+			Value: 6,
+		},
+		cli.StringFlag{
+			Name:  "ecc",
+			Usage: "error correction L|M|Q|H",
+			Value: "M",
+		},
+		cli.StringFlag{
+			Name:  "caption",
+			Usage: "text printed under the code",
+		},
+	}, copyFlags...),
+}
+
+// qrFirst - field of a value given without key=, qr url example.com
+var qrFirst = map[string]string{
+	"wifi":   "ssid",
+	"vcard":  "name",
+	"url":    "url",
+	"crypto": "address",
+}
+
+// keyValues - key=value arguments, first is the key of a value given
+// without key= as the first argument (none when empty)
+func keyValues(args []string, first string) (map[string]string, error) {
+	fields := map[string]string{}
+	for i, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) == 2 && len(kv[0]) > 0 {
+			fields[kv[0]] = kv[1]
+			continue
+		}
+		if len(first) > 0 && i == 0 {
+			fields[first] = arg
+			continue
+		}
+		return nil, fmt.Errorf("key=value expected, not %q", arg)
+	}
+	return fields, nil
+}
+
+func runQr(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "qr "+strings.Join(models.QrKinds, "|")+" key=value...")
+		return
+	}
+	kind := c.Args().First()
+	fields, err := keyValues(c.Args().Tail(), qrFirst[kind])
+	if err != nil {
+		usage(c, fmt.Sprintf("qr %s key=value... (%s)", kind, err.Error()))
+		return
+	}
+	payload, err := models.QrPayload(kind, fields)
+	if err != nil {
+		fail(c, exitUsage, err)
+		return
+	}
+	if c.Bool("payload") {
+		fmt.Fprintln(stdout, payload)
+		return
+	}
+	if c.Int("size") < 1 || c.Int("size") > 16 {
+		usage(c, "qr --size 1..16")
+		return
+	}
+	ecc := strings.ToUpper(c.String("ecc"))
+	if !strings.Contains("LMQH", ecc) || len(ecc) != 1 {
+		usage(c, "qr --ecc L|M|Q|H")
+		return
+	}
+	rows := []models.Printer{{Type: "qrcode", QrCode: true, Align: "center", Text: payload,
+		QrSize: uint8(c.Int("size")), QrEcc: ecc}}
+	if caption := c.String("caption"); len(caption) > 0 {
+		rows = append(rows, models.Printer{Align: "center", Text: caption, Wrap: true})
+	}
+	res := models.PrinterLine{Version: models.ModelVersion,
+		Sections: []models.Section{{Name: "qr " + kind, Feed: 2, Rows: rows}}}
+	p := printer(c)
+	begin(c, p)
+	p.PrintCopies(&res, copies(c), c.String("banner"))
+	checkPrinted(c, p)
+	saveJob(c, res)
+	writeOutput(c)
+}
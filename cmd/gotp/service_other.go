@@ -0,0 +1,26 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/server"
+)
+
+// runAsService - false, only Windows has the service manager; systemd
+// runs serve as it is
+func runAsService(c *cli.Context, srv *server.Server, listen func()) bool {
+	return false
+}
+
+// installService - Windows only, see service_windows.go
+func installService(args []string) error {
+	return fmt.Errorf("Service: Windows only, use a systemd unit")
+}
+
+// removeService - Windows only, see service_windows.go
+func removeService() error {
+	return fmt.Errorf("Service: Windows only, use a systemd unit")
+}
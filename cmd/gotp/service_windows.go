@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/server"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceName - name of the Windows service of serve
+const serviceName = "gotp"
+
+// shutdownTimeout - wait for the receipt printing when the service stops,
+// the service manager gives up on a service in about 20 seconds
+const shutdownTimeout = 15 * time.Second
+
+// service - serve under the Windows service control manager
+type service struct {
+	srv    *server.Server
+	listen func()
+}
+
+// Execute - run listen until the service manager stops the service
+func (s *service) Execute(args []string, r <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	done := make(chan struct{})
+	go func() {
+		s.listen()
+		close(done)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case req := <-r:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+				s.srv.Shutdown(ctx)
+				cancel()
+				<-done
+				return false, 0
+			}
+		case <-done:
+			// listen failed
+			return false, 1
+		}
+	}
+}
+
+// runAsService - run listen of srv as the Windows service when the
+// service manager started gotp, the output goes to <state>/serve.log;
+// false when gotp runs in a console
+func runAsService(c *cli.Context, srv *server.Server, listen func()) bool {
+	ok, err := svc.IsWindowsService()
+	if err != nil || !ok {
+		return false
+	}
+	state := c.GlobalString("state")
+	if err := os.MkdirAll(state, 0755); err == nil {
+		if f, err := os.OpenFile(filepath.Join(state, "serve.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err == nil {
+			os.Stdout = f
+			os.Stderr = f
+		}
+	}
+	if err := svc.Run(serviceName, &service{srv: srv, listen: listen}); err != nil {
+		printError(c, fmt.Errorf("Service: %s", err.Error()))
+	}
+	return true
+}
+
+// installService - register the service running gotp with args, it
+// starts with Windows
+func installService(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("Service: %s", err.Error())
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("Service: %s", err.Error())
+	}
+	defer m.Disconnect()
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return fmt.Errorf("Service %s is installed already", serviceName)
+	}
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "gotp print server",
+		Description: "Prints the receipts posted to gotp serve",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("Service: %s", err.Error())
+	}
+	return s.Close()
+}
+
+// removeService - unregister the service, it stops when running
+func removeService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("Service: %s", err.Error())
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("Service %s is not installed", serviceName)
+	}
+	defer s.Close()
+	s.Control(svc.Stop)
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("Service: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/counter"
+)
+
+var cmdTicket = cli.Command{
+	Name:   "ticket",
+	Usage:  "Print a queue ticket with the next number (ticket [message]), ticket --call serves the next one",
+	Action: runTicket,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "seq",
+			Usage: "counter of the numbers, see gotp seq",
+			Value: "ticket",
+		},
+		cli.StringFlag{
+			Name:  "prefix",
+			Usage: "letter of the queue before the number, A042",
+		},
+		cli.IntFlag{
+			Name:  "digits",
+			Usage: "digits of the number",
+			Value: 3,
+		},
+		cli.BoolFlag{
+			Name:  "daily",
+			Usage: "numbers start from 1 every day",
+		},
+		cli.StringFlag{
+			Name:  "title",
+			Usage: "line on top of the ticket, the shop or the desk",
+		},
+		cli.StringFlag{
+			Name:  "time",
+			Usage: "layout of the time of the ticket (empty - none)",
+			Value: "02.01.2006 15:04",
+		},
+		cli.Float64Flag{
+			Name:  "minutes",
+			Usage: "minutes a customer takes, prints the people ahead and the estimated wait (0 - none)",
+		},
+		cli.StringFlag{
+			Name:  "cut",
+			Usage: "cut after the ticket, full or partial (empty - none)",
+			Value: "partial",
+		},
+		cli.BoolFlag{
+			Name:  "call",
+			Usage: "serve the next ticket instead of printing one, its number is written to stdout",
+		},
+	},
+}
+
+// ticketSeq - counters of the issued and the served tickets
+func ticketSeq(c *cli.Context, now time.Time) (issued, served string) {
+	issued = c.String("seq")
+	if c.Bool("daily") {
+		issued += "-" + now.Format("20060102")
+	}
+	return issued, issued + ".served"
+}
+
+// ticketLast - last number of counter name, 0 before the first one
+func ticketLast(store *counter.Store, name string) (int64, error) {
+	seq, err := store.Get()
+	if err != nil {
+		return 0, err
+	}
+	if s, ok := seq[name]; ok {
+		return s.Last, nil
+	}
+	return 0, nil
+}
+
+func runTicket(c *cli.Context) {
+	if c.Int("digits") < 1 || c.Int("digits") > 9 {
+		usage(c, "ticket --digits 1..9")
+		return
+	}
+	now := time.Now()
+	issuedSeq, servedSeq := ticketSeq(c, now)
+	store := counters(c)
+	if c.Bool("call") {
+		callTicket(c, store, issuedSeq, servedSeq)
+		return
+	}
+	served, err := ticketLast(store, servedSeq)
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	n, err := store.Next(issuedSeq)
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	number := fmt.Sprintf("%s%0*d", c.String("prefix"), c.Int("digits"), n)
+
+	p := printer(c)
+	begin(c, p)
+	p.SetAlign("center")
+	if title := c.String("title"); len(title) > 0 {
+		p.SetBold(true)
+		p.WriteText(p.WordWrap(title))
+		p.Linefeed()
+		p.SetBold(false)
+	}
+	p.Feed(1)
+	if err := p.Banner(number); err != nil {
+		printError(c, err)
+	}
+	p.Feed(1)
+	if layout := c.String("time"); len(layout) > 0 {
+		p.WriteText(now.Format(layout))
+		p.Linefeed()
+	}
+	if minutes := c.Float64("minutes"); minutes > 0 {
+		ahead := n - served - 1
+		if ahead < 0 {
+			ahead = 0
+		}
+		p.WriteText(fmt.Sprintf("%d ahead of you, about %d min", ahead, int64(float64(ahead)*minutes+0.5)))
+		p.Linefeed()
+	}
+	if c.Args().Present() {
+		p.Feed(1)
+		p.WriteText(p.WordWrap(c.Args().First()))
+		p.Linefeed()
+	}
+	p.SetAlign("left")
+	p.Feed(3)
+	if len(c.String("cut")) > 0 {
+		p.CutMode(c.String("cut"))
+	}
+	if err := p.Err(); err != nil {
+		// the number of a ticket nobody got is a gap
+		store.Void(issuedSeq, n)
+		printError(c, err)
+		return
+	}
+	if jsonOutput(c) {
+		printJSON(map[string]interface{}{"ticket": number, "ahead": n - served - 1})
+	} else if verbose(c) {
+		fmt.Fprintln(stdout, "Ticket:", number)
+	}
+	writeOutput(c)
+}
+
+// callTicket - serve the next issued ticket, none when all of them
+// were served
+func callTicket(c *cli.Context, store *counter.Store, issuedSeq, servedSeq string) {
+	issued, err := ticketLast(store, issuedSeq)
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	served, err := ticketLast(store, servedSeq)
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	if served >= issued {
+		printError(c, fmt.Errorf("Ticket: nobody is waiting, last ticket %d", issued))
+		return
+	}
+	n, err := store.Next(servedSeq)
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	number := fmt.Sprintf("%s%0*d", c.String("prefix"), c.Int("digits"), n)
+	if jsonOutput(c) {
+		printJSON(map[string]interface{}{"ticket": number, "waiting": issued - n})
+		return
+	}
+	fmt.Fprintln(stdout, number)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/escpos"
+)
+
+var cmdTop = cli.Command{
+	Name:   "top",
+	Usage:  "Monitor a running serve: queue, job progress, printer status and recent errors",
+	Action: runTop,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "url",
+			Usage: "address of serve: host:port, http://host:port or unix:///run/gotp.sock",
+			Value: "localhost:8080",
+		},
+		cli.IntFlag{
+			Name:  "interval",
+			Usage: "seconds between refreshes",
+			Value: 1,
+		},
+		cli.BoolFlag{
+			Name:  "once",
+			Usage: "print the status once and exit",
+		},
+	},
+}
+
+// topStatus - GET /status of serve
+type topStatus struct {
+	Printing    bool           `json:"printing"`
+	Job         int32          `json:"job"`
+	Section     string         `json:"section"`
+	Percent     int            `json:"percent"`
+	Printer     string         `json:"printer"`
+	Down        bool           `json:"down"`
+	Status      *escpos.Status `json:"status"`
+	StatusError string         `json:"statusError"`
+	Printers    []topStatus    `json:"printers"`
+	Queued      int            `json:"queued"`
+	Errors      []struct {
+		Time  time.Time `json:"time"`
+		Error string    `json:"error"`
+	} `json:"errors"`
+}
+
+// statusClient - client and URL of GET /status of serve at addr
+func statusClient(addr string) (*http.Client, string) {
+	if strings.HasPrefix(addr, "unix://") {
+		path := strings.TrimPrefix(addr, "unix://")
+		return &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}}, "http://unix/status"
+	}
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+		addr = "http://" + addr
+	}
+	return &http.Client{Timeout: 5 * time.Second}, strings.TrimSuffix(addr, "/") + "/status"
+}
+
+// getStatus - status of serve
+func getStatus(client *http.Client, url string) (st topStatus, err error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return st, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return st, fmt.Errorf("Status: %s", res.Status)
+	}
+	err = json.NewDecoder(res.Body).Decode(&st)
+	return st, err
+}
+
+// statusFlags - the status of a printer in words
+func statusFlags(st *escpos.Status, reason string) string {
+	if st == nil {
+		if len(reason) > 0 {
+			return "unknown (" + reason + ")"
+		}
+		return "-"
+	}
+	var flags []string
+	if st.Online {
+		flags = append(flags, "online")
+	} else {
+		flags = append(flags, "OFFLINE")
+	}
+	for _, f := range []struct {
+		on   bool
+		name string
+	}{
+		{st.PaperOut, "PAPER OUT"},
+		{st.PaperNearEnd, "paper low"},
+		{st.CoverOpen, "COVER OPEN"},
+		{st.CutterError, "CUTTER ERROR"},
+		{st.Error, "ERROR"},
+		{st.LowVoltage, "low voltage"},
+		{st.DrawerOpen, "drawer open"},
+	} {
+		if f.on {
+			flags = append(flags, f.name)
+		}
+	}
+	if st.Temperature != nil {
+		flags = append(flags, fmt.Sprintf("%.0f°C", *st.Temperature))
+	}
+	return strings.Join(flags, ", ")
+}
+
+// progressBar - percent as a bar of width characters
+func progressBar(percent, width int) string {
+	n := percent * width / 100
+	return "[" + strings.Repeat("#", n) + strings.Repeat("-", width-n) + "]"
+}
+
+// topScreen - one frame of top
+func topScreen(addr string, st topStatus, err error) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "gotp top - %s - %s\n\n", addr, time.Now().Format("15:04:05"))
+	if err != nil {
+		fmt.Fprintf(&b, "Not connected: %s\n", err)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Queue: %d waiting\n\n", st.Queued)
+	printers := st.Printers
+	if len(printers) == 0 {
+		printers = []topStatus{st}
+	}
+	w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PRINTER\tJOB\tPROGRESS\tSTATUS")
+	for _, p := range printers {
+		name := p.Printer
+		if len(name) == 0 {
+			name = "printer"
+		}
+		if p.Down {
+			name += " (down)"
+		}
+		job, progress := "idle", ""
+		if p.Printing {
+			job = fmt.Sprintf("#%d", p.Job)
+			progress = fmt.Sprintf("%s %3d%% %s", progressBar(p.Percent, 20), p.Percent, p.Section)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, job, progress, statusFlags(p.Status, p.StatusError))
+	}
+	w.Flush()
+	if len(st.Errors) > 0 {
+		fmt.Fprintf(&b, "\nRecent errors\n")
+		for _, e := range st.Errors {
+			fmt.Fprintf(&b, "%s  %s\n", e.Time.Local().Format("01-02 15:04:05"), e.Error)
+		}
+	}
+	return b.String()
+}
+
+func runTop(c *cli.Context) {
+	addr := c.String("url")
+	client, url := statusClient(addr)
+	if c.Bool("once") {
+		st, err := getStatus(client, url)
+		if err != nil {
+			printError(c, err)
+			return
+		}
+		if jsonOutput(c) {
+			printJSON(st)
+			return
+		}
+		fmt.Print(topScreen(addr, st, nil))
+		return
+	}
+	interval := time.Duration(c.Int("interval")) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+	// the alternate screen without cursor, restored on ^C
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		st, err := getStatus(client, url)
+		fmt.Print("\x1b[H\x1b[2J" + topScreen(addr, st, err))
+		select {
+		case <-t.C:
+		case <-stop:
+			fmt.Print("\x1b[?25h\x1b[?1049l")
+			return
+		}
+	}
+}
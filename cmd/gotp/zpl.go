@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/grengojbo/gotp/zpl"
+)
+
+var cmdZpl = cli.Command{
+	Name:   "zpl",
+	Usage:  "Print the labels of a Zebra ZPL file, the ^FO ^FT ^FD ^A ^B* ^BQ ^PQ subset (experimental)",
+	Action: runZpl,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "raster",
+			Usage: "print the labels as images also on printers with page mode",
+		},
+		cli.BoolFlag{
+			Name:  "model",
+			Usage: "write the label models to stdout instead of printing them",
+		},
+	},
+}
+
+func runZpl(c *cli.Context) {
+	if !c.Args().Present() {
+		usage(c, "zpl <file.zpl|->")
+		return
+	}
+	var b []byte
+	var err error
+	if name := c.Args().First(); name == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		printError(c, err)
+		return
+	}
+	labels, err := zpl.Parse(string(b))
+	if err != nil {
+		fail(c, exitUsage, err)
+		return
+	}
+	if c.Bool("model") {
+		for _, l := range labels {
+			printJSON(l.Model)
+		}
+		return
+	}
+	p := printer(c)
+	begin(c, p)
+	for _, l := range labels {
+		if len(l.Ignored) > 0 && verbose(c) {
+			fmt.Fprintln(stdout, "ZPL: ignored", strings.Join(l.Ignored, " "))
+		}
+		if err := printLabel(p, &l.Model, l.Copies, c.Bool("raster")); err != nil {
+			printError(c, err)
+			return
+		}
+		saveJob(c, l.Model)
+	}
+	checkPrinted(c, p)
+	writeOutput(c)
+}
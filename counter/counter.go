@@ -0,0 +1,179 @@
+package counter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sequence - state of one named counter
+type Sequence struct {
+	Last int64 `json:"last"`
+	// Gaps - numbers issued for jobs which did not print
+	Gaps []int64 `json:"gaps,omitempty"`
+}
+
+// Store - counters kept in a JSON file, safe for several processes
+type Store struct {
+	file string
+	mu   sync.Mutex
+}
+
+// Open - counters stored in file
+func Open(file string) *Store {
+	return &Store{file: file}
+}
+
+// lock - exclusive lock file next to the store, stale locks are removed
+func (s *Store) lock() (func(), error) {
+	name := s.file + ".lock"
+	for i := 0; i < 100; i++ {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if st, err := os.Stat(name); err == nil && time.Since(st.ModTime()) > 30*time.Second {
+			os.Remove(name)
+			continue
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil, fmt.Errorf("Counter: can't lock %s", s.file)
+}
+
+func (s *Store) load() (map[string]*Sequence, error) {
+	res := map[string]*Sequence{}
+	b, err := ioutil.ReadFile(s.file)
+	if os.IsNotExist(err) {
+		return res, nil
+	} else if err != nil {
+		return res, fmt.Errorf("Counter: %s", err.Error())
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return res, fmt.Errorf("Counter %s: %s", s.file, err.Error())
+	}
+	return res, nil
+}
+
+// save - write to a temp file and rename, a crash never leaves a half file
+func (s *Store) save(seq map[string]*Sequence) error {
+	b, err := json.MarshalIndent(seq, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Counter: %s", err.Error())
+	}
+	tmp := s.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("Counter: %s", err.Error())
+	}
+	if err := os.Rename(tmp, s.file); err != nil {
+		return fmt.Errorf("Counter: %s", err.Error())
+	}
+	return nil
+}
+
+// update - run fn on the counters under lock and save the result
+func (s *Store) update(fn func(map[string]*Sequence) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	seq, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(seq); err != nil {
+		return err
+	}
+	return s.save(seq)
+}
+
+// Next - next number of counter name, the first number is 1
+func (s *Store) Next(name string) (n int64, err error) {
+	err = s.update(func(seq map[string]*Sequence) error {
+		c, ok := seq[name]
+		if !ok {
+			c = &Sequence{}
+			seq[name] = c
+		}
+		c.Last++
+		n = c.Last
+		return nil
+	})
+	return n, err
+}
+
+// Void - record numbers of counter name as gaps
+func (s *Store) Void(name string, numbers ...int64) error {
+	return s.update(func(seq map[string]*Sequence) error {
+		c, ok := seq[name]
+		if !ok {
+			return fmt.Errorf("Counter: no sequence %s", name)
+		}
+		c.Gaps = append(c.Gaps, numbers...)
+		sort.Slice(c.Gaps, func(i, j int) bool { return c.Gaps[i] < c.Gaps[j] })
+		return nil
+	})
+}
+
+// Reset - set counter name to value (the next number is value+1) and
+// forget its gaps
+func (s *Store) Reset(name string, value int64) error {
+	return s.update(func(seq map[string]*Sequence) error {
+		seq[name] = &Sequence{Last: value}
+		return nil
+	})
+}
+
+// Get - counters by name
+func (s *Store) Get() (map[string]*Sequence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Tracker - numbers issued for one job
+type Tracker struct {
+	store  *Store
+	mu     sync.Mutex
+	issued map[string][]int64
+}
+
+// Job - tracker for numbers used by one job
+func (s *Store) Job() *Tracker {
+	return &Tracker{store: s, issued: map[string][]int64{}}
+}
+
+// Seq - template function {{seq "receipt"}}
+func (t *Tracker) Seq(name string) (int64, error) {
+	n, err := t.store.Next(name)
+	if err != nil {
+		return 0, err
+	}
+	t.mu.Lock()
+	t.issued[name] = append(t.issued[name], n)
+	t.mu.Unlock()
+	return n, nil
+}
+
+// Done - finish job, when it failed its numbers are recorded as gaps
+func (t *Tracker) Done(jobErr error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if jobErr != nil {
+		for name, numbers := range t.issued {
+			if err := t.store.Void(name, numbers...); err != nil {
+				return err
+			}
+		}
+	}
+	t.issued = map[string][]int64{}
+	return nil
+}
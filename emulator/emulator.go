@@ -0,0 +1,231 @@
+// Package emulator - virtual ESC/POS printer: point-of-sale software
+// prints to it over TCP as to a network printer, each job is rendered
+// like the render package previews gotp's own output, so receipts are
+// checked without hardware
+package emulator
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grengojbo/gotp/escpos"
+	"github.com/grengojbo/gotp/render"
+)
+
+// Job - the stream of one job and its rendered pages
+type Job struct {
+	// ID - jobs in the order they were received, from 1
+	ID     int
+	Remote string
+	Start  time.Time
+	Data   []byte
+	Pages  []*image.Gray
+}
+
+// Emulator - printer listening for raw ESC/POS jobs, a job ends when the
+// client closes the connection or sends nothing for Idle; it answers the
+// status queries (DLE EOT, GS r, GS I, GS a) of a ready printer
+type Emulator struct {
+	// Log - hexdump of the commands received, see escpos.Hexdump (nil -
+	// none)
+	Log io.Writer
+	// Job - called with every received job, one at a time
+	Job func(j Job)
+	// Idle - a connection without data this long ends its job, the next
+	// data is another one (0 - the job ends with the connection)
+	Idle time.Duration
+	// Firmware - version reported by GS I 65, "2.68" when empty
+	Firmware string
+	// PaperOut - report the paper out, for the error handling of the
+	// client
+	PaperOut bool
+
+	mu   sync.Mutex
+	jobs int
+}
+
+// ListenAndServe - accept jobs on the TCP address addr, ":9100" the port
+// of network printers
+func (em *Emulator) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("Listen: %s", err.Error())
+	}
+	defer l.Close()
+	return em.Serve(l)
+}
+
+// Serve - accept jobs on l, every connection is served by its own
+// goroutine
+func (em *Emulator) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return fmt.Errorf("Accept: %s", err.Error())
+		}
+		go em.serve(conn)
+	}
+}
+
+// conn - state of one connection
+type conn struct {
+	em    *Emulator
+	c     net.Conn
+	job   []byte
+	start time.Time
+	dump  *escpos.Hexdump
+	// pending - bytes of a command not received complete
+	pending []byte
+}
+
+// serve - jobs of c until it is closed
+func (em *Emulator) serve(c net.Conn) {
+	defer c.Close()
+	s := &conn{em: em, c: c}
+	buf := make([]byte, 4096)
+	for {
+		if em.Idle > 0 {
+			c.SetReadDeadline(time.Now().Add(em.Idle))
+		}
+		n, err := c.Read(buf)
+		if n > 0 {
+			s.received(buf[:n])
+		}
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			s.end()
+			continue
+		}
+		if err != nil {
+			break
+		}
+	}
+	s.end()
+}
+
+// received - data of the job, the queries in it are answered
+func (s *conn) received(data []byte) {
+	if len(s.job) == 0 {
+		s.start = time.Now()
+		if s.em.Log != nil {
+			s.em.mu.Lock()
+			fmt.Fprintf(s.em.Log, "Job from %s\n", s.c.RemoteAddr())
+			s.em.mu.Unlock()
+			s.dump = escpos.NewHexdump(lockedWriter{s.em})
+		}
+	}
+	s.job = append(s.job, data...)
+	if s.dump != nil {
+		s.dump.Write(data)
+	}
+	s.pending = append(s.pending, data...)
+	i := 0
+	for i < len(s.pending) {
+		l := escpos.CommandLen(s.pending[i:])
+		if i+l > len(s.pending) {
+			break
+		}
+		if reply := s.em.reply(s.pending[i : i+l]); len(reply) > 0 {
+			s.c.Write(reply)
+		}
+		i += l
+	}
+	s.pending = append(s.pending[:0], s.pending[i:]...)
+}
+
+// end - the job received so far is done
+func (s *conn) end() {
+	if len(s.job) == 0 {
+		return
+	}
+	if s.dump != nil {
+		s.dump.Flush()
+		s.dump = nil
+	}
+	s.em.mu.Lock()
+	defer s.em.mu.Unlock()
+	s.em.jobs++
+	j := Job{ID: s.em.jobs, Remote: s.c.RemoteAddr().String(), Start: s.start, Data: s.job,
+		Pages: render.Render(s.job)}
+	s.job, s.pending = nil, nil
+	if s.em.Job != nil {
+		s.em.Job(j)
+	}
+}
+
+// lockedWriter - Log of the emulator, the lines of connections printing
+// at the same time don't mix
+type lockedWriter struct {
+	em *Emulator
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.em.mu.Lock()
+	defer w.em.mu.Unlock()
+	return w.em.Log.Write(p)
+}
+
+// reply - answer of a printer to command c, nil for the commands which
+// don't have one
+func (em *Emulator) reply(c []byte) []byte {
+	if len(c) < 3 {
+		return nil
+	}
+	switch {
+	case c[0] == 16 && c[1] == 4:
+		// DLE EOT n: bits 1 and 4 always set
+		b := byte(0x12)
+		if em.PaperOut {
+			switch c[2] {
+			case 2:
+				// printing stopped by the paper end
+				b |= 0x20
+			case 4:
+				b |= 0x6C
+			}
+		}
+		return []byte{b}
+	case c[0] == 29 && c[1] == 'r':
+		// GS r n: paper sensor status
+		if em.PaperOut && (c[2] == 0 || c[2] == 1 || c[2] == '1') {
+			return []byte{0x0F}
+		}
+		return []byte{0}
+	case c[0] == 29 && c[1] == 'I':
+		return em.info(c[2])
+	case c[0] == 29 && c[1] == 'a' && c[2] != 0:
+		// GS a n: the first ASB packet, bit 4 set
+		p := []byte{0x10, 0, 0, 0}
+		if em.PaperOut {
+			p[2] = 0x0F
+		}
+		return p
+	}
+	return nil
+}
+
+// info - reply of GS I n, the printer ID and the information strings
+func (em *Emulator) info(n byte) []byte {
+	firmware := em.Firmware
+	if len(firmware) == 0 {
+		firmware = "2.68"
+	}
+	text := map[byte]string{65: firmware, 66: "gotp", 67: "emulator", 68: "0"}
+	switch {
+	case n == 1 || n == 49:
+		// model ID
+		return []byte{0x20}
+	case n == 2 || n == 50:
+		// type ID: no multi byte characters, cutter, no DM-D
+		return []byte{0x02}
+	case n == 3 || n == 51:
+		return []byte{0x01}
+	}
+	if s, ok := text[n]; ok {
+		return append([]byte("_"+s), 0)
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package escpos
+
+import (
+	"fmt"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// azEcc - error correction percent of the levels, Aztec recommends 23 %
+var azEcc = map[string]int{"L": 10, "M": 23, "Q": 36, "H": 50}
+
+// azWordSize - code word bits by the number of layers, 0 is the mode
+// message
+var azWordSize = [33]int{4, 6, 6, 8, 8, 8, 8, 8, 8, 10, 10, 10, 10, 10, 10, 10, 10,
+	10, 10, 10, 10, 10, 10, 12, 12, 12, 12, 12, 12, 12, 12, 12, 12}
+
+// azPolynomials - primitive polynomials of the Galois fields by code word
+// bits
+var azPolynomials = map[int]int{4: 0x13, 6: 0x43, 8: 0x12D, 10: 0x409, 12: 0x1069}
+
+// azBits - bits of a symbol being built, most significant first
+type azBits []bool
+
+func (b *azBits) put(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>uint(i)&1 != 0)
+	}
+}
+
+// AztecRaster - Aztec code of data in binary shift mode with error
+// correction ecc (L 10 %, M 23 %, Q 36 %, H 50 %), size dots per module.
+// Printers without GS ( k Aztec print it as an image
+func AztecRaster(data string, size uint8, ecc string) (*Raster, error) {
+	m, err := azEncode([]byte(data), ecc)
+	if err != nil {
+		return nil, err
+	}
+	return moduleRaster(m, int(size)), nil
+}
+
+// azLayerBits - bits of layers of a compact or full range symbol
+func azLayerBits(layers int, compact bool) int {
+	n := 112
+	if compact {
+		n = 88
+	}
+	return (n + 16*layers) * layers
+}
+
+// azStuff - bits in words of n bits, a word of all ones or all zeros but
+// the last bit gets that bit inverted and the bit moves to the next word
+func azStuff(bits azBits, n int) azBits {
+	var out azBits
+	mask := 1<<uint(n) - 2
+	for i := 0; i < len(bits); i += n {
+		word := 0
+		for j := 0; j < n; j++ {
+			if i+j >= len(bits) || bits[i+j] {
+				word |= 1 << uint(n-1-j)
+			}
+		}
+		switch {
+		case word&mask == mask:
+			out.put(word&mask, n)
+			i--
+		case word&mask == 0:
+			out.put(word|1, n)
+			i--
+		default:
+			out.put(word, n)
+		}
+	}
+	return out
+}
+
+// azCheckWords - bits words of n bits followed by the Reed-Solomon words
+// filling total bits, the remainder of total padded at the start
+func azCheckWords(bits azBits, total, n int) azBits {
+	words := make([]int, len(bits)/n)
+	for i, b := range bits[:len(words)*n] {
+		if b {
+			words[i/n] |= 1 << uint(n-1-i%n)
+		}
+	}
+	f := newGaloisField(n, azPolynomials[n])
+	words = append(words, f.checkWords(words, total/n-len(words))...)
+	var out azBits
+	out.put(0, total%n)
+	for _, w := range words {
+		out.put(w, n)
+	}
+	return out
+}
+
+// galoisField - GF(2^n) of Reed-Solomon code words
+type galoisField struct {
+	exp, log []int
+}
+
+func newGaloisField(n, poly int) *galoisField {
+	size := 1 << uint(n)
+	f := &galoisField{exp: make([]int, size), log: make([]int, size)}
+	x := 1
+	for i := 0; i < size; i++ {
+		f.exp[i] = x
+		x <<= 1
+		if x >= size {
+			x = (x ^ poly) & (size - 1)
+		}
+	}
+	for i := 0; i < size-1; i++ {
+		f.log[f.exp[i]] = i
+	}
+	return f
+}
+
+func (f *galoisField) mul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return f.exp[(f.log[a]+f.log[b])%(len(f.exp)-1)]
+}
+
+// checkWords - n Reed-Solomon words of data, generator roots 2^1 .. 2^n
+func (f *galoisField) checkWords(data []int, n int) []int {
+	gen := []int{1}
+	for i := 1; i <= n; i++ {
+		next := make([]int, len(gen)+1)
+		root := f.exp[i%(len(f.exp)-1)]
+		for j, c := range gen {
+			next[j] ^= c
+			next[j+1] ^= f.mul(c, root)
+		}
+		gen = next
+	}
+	res := make([]int, n)
+	for _, d := range data {
+		factor := d ^ res[0]
+		copy(res, res[1:])
+		res[n-1] = 0
+		for i := range res {
+			res[i] ^= f.mul(gen[i+1], factor)
+		}
+	}
+	return res
+}
+
+// azEncode - modules of the smallest symbol holding data
+func azEncode(data []byte, ecc string) ([][]bool, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("Aztec code: no data")
+	}
+	percent, ok := azEcc[ecc]
+	if !ok {
+		percent = azEcc["M"]
+	}
+	// binary shift from the upper mode, at most 2078 bytes at a time
+	var bits azBits
+	for rest := data; len(rest) > 0; {
+		n := len(rest)
+		if n > 2078 {
+			n = 2078
+		}
+		for i := 0; i < n; i++ {
+			if i == 0 || i == 31 && n <= 62 {
+				bits.put(31, 5)
+				switch {
+				case n > 62:
+					bits.put(n-31, 16)
+				case i == 0 && n > 31:
+					bits.put(31, 5)
+				case i == 0:
+					bits.put(n, 5)
+				default:
+					bits.put(n-31, 5)
+				}
+			}
+			bits.put(int(rest[i]), 8)
+		}
+		rest = rest[n:]
+	}
+
+	eccBits := len(bits)*percent/100 + 11
+	var layers, n, total int
+	var compact bool
+	var stuffed azBits
+	for i := 0; ; i++ {
+		if i > 32 {
+			return nil, fmt.Errorf("Aztec code: %d bytes do not fit in a symbol", len(data))
+		}
+		compact = i <= 3
+		layers = i
+		if compact {
+			layers = i + 1
+		}
+		total = azLayerBits(layers, compact)
+		if len(bits)+eccBits > total {
+			continue
+		}
+		if stuffed == nil || n != azWordSize[layers] {
+			n = azWordSize[layers]
+			stuffed = azStuff(bits, n)
+		}
+		if compact && len(stuffed) > n*64 {
+			continue
+		}
+		if len(stuffed)+eccBits <= total-total%n {
+			break
+		}
+	}
+	message := azCheckWords(stuffed, total, n)
+	var mode azBits
+	if compact {
+		mode.put(layers-1, 2)
+		mode.put(len(stuffed)/n-1, 6)
+		mode = azCheckWords(mode, 28, 4)
+	} else {
+		mode.put(layers-1, 5)
+		mode.put(len(stuffed)/n-1, 11)
+		mode = azCheckWords(mode, 40, 4)
+	}
+
+	base := 14 + layers*4
+	if compact {
+		base = 11 + layers*4
+	}
+	// module positions skipping the reference grid of full range symbols
+	align := make([]int, base)
+	size := base
+	if compact {
+		for i := range align {
+			align[i] = i
+		}
+	} else {
+		size = base + 1 + 2*((base/2-1)/15)
+		for i := 0; i < base/2; i++ {
+			offset := i + i/15
+			align[base/2-i-1] = size/2 - offset - 1
+			align[base/2+i] = size/2 + offset + 1
+		}
+	}
+	m := make([][]bool, size)
+	for i := range m {
+		m[i] = make([]bool, size)
+	}
+	// set - dark module of column x, row y
+	set := func(x, y int) { m[y][x] = true }
+
+	// data layers from the outside in, counterclockwise
+	for i, row := 0, 0; i < layers; i++ {
+		side := (layers-i)*4 + 12
+		if compact {
+			side = (layers-i)*4 + 9
+		}
+		low, high := i*2, base-1-i*2
+		for j := 0; j < side; j++ {
+			col := j * 2
+			for k := 0; k < 2; k++ {
+				if message[row+col+k] {
+					set(align[low+k], align[low+j])
+				}
+				if message[row+side*2+col+k] {
+					set(align[low+j], align[high-k])
+				}
+				if message[row+side*4+col+k] {
+					set(align[high-k], align[high-j])
+				}
+				if message[row+side*6+col+k] {
+					set(align[high-j], align[low+k])
+				}
+			}
+		}
+		row += side * 8
+	}
+
+	// mode message around the bull's eye
+	c := size / 2
+	if compact {
+		for i := 0; i < 7; i++ {
+			offset := c - 3 + i
+			if mode[i] {
+				set(offset, c-5)
+			}
+			if mode[i+7] {
+				set(c+5, offset)
+			}
+			if mode[20-i] {
+				set(offset, c+5)
+			}
+			if mode[27-i] {
+				set(c-5, offset)
+			}
+		}
+	} else {
+		for i := 0; i < 10; i++ {
+			offset := c - 5 + i + i/5
+			if mode[i] {
+				set(offset, c-7)
+			}
+			if mode[i+10] {
+				set(c+7, offset)
+			}
+			if mode[29-i] {
+				set(offset, c+7)
+			}
+			if mode[39-i] {
+				set(c-7, offset)
+			}
+		}
+	}
+
+	// bull's eye, orientation marks and the reference grid
+	eye := 7
+	if compact {
+		eye = 5
+	}
+	for i := 0; i < eye; i += 2 {
+		for j := c - i; j <= c+i; j++ {
+			set(j, c-i)
+			set(j, c+i)
+			set(c-i, j)
+			set(c+i, j)
+		}
+	}
+	set(c-eye, c-eye)
+	set(c-eye+1, c-eye)
+	set(c-eye, c-eye+1)
+	set(c+eye, c-eye)
+	set(c+eye, c-eye+1)
+	set(c+eye, c+eye-1)
+	if !compact {
+		for i, j := 0, 0; i < base/2-1; i, j = i+15, j+16 {
+			for k := c & 1; k < size; k += 2 {
+				set(c-j, k)
+				set(c+j, k)
+				set(k, c-j)
+				set(k, c+j)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Aztec - print an Aztec code, opt.QrSize module size 1..16 (GS ( k takes
+// 2..16), opt.QrEcc L/M/Q/H; printers which don't have it in
+// Profile.Symbols print it as an image
+func (e *Escpos) Aztec(opt models.BarCodeOption, data string) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func Aztec()\n")
+	}
+	size := opt.QrSize
+	if size == 0 {
+		size = 6
+	} else if size > 16 {
+		size = 16
+	}
+	if sc, ok := e.symbols("aztec"); ok && size >= 2 {
+		percent, ok := azEcc[opt.QrEcc]
+		if !ok {
+			percent = azEcc["M"]
+		}
+		e.WriteRaw(sc.Aztec(data, size, uint8(percent)))
+		e.prevByte = ASCIILF
+		e.Feed(1)
+		return
+	}
+	r, err := AztecRaster(data, size, opt.QrEcc)
+	if err != nil {
+		e.err = err
+		return
+	}
+	e.printRaster(r.Align(e.align, e.dots))
+	e.Feed(1)
+}
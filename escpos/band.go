@@ -0,0 +1,92 @@
+package escpos
+
+import (
+	"time"
+)
+
+// bitmapBuffer - bytes of the printer buffer a DC2 * band fits in
+// without Profile.BufferSize, the one of the Adafruit firmware
+const bitmapBuffer = 256
+
+// rasterBuffer - bytes of the printer buffer a GS v 0 band fits in
+// without Profile.BufferSize
+const rasterBuffer = 4096
+
+// bandTimeout - longest wait for the printer to get through a band
+const bandTimeout = 10 * time.Second
+
+// bandRows - dot rows of the bands of an image of rowBytes a row:
+// Profile.BandHeight or the rows which fit in Profile.BufferSize, buffer
+// without one
+func (e *Escpos) bandRows(rowBytes, buffer int) int {
+	if rowBytes < 1 {
+		rowBytes = 1
+	}
+	n := e.profile.BandHeight
+	if n <= 0 {
+		if e.profile.BufferSize > 0 {
+			buffer = e.profile.BufferSize
+		}
+		n = buffer / rowBytes
+	}
+	if n > int(e.maxChunkHeight) {
+		n = int(e.maxChunkHeight)
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// bandWait - flow control after the image band sent: with
+// Profile.BandWait "status" until the printer answers GS r after it (the
+// print time of the band when it doesn't), "delay" the print time of the
+// band; otherwise only the pacing of the port waits
+func (e *Escpos) bandWait(band []byte) {
+	switch e.profile.BandWait {
+	case "status":
+		if e.src != nil && !e.Debug {
+			prev := e.readTimeout
+			e.readTimeout = bandTimeout
+			_, err := e.queryByte([]byte{29, 'r', 1})
+			e.readTimeout = prev
+			if err == nil {
+				// the printer is through the band
+				e.hold(0)
+				return
+			}
+		}
+		e.bandDelay(band)
+	case "delay":
+		e.bandDelay(band)
+	}
+}
+
+// bandDelay - wait the print time of band, the pacing of a serial port
+// also counts its transfer
+func (e *Escpos) bandDelay(band []byte) {
+	if e.Debug || e.dst == nil {
+		return
+	}
+	if e.pacer != nil {
+		e.drain()
+		return
+	}
+	// the transfer isn't paced, only the print time
+	t := (&pacingWriter{e: e}).cost(band) - int64(len(band))*e.byteTime
+	time.Sleep(time.Duration(t) * time.Microsecond)
+}
+
+// hasImage - data has an image command: DC2 *, GS v 0, ESC * or ESC GS S
+func hasImage(data []byte) bool {
+	for i := 0; i+1 < len(data); i += CommandLen(data[i:]) {
+		c, d := data[i], data[i+1]
+		switch {
+		case c == 18 && d == '*', c == 29 && d == 'v', c == 27 && d == '*':
+			return true
+		case c == 27 && d == 29 && i+2 < len(data) && data[i+2] == 'S':
+			return true
+		}
+	}
+	return false
+}
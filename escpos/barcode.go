@@ -0,0 +1,206 @@
+package escpos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// barCodeWide - wide element dots of the GS w narrow ones of CODE39, I25,
+// CODEBAR, CODE11 and MSI
+var barCodeWide = map[int]int{1: 3, 2: 5, 3: 8, 4: 10, 5: 13, 6: 15}
+
+// BarCodeDots - width of a GS k bar code of data with module dots
+// modules (narrow elements), the quiet zones left out; an estimate at
+// most a few modules wide of the printed one
+func BarCodeDots(code, data string, module int) int {
+	n, w := module, barCodeWide[module]
+	if w == 0 {
+		w = (5*n + 1) / 2
+	}
+	l := len(data)
+	switch code {
+	case "UPC_A", "UPCA", "EAN13":
+		return 95 * n
+	case "UPC_E", "UPCE":
+		return 51 * n
+	case "EAN8":
+		return 67 * n
+	case "CODE93":
+		return ((l+4)*9 + 1) * n
+	case "CODE128":
+		return code128Modules(data) * n
+	case "I25":
+		l += l % 2
+		return (3*l+6)*n + (2*l+1)*w
+	case "CODEBAR":
+		return l * (5*n + 3*w)
+	case "CODE11":
+		// start, stop and 2 check characters
+		return (l + 4) * (5*n + 2*w)
+	case "MSI":
+		return (l+1)*(4*n+4*w) + 3*n + 2*w
+	}
+	// CODE39 with its start and stop, also the bar code of unknown types
+	return (l+2)*(7*n+3*w) - n
+}
+
+// fitBarCode - module width of the bar code from the one set with
+// SetBarcodeWidth down to 2 until it fits the paper, 0 when it doesn't
+func (e *Escpos) fitBarCode(code, data string) uint8 {
+	for w := e.barcodeWidth; w >= 2; w-- {
+		if BarCodeDots(code, data, int(w)) <= e.dots {
+			return w
+		}
+	}
+	return 0
+}
+
+// wideBarCode - print a bar code too wide for GS w 2: a CODE128 as an
+// image of 1 dot modules when that fits, else rotated
+func (e *Escpos) wideBarCode(code, data string) error {
+	if code == CODE128 {
+		r, hri, _ := barCodeRaster(code, data, 1, int(e.barcodeHeight))
+		if r.Width <= e.dots {
+			e.rasterBarCode(r, hri)
+			return nil
+		}
+	}
+	return e.ladderBarCode(code, data)
+}
+
+// barCodeRaster - bars and HRI text of the bar codes encoded here
+// (CODE128, the GS1 ones, ITF14), module dots modules; nil for the other
+// types
+func barCodeRaster(code, data string, module, height int) (*Raster, string, error) {
+	switch {
+	case code == CODE128:
+		chars := code128Chars(data)
+		var hri []byte
+		for _, c := range chars {
+			if c >= 32 {
+				hri = append(hri, byte(c))
+			}
+		}
+		return barRaster(code128Widths(code128Values(code128Parts(chars))), module, height), string(hri), nil
+	case gs1Codes[code]:
+		els, err := ParseGS1(data)
+		if err != nil {
+			return nil, "", err
+		}
+		return barRaster(code128Widths(code128Values(code128Parts(gs1Code128(els)))), module, height), gs1HRIText(els), nil
+	case code == ITF14:
+		digits, err := ITF14Digits(data)
+		if err != nil {
+			return nil, "", err
+		}
+		return ITF14Raster(digits, module, height), itf14HRI(digits), nil
+	}
+	return nil, "", nil
+}
+
+// rasterBarCode - print the bars of r with the HRI text above or below
+// them as set with BarcodeChr
+func (e *Escpos) rasterBarCode(r *Raster, hri string) {
+	if e.barcodeHRI&1 != 0 {
+		e.printHRI(hri)
+	}
+	e.printRaster(r.Align(e.align, e.dots))
+	if e.barcodeHRI&2 != 0 {
+		e.printHRI(hri)
+	}
+}
+
+// printHRI - HRI text of a bar code printed as an image or a custom
+// one, wrapped at spaces, in the style of SetHRIStyle
+func (e *Escpos) printHRI(text string) {
+	st := e.Style()
+	for _, s := range strings.Fields(e.hriStyle) {
+		if s == "bold" {
+			st.Bold = true
+		} else if s == "small" {
+			st.Small = true
+		}
+	}
+	e.WithStyle(st, func() {
+		for _, line := range strings.Split(e.WordWrap(text), "\n") {
+			e.WriteText(line)
+			e.Linefeed()
+		}
+	})
+}
+
+// HRIFormat - data in format: every # is the next character of data,
+// the data left after the last one follows; EAN and UPC data without
+// its check digit gets it first. "#### #### #### #" groups an EAN13
+func HRIFormat(code, format, data string) string {
+	switch {
+	case (code == "EAN13" && len(data) == 12) || (code == "EAN8" && len(data) == 7) ||
+		((code == "UPC_A" || code == "UPCA") && len(data) == 11):
+		data += string(GS1CheckDigit(data))
+	}
+	var b strings.Builder
+	i := 0
+	for _, c := range format {
+		if c == '#' {
+			if i < len(data) {
+				b.WriteByte(data[i])
+				i++
+			}
+			continue
+		}
+		b.WriteRune(c)
+	}
+	if i < len(data) {
+		b.WriteString(data[i:])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ladderBarCode - print a bar code rotated 90 degrees, its bars along
+// the paper: the ones of barCodeRaster as an image, the others in page
+// mode; the bar code height is its width on the paper
+func (e *Escpos) ladderBarCode(code, data string) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func ladderBarCode()\n")
+	}
+	module := int(e.barcodeWidth)
+	r, hri, err := barCodeRaster(code, data, module, int(e.barcodeHeight))
+	if err != nil {
+		return err
+	}
+	if r != nil {
+		e.rasterBarCode(r.Rotate(), hri)
+		return nil
+	}
+	if _, err := e.pageCommands(); err != nil || e.page || e.frame {
+		return fmt.Errorf("Bar code %s: rotated needs page mode", code)
+	}
+	// the HRI lines beside the bars
+	width, base := int(e.barcodeHeight), int(e.barcodeHeight)
+	if e.barcodeHRI&1 != 0 {
+		width += 24
+		base += 24
+	}
+	if e.barcodeHRI&2 != 0 {
+		width += 24
+	}
+	if width > e.dots {
+		return fmt.Errorf("Bar code %s: %d dots high, the paper has %d", code, width, e.dots)
+	}
+	quiet := 10 * module
+	length := BarCodeDots(code, data, module) + 2*quiet
+	x := 0
+	switch e.align {
+	case "center", "C":
+		x = (e.dots - width) / 2
+	case "right", "R":
+		x = e.dots - width
+	}
+	if err := e.BeginPage(e.dots, length); err != nil {
+		return err
+	}
+	e.SetPageArea(x, 0, width, length, 90)
+	e.PageMove(quiet, base)
+	e.WriteRaw(e.cmd.BarCode(code, data, e.barCodeParams()))
+	return e.PrintPage()
+}
@@ -0,0 +1,124 @@
+package escpos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// calibrateLines - text lines of the print workload of Calibrate
+const calibrateLines = 8
+
+// calibrateFeed - dot rows of the feed workload of Calibrate, 30 mm
+const calibrateFeed = 240
+
+// calibrateWait - longest wait for the printer to get through a workload
+const calibrateWait = 30 * time.Second
+
+// defaultFeedTime - microseconds to feed a dot row without a measured one
+const defaultFeedTime = 2100
+
+// Timing - microseconds to print and to feed a dot row measured by
+// Calibrate, DotPrintTime of the normal quality preset
+type Timing struct {
+	DotPrintTime int64 `json:"dot_print_time"`
+	DotFeedTime  int64 `json:"dot_feed_time"`
+}
+
+// Calibrate - measure the pacing of the printer: a blank feed and lines
+// of text are sent at once and timed to the reply of GS r after them, the
+// printer answers it when it got there (it prints while the bytes arrive),
+// less the round trip of GS r alone. The printer is paced with the timing
+// from then on, Profile.DotPrintTime and DotFeedTime keep it. Prints
+// about 60 mm, printers which can't reply fail
+func (e *Escpos) Calibrate() (t Timing, err error) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func Calibrate()\n")
+	}
+	if e.src == nil {
+		return t, fmt.Errorf("Calibrate: the printer can't reply")
+	}
+	prev := e.readTimeout
+	e.readTimeout = calibrateWait
+	defer func() { e.readTimeout = prev }()
+	e.drain()
+	if e.pacer != nil {
+		// the workloads go out at once, GS r times them
+		e.pacer.off = true
+		defer func() { e.pacer.off = false }()
+	}
+
+	rtt, err := e.drainTime(nil)
+	if err != nil {
+		return t, fmt.Errorf("Calibrate: %w", err)
+	}
+	feed, err := e.drainTime(e.cmd.FeedDots(calibrateFeed))
+	if err != nil {
+		return t, fmt.Errorf("Calibrate: %w", err)
+	}
+	t.DotFeedTime = int64((feed-rtt)/time.Microsecond) / calibrateFeed
+	line := strings.Repeat("#", int(e.maxColumn)) + "\n"
+	printed, err := e.drainTime([]byte(strings.Repeat(line, calibrateLines)))
+	if err != nil {
+		return t, fmt.Errorf("Calibrate: %w", err)
+	}
+	// the line spacing of every line is fed
+	spacing := calibrateLines * e.lineSpacing * t.DotFeedTime
+	t.DotPrintTime = (int64((printed-rtt)/time.Microsecond) - spacing) / (calibrateLines * 24)
+	if t.DotFeedTime <= 0 || t.DotPrintTime <= 0 {
+		return t, fmt.Errorf("Calibrate: the printer replied before it printed")
+	}
+	// of the normal preset, the others scale with it
+	normal, _ := FindQuality("normal")
+	t.DotPrintTime = t.DotPrintTime * normal.DotPrintTime / e.quality.DotPrintTime
+	return t, e.SetTiming(t)
+}
+
+// drainTime - time from sending data unpaced to the reply of GS r after
+// it
+func (e *Escpos) drainTime(data []byte) (time.Duration, error) {
+	start := time.Now()
+	if len(data) > 0 {
+		if _, err := e.send(data); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := e.queryByte([]byte{29, 'r', 1}); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// SetTiming - pace the printer with t of Calibrate instead of the
+// estimates, 0 keeps the estimate
+func (e *Escpos) SetTiming(t Timing) error {
+	if t.DotPrintTime < 0 || t.DotPrintTime > 1000000 || t.DotFeedTime < 0 || t.DotFeedTime > 1000000 {
+		return fmt.Errorf("Invalid timing: %d/%d us", t.DotPrintTime, t.DotFeedTime)
+	}
+	e.profile.DotPrintTime, e.profile.DotFeedTime = t.DotPrintTime, t.DotFeedTime
+	e.dotPrintTime, e.dotFeedTime = e.printTime(e.quality), e.feedTime()
+	return nil
+}
+
+// Timing - pacing of Profile.DotPrintTime and DotFeedTime, 0 are estimates
+func (e *Escpos) Timing() Timing {
+	return Timing{DotPrintTime: e.profile.DotPrintTime, DotFeedTime: e.profile.DotFeedTime}
+}
+
+// printTime - dotPrintTime of q, the measured one of the normal preset
+// scaled to it
+func (e *Escpos) printTime(q Quality) int64 {
+	if e.profile.DotPrintTime <= 0 {
+		return q.DotPrintTime
+	}
+	normal, _ := FindQuality("normal")
+	return e.profile.DotPrintTime * q.DotPrintTime / normal.DotPrintTime
+}
+
+// feedTime - dotFeedTime, the measured one or defaultFeedTime
+func (e *Escpos) feedTime() int64 {
+	if e.profile.DotFeedTime <= 0 {
+		return defaultFeedTime
+	}
+	return e.profile.DotFeedTime
+}
@@ -0,0 +1,230 @@
+package escpos
+
+import (
+	"strings"
+)
+
+// code128FNC1 - function 1 among the characters of a Code 128 symbol
+const code128FNC1 = -1
+
+// code128Patterns - bar and space widths of the Code 128 values, bar
+// first; 103..105 are the start codes A, B and C, 106 the stop
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+// code128Part - characters of a symbol in one code set: ASCII in A and
+// B, values of digit pairs in C, code128FNC1 in any of them
+type code128Part struct {
+	set   byte
+	chars []int
+}
+
+// code128Digits - digits of s from i
+func code128Digits(s []int, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] >= '0' && s[i+n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// code128Parts - s in the code sets of the shortest symbol: C for runs
+// of 4 and more digits, A for control characters, B for the others
+func code128Parts(s []int) []code128Part {
+	var parts []code128Part
+	add := func(set byte, c int) {
+		if len(parts) == 0 || parts[len(parts)-1].set != set {
+			parts = append(parts, code128Part{set: set})
+		}
+		parts[len(parts)-1].chars = append(parts[len(parts)-1].chars, c)
+	}
+	set := byte(0)
+	for i := 0; i < len(s); {
+		c := s[i]
+		if n := code128Digits(s, i); n >= 4 || (set == 'C' && n >= 2) {
+			if n%2 == 1 && set != 'C' && set != 0 {
+				// the odd digit before the run in the current set
+				add(set, c)
+				i++
+				n--
+			}
+			set = 'C'
+			for ; n >= 2; n -= 2 {
+				add(set, int(s[i]-'0')*10+int(s[i+1]-'0'))
+				i += 2
+			}
+			continue
+		}
+		switch {
+		case c == code128FNC1:
+			if set == 0 {
+				set = 'B'
+				if n := code128Digits(s, i+1); n >= 4 {
+					set = 'C'
+				}
+			}
+		case c < 32:
+			set = 'A'
+		case c >= 96 || set != 'A':
+			set = 'B'
+		}
+		add(set, c)
+		i++
+	}
+	return parts
+}
+
+// code128Values - start code, values of parts, check value and stop
+func code128Values(parts []code128Part) []int {
+	if len(parts) == 0 {
+		return nil
+	}
+	start := map[byte]int{'A': 103, 'B': 104, 'C': 105}
+	shift := map[byte]int{'A': 101, 'B': 100, 'C': 99}
+	res := []int{start[parts[0].set]}
+	for i, p := range parts {
+		if i > 0 {
+			res = append(res, shift[p.set])
+		}
+		for _, c := range p.chars {
+			switch {
+			case c == code128FNC1:
+				res = append(res, 102)
+			case p.set == 'C':
+				res = append(res, c)
+			case c < 32:
+				res = append(res, c+64)
+			default:
+				res = append(res, c-32)
+			}
+		}
+	}
+	sum := res[0]
+	for i, v := range res[1:] {
+		sum += (i + 1) * v
+	}
+	return append(res, sum%103, 106)
+}
+
+// code128Widths - bar and space widths of values, the quiet zones left
+// to the margins
+func code128Widths(values []int) string {
+	var b strings.Builder
+	for _, v := range values {
+		b.WriteString(code128Patterns[v])
+	}
+	return b.String()
+}
+
+// code128Escpos - GS k CODE128 data of parts: {A, {B or {C before the
+// characters of a code set, {1 for FNC1 and values of digit pairs in C
+func code128Escpos(parts []code128Part) []byte {
+	var res []byte
+	for _, p := range parts {
+		res = append(res, '{', p.set)
+		for _, c := range p.chars {
+			switch {
+			case c == code128FNC1:
+				res = append(res, '{', '1')
+			case c == '{' && p.set != 'C':
+				res = append(res, '{', '{')
+			default:
+				res = append(res, byte(c))
+			}
+		}
+	}
+	return res
+}
+
+// barRaster - bars of widths (bar, space, bar, ... in modules), module
+// dots per module, height dots high
+func barRaster(widths string, module, height int) *Raster {
+	if module < 1 {
+		module = 1
+	}
+	dots := make([]int, len(widths))
+	for i, c := range widths {
+		dots[i] = int(c-'0') * module
+	}
+	return barsRaster(dots, height)
+}
+
+// barsRaster - bars of widths in dots (bar, space, bar, ...), height
+// dots high
+func barsRaster(widths []int, height int) *Raster {
+	w := 0
+	for _, n := range widths {
+		w += n
+	}
+	r := &Raster{Width: w, Height: height}
+	r.Data = make([]byte, r.RowBytes()*height)
+	x := 0
+	for i, n := range widths {
+		if i%2 == 0 {
+			r.fill(x, 0, n, 1)
+		}
+		x += n
+	}
+	for y := 1; y < height; y++ {
+		copy(r.Data[y*r.RowBytes():(y+1)*r.RowBytes()], r.Data[:r.RowBytes()])
+	}
+	return r
+}
+
+// code128Chars - characters of GS k CODE128 data: {A, {B and {C select
+// the code set of the next ones (values of digit pairs in C), {1 is FNC1
+// and {{ a {; data without them is ASCII
+func code128Chars(data string) []int {
+	var res []int
+	set := byte('B')
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c == '{' && i+1 < len(data) {
+			i++
+			switch data[i] {
+			case '1':
+				res = append(res, code128FNC1)
+			case '{':
+				res = append(res, '{')
+			case 'A', 'B', 'C':
+				set = data[i]
+			}
+			continue
+		}
+		if set == 'C' {
+			res = append(res, '0'+int(c)/10%10, '0'+int(c)%10)
+			continue
+		}
+		res = append(res, int(c))
+	}
+	return res
+}
+
+// code128Modules - modules of a GS k CODE128 of data, every character
+// and code set selection one symbol
+func code128Modules(data string) int {
+	values := 0
+	for i := 0; i < len(data); i++ {
+		if data[i] == '{' && i+1 < len(data) {
+			i++
+		}
+		values++
+	}
+	if !strings.HasPrefix(data, "{") {
+		// start code
+		values++
+	}
+	// check value and stop
+	return 11*(values+1) + 13
+}
@@ -0,0 +1,93 @@
+package escpos
+
+import (
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// CodePage - character table selected with ESC t
+type CodePage struct {
+	Name        string           `json:"name"`
+	Number      byte             `json:"number"`
+	Charmap     *charmap.Charmap `json:"-"`
+	Description string           `json:"description"`
+}
+
+// CodePages - code pages SetCodePage accepts, ESC t numbers of the
+// Adafruit / CSN-A2 firmware
+var CodePages = []CodePage{
+	{"PC437", 0, charmap.CodePage437, "USA, Standard Europe"},
+	{"PC850", 2, charmap.CodePage850, "Multilingual, Western Europe"},
+	{"PC860", 3, charmap.CodePage860, "Portuguese"},
+	{"PC863", 4, charmap.CodePage863, "Canadian-French"},
+	{"PC865", 5, charmap.CodePage865, "Nordic"},
+	{"CP1251", 6, charmap.Windows1251, "Windows Cyrillic"},
+	{"PC866", 7, charmap.CodePage866, "Cyrillic #2"},
+	{"PC862", 15, charmap.CodePage862, "Hebrew"},
+	{"CP1252", 16, charmap.Windows1252, "Windows Latin 1"},
+	{"CP1253", 17, charmap.Windows1253, "Windows Greek"},
+	{"PC852", 18, charmap.CodePage852, "Latin 2"},
+	{"PC858", 19, charmap.CodePage858, "Multilingual Latin 1 + Euro"},
+	{"ISO8859-1", 23, charmap.ISO8859_1, "Latin 1"},
+	{"CP1257", 25, charmap.Windows1257, "Windows Baltic"},
+	{"PC855", 28, charmap.CodePage855, "Cyrillic"},
+	{"CP1250", 30, charmap.Windows1250, "Windows Central Europe"},
+	{"CP1254", 32, charmap.Windows1254, "Windows Turkish"},
+	{"CP1255", 33, charmap.Windows1255, "Windows Hebrew"},
+	{"CP1256", 34, charmap.Windows1256, "Windows Arabic"},
+	{"CP1258", 35, charmap.Windows1258, "Windows Vietnamese"},
+	{"ISO8859-2", 36, charmap.ISO8859_2, "Latin 2"},
+	{"ISO8859-3", 37, charmap.ISO8859_3, "Latin 3"},
+	{"ISO8859-4", 38, charmap.ISO8859_4, "Baltic"},
+	{"ISO8859-5", 39, charmap.ISO8859_5, "Cyrillic"},
+	{"ISO8859-6", 40, charmap.ISO8859_6, "Arabic"},
+	{"ISO8859-7", 41, charmap.ISO8859_7, "Greek"},
+	{"ISO8859-8", 42, charmap.ISO8859_8, "Hebrew"},
+	{"ISO8859-9", 43, charmap.ISO8859_9, "Turkish"},
+	{"ISO8859-15", 44, charmap.ISO8859_15, "Latin 9"},
+	{"CP874", 47, charmap.Windows874, "Thai"},
+}
+
+// FindCodePage - code page by name
+func FindCodePage(name string) (CodePage, bool) {
+	for _, cp := range CodePages {
+		if cp.Name == name {
+			return cp, true
+		}
+	}
+	return CodePage{}, false
+}
+
+// PrintCodePageSample - print name and the upper half (0x80-0xFF) of
+// code page cp as the firmware renders it
+func (e *Escpos) PrintCodePageSample(cp CodePage) {
+	e.SetCodePage(cp.Name)
+	e.SetBold(true)
+	e.Write(cp.Name)
+	e.Linefeed()
+	e.SetBold(false)
+	for row := 0x80; row < 0x100; row += 16 {
+		line := make([]byte, 0, 32)
+		for c := row; c < row+16; c++ {
+			line = append(line, byte(c), ' ')
+		}
+		e.WriteRaw(line)
+		e.Linefeed()
+	}
+}
+
+// supported - s with the characters the code page doesn't have as "?"
+func (e *Escpos) supported(s string) string {
+	if _, err := e.enc.String(s); err == nil {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if _, err := e.enc.String(string(r)); err != nil {
+			r = '?'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
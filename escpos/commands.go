@@ -0,0 +1,454 @@
+package escpos
+
+import (
+	"strings"
+)
+
+// BarCodeParams - bar code settings sent with the symbol
+type BarCodeParams struct {
+	// HRI - 0 none, 1 above, 2 below, 3 both
+	HRI    uint8
+	Height uint8
+	// Width - module width 2..6
+	Width uint8
+	// Legacy - firmware before 2.64, types 0-10 and NUL terminated data
+	Legacy bool
+	// Font - HRI font 0 (A) or 1 (B, small)
+	Font uint8
+}
+
+// CommandSet - byte sequences of a printer command language, Escpos
+// builds every job from these
+type CommandSet interface {
+	Name() string
+	Init() []byte
+	// Align - 0 left, 1 center, 2 right
+	Align(a byte) []byte
+	Bold(on bool) []byte
+	Underline(n uint8) []byte
+	Reverse(on bool) []byte
+	// Font - 0 font A, 1 font B (small), 2 font C
+	Font(n uint8) []byte
+	// Small - Font 1 or 0
+	Small(on bool) []byte
+	DoubleHeight(on bool) []byte
+	// Size - character width and height multiples 1..8
+	Size(width, height uint8) []byte
+	FeedLines(n uint8) []byte
+	FeedDots(n uint8) []byte
+	// LineSpacing - line feed of n dots, character height included
+	LineSpacing(n uint8) []byte
+	// MoveX - print position x dots from the left margin, or from the
+	// current position when relative
+	MoveX(x int, relative bool) []byte
+	Cut(partial bool) []byte
+	Drawer() []byte
+	Beep(n uint8) []byte
+	// CodePage - select cp, false when the printer doesn't have it
+	CodePage(cp CodePage) ([]byte, bool)
+	BarCode(code string, data string, p BarCodeParams) []byte
+	// QrCode - model 2 symbol, size module dots, ecc L/M/Q/H
+	QrCode(data string, size uint8, ecc string) []byte
+	// BitImage - rows of rowBytes bytes, MSB left, 1 = black
+	BitImage(rowBytes, rows int, data []byte) []byte
+	// Raster - BitImage with the standard raster command of the printer
+	Raster(rowBytes, rows int, data []byte) []byte
+	// ColumnImage - up to 24 raster rows as a line of column graphics,
+	// line feed included
+	ColumnImage(rowBytes, rows int, data []byte) []byte
+	// FeedMark - feed label or black mark stock to the next print start
+	FeedMark() []byte
+	// MarkOffset - print start (or cut position) dots after the mark,
+	// false when the printer can't set it
+	MarkOffset(cut bool, dots int) ([]byte, bool)
+}
+
+// CommandSets - command sets by Profile.Commands name
+var CommandSets = map[string]CommandSet{
+	"escpos": EscposCommands{},
+	"star":   StarCommands{},
+}
+
+// FindCommandSet - command set by name, empty name is escpos
+func FindCommandSet(name string) (CommandSet, bool) {
+	if len(name) == 0 {
+		name = "escpos"
+	}
+	c, ok := CommandSets[name]
+	return c, ok
+}
+
+// flag - 1 for true
+func flag(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+// EscposCommands - ESC/POS with the Adafruit / CSN-A2 extensions
+type EscposCommands struct{}
+
+// Name - "escpos"
+func (EscposCommands) Name() string { return "escpos" }
+
+// Init - ESC @
+func (EscposCommands) Init() []byte { return []byte{27, '@'} }
+
+// Align - ESC a n
+func (EscposCommands) Align(a byte) []byte { return []byte{27, 'a', a} }
+
+// Bold - ESC E n
+func (EscposCommands) Bold(on bool) []byte { return []byte{27, 'E', flag(on)} }
+
+// Underline - ESC - n, n 0..2 dots thick
+func (EscposCommands) Underline(n uint8) []byte { return []byte{27, '-', n} }
+
+// Reverse - GS B n
+func (EscposCommands) Reverse(on bool) []byte { return []byte{29, 'B', flag(on)} }
+
+// Font - ESC M n
+func (EscposCommands) Font(n uint8) []byte { return []byte{27, 'M', n} }
+
+// Small - ESC M 1, font B
+func (c EscposCommands) Small(on bool) []byte { return c.Font(flag(on)) }
+
+// DoubleHeight - GS ! 1, the width normal
+func (EscposCommands) DoubleHeight(on bool) []byte { return []byte{29, '!', flag(on)} }
+
+// Size - GS ! n
+func (EscposCommands) Size(width, height uint8) []byte {
+	return []byte{29, 33, (width-1)<<4 | (height - 1)}
+}
+
+// FeedLines - ESC d n
+func (EscposCommands) FeedLines(n uint8) []byte { return []byte{27, 'd', n} }
+
+// FeedDots - ESC J n
+func (EscposCommands) FeedDots(n uint8) []byte { return []byte{27, 74, n} }
+
+// LineSpacing - ESC 3 n
+func (EscposCommands) LineSpacing(n uint8) []byte { return []byte{27, '3', n} }
+
+// MoveX - ESC $ nL nH, ESC \ nL nH
+func (EscposCommands) MoveX(x int, relative bool) []byte {
+	if relative {
+		x &= 0xFFFF
+		return []byte{27, '\\', byte(x % 256), byte(x / 256)}
+	}
+	return []byte{27, '$', byte(x % 256), byte(x / 256)}
+}
+
+// Cut - GS V A 0 / GS V B 0, feed to the cutter and cut
+func (EscposCommands) Cut(partial bool) []byte {
+	if partial {
+		return []byte("\x1DVB0")
+	}
+	return []byte("\x1DVA0")
+}
+
+// Drawer - ESC p 0, pulse pin 2
+func (EscposCommands) Drawer() []byte { return []byte("\x1B\x70\x00\x0A\xFF") }
+
+// Beep - ESC B n t
+func (EscposCommands) Beep(n uint8) []byte { return []byte{27, 66, n, 2} }
+
+// CodePage - ESC t n
+func (EscposCommands) CodePage(cp CodePage) ([]byte, bool) {
+	return []byte{27, 't', cp.Number}, true
+}
+
+// BarCode - GS H, GS h, GS w and GS k, types numbered from 65 with a
+// length byte, or 0-10 NUL terminated for Legacy and CODE11 / MSI
+func (EscposCommands) BarCode(code string, data string, p BarCodeParams) []byte {
+	a, ok := barCodeTypes[code]
+	if !ok {
+		a = barCodeTypes["CODE39"]
+	}
+	res := []byte{29, 0x48, p.HRI, 29, 0x68, p.Height, 29, 0x77, p.Width}
+	if p.Font > 0 {
+		// GS f, font A again after the bar code
+		res = append(res, 29, 'f', p.Font)
+	}
+	if !p.Legacy && a <= 8 {
+		if len(data) > 255 {
+			data = data[:255]
+		}
+		res = append(res, 29, 107, a+65, byte(len(data)))
+		res = append(res, data...)
+	} else {
+		res = append(res, 29, 107, a)
+		res = append(res, data...)
+		res = append(res, 0)
+	}
+	if p.Font > 0 {
+		res = append(res, 29, 'f', 0)
+	}
+	return res
+}
+
+// qrEcc - error correction level for GS ( k <Function 169>
+var qrEcc = map[string]byte{
+	"L": 48,
+	"M": 49,
+	"Q": 50,
+	"H": 51,
+}
+
+// QrCode - GS ( k model, module size, error correction, store and print
+func (EscposCommands) QrCode(data string, size uint8, ecc string) []byte {
+	e, ok := qrEcc[ecc]
+	if !ok {
+		e = qrEcc["M"]
+	}
+	// model 2
+	res := []byte{29, 40, 107, 4, 0, 49, 65, 50, 0}
+	// module size
+	res = append(res, 29, 40, 107, 3, 0, 49, 67, size)
+	// error correction
+	res = append(res, 29, 40, 107, 3, 0, 49, 69, e)
+	// store data in the symbol storage area
+	l := len(data) + 3
+	res = append(res, 29, 40, 107, byte(l%256), byte(l/256), 49, 80, 48)
+	res = append(res, data...)
+	// print symbol
+	return append(res, 29, 40, 107, 3, 0, 49, 81, 48)
+}
+
+// BitImage - DC2 * r n
+func (EscposCommands) BitImage(rowBytes, rows int, data []byte) []byte {
+	return append([]byte{18, 42, byte(rows), byte(rowBytes)}, data...)
+}
+
+// Raster - GS v 0 m xL xH yL yH, normal density
+func (EscposCommands) Raster(rowBytes, rows int, data []byte) []byte {
+	res := []byte{29, 'v', '0', 0, byte(rowBytes % 256), byte(rowBytes / 256), byte(rows % 256), byte(rows / 256)}
+	return append(res, data...)
+}
+
+// columns - 24 dot columns of raster rows, 3 bytes per column, MSB top
+func columns(rowBytes, rows int, data []byte) []byte {
+	res := make([]byte, rowBytes*8*3)
+	for y := 0; y < rows && y < 24; y++ {
+		for x := 0; x < rowBytes*8; x++ {
+			if data[y*rowBytes+x/8]&(0x80>>uint(x%8)) != 0 {
+				res[x*3+y/8] |= 0x80 >> uint(y%8)
+			}
+		}
+	}
+	return res
+}
+
+// ColumnImage - ESC 3 24, ESC * 33 nL nH 24 dot double density, LF and
+// ESC 2 back to the default line spacing
+func (EscposCommands) ColumnImage(rowBytes, rows int, data []byte) []byte {
+	n := rowBytes * 8
+	res := []byte{27, '3', 24, 27, '*', 33, byte(n % 256), byte(n / 256)}
+	res = append(res, columns(rowBytes, rows, data)...)
+	return append(res, 10, 27, '2')
+}
+
+// FeedMark - GS FF
+func (EscposCommands) FeedMark() []byte { return []byte{29, 12} }
+
+// MarkOffset - GS ( F pL pH a m nL nH, a 1 print start, 2 cut, m 48
+// forward, 49 backward
+func (EscposCommands) MarkOffset(cut bool, dots int) ([]byte, bool) {
+	a, m := byte(1), byte(48)
+	if cut {
+		a = 2
+	}
+	if dots < 0 {
+		m, dots = 49, -dots
+	}
+	if dots > 1700 {
+		dots = 1700
+	}
+	return []byte{29, '(', 'F', 4, 0, a, m, byte(dots % 256), byte(dots / 256)}, true
+}
+
+// StarCommands - Star Micronics line mode (TSP100, TSP650, TSP700)
+type StarCommands struct{}
+
+// Name - "star"
+func (StarCommands) Name() string { return "star" }
+
+// Init - ESC @
+func (StarCommands) Init() []byte { return []byte{27, '@'} }
+
+// Align - ESC GS a n
+func (StarCommands) Align(a byte) []byte { return []byte{27, 29, 'a', a} }
+
+// Bold - ESC E / ESC F
+func (StarCommands) Bold(on bool) []byte {
+	if on {
+		return []byte{27, 'E'}
+	}
+	return []byte{27, 'F'}
+}
+
+// Underline - ESC - n
+func (StarCommands) Underline(n uint8) []byte {
+	if n > 1 {
+		n = 1
+	}
+	return []byte{27, '-', n}
+}
+
+// Reverse - ESC 4 / ESC 5, white on black
+func (StarCommands) Reverse(on bool) []byte {
+	if on {
+		return []byte{27, '4'}
+	}
+	return []byte{27, '5'}
+}
+
+// Font - ESC RS F n
+func (StarCommands) Font(n uint8) []byte { return []byte{27, 30, 'F', n} }
+
+// Small - ESC RS F 1, font B
+func (c StarCommands) Small(on bool) []byte { return c.Font(flag(on)) }
+
+// DoubleHeight - ESC h n
+func (StarCommands) DoubleHeight(on bool) []byte { return []byte{27, 'h', flag(on)} }
+
+// Size - ESC i n1 n2, height and width expansion 0..5
+func (StarCommands) Size(width, height uint8) []byte {
+	if width > 6 {
+		width = 6
+	}
+	if height > 6 {
+		height = 6
+	}
+	return []byte{27, 'i', height - 1, width - 1}
+}
+
+// FeedLines - ESC a n
+func (StarCommands) FeedLines(n uint8) []byte { return []byte{27, 'a', n} }
+
+// FeedDots - ESC I n, n/8 mm (one dot at 203 dpi)
+func (StarCommands) FeedDots(n uint8) []byte { return []byte{27, 'I', n} }
+
+// LineSpacing - ESC 3 n, n/4 mm (two dots at 203 dpi)
+func (StarCommands) LineSpacing(n uint8) []byte { return []byte{27, '3', (n + 1) / 2} }
+
+// MoveX - ESC GS A n1 n2, ESC GS R n1 n2
+func (StarCommands) MoveX(x int, relative bool) []byte {
+	if relative {
+		x &= 0xFFFF
+		return []byte{27, 29, 'R', byte(x % 256), byte(x / 256)}
+	}
+	return []byte{27, 29, 'A', byte(x % 256), byte(x / 256)}
+}
+
+// Cut - ESC d 2 / ESC d 3, feed to the cutter and cut
+func (StarCommands) Cut(partial bool) []byte {
+	if partial {
+		return []byte{27, 'd', 3}
+	}
+	return []byte{27, 'd', 2}
+}
+
+// Drawer - BEL, drawer 1
+func (StarCommands) Drawer() []byte { return []byte{7} }
+
+// Beep - ESC GS BEL m t1 t2, external buzzer 1 for 200 ms each time
+func (StarCommands) Beep(n uint8) []byte {
+	var res []byte
+	for i := uint8(0); i < n; i++ {
+		res = append(res, 27, 29, 7, 1, 10, 10)
+	}
+	return res
+}
+
+// starCodePages - ESC GS t numbers of CodePages names
+var starCodePages = map[string]byte{
+	"PC437": 1, "PC858": 4, "PC852": 5, "PC860": 6, "PC863": 8,
+	"PC865": 9, "PC866": 10, "PC855": 11, "PC862": 13, "CP874": 21,
+	"CP1252": 32, "CP1250": 33, "CP1251": 34, "CP1253": 35,
+	"CP1254": 36, "CP1257": 37,
+}
+
+// CodePage - ESC GS t n
+func (StarCommands) CodePage(cp CodePage) ([]byte, bool) {
+	n, ok := starCodePages[cp.Name]
+	if !ok {
+		return nil, false
+	}
+	return []byte{27, 29, 't', n}, true
+}
+
+// starBarCodes - ESC b types
+var starBarCodes = map[string]byte{
+	"UPC_E": 0, "UPCE": 0, "UPC_A": 1, "UPCA": 1, "EAN8": 2, "EAN13": 3,
+	"CODE39": 4, "I25": 5, "CODE128": 6, "CODE93": 7, "CODEBAR": 8,
+}
+
+// BarCode - ESC b n1 n2 n3 n4 d RS, CODE11 and MSI print as CODE39
+func (StarCommands) BarCode(code string, data string, p BarCodeParams) []byte {
+	t, ok := starBarCodes[code]
+	if !ok {
+		t = starBarCodes["CODE39"]
+	}
+	// 2 - HRI below with feed, 1 - no HRI with feed
+	hri := byte(1)
+	if p.HRI > 0 {
+		hri = 2
+	}
+	// mode 1..3 - narrow module 2..4 dots
+	mode := byte(1)
+	if p.Width > 2 {
+		mode = p.Width - 2
+	}
+	if mode > 3 {
+		mode = 3
+	}
+	res := []byte{27, 'b', t, hri, mode, p.Height}
+	res = append(res, data...)
+	return append(res, 30)
+}
+
+// QrCode - ESC GS y S model, ecc and cell size, ESC GS y D store and
+// ESC GS y P print
+func (StarCommands) QrCode(data string, size uint8, ecc string) []byte {
+	level := strings.Index("LMQH", ecc)
+	if level < 0 || len(ecc) != 1 {
+		level = 1
+	}
+	if size > 8 {
+		size = 8
+	}
+	res := []byte{27, 29, 'y', 'S', '0', 2}
+	res = append(res, 27, 29, 'y', 'S', '1', byte(level))
+	res = append(res, 27, 29, 'y', 'S', '2', size)
+	res = append(res, 27, 29, 'y', 'D', '1', 0, byte(len(data)%256), byte(len(data)/256))
+	res = append(res, data...)
+	return append(res, 27, 29, 'y', 'P')
+}
+
+// BitImage - ESC GS S m xL xH yL yH n raster
+func (StarCommands) BitImage(rowBytes, rows int, data []byte) []byte {
+	res := []byte{27, 29, 'S', 1, byte(rowBytes % 256), byte(rowBytes / 256), byte(rows % 256), byte(rows / 256), 0}
+	return append(res, data...)
+}
+
+// Raster - ESC GS S, the same as BitImage
+func (c StarCommands) Raster(rowBytes, rows int, data []byte) []byte {
+	return c.BitImage(rowBytes, rows, data)
+}
+
+// ColumnImage - ESC X nL nH 24 dot fine density, the line feed is 24 dots
+// with ESC 0 and ESC z 1 restores 4 mm
+func (StarCommands) ColumnImage(rowBytes, rows int, data []byte) []byte {
+	n := rowBytes * 8
+	res := []byte{27, '0', 27, 'X', byte(n % 256), byte(n / 256)}
+	res = append(res, columns(rowBytes, rows, data)...)
+	return append(res, 10, 27, 'z', 1)
+}
+
+// FeedMark - FF, top of the next form with the black mark sensor enabled
+// in the memory switches
+func (StarCommands) FeedMark() []byte { return []byte{12} }
+
+// MarkOffset - set with the memory switches on Star printers
+func (StarCommands) MarkOffset(cut bool, dots int) ([]byte, bool) { return nil, false }
@@ -0,0 +1,83 @@
+package escpos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// config - printers file of LoadConfig
+type config struct {
+	Printers map[string]json.RawMessage `json:"printers"`
+}
+
+// LoadConfig - printers of the JSON config file path by name:
+//
+//	{"printers": {
+//	  "kitchen": {"profile": "adafruit", "port": "/dev/ttyUSB0", "codepage": "PC866"},
+//	  "bar": {"profile": "star", "port": "tcp:192.168.1.20", "width": 576, "heat_time": 120}
+//	}}
+//
+// every printer starts from its profile (adafruit when not given) and
+// replaces the settings it has, the name of the profile is the one of the
+// printer
+func LoadConfig(path string) (map[string]Profile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	res := map[string]Profile{}
+	for name, raw := range cfg.Printers {
+		var base struct {
+			Profile string `json:"profile"`
+		}
+		if err := json.Unmarshal(raw, &base); err != nil {
+			return nil, fmt.Errorf("Invalid printer %s: %s", name, err)
+		}
+		if len(base.Profile) == 0 {
+			base.Profile = "adafruit"
+		}
+		p, ok := FindProfile(base.Profile)
+		if !ok {
+			return nil, fmt.Errorf("Invalid profile of printer %s: %s", name, base.Profile)
+		}
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("Invalid printer %s: %s", name, err)
+		}
+		p.Name = name
+		res[name] = p
+	}
+	return res, nil
+}
+
+// SaveTiming - write t of Calibrate into printer name of the config file
+// path, the rest of the file stays
+func SaveTiming(path, name string, t Timing) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&cfg); err != nil {
+		return fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	printers, _ := cfg["printers"].(map[string]interface{})
+	p, ok := printers[name].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("No printer %s in %s", name, path)
+	}
+	p["dot_print_time"] = t.DotPrintTime
+	p["dot_feed_time"] = t.DotFeedTime
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(out, '\n'), 0644)
+}
@@ -0,0 +1,292 @@
+package escpos
+
+import (
+	"fmt"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// dmSize - ECC200 square symbol: modules per side, of a data region,
+// data and error correction codewords, interleaved blocks
+type dmSize struct {
+	size, region, data, ecc, blocks int
+}
+
+// dmSizes - the square ECC200 symbols, smallest first
+var dmSizes = []dmSize{
+	{10, 8, 3, 5, 1}, {12, 10, 5, 7, 1}, {14, 12, 8, 10, 1}, {16, 14, 12, 12, 1},
+	{18, 16, 18, 14, 1}, {20, 18, 22, 18, 1}, {22, 20, 30, 20, 1}, {24, 22, 36, 24, 1},
+	{26, 24, 44, 28, 1}, {32, 14, 62, 36, 1}, {36, 16, 86, 42, 1}, {40, 18, 114, 48, 1},
+	{44, 20, 144, 56, 1}, {48, 22, 174, 68, 1}, {52, 24, 204, 84, 2}, {64, 14, 280, 112, 2},
+	{72, 16, 368, 144, 4}, {80, 18, 456, 192, 4}, {88, 20, 576, 224, 4}, {96, 22, 696, 272, 4},
+	{104, 24, 816, 336, 6}, {120, 18, 1050, 408, 6}, {132, 20, 1304, 496, 8}, {144, 22, 1558, 620, 10},
+}
+
+// DataMatrixRaster - ECC200 DataMatrix of data in ASCII encodation, size
+// dots per module. Printers without GS ( k DataMatrix print it as an
+// image
+func DataMatrixRaster(data string, size uint8) (*Raster, error) {
+	m, err := dmEncode([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	return moduleRaster(m, int(size)), nil
+}
+
+// dmASCII - ASCII encodation codewords: digit pairs, characters + 1 and
+// upper shift before bytes over 127
+func dmASCII(data []byte) []byte {
+	var res []byte
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c >= '0' && c <= '9' && i+1 < len(data) && data[i+1] >= '0' && data[i+1] <= '9':
+			res = append(res, 130+(c-'0')*10+data[i+1]-'0')
+			i++
+		case c > 127:
+			res = append(res, 235, c-127)
+		default:
+			res = append(res, c+1)
+		}
+	}
+	return res
+}
+
+// dmEncode - modules of the smallest square symbol holding data
+func dmEncode(data []byte) ([][]bool, error) {
+	cw := dmASCII(data)
+	var s dmSize
+	for _, s = range dmSizes {
+		if len(cw) <= s.data {
+			break
+		}
+	}
+	if len(cw) > s.data {
+		return nil, fmt.Errorf("DataMatrix: %d bytes do not fit in a symbol", len(data))
+	}
+	// pad: 129, then the 253-state randomized 129 of each position
+	end := len(cw)
+	for i := end; i < s.data; i++ {
+		if i == end {
+			cw = append(cw, 129)
+			continue
+		}
+		r := 129 + (149*(i+1))%253 + 1
+		if r > 254 {
+			r -= 254
+		}
+		cw = append(cw, byte(r))
+	}
+	cw = append(cw, dmInterleave(cw, s)...)
+
+	// codewords in the data region matrix, then regions with their finder
+	// and timing patterns
+	n := s.size / (s.region + 2) * s.region
+	bits := dmPlacement(n, n)
+	m := make([][]bool, s.size)
+	for i := range m {
+		m[i] = make([]bool, s.size)
+	}
+	for y := 0; y < s.size; y++ {
+		for x := 0; x < s.size; x++ {
+			ry, rx := y%(s.region+2), x%(s.region+2)
+			switch {
+			case ry == s.region+1 || rx == 0:
+				// solid L
+				m[y][x] = true
+			case ry == 0:
+				m[y][x] = rx%2 == 0
+			case rx == s.region+1:
+				m[y][x] = ry%2 == 1
+			default:
+				v := bits[(y/(s.region+2)*s.region+ry-1)*n+x/(s.region+2)*s.region+rx-1]
+				if v >= 10 {
+					m[y][x] = cw[v/10-1]&(1<<uint(8-v%10)) != 0
+				} else {
+					m[y][x] = v == 1
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// dmInterleave - error correction codewords of the blocks of data,
+// codeword i of the data and the error correction is in block i % blocks
+func dmInterleave(data []byte, s dmSize) []byte {
+	per := s.ecc / s.blocks
+	gen := dmGenerator(per)
+	res := make([]byte, s.ecc)
+	for b := 0; b < s.blocks; b++ {
+		var block []byte
+		for i := b; i < len(data); i += s.blocks {
+			block = append(block, data[i])
+		}
+		for i, c := range dmRemainder(block, gen) {
+			res[i*s.blocks+b] = c
+		}
+	}
+	return res
+}
+
+// dmMul - product in GF(256) modulo x^8 + x^5 + x^3 + x^2 + 1
+func dmMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ (z>>7)*0x12D
+		z ^= int(y>>uint(i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// dmGenerator - Reed-Solomon generator polynomial with the roots 2^1 ..
+// 2^n, highest coefficient dropped
+func dmGenerator(n int) []byte {
+	res := make([]byte, n)
+	res[n-1] = 1
+	root := byte(2)
+	for i := 0; i < n; i++ {
+		for j := range res {
+			res[j] = dmMul(res[j], root)
+			if j+1 < n {
+				res[j] ^= res[j+1]
+			}
+		}
+		root = dmMul(root, 2)
+	}
+	return res
+}
+
+// dmRemainder - error correction codewords of data
+func dmRemainder(data, gen []byte) []byte {
+	res := make([]byte, len(gen))
+	for _, b := range data {
+		factor := b ^ res[0]
+		copy(res, res[1:])
+		res[len(res)-1] = 0
+		for i, c := range gen {
+			res[i] ^= dmMul(c, factor)
+		}
+	}
+	return res
+}
+
+// dmPlacement - ECC200 module placement of a rows x cols data region
+// matrix: 10 * codeword + bit (1 the most significant) of each module,
+// 1 and 0 for the dark and light fixed corner modules
+func dmPlacement(rows, cols int) []int {
+	a := make([]int, rows*cols)
+	module := func(r, c, ch, bit int) {
+		if r < 0 {
+			r += rows
+			c += 4 - (rows+4)%8
+		}
+		if c < 0 {
+			c += cols
+			r += 4 - (cols+4)%8
+		}
+		a[r*cols+c] = 10*ch + bit
+	}
+	utah := func(r, c, ch int) {
+		module(r-2, c-2, ch, 1)
+		module(r-2, c-1, ch, 2)
+		module(r-1, c-2, ch, 3)
+		module(r-1, c-1, ch, 4)
+		module(r-1, c, ch, 5)
+		module(r, c-2, ch, 6)
+		module(r, c-1, ch, 7)
+		module(r, c, ch, 8)
+	}
+	corner := func(ch int, pos [8][2]int) {
+		for i, p := range pos {
+			r, c := p[0], p[1]
+			if r < 0 {
+				r += rows
+			}
+			if c < 0 {
+				c += cols
+			}
+			module(r, c, ch, i+1)
+		}
+	}
+	ch, r, c := 1, 4, 0
+	for r < rows || c < cols {
+		if r == rows && c == 0 {
+			corner(ch, [8][2]int{{-1, 0}, {-1, 1}, {-1, 2}, {0, -2}, {0, -1}, {1, -1}, {2, -1}, {3, -1}})
+			ch++
+		}
+		if r == rows-2 && c == 0 && cols%4 != 0 {
+			corner(ch, [8][2]int{{-3, 0}, {-2, 0}, {-1, 0}, {0, -4}, {0, -3}, {0, -2}, {0, -1}, {1, -1}})
+			ch++
+		}
+		if r == rows-2 && c == 0 && cols%8 == 4 {
+			corner(ch, [8][2]int{{-3, 0}, {-2, 0}, {-1, 0}, {0, -2}, {0, -1}, {1, -1}, {2, -1}, {3, -1}})
+			ch++
+		}
+		if r == rows+4 && c == 2 && cols%8 == 0 {
+			corner(ch, [8][2]int{{-1, 0}, {-1, -1}, {0, -3}, {0, -2}, {0, -1}, {1, -3}, {1, -2}, {1, -1}})
+			ch++
+		}
+		// up and to the right
+		for {
+			if r < rows && c >= 0 && a[r*cols+c] == 0 {
+				utah(r, c, ch)
+				ch++
+			}
+			r -= 2
+			c += 2
+			if r < 0 || c >= cols {
+				break
+			}
+		}
+		r++
+		c += 3
+		// down and to the left
+		for {
+			if r >= 0 && c < cols && a[r*cols+c] == 0 {
+				utah(r, c, ch)
+				ch++
+			}
+			r += 2
+			c -= 2
+			if r >= rows || c < 0 {
+				break
+			}
+		}
+		r += 3
+		c++
+	}
+	if a[rows*cols-1] == 0 {
+		a[rows*cols-1] = 1
+		a[rows*cols-cols-2] = 1
+	}
+	return a
+}
+
+// DataMatrix - print a square ECC200 DataMatrix, opt.QrSize module size
+// 1..16 (GS ( k takes 2..16); printers which don't have it in
+// Profile.Symbols print it as an image
+func (e *Escpos) DataMatrix(opt models.BarCodeOption, data string) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func DataMatrix()\n")
+	}
+	size := opt.QrSize
+	if size == 0 {
+		size = 6
+	} else if size > 16 {
+		size = 16
+	}
+	if sc, ok := e.symbols("datamatrix"); ok && size >= 2 {
+		e.WriteRaw(sc.DataMatrix(data, size))
+		e.prevByte = ASCIILF
+		e.Feed(1)
+		return
+	}
+	r, err := DataMatrixRaster(data, size)
+	if err != nil {
+		e.err = err
+		return
+	}
+	e.printRaster(r.Align(e.align, e.dots))
+	e.Feed(1)
+}
@@ -0,0 +1,56 @@
+package escpos
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetDeadline - writes after t fail with ErrTimeout, as does a write
+// still blocked at t (wedged port, printer asleep); the zero time is no
+// deadline
+func (e *Escpos) SetDeadline(t time.Time) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetDeadline() %s\n", t)
+	}
+	e.deadline = t
+}
+
+// write - data to the destination within the deadline; after a write
+// timed out the following ones fail until it returns
+func (e *Escpos) write(data []byte) (int, error) {
+	if e.blocked != nil {
+		select {
+		case <-e.blocked:
+			e.blocked = nil
+		default:
+			return 0, fmt.Errorf("%w: printer port blocked", ErrTimeout)
+		}
+	}
+	if e.deadline.IsZero() {
+		return e.dst.Write(data)
+	}
+	wait := time.Until(e.deadline)
+	if wait <= 0 {
+		return 0, fmt.Errorf("%w: job deadline passed", ErrTimeout)
+	}
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	blocked := make(chan struct{})
+	go func() {
+		n, err := e.dst.Write(data)
+		done <- result{n, err}
+		close(blocked)
+	}()
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-t.C:
+		e.blocked = blocked
+		return 0, fmt.Errorf("%w: printer port blocked", ErrTimeout)
+	}
+}
@@ -0,0 +1,130 @@
+package escpos
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// demoBarCodes - sample data valid for each bar code type
+var demoBarCodes = []struct {
+	code string
+	data string
+}{
+	{"UPC_A", "01234567890"},
+	{"UPC_E", "0123456"},
+	{"EAN13", "400638133393"},
+	{"EAN8", "9638507"},
+	{"CODE39", "GOTP-39"},
+	{"I25", "1234567890"},
+	{"CODEBAR", "A40156B"},
+	{"CODE93", "GOTP93"},
+	{"CODE128", "Gotp-128"},
+	{"CODE11", "0123452"},
+	{"MSI", "1234567"},
+}
+
+// demoHeading - bold caption of a demo block
+func (e *Escpos) demoHeading(text string) {
+	e.Feed(1)
+	e.SetBold(true)
+	e.WriteText(text)
+	e.Linefeed()
+	e.SetBold(false)
+}
+
+// demoImage - gray gradient with a checker border to show dithering
+func demoImage() image.Image {
+	img := image.NewGray(image.Rect(0, 0, 256, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 256; x++ {
+			v := uint8(x)
+			if y < 8 || y >= 56 {
+				v = 255
+				if (x/8+y/8)%2 == 0 {
+					v = 0
+				}
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+// Demo - print a page showing text styles, alignment, bar codes, QR code
+// and image output of the driver
+func (e *Escpos) Demo() {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func Demo()\n")
+	}
+	e.SetAlign("center")
+	e.SetFontSize("large")
+	e.WriteText("gotp demo")
+	e.Linefeed()
+	e.SetFontSize("normal")
+	e.SetAlign("left")
+
+	e.demoHeading("Font size")
+	for _, size := range []string{"normal", "medium", "large"} {
+		e.SetFontSize(size)
+		e.WriteText(size)
+		e.Linefeed()
+	}
+	e.SetFontSize("normal")
+	e.SetSmall(true)
+	e.WriteText("small font")
+	e.Linefeed()
+	e.SetSmall(false)
+
+	e.demoHeading("Style")
+	e.SetBold(true)
+	e.WriteText("bold")
+	e.Linefeed()
+	e.SetBold(false)
+	e.SetUnderline(1)
+	e.WriteText("underline")
+	e.Linefeed()
+	e.SetUnderline(2)
+	e.WriteText("thick underline")
+	e.Linefeed()
+	e.SetUnderline(0)
+	e.SetReverse(1)
+	e.WriteText(" inverse ")
+	e.Linefeed()
+	e.SetReverse(0)
+	e.DoubleHeight(true)
+	e.WriteText("double height")
+	e.Linefeed()
+	e.DoubleHeight(false)
+
+	e.demoHeading("Align")
+	for _, align := range []string{"left", "center", "right"} {
+		e.SetAlign(align)
+		e.WriteText(align)
+		e.Linefeed()
+	}
+	e.SetAlign("left")
+
+	e.demoHeading("Line")
+	e.LinePrint()
+
+	e.demoHeading("Bar codes")
+	for _, bc := range demoBarCodes {
+		e.WriteText(bc.code)
+		e.Linefeed()
+		e.SetAlign("center")
+		e.PrintBarCode(models.BarCodeOption{Code: bc.code, Chr: 2, Height: 50, Width: 2}, bc.data)
+		e.SetAlign("left")
+	}
+
+	e.demoHeading("QR code")
+	e.SetAlign("center")
+	e.QrCode(models.BarCodeOption{QrSize: 6, QrEcc: "M"}, "https://github.com/grengojbo/gotp")
+	e.SetAlign("left")
+
+	e.demoHeading("Image")
+	e.PrintImage(demoImage(), 0, "floyd", "center")
+	e.Feed(3)
+}
@@ -0,0 +1,265 @@
+package escpos
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// Alignment - Document.Align values
+type Alignment string
+
+// alignments
+const (
+	Left   Alignment = "left"
+	Center Alignment = "center"
+	Right  Alignment = "right"
+)
+
+// bar code types of Document.Barcode
+const (
+	UPCA    = "UPC_A"
+	UPCE    = "UPC_E"
+	EAN13   = "EAN13"
+	EAN8    = "EAN8"
+	CODE39  = "CODE39"
+	ITF     = "I25"
+	CODABAR = "CODEBAR"
+	CODE93  = "CODE93"
+	CODE128 = "CODE128"
+	// GS1128, DATABAR, DATABAREXPANDED - element strings like
+	// "(01)09501101530003(17)250101", see ParseGS1
+	GS1128          = "GS1_128"
+	DATABAR         = "DATABAR"
+	DATABAREXPANDED = "DATABAR_EXPANDED"
+	// ITF14 - 13 or 14 digits in a bearer bar box, see ITF14Digits
+	ITF14 = "ITF14"
+)
+
+// Document - receipt built from chained calls and printed in one go:
+//
+//	escpos.NewDocument().Align(escpos.Center).Bold().Text("Shop").NL().
+//		Normal().Barcode(escpos.EAN13, "400638133393").Cut().Print(p)
+//
+// Nothing is sent before Print, a failing step prints nothing
+type Document struct {
+	ops []func(e *Escpos) error
+}
+
+// NewDocument - empty document
+func NewDocument() *Document {
+	return &Document{}
+}
+
+// add - append an operation
+func (d *Document) add(op func(e *Escpos) error) *Document {
+	d.ops = append(d.ops, op)
+	return d
+}
+
+// Align - alignment of the following lines
+func (d *Document) Align(a Alignment) *Document {
+	return d.add(func(e *Escpos) error { return e.SetAlign(string(a)) })
+}
+
+// Bold - bold text until Normal
+func (d *Document) Bold() *Document {
+	return d.add(func(e *Escpos) error { e.SetBold(true); return nil })
+}
+
+// Small - font B until Normal
+func (d *Document) Small() *Document {
+	return d.add(func(e *Escpos) error { e.SetSmall(true); return nil })
+}
+
+// Underline - underlined text until Normal
+func (d *Document) Underline() *Document {
+	return d.add(func(e *Escpos) error { e.SetUnderline(1); return nil })
+}
+
+// Reverse - white on black until Normal
+func (d *Document) Reverse() *Document {
+	return d.add(func(e *Escpos) error { e.SetReverse(1); return nil })
+}
+
+// Size - character width and height multiples 1..8 until Normal
+func (d *Document) Size(width, height uint8) *Document {
+	return d.add(func(e *Escpos) error { return e.SetSize(width, height) })
+}
+
+// Normal - plain text, the alignment is kept
+func (d *Document) Normal() *Document {
+	return d.add(func(e *Escpos) error {
+		e.SetStyle(Style{Align: e.align})
+		return nil
+	})
+}
+
+// Text - text, wrapped at the line width
+func (d *Document) Text(s string) *Document {
+	return d.add(func(e *Escpos) error { return e.WriteText(s) })
+}
+
+// Line - text and a line feed
+func (d *Document) Line(s string) *Document {
+	return d.Text(s).NL()
+}
+
+// NL - line feed
+func (d *Document) NL() *Document {
+	return d.add(func(e *Escpos) error { e.Linefeed(); return nil })
+}
+
+// Feed - n line feeds
+func (d *Document) Feed(n int) *Document {
+	return d.add(func(e *Escpos) error { e.Feed(n); return nil })
+}
+
+// Pad - left and right on one line with fill between, see PadBetween
+func (d *Document) Pad(left, right string, fill byte) *Document {
+	return d.add(func(e *Escpos) error {
+		if err := e.WriteText(e.PadBetween(left, right, fill)); err != nil {
+			return err
+		}
+		e.Linefeed()
+		return nil
+	})
+}
+
+// Rule - horizontal rule, see Rule
+func (d *Document) Rule(style string) *Document {
+	return d.add(func(e *Escpos) error { return e.Rule(style) })
+}
+
+// Banner - text in the largest characters, see Banner
+func (d *Document) Banner(s string) *Document {
+	return d.add(func(e *Escpos) error { return e.Banner(s) })
+}
+
+// Barcode - bar code of type code (EAN13, CODE128, ...) with the text
+// below it
+func (d *Document) Barcode(code, data string) *Document {
+	return d.add(func(e *Escpos) error {
+		if _, ok := barCodeTypes[code]; !ok && !gs1Codes[code] && code != ITF14 {
+			return fmt.Errorf("Invalid bar code type: %s", code)
+		}
+		e.PrintBarCode(models.BarCodeOption{Code: code, Chr: 2}, data)
+		return nil
+	})
+}
+
+// QR - QR code, size module dots (0 is 6)
+func (d *Document) QR(data string, size uint8) *Document {
+	return d.add(func(e *Escpos) error {
+		if size == 0 {
+			size = 6
+		}
+		e.QrCode(models.BarCodeOption{QrSize: size, QrEcc: "M"}, data)
+		return nil
+	})
+}
+
+// Image - img dithered to width dots (0 is the print width), aligned
+// like the text
+func (d *Document) Image(img image.Image, width int) *Document {
+	return d.add(func(e *Escpos) error {
+		e.PrintImage(img, width, "floyd", e.align)
+		return nil
+	})
+}
+
+// Raw - bytes sent as they are
+func (d *Document) Raw(data []byte) *Document {
+	return d.add(func(e *Escpos) error {
+		_, err := e.WriteRaw(data)
+		return err
+	})
+}
+
+// Drawer - open the cash drawer
+func (d *Document) Drawer() *Document {
+	return d.add(func(e *Escpos) error { e.Cash(); return nil })
+}
+
+// FormFeed - feed to the next page of the page length of the profile
+func (d *Document) FormFeed() *Document {
+	return d.add(func(e *Escpos) error { e.FormFeed(); return nil })
+}
+
+// Cut - feed to the cutter and cut
+func (d *Document) Cut() *Document {
+	return d.add(func(e *Escpos) error { e.Cut(); return nil })
+}
+
+// PartialCut - feed to the cutter and cut leaving a bridge
+func (d *Document) PartialCut() *Document {
+	return d.add(func(e *Escpos) error { e.PartialCut(); return nil })
+}
+
+// Bytes - the document as it is printed on e, e isn't written to
+func (d *Document) Bytes(e *Escpos) ([]byte, error) {
+	var buf bytes.Buffer
+	b := e.buffer(&buf)
+	for _, op := range d.ops {
+		if err := op(b); err != nil {
+			return nil, err
+		}
+		if b.err != nil {
+			return nil, b.err
+		}
+	}
+	// back to the style of e, its settings stay valid
+	b.SetStyle(e.Style())
+	return buf.Bytes(), b.err
+}
+
+// Print - send the document to e in one stream paced by the time its
+// commands take
+func (d *Document) Print(e *Escpos) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func Document.Print() %d operations\n", len(d.ops))
+	}
+	data, err := d.Bytes(e)
+	if err != nil {
+		return err
+	}
+	e.setTotal(int64(len(data)))
+	_, err = e.WriteStream(data)
+	return err
+}
+
+// Snapshot - copy of e with its settings writing nowhere: ModelBytes of it
+// renders a job for e on another goroutine while e prints the one before;
+// take it between the jobs of e
+func (e *Escpos) Snapshot() *Escpos {
+	return e.buffer(ioutil.Discard)
+}
+
+// buffer - unpaced copy of e writing to w, with the command set, code
+// page, firmware and style of e
+func (e *Escpos) buffer(w io.Writer) *Escpos {
+	b := *e
+	b.dst = w
+	b.src = nil
+	b.tee = nil
+	b.Serial = nil
+	b.rx = nil
+	b.OnStatus = nil
+	b.deadline = time.Time{}
+	b.blocked = nil
+	b.queue = &jobQueue{}
+	b.prog = &progress{}
+	b.pacer = nil
+	// a stream of the copy waits between the bands in WriteStream
+	b.profile.BandWait = ""
+	b.Debug = false
+	b.Verbose = false
+	b.err = nil
+	b.styles = nil
+	return &b
+}
@@ -0,0 +1,20 @@
+package escpos
+
+import (
+	"errors"
+)
+
+// errors of the printer, the errors returned wrap them, test with
+// errors.Is
+var (
+	// ErrNoDevice - the printer port couldn't be opened
+	ErrNoDevice = errors.New("Printer is not open")
+	// ErrEncode - text with characters the code page doesn't have
+	ErrEncode = errors.New("Couldn't encode to charset")
+	// ErrPaperOut - the printer reports it is out of paper
+	ErrPaperOut = errors.New("Paper out")
+	// ErrTimeout - the printer didn't answer in time
+	ErrTimeout = errors.New("Timeout")
+	// ErrRefused - a hook refused the job, see StartJob
+	ErrRefused = errors.New("Job refused")
+)
@@ -1,9 +1,11 @@
 package escpos
 
 import (
-	"encoding/base64"
 	"fmt"
-	"log"
+	"html"
+	"image"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -46,26 +48,87 @@ var textReplaceMap = map[string]string{
 	"&gt;":   ">",
 	"&lt;":   "<",
 
-	// ampersand must be last to avoid double decoding
 	"&amp;": "&",
 }
 
-// replace text from the above map
-func (e *Escpos) textReplace(data string) string {
+// textReplacer - textReplaceMap in one pass, "&amp;lt;" is "&lt;"
+var textReplacer = func() *strings.Replacer {
+	var pairs []string
 	for k, v := range textReplaceMap {
-		data = strings.Replace(data, k, v, -1)
+		pairs = append(pairs, k, v)
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// replace text from the above map, or every HTML entity after
+// SetEntities; invalid UTF-8 prints as "?"
+func (e *Escpos) textReplace(data string) string {
+	data = strings.ToValidUTF8(data, "?")
+	if e.entities {
+		return e.supported(html.UnescapeString(data))
 	}
-	return data
+	return textReplacer.Replace(data)
+}
+
+// SetEntities - decode all HTML entities (&eacute;, &#8364;) instead of
+// the XML ones, characters the code page doesn't have print as "?"
+func (e *Escpos) SetEntities(all bool) {
+	e.entities = all
 }
 
 // Escpos - library for the Adafruit Thermal Printer:
 // https://www.adafruit.com/product/597
 type Escpos struct {
 	enc *encoding.Encoder
-	// destination
-	// dst io.Writer
-	// config *serial.Config
-	Serial *serial.Port
+	// codePage - name of the code page of enc, see CodePage
+	codePage string
+	// begun - Begin woke the printer up, jobs may send SetDefault
+	begun bool
+	// paper - dots of paper fed since the start of the page, see FormFeed
+	paper int
+	// cmd - command language of the printer
+	cmd CommandSet
+	// destination, the serial port or the writer of NewWriter
+	dst io.Writer
+	// src - replies of the printer, nil when it can't send any
+	src io.Reader
+	// tee - copy of everything sent, see Tee
+	tee io.Writer
+	// hexdump - debug mode output, see send
+	hexdump *Hexdump
+	// deadline - of the job, see SetDeadline
+	deadline time.Time
+	// blocked - closed when a write that timed out returns
+	blocked chan struct{}
+	// queue - jobs of Submit
+	queue *jobQueue
+	// prog - progress of the current job
+	prog *progress
+	// quality - heating and line spacing preset, see SetQuality
+	quality Quality
+	// sensors - temperature and voltage queries, see Profile.Sensors
+	sensors bool
+	// symbolNames - 2D codes of the printer, see Profile.Symbols
+	symbolNames []string
+	// host - of TCP printers, snmpCommunity - see SetSNMP
+	host          string
+	snmpCommunity string
+	Serial        *serial.Port
+	// bytes received from the printer, see startReader
+	rx chan byte
+	// readTimeout - wait for query replies, 0 is replyTimeout
+	readTimeout time.Duration
+	// asb - 1 while automatic status back is on
+	asb int32
+	// querying - 1 while a query waits for its reply, the reader takes no
+	// ASB packets then
+	querying int32
+	// OnStatus - called with the status of every ASB packet
+	OnStatus func(Status)
+	// hooks - see AddHook
+	hooks jobHooks
+	// gpio - pins of Profile.GPIO, nil without them
+	gpio *gpioPins
 
 	// font metrics
 	width, height uint8
@@ -75,26 +138,66 @@ type Escpos struct {
 	emphasize  uint8
 	upsidedown uint8
 	rotate     uint8
-
-	prevByte      byte
-	column        uint8
-	maxColumn     uint8
+	// bold, small, align - last SetBold, SetSmall, SetAlign
+	bold, small bool
+	align       string
+	// styles - PushStyle stack
+	styles []Style
+
+	prevByte byte
+	// column - dots of the line taken by text, see charWidth
+	column    int
+	maxColumn uint8
+	// lineColumns - characters per line of font A at normal size of
+	// Profile.Columns or SetColumns, 0 - from the width
+	lineColumns int
+	// tabStops - columns of SetTabStops, tabDots - their dots, tabAlign -
+	// SetTabAlign of them
+	tabStops      []uint8
+	tabDots       []int
+	tabAlign      []string
 	charHeight    int64
 	lineSpacing   int64
 	barcodeHeight uint8
+	barcodeHRI    uint8
+	barcodeWidth  uint8
+	// hriStyle - "small", "bold" or both, see SetHRIStyle
+	hriStyle string
 
 	printDensity   uint8
 	printBreakTime uint8
 	// state toggles GS[char]
 	reverse, smooth uint8
 
-	resumeTime     int64
+	byteTime int64
+	// pacer - pacing of a serial port, nil writes at once (NewWriter)
+	pacer          *pacingWriter
 	dotPrintTime   int64
 	dotFeedTime    int64
 	maxChunkHeight uint8
-
-	Verbose  bool
-	Debug    bool
+	// imageMode - image command of the profile: bitmap, raster or column
+	imageMode string
+	// media - receipt or label stock, see Profile.Media
+	media string
+	// page - in page mode, pageHeight dots of its areas
+	page       bool
+	pageHeight int
+	// dpi - Profile.DPI, see Dots
+	dpi int
+	// dots - print head width, see Profile.Width
+	dots int
+	// profile - see SetProfile
+	profile Profile
+	// frame - text rows are printed in a box, see FrameBegin
+	frame bool
+	// entities - see SetEntities
+	entities bool
+
+	Verbose bool
+	Debug   bool
+	// Log - Verbose traces and the errors of print calls, nil is stdout
+	// (stderr when stdout gets the stream)
+	Log      io.Writer
 	Firmware int
 	err      error
 }
@@ -102,13 +205,17 @@ type Escpos struct {
 // reset toggles
 func (e *Escpos) reset() {
 	if e.Verbose {
-		fmt.Printf("func reset()\n")
+		fmt.Fprintf(e.logOut(), "func reset()\n")
 	}
 	// x1B -> ESC byte{27}
-	e.Write("\x1B@")
+	e.WriteRaw(e.cmd.Init())
 
 	e.width = 1
 	e.height = 1
+	e.bold = false
+	e.small = false
+	e.align = "left"
+	e.styles = nil
 
 	e.underline = 0
 	e.emphasize = 0
@@ -120,41 +227,128 @@ func (e *Escpos) reset() {
 
 	e.prevByte = ASCIILF
 	e.column = 0
-	e.maxColumn = 32
-	e.charHeight = 24
+	e.charMetrics()
 	e.lineSpacing = 6
 	e.barcodeHeight = 50
+	e.barcodeHRI = 0
+	e.barcodeWidth = 3
+	e.hriStyle = ""
 	e.printDensity = 10
 
-	//  // Configure tab stops on recent printers
-	// Set tab stops...
-	if e.Firmware >= 264 {
-		e.Write("\x1BD")
-		e.WriteBytes([]byte{4, 8, 12, 16})  // ...every 4 columns,
-		e.WriteBytes([]byte{20, 24, 28, 0}) // 0 marks end-of-list.
-	}
+	// tab stops every 4 columns, sent to recent printers
+	e.SetTabStops(defaultTabStops)
 }
 
 // New - create Escpos printer
 func New(debug bool, port string, baud int) (e *Escpos) {
+	return NewConfig(debug, &serial.Config{Name: SerialPort(port), Baud: baud})
+}
+
+// NewConfig - create Escpos printer on the serial port of config
+func NewConfig(debug bool, config *serial.Config) (e *Escpos) {
 	e = &Escpos{Debug: debug}
-	e.enc = charmap.CodePage437.NewEncoder()
-	e.Firmware = 268
+	e.readTimeout = config.ReadTimeout
+	e.byteTime = BYTETIME
+	if config.Baud > 0 {
+		// BYTETIME at the port baud rate
+		e.byteTime = int64((11*1000000 + config.Baud/2) / config.Baud)
+	}
 	if !e.Debug {
-		config := &serial.Config{Name: port, Baud: baud}
 		s, err := serial.OpenPort(config)
 		if err != nil {
-			e.err = err
+			e.err = fmt.Errorf("%w: %s", ErrNoDevice, err)
 		} else {
 			e.Serial = s
+			e.dst = s
+			e.src = s
 		}
 	}
+	e.pacer = newPacer(e, writerFunc(e.write))
+	e.init()
+	return
+}
 
+// NewProfile - create Escpos printer on the port of p (serial port or
+// "tcp:<host[:port]>") with its settings; on an error of SetProfile the
+// printer is still usable with the defaults it could not replace
+func NewProfile(debug bool, p Profile) (e *Escpos, err error) {
+	if strings.HasPrefix(p.Port, "tcp:") {
+		e = NewTCP(debug, strings.TrimPrefix(p.Port, "tcp:"))
+	} else {
+		baud := p.Baud
+		if baud <= 0 {
+			baud = BAUDRATE
+		}
+		config, err := p.SerialConfig(strings.TrimPrefix(p.Port, "serial:"), baud)
+		if err != nil {
+			return NewWriter(nil), err
+		}
+		e = NewConfig(debug, config)
+	}
+	return e, e.SetProfile(p)
+}
+
+// NewWriter - create Escpos printer writing the ESC/POS stream to w
+// (file, stdout) without pacing, it can't answer status queries
+func NewWriter(w io.Writer) (e *Escpos) {
+	e = &Escpos{dst: w, byteTime: BYTETIME}
+	e.init()
+	return
+}
+
+// NewReadWriter - NewWriter with printer replies (status, firmware) read
+// from rw
+func NewReadWriter(rw io.ReadWriter) (e *Escpos) {
+	e = NewWriter(rw)
+	e.src = rw
+	return
+}
+
+// logOut - where traces and errors go, see Log
+func (e *Escpos) logOut() io.Writer {
+	if e.Log == nil {
+		return os.Stdout
+	}
+	return e.Log
+}
+
+// Tee - also write everything sent to the printer to w (nil stops),
+// in debug mode w gets the stream the printer would have got
+func (e *Escpos) Tee(w io.Writer) {
+	e.tee = w
+}
+
+// init - defaults and reset of a new printer
+func (e *Escpos) init() {
+	e.queue = &jobQueue{}
+	e.prog = &progress{}
+	e.dots = MAXIMAGEWIDTH
+	e.quality, _ = FindQuality("normal")
+	e.enc = charmap.CodePage437.NewEncoder()
+	e.cmd = EscposCommands{}
+	e.Firmware = FirmwareDefault
 	e.printDensity = 10
 	e.printBreakTime = 2
-	e.timeoutSet(500000)
+	e.maxChunkHeight = 255
+	e.hold(500 * time.Millisecond)
 	e.reset()
-	return
+}
+
+// Close - print the jobs of Submit, then close the serial port or the
+// writer of NewWriter
+func (e *Escpos) Close() error {
+	e.closeQueue()
+	if e.hexdump != nil {
+		e.hexdump.Flush()
+	}
+	if e.gpio != nil {
+		e.gpio.close()
+		e.gpio = nil
+	}
+	if c, ok := e.dst.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // IsOk - check error
@@ -164,21 +358,55 @@ func (e *Escpos) IsOk() bool {
 	}
 	return true
 }
+
+// Err - last serial error
+func (e *Escpos) Err() error {
+	return e.err
+}
+
+// ClearErr - forget the error of the last job, the next job starts clean
+func (e *Escpos) ClearErr() {
+	e.err = nil
+}
+
+// SetDefault - the settings a job starts with, sent without ESC @ which
+// would clear the buffer of the printer: online, left aligned, the styles
+// off, the line height of the quality, the bar code defaults, the default
+// tab stops, charset 0 and the code page the job started with (see
+// StartJob, which calls it with EndJob)
 func (e *Escpos) SetDefault() {
 	if e.Verbose {
-		fmt.Println("TODO: SetDefault()")
+		fmt.Fprintf(e.logOut(), "func SetDefault()\n")
+	}
+	if e.adafruit() {
+		// ESC = 1: online, ESC ! 0: the print mode of other software off
+		e.WriteRaw([]byte{27, '=', 1, 27, '!', 0})
+	}
+	e.styles = nil
+	e.SetAlign("left")
+	e.SetSmall(false)
+	e.setSize(1, 1)
+	e.SetBold(false)
+	e.SetUnderline(0)
+	e.SetReverse(0)
+	if e.adafruit() {
+		e.SetEmphasize(0)
+		e.SetUpsidedown(0)
+		// ESC R 0, also the one of SetRotate
+		e.SetCharset(0)
 	}
-	// online();
-	// justify('L');
-	// inverseOff();
-	// doubleHeightOff();
-	// setLineHeight(30);
-	// boldOff();
-	// underlineOff();
-	// setBarcodeHeight(50);
-	// setSize('s');
-	// setCharset();
-	// setCodePage();
+	e.emphasize, e.upsidedown, e.rotate = 0, 0, 0
+	e.applyQuality(true)
+	e.setBarcodeHeight(50)
+	e.barcodeHRI = 0
+	e.barcodeWidth = 3
+	e.hriStyle = ""
+	e.SetTabStops(defaultTabStops)
+	codePage := e.hooks.codePage
+	if len(codePage) == 0 {
+		codePage = e.CodePage()
+	}
+	e.SetCodePage(codePage)
 }
 
 // func (e *Escpos) WriteString(src ...string) {
@@ -191,38 +419,28 @@ func (e *Escpos) SetDefault() {
 
 // WriteBytes - write byte
 func (e *Escpos) WriteBytes(data []byte) {
-	e.timeoutWait()
 	if e.Verbose {
-		fmt.Println(data)
+		fmt.Fprintln(e.logOut(), data)
 	}
-	if !e.Debug {
-		// e.dst.Write(data)
-		_, err := e.Serial.Write(data)
-		if err != nil {
-			e.err = err
-		}
+	// e.dst.Write(data)
+	_, err := e.send(data)
+	if err != nil {
+		e.err = err
 	}
-	e.timeoutSet(int64(len(data)) * BYTETIME)
 }
 
 // WriteRaw - write raw bytes to printer
 func (e *Escpos) WriteRaw(data []byte) (n int, err error) {
 	if len(data) > 0 {
-		e.timeoutWait()
 		if e.Verbose {
-			fmt.Printf("Writing %d bytes\n", len(data))
-			fmt.Println(data)
-		}
-		if !e.Debug {
-			// e.dst.Write(data)
-			n, err = e.Serial.Write(data)
+			fmt.Fprintf(e.logOut(), "Writing %d bytes\n", len(data))
+			fmt.Fprintln(e.logOut(), data)
 		}
-		e.timeoutSet(int64(len(data)) * BYTETIME)
-		// OR
-		// e.timeoutSet(BYTETIME)
+		// e.dst.Write(data)
+		n, err = e.send(data)
 	} else {
 		if e.Verbose {
-			fmt.Printf("Wrote NO bytes\n")
+			fmt.Fprintf(e.logOut(), "Wrote NO bytes\n")
 		}
 	}
 	return n, err
@@ -231,34 +449,47 @@ func (e *Escpos) WriteRaw(data []byte) (n int, err error) {
 // Write - write a string to the printer
 func (e *Escpos) Write(data string) (int, error) {
 	// if e.Verbose {
-	// 	fmt.Printf("func Write()\n")
+	// 	fmt.Fprintf(e.logOut(), "func Write()\n")
 	// }
 	return e.WriteRaw([]byte(data))
 }
 
-func (e *Escpos) timeoutSet(x int64) {
-	// if(!dtrEnabled) resumeTime = micros() + x;
-	e.resumeTime = x
+// Sleep - put the printer into a low-energy state immediately
+func (e *Escpos) Sleep() {
+	e.SleepAfter(1)
 }
 
-func (e *Escpos) timeoutWait() {
-	time.Sleep(time.Microsecond * time.Duration(e.resumeTime))
-	// if(dtrEnabled) {
-	//    while(digitalRead(dtrPin) == HIGH);
-	//  } else {
-	//    while((long)(micros() - resumeTime) < 0L); // (syntax is rollover-proof)
-	//  }
+// SleepAfter - put the printer into a low-energy state after the given
+// number of seconds, firmware before 2.64 takes at most 255
+func (e *Escpos) SleepAfter(seconds uint16) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SleepAfter()\n")
+	}
+	if !e.adafruit() {
+		return
+	}
+	if e.recent() {
+		e.WriteBytes([]byte{27, 56, byte(seconds), byte(seconds >> 8)})
+	} else {
+		if seconds > 255 {
+			seconds = 255
+		}
+		e.WriteBytes([]byte{27, 56, byte(seconds)})
+	}
 }
 
 // Wake the printer from a low-energy state.
-func (e *Escpos) wake() {
+func (e *Escpos) Wake() {
 
 	if e.Verbose {
-		fmt.Printf("func wake()\n")
+		fmt.Fprintf(e.logOut(), "func Wake()\n")
+	}
+	e.hold(0) // Reset timeout counter
+	if !e.adafruit() {
+		return
 	}
-	e.timeoutSet(0)           // Reset timeout counter
 	e.WriteBytes([]byte{255}) // Wake
-	if e.Firmware >= 264 {
+	if e.recent() {
 		//   delay(50);
 		time.Sleep(time.Millisecond * 50)
 		//   writeBytes(ASCII_ESC, '8', 0, 0); // Sleep off (important!)
@@ -272,7 +503,7 @@ func (e *Escpos) wake() {
 		//     writeBytes(0);
 		//     timeoutSet(10000L);
 		e.WriteBytes([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
-		e.timeoutSet(10000)
+		e.hold(10 * time.Millisecond)
 	}
 }
 
@@ -281,12 +512,14 @@ func (e *Escpos) wake() {
 // sec of uptime before printer can receive data.
 // func (e *Escpos) Begin(heatTime uint8) {
 func (e *Escpos) Begin() {
-	e.timeoutSet(500000)
-	e.wake()
+	e.begun = true
+	e.paper = 0
+	e.hold(500 * time.Millisecond)
+	e.Wake()
 	e.reset()
 
 	if e.Verbose {
-		fmt.Printf("func Begin()\n")
+		fmt.Fprintf(e.logOut(), "func Begin()\n")
 	}
 	// ESC 7 n1 n2 n3 Setting Control Parameter Command
 	// n1 = "max heating dots" 0-255 -- max number of thermal print head
@@ -307,12 +540,8 @@ func (e *Escpos) Begin() {
 	// but slower printing speed.
 
 	// writeBytes(ASCII_ESC, '7');   // Esc 7 (print settings)
-	e.Write("\x1B7")
-	e.WriteBytes([]byte{11, 80, 40})
-	// OR
-	// e.WriteBytes([]byte{7, 80, 2})
-
 	// writeBytes(11, heatTime, 40); // Heating dots, heat time, heat interval
+	// the values are the ones of the quality preset, see Qualities
 
 	// Print density description from manual:
 	// DC2 # n Set printing density
@@ -322,24 +551,21 @@ func (e *Escpos) Begin() {
 	// is n(D7-D5)*250us.
 	// (Unsure of the default value for either -- not documented)
 
-	e.printDensity = 10  // 100% (? can go higher, text is darker but fuzzy)
-	e.printBreakTime = 2 // 500 uS
-
 	// writeBytes(ASCII_DC2, '#', (printBreakTime << 5) | printDensity);
-	// fmt.Println((e.printBreakTime << 5) | e.printDensity)
-	// e.Write(fmt.Sprintf("\x12#%v", (e.printBreakTime<<5)|e.printDensity))
-	e.Write(fmt.Sprintf("\x12#%c", (e.printBreakTime<<5)|e.printDensity))
-
-	// Enable DTR pin if requested
-	// if(dtrPin < 255) {
-	//   pinMode(dtrPin, INPUT_PULLUP);
-	//   writeBytes(ASCII_GS, 'a', (1 << 5));
-	// e.Write(fmt.Sprintf("\x1Da%c", (1 << 5)))
-	//   dtrEnabled = true;
-	// }
+	// normal: density 10 100% (? can go higher, text is darker but fuzzy),
+	// break time 2 500 uS; printers reset to the normal line spacing
+	e.applyQuality(e.quality.Name != "normal")
+	if len(e.profile.CodePage) > 0 {
+		e.SetCodePage(e.profile.CodePage)
+	}
 
-	e.dotPrintTime = 30000 // See comments near top of file for
-	e.dotFeedTime = 2100   // an explanation of these values.
+	// DTR pin of Profile.GPIO: the printer signals busy on it
+	if n := e.dtrBusy(); n != 0 {
+		e.WriteBytes([]byte{29, 'a', n})
+	}
+
+	// dotPrintTime is the one of the quality preset
+	e.dotFeedTime = e.feedTime() // See comments near top of file for an explanation.
 	e.maxChunkHeight = 255
 }
 
@@ -351,21 +577,17 @@ func (e *Escpos) Begin() {
 // TestPage - print test page
 func (e *Escpos) TestPage() {
 	if e.Verbose {
-		fmt.Printf("func TestPage()\n")
+		fmt.Fprintf(e.logOut(), "func TestPage()\n")
 	}
 	// writeBytes(ASCII_DC2, 'T');
 	e.Write("\x12T")
-	// timeoutSet(
-	//   e.dotPrintTime * 24 * 26 +      // 26 lines w/text (ea. 24 dots high)
-	//   e.dotFeedTime * (6 * 26 + 30)); // 26 text lines (feed 6 dots) + blank line
-	e.timeoutSet(e.dotPrintTime*24*26 + e.dotFeedTime*(6*26+30))
 }
 
 // SetAlign - set alignment
 // align (left, center, right)
 func (e *Escpos) SetAlign(align string) (err error) {
 	if e.Verbose {
-		fmt.Printf("func SetAlign()\n")
+		fmt.Fprintf(e.logOut(), "func SetAlign()\n")
 	}
 	a := 0
 	switch align {
@@ -384,7 +606,8 @@ func (e *Escpos) SetAlign(align string) (err error) {
 	default:
 		err = fmt.Errorf("Invalid alignment: %s", align)
 	}
-	e.Write(fmt.Sprintf("\x1Ba%c", a))
+	e.align = [...]string{"left", "center", "right"}[a]
+	e.WriteRaw(e.cmd.Align(byte(a)))
 	return err
 }
 
@@ -392,48 +615,50 @@ func (e *Escpos) SetAlign(align string) (err error) {
 // The inherited Print class handles the rest!
 func (e *Escpos) WriteText(data string) (err error) {
 	if e.Verbose {
-		fmt.Printf("func SetAlign()\n")
+		fmt.Fprintf(e.logOut(), "func SetAlign()\n")
 	}
 	data = e.textReplace(data)
+	if !e.recent() || e.tabAligned() {
+		// no tab stops in the printer, or ones it can't align at
+		data = e.expandTabs(data, e.column)
+	}
 	rawData, err := e.enc.String(data)
 	if err != nil {
-		return fmt.Errorf("Couldn't encode to charset (%s)", err)
+		err = fmt.Errorf("%w (%s)", ErrEncode, err)
+		// the job misses the text, Err reports it
+		if e.err == nil {
+			e.err = err
+		}
+		return err
 	}
 	if len(rawData) > 0 {
-		// b := byte{19}
 		for _, c := range []byte(rawData) {
-			if c != 0x13 {
-				e.timeoutWait()
-				if !e.Debug {
-					_, err := e.Serial.Write([]byte{c})
-					if err != nil {
-						e.err = err
-					}
-				} else {
-					// fmt.Printf("%c", c)
-					fmt.Printf("%d ", c)
+			if c == 0x13 {
+				continue
+			}
+			if c != ASCIILF && c != 9 && e.column > 0 && e.column+e.charWidth() > e.lineDots() {
+				// the character doesn't fit, the line ends before it
+				if _, err := e.send([]byte{ASCIILF}); err != nil {
+					e.err = err
 				}
-				d := int64(BYTETIME)
-				if c == ASCIILF || e.column == e.maxColumn {
-					e.timeoutSet(int64(BYTETIME) + ((e.charHeight + e.lineSpacing) * e.dotFeedTime))
-					e.timeoutWait()
-					e.column = 0
-					c = ASCIILF
-					if !e.Debug {
-						_, err := e.Serial.Write([]byte{c})
-						if err != nil {
-							e.err = err
-						}
-					} else {
-						fmt.Println("")
-					}
-					d += ((e.charHeight * e.dotPrintTime) + (e.lineSpacing * e.dotFeedTime))
-				} else {
-					e.column++
+				e.advance(e.lineHeight())
+				e.prevByte = ASCIILF
+				e.column = 0
+			}
+			if _, err := e.send([]byte{c}); err != nil {
+				e.err = err
+			}
+			if c == ASCIILF {
+				e.advance(e.lineHeight())
+				e.column = 0
+			} else if c == 9 {
+				if t := e.nextTab(e.column); t >= 0 {
+					e.column = e.tabDots[t]
 				}
-				e.timeoutSet(int64(d))
-				e.prevByte = c
+			} else {
+				e.column += e.charWidth()
 			}
+			e.prevByte = c
 		}
 	} else {
 		return fmt.Errorf("len data = 0 :)")
@@ -451,7 +676,7 @@ func (e *Escpos) Flush() {
 // SetCharset - Alters some chars in ASCII 0x23-0x7E range; see datasheet
 func (e *Escpos) SetCharset(val uint8) {
 	if e.Verbose {
-		fmt.Printf("func SetCharset()\n")
+		fmt.Fprintf(e.logOut(), "func SetCharset()\n")
 	}
 	if val > 15 {
 		val = 15
@@ -459,61 +684,106 @@ func (e *Escpos) SetCharset(val uint8) {
 	e.Write(fmt.Sprintf("\x1BR%c", val))
 }
 
-// SetCodePage - Selects alt symbols for 'upper' ASCII values 0x80-0xFF
-func (e *Escpos) SetCodePage(code string) {
+// SetCodePage - Selects alt symbols for 'upper' ASCII values 0x80-0xFF,
+// code is a CodePages name
+func (e *Escpos) SetCodePage(code string) error {
 	if e.Verbose {
-		fmt.Printf("func SetCodePage()\n")
+		fmt.Fprintf(e.logOut(), "func SetCodePage()\n")
 	}
-	var n byte
-	switch code {
-	case "PC437": // USA: Standard Europe
-		e.enc = charmap.CodePage437.NewEncoder()
-		n = 0
-	case "PC850": // Western Europe
-		e.enc = charmap.CodePage850.NewEncoder()
-		n = 2
-	case "CP1251": // Cyrillic
-		e.enc = charmap.Windows1251.NewEncoder()
-		n = 6
-	default:
-		n = 47
+	cp, ok := FindCodePage(code)
+	if !ok {
+		return fmt.Errorf("Invalid code page: %s", code)
+	}
+	b, ok := e.cmd.CodePage(cp)
+	if !ok {
+		return fmt.Errorf("Code page %s is not supported by %s printers", code, e.cmd.Name())
+	}
+	e.enc = cp.Charmap.NewEncoder()
+	e.codePage = cp.Name
+	if e.hooks.job != nil {
+		e.hooks.codePage = cp.Name
 	}
-	e.Write(fmt.Sprintf("\x1Bt%c", n))
+	e.WriteRaw(b)
+	return nil
 }
 
+// CodePage - name of the code page of SetCodePage, PC437 before it
+func (e *Escpos) CodePage() string {
+	if len(e.codePage) == 0 {
+		return "PC437"
+	}
+	return e.codePage
+}
+
+// tab - HT to the next tab stop
 func (e *Escpos) tab() {
 	if e.Verbose {
-		fmt.Printf("func tab()\n")
+		fmt.Fprintf(e.logOut(), "func tab()\n")
 	}
-	e.Write("\t")
-	e.column = (e.column + 4)
+	e.WriteText("\t")
 }
 
 // LinePrint - print line -------
 func (e *Escpos) LinePrint() {
-	for i := 0; i < int(e.maxColumn); i++ {
-		e.Write("-")
-	}
+	e.Rule("-")
 }
 
-// Feed - send N feeds
+// Feed - send N feeds, with the command of Profile.FeedMode
 func (e *Escpos) Feed(n int) {
-	if e.Firmware >= 264 {
-		e.Write(fmt.Sprintf("\x1Bd%c", n))
-		e.timeoutSet(e.dotFeedTime * e.charHeight)
-		e.prevByte = ASCIILF
-		e.column = 0
-	} else {
+	switch e.feedMode() {
+	case "lines":
+		for rest := n; rest > 0; rest -= 255 {
+			l := rest
+			if l > 255 {
+				l = 255
+			}
+			e.WriteRaw(e.cmd.FeedLines(uint8(l)))
+		}
+		e.advance(n * e.lineHeight())
+	case "dots":
+		// the lines of the current font and line spacing
+		for dots := n * int(e.charHeight+e.lineSpacing); dots > 0; dots -= 255 {
+			d := dots
+			if d > 255 {
+				d = 255
+			}
+			e.FeedDots(uint8(d))
+		}
+	default:
 		for i := 0; i < n; i++ {
 			e.WriteBytes([]byte{10})
 		}
+		e.advance(n * e.lineHeight())
 	}
+	e.prevByte = ASCIILF
+	e.column = 0
+}
+
+// feedMode - command of Feed: lines (ESC d) on FirmwareRecent firmware, LF
+// before, unless the profile sets one
+func (e *Escpos) feedMode() string {
+	switch e.profile.FeedMode {
+	case "", "auto":
+		if e.recent() {
+			return "lines"
+		}
+		return "lf"
+	}
+	return e.profile.FeedMode
+}
+
+// FeedDots - feed n dot rows (ESC J), a part of a line
+func (e *Escpos) FeedDots(n uint8) {
+	e.WriteBytes(e.cmd.FeedDots(n))
+	e.advance(int(n))
+	e.prevByte = ASCIILF
+	e.column = 0
 }
 
 // Linefeed -  send linefeed
 func (e *Escpos) Linefeed() {
 	if e.Verbose {
-		fmt.Printf("func Linefeed()\n")
+		fmt.Fprintf(e.logOut(), "func Linefeed()\n")
 	}
 	e.Feed(1)
 	// byte 110
@@ -522,56 +792,60 @@ func (e *Escpos) Linefeed() {
 	// e.WriteBytes([]byte{10})
 }
 
-// FormFeed -  send formfeed
-func (e *Escpos) FormFeed() {
-	e.Feed(1)
-}
-
 // SetBold - bold mode true/false
 func (e *Escpos) SetBold(state bool) {
-	if state {
-		e.WriteBytes([]byte{27, 32, 1})
-		e.WriteBytes([]byte{27, 69, 1})
-	} else {
-		e.WriteBytes([]byte{27, 32, 0})
-		e.WriteBytes([]byte{27, 69, 0})
-	}
+	e.bold = state
+	e.WriteBytes(e.cmd.Bold(state))
 }
 
-// SetSmall - set small font true/false
+// SetSmall - font B (small) true/false, bold, size and underline are
+// kept
 func (e *Escpos) SetSmall(state bool) {
-	if state {
-		e.WriteBytes([]byte{27, 33, 1})
-	} else {
-		e.WriteBytes([]byte{27, 33, 0})
-	}
+	e.small = state
+	e.charMetrics()
+	e.WriteBytes(e.cmd.Small(state))
 }
 
 // SetFontSize - set font size
 // large/medium/normal
 func (e *Escpos) SetFontSize(name string) {
 	if name == "large" || name == "L" {
-		e.charHeight = 48
-		e.maxColumn = 16
-		e.WriteBytes([]byte{29, 33, 17, 10})
+		e.setSize(2, 2)
 	} else if name == "medium" || name == "M" {
-		e.charHeight = 48
-		e.maxColumn = 32
-		e.WriteBytes([]byte{29, 33, 1, 10})
+		e.setSize(1, 2)
 	} else {
-		e.charHeight = 24
-		e.maxColumn = 32
-		e.WriteBytes([]byte{29, 33, 0, 10})
+		e.setSize(1, 1)
+	}
+	// in page mode the LF would move the base line of a positioned row
+	if !e.page {
+		e.WriteBytes([]byte{10})
+		e.advance(e.lineHeight())
+		e.prevByte = ASCIILF
+		e.column = 0
 	}
 }
 
-// DoubleHeight - set double height
-func (e *Escpos) DoubleHeight(state bool) {
-	if state {
-		e.Write(fmt.Sprintf("\x1B!%c", (1 << 4)))
-	} else {
-		e.Write(fmt.Sprintf("\x1B!%c", 0))
+// SetSize - character width and height multiples 1..8, the font and
+// bold are kept
+func (e *Escpos) SetSize(width, height uint8) error {
+	if width < 1 || width > 8 || height < 1 || height > 8 {
+		return fmt.Errorf("Invalid font size: %d x %d", width, height)
 	}
+	e.setSize(width, height)
+	return nil
+}
+
+// setSize - character width and height multiples, the line metrics follow
+func (e *Escpos) setSize(width, height uint8) {
+	e.width = width
+	e.height = height
+	e.charMetrics()
+	e.WriteRaw(e.cmd.Size(width, height))
+}
+
+// DoubleHeight - set double height, the width is kept
+func (e *Escpos) DoubleHeight(state bool) {
+	e.setSize(e.width, 1+flag(state))
 }
 
 func (e *Escpos) setBarcodeHeight(val uint8) {
@@ -581,132 +855,505 @@ func (e *Escpos) setBarcodeHeight(val uint8) {
 		val = 255
 	}
 	e.barcodeHeight = val
-	e.Write(fmt.Sprintf("\x1D\x68%c", val))
 }
 
-// BarcodeChr - 1:Abovebarcode 2:Below 3:Both 0:Not printed
+// BarcodeChr - 1:Abovebarcode 2:Below 3:Both 0:Not printed, sent with
+// the next bar code
 func (e *Escpos) BarcodeChr(val uint8) {
-	e.Write(fmt.Sprintf("\x1D\x68%c", val))
-	// 		self.write(chr(29)) # Leave
-	// 		self.write(chr(72)) # Leave
-	// 		self.write(msg)     # Print barcode # 1:Abovebarcode 2:Below 3:Both 0:Not printed
+	if val > 3 {
+		val = 2
+	}
+	e.barcodeHRI = val
+}
+
+// SetBarcodeWidth - module width 2..6, sent with the next bar code
+func (e *Escpos) SetBarcodeWidth(val uint8) {
+	if val < 2 {
+		val = 2
+	} else if val > 6 {
+		val = 6
+	}
+	e.barcodeWidth = val
 }
 
-// BarCode print barcode
+// SetHRIStyle - style of the HRI of the next bar code: "small" (font B),
+// "bold" or both; printers draw their own HRI small, not bold
+func (e *Escpos) SetHRIStyle(style string) {
+	e.hriStyle = style
+}
+
+// barCodeTypes - GS k bar code numbers of firmware before 2.64
+var barCodeTypes = map[string]uint8{
+	"UPC_A":   0,
+	"UPCA":    0,
+	"UPC_E":   1,
+	"UPCE":    1,
+	"EAN13":   2,
+	"EAN8":    3,
+	"CODE39":  4,
+	"I25":     5,
+	"CODEBAR": 6,
+	"CODE93":  7,
+	"CODE128": 8,
+	"CODE11":  9,
+	"MSI":     10,
+}
+
+// BarCode print barcode, its module narrowed until it fits the paper
+// (see BarCodeDots); a wider one prints rotated, a CODE128 as an image
+// of 1 dot modules when that fits
 func (e *Escpos) BarCode(code string, data string) {
 	if e.Verbose {
-		fmt.Printf("func BarCode()\n")
+		fmt.Fprintf(e.logOut(), "func BarCode()\n")
 	}
-	var a uint8
-	switch code {
-	case "UPC_A":
-		a = 0
-	case "UPC_E":
-		a = 1
-	case "UPCA":
-		a = 0
-	case "UPCE":
-		a = 1
-	case "EAN13":
-		a = 2
-	case "EAN8":
-		a = 3
-	case "CODE39":
-		a = 4
-	case "I25":
-		a = 5
-	case "CODEBAR":
-		a = 6
-	case "CODE93":
-		a = 7
-	case "CODE128":
-		a = 8
-	case "CODE11":
-		a = 9
-	case "MSI":
-		a = 10
-	default:
-		a = 4
-	}
-	// Print label below barcode
-	e.WriteBytes([]byte{29, 72, 2})
-	// Barcode width
-	e.WriteBytes([]byte{29, 119, 3})
-	//  Barcode type
-	e.Write(fmt.Sprintf("\x1D\x6B%c", a))
-	e.timeoutWait()
-	e.timeoutSet((int64(e.barcodeHeight) + 40) * e.dotPrintTime)
-	e.Write(data)
+	if err := e.barCode(code, data); err != nil {
+		e.err = err
+		return
+	}
+	e.Feed(2)
+}
+
+// barCode - BarCode without the feed after it
+func (e *Escpos) barCode(code string, data string) error {
+	width := e.fitBarCode(code, data)
+	if width == 0 {
+		return e.wideBarCode(code, data)
+	}
+	p := e.barCodeParams()
+	p.Width = width
+	e.WriteRaw(e.cmd.BarCode(code, data, p))
+	e.advance(int(e.barcodeHeight))
 	// super(Adafruit_Thermal, self).write(text)
 	e.prevByte = ASCIILF
+	return nil
+}
+
+// barCodeParams - bar code settings of the next GS k bar code
+func (e *Escpos) barCodeParams() BarCodeParams {
+	p := BarCodeParams{HRI: e.barcodeHRI, Height: e.barcodeHeight, Width: e.barcodeWidth, Legacy: !e.recent()}
+	if strings.Contains(e.hriStyle, "small") {
+		p.Font = 1
+	}
+	return p
+}
+
+// writeImageRow - print image row from Src (or Text for old models) or base64 Data
+func (e *Escpos) writeImageRow(row models.Printer) error {
+	var img image.Image
+	var err error
+	if len(row.Data) > 0 {
+		img, err = DecodeImage(row.Data)
+	} else if len(row.Src) > 0 {
+		img, err = LoadImage(row.Src)
+	} else {
+		img, err = LoadImage(row.Text)
+	}
+	if err != nil {
+		return err
+	}
+	e.PrintImage(img, int(row.Width), row.Dither, row.Align)
+	return nil
+}
+
+// PrintBarCode - print bar code with HRI, height, width and type from opt,
+// opt.HriText or the data in opt.HriFormat under it
+func (e *Escpos) PrintBarCode(opt models.BarCodeOption, data string) {
+	if opt.Width == 0 {
+		opt.Width = 3
+	}
+	if opt.Height == 0 {
+		opt.Height = 50
+	}
+	e.BarcodeChr(opt.Chr)
+	e.setBarcodeHeight(opt.Height)
+	e.SetBarcodeWidth(opt.Width)
+	e.SetHRIStyle(opt.HriStyle)
+	var err error
+	switch {
+	case opt.Rotate:
+		err = e.ladderBarCode(opt.Code, data)
+	case gs1Codes[opt.Code]:
+		err = e.gs1BarCode(opt.Code, data)
+	case opt.Code == ITF14:
+		err = e.itf14(data)
+	default:
+		err = e.barCode(opt.Code, data)
+	}
+	if err != nil {
+		e.err = err
+		return
+	}
+	// the custom HRI under the bars, after the one of the printer
+	if len(opt.HriText) > 0 {
+		e.printHRI(opt.HriText)
+	} else if len(opt.HriFormat) > 0 {
+		e.printHRI(HRIFormat(opt.Code, opt.HriFormat, data))
+	}
 	e.Feed(2)
 }
 
+// QrCode - print QR code (GS ( k), opt.QrSize module size 1..16, opt.QrEcc L/M/Q/H
+func (e *Escpos) QrCode(opt models.BarCodeOption, data string) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func QrCode()\n")
+	}
+	size := opt.QrSize
+	if size == 0 {
+		size = 6
+	} else if size > 16 {
+		size = 16
+	}
+	if e.imageMode == "column" {
+		// no GS ( k, encode the symbol and print it as an image
+		r, err := QrRaster(data, size, strings.ToUpper(opt.QrEcc))
+		if err != nil {
+			e.err = err
+			return
+		}
+		e.PrintColumns(r)
+		e.Feed(1)
+		return
+	}
+	e.WriteRaw(e.cmd.QrCode(data, size, strings.ToUpper(opt.QrEcc)))
+	e.prevByte = ASCIILF
+	e.Feed(1)
+}
+
 // WriteNode write a "node" to the printer
 func (e *Escpos) WriteNode(data []models.Printer, set *models.BarCodeOption) {
 	for _, row := range data {
-		// if i%20 == 0 {
-		// 	time.Sleep(1000 * time.Millisecond)
-		// }
-		if row.Line && len(row.Text) == 0 {
-			e.LinePrint()
-		} else if row.Image {
-			if e.Debug {
-				fmt.Println("TODO: add print image")
+		if err := e.Position(row.X, row.Y); err != nil {
+			fmt.Fprintln(e.logOut(), err)
+		}
+		switch row.Kind() {
+		case "section":
+			if a := row.Area; a != nil && e.page {
+				if err := e.SetPageArea(int(a.X), int(a.Y), int(a.Width), int(a.Height), int(a.Rotate)); err != nil {
+					fmt.Fprintln(e.logOut(), err)
+				}
 			}
-		} else if row.BarCode {
-			e.SetAlign(row.Align)
-			// if len(row.Size) > 0 {
-			// 	if msg, err :=  strconv.Atoi(row.Size); err == nil {
-			e.BarcodeChr(set.Chr)
-			e.setBarcodeHeight(set.Height)
-			e.BarCode(set.Code, row.Text)
-			// 		e.
-			// 	}
-			// }
-		} else if row.QrCode {
-			if e.Debug {
-				fmt.Println("TODO: add print QR code")
+			if len(row.Side) > 0 {
+				e.writeTwoUp(row, set)
+			} else if row.Frame {
+				e.writeFrame(row.Rows, set)
+			} else {
+				e.WriteNode(row.Rows, set)
 			}
-		} else {
-			if row.Style == "bold" {
-				e.SetBold(true)
-			} else if row.Style == "small" {
-				e.SetSmall(true)
+		case "repeat", "include":
+			// expanded by models.Render / LoadPrintModel
+		case "cut":
+			e.CutMode(row.Cut)
+		case "drawer":
+			e.Cash()
+		case "beep":
+			e.Beep(row.Beep)
+		case "feed":
+			if e.frame {
+				e.FrameText(strings.Repeat("\n", int(row.Feed)-1), "left")
+				continue
 			}
-			if row.Size != "normal" {
-				e.SetFontSize(row.Size)
+			e.Feed(int(row.Feed))
+		case "formfeed":
+			e.FormFeed()
+		case "space":
+			if err := e.Space(row.Space); err != nil {
+				fmt.Fprintln(e.logOut(), err)
+			}
+		case "signature":
+			if err := e.Signature(row.Space, row.Text); err != nil {
+				fmt.Fprintln(e.logOut(), err)
+			}
+		case "line":
+			if e.frame {
+				e.FrameRule()
+				continue
+			}
+			if err := e.Rule(row.LineStyle); err != nil {
+				fmt.Fprintln(e.logOut(), err)
 			}
+		case "image":
+			if err := e.writeImageRow(row); err != nil {
+				fmt.Fprintln(e.logOut(), err)
+			}
+		case "barcode":
 			e.SetAlign(row.Align)
-			e.WriteText(row.Text)
-
-			e.timeoutWait()
-			e.Linefeed()
-			e.timeoutWait()
+			e.PrintBarCode(row.BarCodeOptions(*set), row.Text)
+		case "qrcode":
+			e.SetAlign(row.Align)
+			e.QrCode(row.BarCodeOptions(*set), row.Text)
+			e.SetAlign("left")
+		case "datamatrix":
+			e.SetAlign(row.Align)
+			e.DataMatrix(row.BarCodeOptions(*set), row.Text)
 			e.SetAlign("left")
-			if row.Style == "bold" {
-				e.SetBold(false)
-			} else if row.Style == "small" {
-				e.SetSmall(false)
+		case "aztec":
+			e.SetAlign(row.Align)
+			e.Aztec(row.BarCodeOptions(*set), row.Text)
+			e.SetAlign("left")
+		default:
+			// the code page of the row is for the row only
+			var restore string
+			if prev := e.CodePage(); len(row.Encoding) > 0 && row.Encoding != prev {
+				if err := e.SetCodePage(row.Encoding); err != nil {
+					fmt.Fprintln(e.logOut(), err)
+				} else {
+					restore = prev
+				}
+			}
+			e.PushStyle()
+			for _, style := range strings.Fields(row.Style) {
+				if style == "bold" {
+					e.SetBold(true)
+				} else if style == "small" {
+					e.SetSmall(true)
+				}
+			}
+			if len(row.Size) > 0 && row.Size != "normal" {
+				e.SetFontSize(row.Size)
+			}
+			if row.Underline > 0 {
+				e.SetUnderline(row.Underline)
+			}
+			cols := int(e.maxColumn)
+			if e.frame {
+				cols -= 4
 			}
-			if row.Size != "normal" {
-				e.SetFontSize("normal")
+			text := e.rowText(row, cols)
+			if row.Wrap && e.frame {
+				text = wordWrap(text, int(e.maxColumn)-4)
+			}
+			if len(row.Right) > 0 || len(row.Fill) > 0 {
+				var fill byte
+				if len(row.Fill) > 0 {
+					fill = row.Fill[0]
+				}
+				text = padBetween(e.rowText(row, cols), row.Right, fill, cols)
+			}
+			if e.frame {
+				if err := e.FrameText(text, row.Align); err != nil {
+					fmt.Fprintln(e.logOut(), err)
+				}
+			} else if row.Wrap {
+				e.SetAlign(row.Align)
+				for _, line := range strings.Split(e.WordWrap(text), "\n") {
+					e.WriteText(line)
+					e.Linefeed()
+				}
+			} else {
+				e.SetAlign(row.Align)
+				e.WriteText(text)
+				e.Linefeed()
+			}
+			e.PopStyle()
+			if len(restore) > 0 {
+				e.SetCodePage(restore)
 			}
 			if row.Line {
-				e.LinePrint()
+				if err := e.Rule(row.LineStyle); err != nil {
+					fmt.Fprintln(e.logOut(), err)
+				}
 			}
 			if e.Debug {
-				fmt.Println(">>>>>>>>>>>>>>>>>>>>", row.Text)
+				fmt.Fprintln(e.logOut(), ">>>>>>>>>>>>>>>>>>>>", row.Text)
+			}
+		}
+	}
+}
+
+// writeFrame - rows of a framed section in a box, nested frames are
+// printed inside the outer one
+func (e *Escpos) writeFrame(rows []models.Printer, set *models.BarCodeOption) {
+	if e.frame {
+		e.WriteNode(rows, set)
+		return
+	}
+	if err := e.FrameBegin(); err != nil {
+		fmt.Fprintln(e.logOut(), err)
+	}
+	e.WriteNode(rows, set)
+	if err := e.FrameEnd(); err != nil {
+		fmt.Fprintln(e.logOut(), err)
+	}
+}
+
+// writeTwoUp - the QR code or image of the first of the rows of a side
+// row beside the others, see TwoUp; a side row without rows prints its
+// text across the line
+func (e *Escpos) writeTwoUp(row models.Printer, set *models.BarCodeOption) {
+	if len(row.Rows) == 0 {
+		if len(row.Text) > 0 {
+			e.WriteNode([]models.Printer{{Align: row.Align, Style: row.Style, Size: row.Size, Text: row.Text}}, set)
+		}
+		return
+	}
+	fig, err := e.figure(row.Rows[0], set, e.dots/2)
+	if err != nil {
+		fmt.Fprintln(e.logOut(), err)
+		e.WriteNode(row.Rows[1:], set)
+		return
+	}
+	if err := e.TwoUp(fig, row.Side, row.Rows[1:], set); err != nil {
+		fmt.Fprintln(e.logOut(), err)
+	}
+}
+
+// figure - raster of a qrcode, datamatrix, aztec or image row, at most max dots
+// wide
+func (e *Escpos) figure(row models.Printer, set *models.BarCodeOption, max int) (*Raster, error) {
+	switch row.Kind() {
+	case "qrcode":
+		opt := row.BarCodeOptions(*set)
+		size := opt.QrSize
+		if size == 0 {
+			size = 6
+		} else if size > 16 {
+			size = 16
+		}
+		for {
+			r, err := QrRaster(row.Text, size, strings.ToUpper(opt.QrEcc))
+			if err != nil || r.Width <= max || size == 1 {
+				return r, err
 			}
+			size--
+		}
+	case "datamatrix", "aztec":
+		opt := row.BarCodeOptions(*set)
+		size := opt.QrSize
+		if size == 0 {
+			size = 6
+		}
+		for {
+			var r *Raster
+			var err error
+			if row.Kind() == "aztec" {
+				r, err = AztecRaster(row.Text, size, strings.ToUpper(opt.QrEcc))
+			} else {
+				r, err = DataMatrixRaster(row.Text, size)
+			}
+			if err != nil || r.Width <= max || size == 1 {
+				return r, err
+			}
+			size--
+		}
+	case "image":
+		var img image.Image
+		var err error
+		if len(row.Data) > 0 {
+			img, err = DecodeImage(row.Data)
+		} else if len(row.Src) > 0 {
+			img, err = LoadImage(row.Src)
+		} else {
+			img, err = LoadImage(row.Text)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return scaleRaster(img, int(row.Width), max, row.Dither), nil
+	}
+	return nil, fmt.Errorf("Two-up: the first row is a %s, not a 2D code or an image", row.Kind())
+}
+
+// writePage - rows of a page mode section printed as one page, in line
+// mode when the printer has no page mode
+func (e *Escpos) writePage(page *models.Area, rows []models.Printer, set *models.BarCodeOption) {
+	if err := e.BeginPage(int(page.Width), int(page.Height)); err != nil {
+		fmt.Fprintln(e.logOut(), err)
+		e.WriteNode(rows, set)
+		return
+	}
+	if page.Rotate != 0 {
+		if err := e.SetPageArea(0, 0, int(page.Width), int(page.Height), int(page.Rotate)); err != nil {
+			fmt.Fprintln(e.logOut(), err)
+		}
+	}
+	e.WriteNode(rows, set)
+	e.PrintPage()
+}
+
+// PrintModel - print all sections of a rendered model
+func (e *Escpos) PrintModel(m *models.PrinterLine) {
+	// the quality of the model is for this job only
+	if prev := e.quality.Name; len(m.Quality) > 0 && m.Quality != prev {
+		if err := e.SetQuality(m.Quality); err != nil {
+			fmt.Fprintln(e.logOut(), err)
+		} else {
+			defer e.SetQuality(prev)
+		}
+	}
+	if m.Columns > 0 && m.Columns != e.lineColumns {
+		prev := e.lineColumns
+		if err := e.SetColumns(m.Columns); err != nil {
+			fmt.Fprintln(e.logOut(), err)
+		} else {
+			defer e.SetColumns(prev)
+		}
+	}
+	if len(m.Tabs) > 0 {
+		prev, align := e.TabStops(), e.TabAlign()
+		if err := e.setModelTabs(m.Tabs, m.TabAlign); err != nil {
+			fmt.Fprintln(e.logOut(), err)
+		} else {
+			defer func() {
+				e.SetTabStops(prev)
+				e.SetTabAlign(align)
+			}()
+		}
+	}
+	if m.Label != nil {
+		e.writeLabel(m)
+	} else {
+		e.writeSections(m)
+	}
+	e.setSection("")
+	// one label per model
+	if e.label() {
+		e.FeedLabel()
+	}
+}
+
+// writeSections - the sections of m one after the other
+func (e *Escpos) writeSections(m *models.PrinterLine) {
+	for _, s := range m.Sections {
+		e.setSection(s.Name)
+		if s.Page != nil {
+			e.writePage(s.Page, s.Rows, &m.BarCode)
+		} else if s.Frame {
+			e.writeFrame(s.Rows, &m.BarCode)
+		} else if len(s.Rows) > 0 {
+			e.WriteNode(s.Rows, &m.BarCode)
+		}
+		if s.Feed > 0 {
+			e.Feed(int(s.Feed))
+		}
+	}
+}
+
+// PrintCopies - print model n times, every copy after the first starts
+// with a centered banner line (no banner when empty)
+func (e *Escpos) PrintCopies(m *models.PrinterLine, n int, banner string) {
+	for i := 0; i < n; i++ {
+		if i > 0 && len(banner) > 0 {
+			e.PrintBanner(banner)
 		}
+		e.PrintModel(m)
 	}
 }
 
+// PrintBanner - print bold centered line, e.g. "*** COPY ***"
+func (e *Escpos) PrintBanner(text string) {
+	e.SetAlign("center")
+	e.SetBold(true)
+	e.WriteText(text)
+	e.Linefeed()
+	e.SetBold(false)
+	e.SetAlign("left")
+}
+
 // -------------- TODO --------------
 
 // func (e *Escpos) SetCharSpacing(val uint8) {
 // 	if e.Verbose {
-// 		fmt.Printf("func SetCharSpacing()\n")
+// 		fmt.Fprintf(e.logOut(), "func SetCharSpacing()\n")
 // 	}
 // 	e.Write(fmt.Sprintf("\x1B %c", val))
 // }
@@ -722,18 +1369,76 @@ func (e *Escpos) End() {
 	e.Write("\xFA")
 }
 
+// tearLines - lines a tear bar printer feeds for a cut, the print head
+// to the bar
+const tearLines = 4
+
 // Cut - send cut
 func (e *Escpos) Cut() {
-	e.Write("\x1DVA0")
+	e.cut(false)
+}
+
+// PartialCut - send partial cut
+func (e *Escpos) PartialCut() {
+	e.cut(true)
+}
+
+// cut - the cut of Profile.CutMode, a feed to the tear bar with "none"
+func (e *Escpos) cut(partial bool) {
+	switch e.profile.CutMode {
+	case "none":
+		e.Feed(tearLines)
+		e.paper = 0
+		return
+	case "full":
+		partial = false
+	case "partial":
+		partial = true
+	}
+	e.WriteRaw(e.cmd.Cut(partial))
+	e.paper = 0
+}
+
+// CutMode - cut paper, mode full/partial
+func (e *Escpos) CutMode(mode string) {
+	if mode == "partial" {
+		e.PartialCut()
+	} else {
+		e.Cut()
+	}
+}
+
+// Beep - sound the buzzer n times (ESC B n t)
+func (e *Escpos) Beep(n uint8) {
+	if n > 9 {
+		n = 9
+	}
+	e.WriteBytes(e.cmd.Beep(n))
+}
+
+// SetPanelButtons - enable or disable the panel (feed) button (ESC c 5 n),
+// the setting lasts until reset or power off
+func (e *Escpos) SetPanelButtons(enabled bool) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetPanelButtons()\n")
+	}
+	if !e.adafruit() {
+		return
+	}
+	var n byte
+	if !enabled {
+		n = 1
+	}
+	e.WriteBytes([]byte{27, 99, 53, n})
 }
 
 // Cash - send cash
 func (e *Escpos) Cash() {
-	e.Write("\x1B\x70\x00\x0A\xFF")
+	e.WriteRaw(e.cmd.Drawer())
 }
 
 // SetFont - set font
-func (e *Escpos) SetFont(font string) {
+func (e *Escpos) SetFont(font string) (err error) {
 	f := 0
 
 	switch font {
@@ -744,11 +1449,15 @@ func (e *Escpos) SetFont(font string) {
 	case "C":
 		f = 2
 	default:
-		log.Fatal(fmt.Sprintf("Invalid font: '%s', defaulting to 'A'", font))
+		err = fmt.Errorf("Invalid font: '%s', defaulting to 'A'", font)
 		f = 0
 	}
+	// lines of font B and C take as many characters as small ones
+	e.small = f > 0
+	e.charMetrics()
 
-	e.Write(fmt.Sprintf("\x1BM%c", f))
+	e.WriteRaw(e.cmd.Font(uint8(f)))
+	return err
 }
 
 // SendFontSize -
@@ -769,7 +1478,7 @@ func (e *Escpos) SendFontSize() {
 
 // SendUnderline - send underline
 func (e *Escpos) SendUnderline() {
-	e.Write(fmt.Sprintf("\x1B-%c", e.underline))
+	e.WriteRaw(e.cmd.Underline(e.underline))
 }
 
 // SendEmphasize - send emphasize / doublestrike
@@ -789,7 +1498,7 @@ func (e *Escpos) SendRotate() {
 
 // SendReverse - send reverse
 func (e *Escpos) SendReverse() {
-	e.Write(fmt.Sprintf("\x1DB%c", e.reverse))
+	e.WriteRaw(e.cmd.Reverse(e.reverse > 0))
 }
 
 // SendSmooth - send smooth
@@ -797,14 +1506,14 @@ func (e *Escpos) SendSmooth() {
 	e.Write(fmt.Sprintf("\x1Db%c", e.smooth))
 }
 
-// SendMoveX - send move x
+// SendMoveX - send move x, see MoveX
 func (e *Escpos) SendMoveX(x uint16) {
-	e.Write(string([]byte{0x1b, 0x24, byte(x % 256), byte(x / 256)}))
+	e.MoveX(int(x))
 }
 
-// SendMoveY - send move y
+// SendMoveY - send move y, see MoveY
 func (e *Escpos) SendMoveY(y uint16) {
-	e.Write(string([]byte{0x1d, 0x24, byte(y % 256), byte(y / 256)}))
+	e.MoveY(int(y))
 }
 
 // SetUnderline - set underline
@@ -850,7 +1559,7 @@ func (e *Escpos) Pulse() {
 }
 
 // SetLang - set language -- ESC R
-func (e *Escpos) SetLang(lang string) {
+func (e *Escpos) SetLang(lang string) error {
 	l := 0
 
 	switch lang {
@@ -875,9 +1584,10 @@ func (e *Escpos) SetLang(lang string) {
 	case "no":
 		l = 9
 	default:
-		log.Fatal(fmt.Sprintf("Invalid language: %s", lang))
+		return fmt.Errorf("Invalid language: %s", lang)
 	}
 	e.Write(fmt.Sprintf("\x1BR%c", l))
+	return nil
 }
 
 // Text - do a block of text
@@ -890,7 +1600,9 @@ func (e *Escpos) Text(params map[string]string, data string) {
 
 	// set lang
 	if lang, ok := params["lang"]; ok {
-		e.SetLang(lang)
+		if err := e.SetLang(lang); err != nil {
+			fmt.Fprintln(e.logOut(), err)
+		}
 	}
 
 	// set smooth
@@ -919,8 +1631,14 @@ func (e *Escpos) Text(params map[string]string, data string) {
 	}
 
 	// set font
+	// "font_b" or "b"
 	if font, ok := params["font"]; ok {
-		e.SetFont(strings.ToUpper(font[5:6]))
+		if len(font) > 5 {
+			font = font[5:6]
+		}
+		if err := e.SetFont(strings.ToUpper(font)); err != nil {
+			fmt.Fprintln(e.logOut(), err)
+		}
 	}
 
 	// do dw (double font width)
@@ -951,22 +1669,9 @@ func (e *Escpos) Text(params map[string]string, data string) {
 	// 	}
 	// }
 
-	// do y positioning
-	if x, ok := params["x"]; ok {
-		if i, err := strconv.Atoi(x); err == nil {
-			e.SendMoveX(uint16(i))
-		} else {
-			log.Fatal("Invalid x param %d", x)
-		}
-	}
-
-	// do y positioning
-	if y, ok := params["y"]; ok {
-		if i, err := strconv.Atoi(y); err == nil {
-			e.SendMoveY(uint16(i))
-		} else {
-			log.Fatal("Invalid y param %d", y)
-		}
+	// do x and y positioning, dots or millimeters
+	if err := e.Position(params["x"], params["y"]); err != nil {
+		fmt.Fprintln(e.logOut(), err)
 	}
 
 	// do text replace, then write data
@@ -985,71 +1690,20 @@ func (e *Escpos) FeedAndCut(params map[string]string) {
 	e.Cut()
 }
 
-// used to send graphics headers
-func (e *Escpos) gSend(m byte, fn byte, data []byte) {
-	l := len(data) + 2
-
-	e.Write("\x1b(L")
-	e.WriteRaw([]byte{byte(l % 256), byte(l / 256), m, fn})
-	e.WriteRaw(data)
-}
-
-// Image - write an image
-func (e *Escpos) Image(params map[string]string, data string) {
-	// send alignment to printer
-	if align, ok := params["align"]; ok {
-		e.SetAlign(align)
-	}
-
-	// get width
-	wstr, ok := params["width"]
-	if !ok {
-		log.Fatal("No width specified on image")
-	}
-
-	// get height
-	hstr, ok := params["height"]
-	if !ok {
-		log.Fatal("No height specified on image")
-	}
-
-	// convert width
-	width, err := strconv.Atoi(wstr)
-	if err != nil {
-		log.Fatal("Invalid image width %s", wstr)
-	}
-
-	// convert height
-	height, err := strconv.Atoi(hstr)
+// Image - print base64 encoded png/jpeg/gif data with GS v 0, params are
+// align, width (dots, 0 - image width) and dither (floyd, threshold)
+func (e *Escpos) Image(params map[string]string, data string) error {
+	img, err := DecodeImage(data)
 	if err != nil {
-		log.Fatal("Invalid image height %s", hstr)
+		e.err = err
+		return err
 	}
-
-	// decode data frome b64 string
-	dec, err := base64.StdEncoding.DecodeString(data)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	log.Printf("Image len:%d w: %d h: %d\n", len(dec), width, height)
-
-	// $imgHeader = self::dataHeader(array($img -> getWidth(), $img -> getHeight()), true);
-	// $tone = '0';
-	// $colors = '1';
-	// $xm = (($size & self::IMG_DOUBLE_WIDTH) == self::IMG_DOUBLE_WIDTH) ? chr(2) : chr(1);
-	// $ym = (($size & self::IMG_DOUBLE_HEIGHT) == self::IMG_DOUBLE_HEIGHT) ? chr(2) : chr(1);
-	//
-	// $header = $tone . $xm . $ym . $colors . $imgHeader;
-	// $this -> graphicsSendData('0', 'p', $header . $img -> toRasterFormat());
-	// $this -> graphicsSendData('0', '2');
-
-	header := []byte{
-		byte('0'), 0x01, 0x01, byte('1'),
+	width := 0
+	if wstr, ok := params["width"]; ok {
+		if width, err = strconv.Atoi(wstr); err != nil {
+			return fmt.Errorf("Invalid image width: %s", wstr)
+		}
 	}
-
-	a := append(header, dec...)
-
-	e.gSend(byte('0'), byte('p'), a)
-	e.gSend(byte('0'), byte('2'), []byte{})
-
+	e.PrintRaster(e.newRaster(img, width, params["dither"]).Align(params["align"], e.dots))
+	return e.err
 }
@@ -0,0 +1,123 @@
+// Package escpostest - fake printer recording the ESC/POS stream, and
+// golden file checks of the recorded bytes
+package escpostest
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/grengojbo/gotp/escpos"
+)
+
+// Device - fake printer port: records everything written, answers the
+// queries registered with Reply
+type Device struct {
+	mu      sync.Mutex
+	written bytes.Buffer
+	replies []reply
+	pending []byte
+	rx      chan struct{}
+	closed  bool
+}
+
+// reply - answer sent when cmd is written
+type reply struct {
+	cmd, data []byte
+}
+
+// NewDevice - device without replies
+func NewDevice() *Device {
+	return &Device{rx: make(chan struct{}, 1)}
+}
+
+// NewPrinter - unpaced printer on a new device, the initialization bytes
+// of the printer are not recorded
+func NewPrinter() (*escpos.Escpos, *Device) {
+	d := NewDevice()
+	p := escpos.NewReadWriter(d)
+	d.Reset()
+	return p, d
+}
+
+// Reply - send data every time cmd is written, e.g. DLE EOT 1 -> 0x12
+func (d *Device) Reply(cmd, data []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.replies = append(d.replies, reply{cmd: cmd, data: data})
+}
+
+// Write - record p and queue the replies of the commands in it
+func (d *Device) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return 0, io.ErrClosedPipe
+	}
+	d.written.Write(p)
+	queued := false
+	for _, r := range d.replies {
+		if bytes.Contains(p, r.cmd) {
+			d.pending = append(d.pending, r.data...)
+			queued = true
+		}
+	}
+	if queued {
+		select {
+		case d.rx <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Read - queued replies, blocks until there are some or Close
+func (d *Device) Read(p []byte) (int, error) {
+	for {
+		d.mu.Lock()
+		if len(d.pending) > 0 {
+			n := copy(p, d.pending)
+			d.pending = d.pending[n:]
+			d.mu.Unlock()
+			return n, nil
+		}
+		if d.closed {
+			d.mu.Unlock()
+			return 0, io.ErrClosedPipe
+		}
+		d.mu.Unlock()
+		<-d.rx
+	}
+}
+
+// Close - stop the reader of the printer, writes fail after it
+func (d *Device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.closed {
+		d.closed = true
+		close(d.rx)
+	}
+	return nil
+}
+
+// Bytes - everything written since the last Reset
+func (d *Device) Bytes() []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]byte{}, d.written.Bytes()...)
+}
+
+// Reset - forget the written bytes
+func (d *Device) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.written.Reset()
+}
+
+// Record - bytes written by fn on a new printer
+func Record(fn func(p *escpos.Escpos)) []byte {
+	p, d := NewPrinter()
+	fn(p)
+	return d.Bytes()
+}
@@ -0,0 +1,111 @@
+package escpostest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grengojbo/gotp/escpos"
+)
+
+// update - go test -update-golden writes the golden files
+var update = flag.Bool("update-golden", false, "write the escpostest golden files")
+
+// prefixes - names of the command introducers
+var prefixes = map[byte]string{16: "DLE", 18: "DC2", 27: "ESC", 28: "FS", 29: "GS"}
+
+// controls - names of single byte controls
+var controls = map[byte]string{9: "HT", 10: "LF", 12: "FF", 13: "CR", 24: "CAN"}
+
+// Dump - data one command per line: text in quotes, controls by name,
+// commands as "ESC @" or "GS V 41 00" with the parameters in hex
+func Dump(data []byte) string {
+	var b strings.Builder
+	var text []byte
+	flush := func() {
+		if len(text) > 0 {
+			fmt.Fprintf(&b, "%q\n", text)
+			text = nil
+		}
+	}
+	for i := 0; i < len(data); {
+		l := escpos.CommandLen(data[i:])
+		if i+l > len(data) {
+			l = len(data) - i
+		}
+		c := data[i : i+l]
+		i += l
+		if l == 1 && c[0] >= 32 {
+			text = append(text, c[0])
+			continue
+		}
+		flush()
+		if name, ok := controls[c[0]]; ok && l == 1 {
+			b.WriteString(name + "\n")
+			continue
+		}
+		name, ok := prefixes[c[0]]
+		if !ok || l == 1 {
+			fmt.Fprintf(&b, "%02X\n", c)
+			continue
+		}
+		b.WriteString(name)
+		if c[1] > 32 && c[1] < 127 {
+			fmt.Fprintf(&b, " %c", c[1])
+		} else {
+			fmt.Fprintf(&b, " %02X", c[1])
+		}
+		for _, p := range c[2:] {
+			fmt.Fprintf(&b, " %02X", p)
+		}
+		b.WriteString("\n")
+	}
+	flush()
+	return b.String()
+}
+
+// Golden - fail t when the Dump of got differs from testdata/name.golden,
+// -update-golden writes the file instead
+func Golden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	dump := []byte(Dump(got))
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, dump, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Golden %s: %s (run go test -update-golden)", name, err.Error())
+	}
+	if !bytes.Equal(want, dump) {
+		t.Errorf("Golden %s differs:\n%s", name, diff(string(want), string(dump)))
+	}
+}
+
+// diff - the lines of want and got from the first one that differs
+func diff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	i := 0
+	for i < len(w) && i < len(g) && w[i] == g[i] {
+		i++
+	}
+	end := func(l []string) []string {
+		if i+5 < len(l) {
+			return l[i : i+5]
+		}
+		return l[i:]
+	}
+	return fmt.Sprintf("line %d\nwant:\n%s\ngot:\n%s", i+1,
+		strings.Join(end(w), "\n"), strings.Join(end(g), "\n"))
+}
@@ -0,0 +1,339 @@
+package escpos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FirmwareDefault - firmware assumed when it is not configured or
+	// detection fails (Adafruit PRINTER_FIRMWARE)
+	FirmwareDefault = 268
+
+	// FirmwareRecent - first firmware with tab stops, ESC d feeds, 16 bit
+	// sleep time and GS k bar codes with a length byte. Older units get
+	// no tab stops, feed with LF, sleep with ESC 8 n and take NUL
+	// terminated bar codes numbered 0-10
+	FirmwareRecent = 264
+)
+
+// Profile - printer model settings, the printers of a config file (see
+// LoadConfig) add their port and overrides
+type Profile struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Port - serial port or "tcp:<host[:port]>" of NewProfile
+	Port string `json:"port,omitempty"`
+	// Baud - serial baud rate, 0 is BAUDRATE
+	Baud int `json:"baud,omitempty"`
+	// Firmware - version * 100 (2.68 -> 268), 0 detects it with GS I
+	Firmware int `json:"firmware,omitempty"`
+	// Frame - data bits, parity and stop bits ("8N1", "7E1"), empty is 8N1
+	Frame string `json:"frame,omitempty"`
+	// ReadTimeout - serial read timeout, also the wait for query replies
+	ReadTimeout time.Duration `json:"-"`
+	// Commands - CommandSets name, empty is escpos
+	Commands string `json:"commands,omitempty"`
+	// Image - image command: "bitmap" (DC2 *, default), "raster" (GS v 0)
+	// or "column" (ESC *, also QR codes as images for firmware without them)
+	Image string `json:"image,omitempty"`
+	// Media - "receipt" (continuous roll, default) or "label" (gap or black
+	// mark stock, every model ends with a feed to the next label)
+	Media string `json:"media,omitempty"`
+	// FeedMode - command of Feed: "lf" (an LF a line), "lines" (ESC d n),
+	// "dots" (ESC J of the line height) for clones which misfeed with the
+	// others; empty or "auto" is lines on FirmwareRecent firmware, LF before
+	FeedMode string `json:"feed_mode,omitempty"`
+	// PageLength - length of the pages of pre-printed roll stock, "70mm",
+	// "24lines" of the line height or dots; FormFeed feeds to the start of
+	// the next one, counted from Begin or the last cut (empty - none)
+	PageLength string `json:"page_length,omitempty"`
+	// CutMode - cuts of the jobs: "full" or "partial" for all of them,
+	// "none" of tear bar printers feeds the paper to the bar instead; empty
+	// or "auto" cuts as the job asks
+	CutMode string `json:"cut_mode,omitempty"`
+	// DotPrintTime, DotFeedTime - microseconds to print (at the normal
+	// quality, the others scale) and to feed a dot row measured by
+	// Calibrate, gotp calibrate --save writes them; 0 - estimates
+	DotPrintTime int64 `json:"dot_print_time,omitempty"`
+	DotFeedTime  int64 `json:"dot_feed_time,omitempty"`
+	// BufferSize - bytes of the printer buffer an image band fits in, 0 -
+	// 256 with DC2 *, 4096 with GS v 0
+	BufferSize int `json:"buffer_size,omitempty"`
+	// BandHeight - dot rows of the image bands instead of the ones which
+	// fit in BufferSize
+	BandHeight int `json:"band_height,omitempty"`
+	// BandWait - wait between image bands: "status" until the printer
+	// answers GS r (the print time when it doesn't), "delay" the print
+	// time of the band; empty or "none" - the pacing of the port only
+	BandWait string `json:"band_wait,omitempty"`
+	// DPI - print head resolution for millimeter positions, 0 is 203
+	DPI int `json:"dpi,omitempty"`
+	// Width - print head width in dots, 0 is MAXIMAGEWIDTH (58 mm paper),
+	// 576 for 80 mm; the characters per line follow
+	Width int `json:"width,omitempty"`
+	// Columns - characters per line of font A at normal size when they
+	// don't follow Width (42 of 80 mm printers with a narrower print
+	// area), the ones of font B and the other sizes follow; 0 - from Width
+	Columns int `json:"columns,omitempty"`
+	// CodePage - code page Begin selects (see encodings), empty keeps the
+	// one of the printer
+	CodePage string `json:"codepage,omitempty"`
+	// Quality - quality preset, empty is normal
+	Quality string `json:"quality,omitempty"`
+	// HeatDots, HeatTime, HeatInterval, Density - heat settings replacing
+	// the ones of the quality preset when not 0
+	HeatDots     uint8 `json:"heat_dots,omitempty"`
+	HeatTime     uint8 `json:"heat_time,omitempty"`
+	HeatInterval uint8 `json:"heat_interval,omitempty"`
+	Density      uint8 `json:"density,omitempty"`
+	// Sensors - the printer answers the head temperature and voltage
+	// queries of SensorCommands
+	Sensors bool `json:"sensors,omitempty"`
+	// Symbols - 2D codes besides QR the printer draws itself
+	// ("datamatrix", "aztec", "databar"), the others print as images
+	Symbols []string `json:"symbols,omitempty"`
+	// BeforeJob, AfterJob - shell commands around every job, see
+	// ShellHook: "till-open || exit 1", "curl -s -d \"$GOTP_ERROR\" ..."
+	BeforeJob string `json:"before_job,omitempty"`
+	AfterJob  string `json:"after_job,omitempty"`
+	// GPIO - Raspberry Pi pins of the paper door, a status LED and DTR
+	GPIO *GPIO `json:"gpio,omitempty"`
+}
+
+// Profiles - printers --profile accepts
+var Profiles = []Profile{
+	{Name: "adafruit", Description: "Adafruit / CSN-A2, firmware 2.68", Firmware: FirmwareDefault},
+	{Name: "adafruit-old", Description: "Adafruit / CSN-A2 before firmware 2.64", Firmware: 260, Image: "column"},
+	{Name: "adafruit-label", Description: "Adafruit / CSN-A2 on label or black mark rolls", Firmware: FirmwareDefault, Media: "label"},
+	{Name: "csn-a2", Description: "CSN-A2 vendor firmware, reports head temperature and voltage", Firmware: FirmwareDefault, Sensors: true},
+	{Name: "auto", Description: "Detect firmware with GS I, 2.68 when the printer does not answer"},
+	{Name: "star", Description: "Star Micronics TSP100 / TSP650 / TSP700 line mode", Firmware: FirmwareDefault, Commands: "star"},
+}
+
+// FindProfile - profile by name
+func FindProfile(name string) (Profile, bool) {
+	for _, p := range Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// SetProfile - use the firmware of profile p, detecting it when p has none
+func (e *Escpos) SetProfile(p Profile) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetProfile() %s\n", p.Name)
+	}
+	cmd, ok := FindCommandSet(p.Commands)
+	if !ok {
+		return fmt.Errorf("Invalid command set: %s", p.Commands)
+	}
+	e.cmd = cmd
+	switch p.Image {
+	case "", "bitmap", "raster", "column":
+		e.imageMode = p.Image
+	default:
+		return fmt.Errorf("Invalid image command: %s", p.Image)
+	}
+	switch p.Media {
+	case "", "receipt", "label":
+		e.media = p.Media
+	default:
+		return fmt.Errorf("Invalid media: %s", p.Media)
+	}
+	switch p.FeedMode {
+	case "", "auto", "lf", "lines", "dots":
+	default:
+		return fmt.Errorf("Invalid feed mode: %s", p.FeedMode)
+	}
+	switch p.CutMode {
+	case "", "auto", "full", "partial", "none":
+	default:
+		return fmt.Errorf("Invalid cut mode: %s", p.CutMode)
+	}
+	switch p.BandWait {
+	case "", "none", "status", "delay":
+	default:
+		return fmt.Errorf("Invalid band wait: %s", p.BandWait)
+	}
+	if p.BandHeight < 0 || p.BandHeight > 255 {
+		return fmt.Errorf("Invalid band height: %d", p.BandHeight)
+	}
+	if p.BufferSize < 0 || p.BufferSize > 1<<20 {
+		return fmt.Errorf("Invalid buffer size: %d", p.BufferSize)
+	}
+	e.dpi = p.DPI
+	if _, err := e.parsePageLength(p.PageLength); err != nil {
+		return err
+	}
+	e.sensors = p.Sensors
+	e.symbolNames = p.Symbols
+	if p.Width < 0 || p.Width > 1024 {
+		return fmt.Errorf("Invalid width: %d", p.Width)
+	}
+	e.dots = MAXIMAGEWIDTH
+	if p.Width > 0 {
+		e.dots = p.Width
+	}
+	if p.Columns < 0 || p.Columns > 255 {
+		return fmt.Errorf("Invalid columns: %d", p.Columns)
+	}
+	e.lineColumns = p.Columns
+	e.charMetrics()
+	if len(p.CodePage) > 0 {
+		cp, ok := FindCodePage(p.CodePage)
+		if !ok {
+			return fmt.Errorf("Invalid code page: %s", p.CodePage)
+		}
+		if _, ok := cmd.CodePage(cp); !ok {
+			return fmt.Errorf("Code page %s is not supported by %s printers", p.CodePage, cmd.Name())
+		}
+	}
+	q, ok := FindQuality(p.Quality)
+	if !ok {
+		return fmt.Errorf("Invalid quality: %s", p.Quality)
+	}
+	if p.HeatDots > 0 {
+		q.HeatDots = p.HeatDots
+	}
+	if p.HeatTime > 0 {
+		q.HeatTime = p.HeatTime
+	}
+	if p.HeatInterval > 0 {
+		q.HeatInterval = p.HeatInterval
+	}
+	if p.Density > 0 {
+		q.Density = p.Density
+	}
+	e.quality = q
+	if p.GPIO != nil && e.gpio == nil {
+		g, err := openGPIO(*p.GPIO)
+		if err != nil {
+			return err
+		}
+		e.gpio = g
+	}
+	e.profile = p
+	if err := e.SetTiming(e.Timing()); err != nil {
+		return err
+	}
+	if p.Firmware > 0 {
+		e.Firmware = p.Firmware
+		return nil
+	}
+	_, err := e.DetectFirmware()
+	return err
+}
+
+// Profile - profile of SetProfile
+func (e *Escpos) Profile() Profile {
+	return e.profile
+}
+
+// DetectFirmware - set Firmware from the GS I 65 reply, on error Firmware
+// is left unchanged
+func (e *Escpos) DetectFirmware() (int, error) {
+	s, err := e.firmwareString()
+	if err != nil {
+		return e.Firmware, fmt.Errorf("Firmware detection: %s, using %d", err.Error(), e.Firmware)
+	}
+	v, err := parseFirmware(s)
+	if err != nil {
+		return e.Firmware, fmt.Errorf("Firmware detection: %s, using %d", err.Error(), e.Firmware)
+	}
+	e.Firmware = v
+	return v, nil
+}
+
+// parseFirmware - version number of a firmware string ("2.68", "V2.6.8")
+func parseFirmware(s string) (int, error) {
+	start := strings.IndexAny(s, "0123456789")
+	if start < 0 {
+		return 0, fmt.Errorf("invalid version %q", s)
+	}
+	end := start
+	for end < len(s) && strings.IndexByte("0123456789.", s[end]) >= 0 {
+		end++
+	}
+	v := strings.Replace(strings.Trim(s[start:end], "."), ".", "", -1)
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q", s)
+	}
+	return n, nil
+}
+
+// columns - characters per line of font A at normal size
+func (e *Escpos) columns() uint8 {
+	return uint8(e.lineDots() / 12)
+}
+
+// lineDots - dots of a line of text: Profile.Columns or SetColumns
+// characters of font A, the print head width without them
+func (e *Escpos) lineDots() int {
+	if e.lineColumns > 0 {
+		return e.lineColumns * 12
+	}
+	return e.dots
+}
+
+// SetColumns - characters per line of font A at normal size, the ones of
+// font B and the other sizes follow; wrapping, rules, frames and padding
+// honor them. 0 - from the print head width
+func (e *Escpos) SetColumns(n int) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetColumns() %d\n", n)
+	}
+	if n < 0 || n > 255 {
+		return fmt.Errorf("Invalid columns: %d", n)
+	}
+	e.lineColumns = n
+	e.charMetrics()
+	return nil
+}
+
+// charWidth - dots of a character of the current font and size: font A
+// 12, font B (small) 9, times the width
+func (e *Escpos) charWidth() int {
+	w := 12
+	if e.small {
+		w = 9
+	}
+	if e.width > 1 {
+		w *= int(e.width)
+	}
+	return w
+}
+
+// charMetrics - character height and the characters per line of the
+// current font and size
+func (e *Escpos) charMetrics() {
+	h := int64(24)
+	if e.small {
+		h = 17
+	}
+	if e.height > 1 {
+		h *= int64(e.height)
+	}
+	e.charHeight = h
+	n := e.lineDots() / e.charWidth()
+	if n > 255 {
+		n = 255
+	}
+	e.maxColumn = uint8(n)
+}
+
+// recent - printer firmware has the FirmwareRecent commands
+func (e *Escpos) recent() bool {
+	return e.Firmware >= FirmwareRecent
+}
+
+// adafruit - command set has the Adafruit / CSN-A2 extensions: heat
+// settings, sleep and panel buttons
+func (e *Escpos) adafruit() bool {
+	return e.cmd.Name() == "escpos"
+}
@@ -0,0 +1,95 @@
+package escpos
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lineHeight - dots an LF feeds in the current font and line spacing
+func (e *Escpos) lineHeight() int {
+	return int(e.charHeight + e.lineSpacing)
+}
+
+// advance - the printer fed dots of paper, printing or feeding
+func (e *Escpos) advance(dots int) {
+	e.paper += dots
+}
+
+// streamed - advance by the feeds, line feeds and rasters of a stream sent
+// with WriteStream, a cut or ESC @ starts the paper over; column images
+// count by their line feed
+func (e *Escpos) streamed(data []byte) {
+	for i := 0; i < len(data); i += CommandLen(data[i:]) {
+		c := data[i:]
+		switch {
+		case c[0] == ASCIILF:
+			e.advance(e.lineHeight())
+		case bytes.HasPrefix(c, e.cmd.Cut(false)), bytes.HasPrefix(c, e.cmd.Cut(true)),
+			bytes.HasPrefix(c, []byte{27, '@'}):
+			e.paper = 0
+		case len(c) > 2 && bytes.HasPrefix(c, e.cmd.FeedLines(0)[:2]):
+			e.advance(int(c[2]) * e.lineHeight())
+		case len(c) > 2 && bytes.HasPrefix(c, e.cmd.FeedDots(0)[:2]):
+			e.advance(int(c[2]))
+		case len(c) > 2 && c[0] == 18 && c[1] == '*':
+			e.advance(int(c[2]))
+		case len(c) > 7 && (bytes.HasPrefix(c, []byte{29, 'v', '0'}) || bytes.HasPrefix(c, []byte{27, 29, 'S'})):
+			e.advance(int(c[6]) | int(c[7])<<8)
+		}
+	}
+}
+
+// parsePageLength - dots of a Profile.PageLength: "70mm", "24lines" of
+// font A lines at the line spacing or dots; 0 without one
+func (e *Escpos) parsePageLength(s string) (int, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	if len(v) == 0 {
+		return 0, nil
+	}
+	if strings.HasSuffix(v, "lines") {
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(v, "lines")))
+		if err != nil || n < 1 || n > 1000 {
+			return 0, fmt.Errorf("Invalid page length: %s", s)
+		}
+		return n * (24 + int(e.lineSpacing)), nil
+	}
+	dots, err := e.ParseDots(v)
+	if err != nil || dots == 0 {
+		return 0, fmt.Errorf("Invalid page length: %s", s)
+	}
+	return dots, nil
+}
+
+// FormFeed - feed to the start of the next page of Profile.PageLength,
+// nothing at the start of one; on label stock to the next label, without
+// a page length one line. The paper is counted from Begin or the last cut
+// by the text lines, feeds, images, bar codes and pages printed, streams
+// of WriteStream by their feeds, see streamed
+func (e *Escpos) FormFeed() {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func FormFeed()\n")
+	}
+	if e.label() {
+		e.FeedLabel()
+		return
+	}
+	page, _ := e.parsePageLength(e.profile.PageLength)
+	if page == 0 {
+		e.Feed(1)
+		return
+	}
+	if e.column > 0 {
+		// the line printed so far
+		e.Feed(1)
+	}
+	for rest := (page - e.paper%page) % page; rest > 0; rest -= 255 {
+		n := rest
+		if n > 255 {
+			n = 255
+		}
+		e.FeedDots(uint8(n))
+	}
+	e.paper = 0
+}
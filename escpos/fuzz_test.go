@@ -0,0 +1,86 @@
+package escpos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// fuzzSeeds - text of the fuzz targets: entity chains, invalid UTF-8,
+// controls and long words
+var fuzzSeeds = []string{
+	"&amp;lt;",
+	"&amp;amp;amp;",
+	"&lt;b&gt;&#10;&#xA;&quot;&apos;",
+	"&#8364; &eacute; &#x1F600; &#99999999999;",
+	"\xff\xfe\xfd",
+	"caf\xc3",
+	"\t\ttab\tstops\n\n",
+	"\x1b@\x1dV\x00",
+	strings.Repeat("W", 300),
+	"Привет, мир",
+	"",
+}
+
+func FuzzTextReplace(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s, false)
+	}
+	f.Fuzz(func(t *testing.T, s string, entities bool) {
+		e := NewWriter(ioutil.Discard)
+		e.SetEntities(entities)
+		if r := e.textReplace(s); !utf8.ValidString(r) {
+			t.Errorf("textReplace(%q) = %q, invalid UTF-8", s, r)
+		}
+	})
+}
+
+func FuzzWriteText(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s, false)
+	}
+	f.Fuzz(func(t *testing.T, s string, entities bool) {
+		e := NewWriter(ioutil.Discard)
+		e.SetEntities(entities)
+		// an unencodable text is an error of the job, never a panic
+		e.WriteText(s)
+		e.WriteText(e.WordWrap(s))
+	})
+}
+
+func FuzzPrintModel(f *testing.F) {
+	for _, seed := range []string{
+		`{"version": 2, "barCode": {"code": "CODE128", "height": 60},
+		  "sections": [{"name": "head", "feed": 2, "rows": [
+		  {"align": "center", "style": "bold", "size": "large", "text": "GOTP"},
+		  {"line": true}, {"text": "Coffee", "right": "2.50", "fill": "."}]},
+		  {"frame": true, "rows": [{"text": "boxed"}]},
+		  {"rows": [{"barCode": true, "text": "123"}, {"qrCode": true, "text": "x"},
+		  {"feed": 3}, {"beep": 2}, {"drawer": true}, {"cut": "partial"}]}]}`,
+		`{"version": 2, "sections": [{"page": {"width": 384, "height": 200, "rotate": 90},
+		  "rows": [{"area": {"x": 10, "y": 10, "width": 100, "height": 50}, "rows": [{"text": "a"}]},
+		  {"x": "3mm", "y": "40", "text": "b"}]}]}`,
+		`{"version": 2, "sections": [{"page": {"width": 0, "height": 0, "rotate": 45}, "rows": [{"text": "x"}]}]}`,
+		`{"version": 2, "sections": [{"rows": [{"barCode": true, "code": "EAN13", "text": "12"},
+		  {"qrCode": true, "qrSize": 255, "qrEcc": "Z", "text": ""}, {"image": true, "data": "!!"}]}]}`,
+		`{"version": 2, "sections": [{"rows": [{"text": "x", "x": "-99999mm", "y": "1e9"}, {"size": "9x9", "text": "y"}]}]}`,
+		`{"version": 2, "sections": [{"rows": [{"type": "section", "side": "left"},
+		  {"type": "section", "side": "right", "rows": [{"qrCode": true, "text": "x"}]}]}]}`,
+		`{"version": 1, "header": [{"text": "h"}], "lines": [{"text": "l"}], "footer": [{"cut": "full"}]}`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := models.ReadPrintModel(bytes.NewReader(data), t.TempDir())
+		if err != nil || m.Render() != nil {
+			return
+		}
+		// whatever the model, printing it is never a panic or a hang
+		e := NewWriter(ioutil.Discard)
+		e.PrintModel(&m)
+	})
+}
@@ -0,0 +1,371 @@
+package escpos_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/grengojbo/gotp/escpos"
+	"github.com/grengojbo/gotp/escpos/escpostest"
+	"github.com/grengojbo/gotp/models"
+)
+
+// record - bytes fn writes on a printer of profile p, the ones of
+// SetProfile left out
+func record(t *testing.T, p escpos.Profile, fn func(e *escpos.Escpos)) []byte {
+	t.Helper()
+	e, d := escpostest.NewPrinter()
+	if err := e.SetProfile(p); err != nil {
+		t.Fatal(err)
+	}
+	d.Reset()
+	fn(e)
+	if err := e.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return d.Bytes()
+}
+
+// adafruit - profile of the golden files, firmware 2.68
+var adafruit = escpos.Profile{Name: "adafruit", Firmware: escpos.FirmwareDefault}
+
+// testModel - model of the WriteNode / PrintModel golden files
+const testModel = `{
+  "version": 2,
+  "barCode": {"code": "CODE128", "height": 60, "qrSize": 4, "qrEcc": "M"},
+  "sections": [
+    {"name": "header", "feed": 1, "rows": [
+      {"align": "center", "style": "bold", "size": "large", "text": "GOTP"},
+      {"line": true},
+      {"text": "Coffee", "right": "2.50", "fill": "."},
+      {"text": "Tea", "right": "1.80", "fill": "."}
+    ]},
+    {"name": "box", "frame": true, "rows": [
+      {"align": "center", "text": "Thank you"}
+    ]},
+    {"name": "codes", "rows": [
+      {"barCode": true, "text": "GOTP-1234"},
+      {"qrCode": true, "text": "https://example.com/r/42"},
+      {"feed": 2},
+      {"cut": "partial"}
+    ]}
+  ]
+}`
+
+// loadModel - testModel parsed
+func loadModel(t *testing.T) *models.PrinterLine {
+	t.Helper()
+	m, err := models.ReadPrintModel(strings.NewReader(testModel), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &m
+}
+
+// testRaster - w x h dots of diagonal stripes
+func testRaster(w, h int) *escpos.Raster {
+	r := &escpos.Raster{Width: w, Height: h}
+	r.Data = make([]byte, r.RowBytes()*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (x+y)%4 == 0 {
+				r.Data[y*r.RowBytes()+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return r
+}
+
+func TestGoldenAlign(t *testing.T) {
+	escpostest.Golden(t, "align", record(t, adafruit, func(e *escpos.Escpos) {
+		for _, a := range []string{"left", "center", "right"} {
+			if err := e.SetAlign(a); err != nil {
+				t.Fatal(err)
+			}
+			e.WriteText(a)
+			e.Linefeed()
+		}
+	}))
+}
+
+func TestGoldenStyle(t *testing.T) {
+	escpostest.Golden(t, "style", record(t, adafruit, func(e *escpos.Escpos) {
+		e.SetBold(true)
+		e.WriteText("bold")
+		e.SetBold(false)
+		e.Linefeed()
+		e.SetSmall(true)
+		e.WriteText("small")
+		e.SetSmall(false)
+		e.Linefeed()
+		e.SetFontSize("large")
+		e.WriteText("large")
+		e.SetFontSize("normal")
+	}))
+}
+
+func TestGoldenBarCode(t *testing.T) {
+	escpostest.Golden(t, "barcode", record(t, adafruit, func(e *escpos.Escpos) {
+		e.BarCode(escpos.CODE128, "GOTP-1234")
+		e.BarCode(escpos.EAN13, "123456789012")
+	}))
+}
+
+func TestGoldenBarCodeLegacy(t *testing.T) {
+	// firmware before 2.64 numbers the bar code types from 0
+	old := escpos.Profile{Name: "adafruit-old", Firmware: 260}
+	escpostest.Golden(t, "barcode-legacy", record(t, old, func(e *escpos.Escpos) {
+		e.BarCode(escpos.CODE128, "GOTP-1234")
+		e.BarCode(escpos.EAN13, "123456789012")
+	}))
+}
+
+func TestGoldenQrCode(t *testing.T) {
+	escpostest.Golden(t, "qrcode", record(t, adafruit, func(e *escpos.Escpos) {
+		e.QrCode(models.BarCodeOption{QrSize: 4, QrEcc: "M"}, "https://example.com/r/42")
+	}))
+}
+
+func TestGoldenBitmap(t *testing.T) {
+	escpostest.Golden(t, "bitmap", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintBitmap(testRaster(16, 30))
+	}))
+}
+
+func TestGoldenRaster(t *testing.T) {
+	escpostest.Golden(t, "raster", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintRaster(testRaster(16, 30))
+	}))
+}
+
+func TestGoldenColumns(t *testing.T) {
+	escpostest.Golden(t, "columns", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintColumns(testRaster(16, 30))
+	}))
+}
+
+func TestGoldenCut(t *testing.T) {
+	escpostest.Golden(t, "cut", record(t, adafruit, func(e *escpos.Escpos) {
+		e.Cut()
+		e.PartialCut()
+	}))
+}
+
+func TestGoldenStar(t *testing.T) {
+	star, _ := escpos.FindProfile("star")
+	escpostest.Golden(t, "star", record(t, star, func(e *escpos.Escpos) {
+		if err := e.SetAlign("center"); err != nil {
+			t.Fatal(err)
+		}
+		e.WriteText("Star")
+		e.Linefeed()
+		e.BarCode(escpos.CODE128, "GOTP-1234")
+		e.QrCode(models.BarCodeOption{QrSize: 4, QrEcc: "M"}, "https://example.com/r/42")
+		e.PrintRaster(testRaster(16, 30))
+		e.Cut()
+	}))
+}
+
+func TestGoldenWriteNode(t *testing.T) {
+	m := loadModel(t)
+	escpostest.Golden(t, "writenode", record(t, adafruit, func(e *escpos.Escpos) {
+		e.WriteNode(m.Sections[0].Rows, &m.BarCode)
+	}))
+}
+
+func TestGoldenPrintModel(t *testing.T) {
+	m := loadModel(t)
+	escpostest.Golden(t, "printmodel", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintModel(m)
+	}))
+}
+
+func TestGoldenPrintCopies(t *testing.T) {
+	m := models.TextModel([]string{"one", "two"}, "left")
+	escpostest.Golden(t, "printcopies", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintCopies(&m, 2, "*** COPY ***")
+	}))
+}
+
+func TestGoldenPrintBanner(t *testing.T) {
+	escpostest.Golden(t, "printbanner", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintBanner("*** COPY ***")
+	}))
+}
+
+func TestGoldenFeed(t *testing.T) {
+	escpostest.Golden(t, "feed", record(t, adafruit, func(e *escpos.Escpos) {
+		e.WriteText("lines")
+		e.Feed(3)
+		e.WriteText("dots")
+		e.FeedDots(40)
+		e.WriteText("lf")
+		e.Linefeed()
+		e.WriteText("form feed")
+		e.FormFeed()
+	}))
+}
+
+func TestGoldenRule(t *testing.T) {
+	escpostest.Golden(t, "rule", record(t, adafruit, func(e *escpos.Escpos) {
+		for _, s := range []string{"", "double", "*", "-="} {
+			if err := e.Rule(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}))
+}
+
+func TestGoldenFrame(t *testing.T) {
+	escpostest.Golden(t, "frame", record(t, adafruit, func(e *escpos.Escpos) {
+		if err := e.FrameBegin(); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.FrameText("Order 42", "center"); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.FrameRule(); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.FrameText("a line too long for the inner width of the box", "left"); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.FrameEnd(); err != nil {
+			t.Fatal(err)
+		}
+	}))
+}
+
+func TestGoldenPage(t *testing.T) {
+	escpostest.Golden(t, "page", record(t, adafruit, func(e *escpos.Escpos) {
+		if err := e.BeginPage(384, 200); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.SetPageArea(0, 0, 384, 200, 90); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.PageMove(10, 40); err != nil {
+			t.Fatal(err)
+		}
+		e.WriteText("page mode")
+		e.Linefeed()
+		if err := e.PrintPage(); err != nil {
+			t.Fatal(err)
+		}
+	}))
+}
+
+func TestGoldenDataMatrix(t *testing.T) {
+	opt := models.BarCodeOption{QrSize: 4}
+	symbols := escpos.Profile{Name: "adafruit", Firmware: escpos.FirmwareDefault, Symbols: []string{"datamatrix"}}
+	escpostest.Golden(t, "datamatrix", record(t, symbols, func(e *escpos.Escpos) {
+		e.DataMatrix(opt, "GOTP-1234")
+	}))
+	// printers without the symbol print it as an image
+	escpostest.Golden(t, "datamatrix-raster", record(t, adafruit, func(e *escpos.Escpos) {
+		e.DataMatrix(opt, "GOTP-1234")
+	}))
+}
+
+func TestGoldenAztec(t *testing.T) {
+	opt := models.BarCodeOption{QrSize: 4, QrEcc: "M"}
+	symbols := escpos.Profile{Name: "adafruit", Firmware: escpos.FirmwareDefault, Symbols: []string{"aztec"}}
+	escpostest.Golden(t, "aztec", record(t, symbols, func(e *escpos.Escpos) {
+		e.Aztec(opt, "GOTP-1234")
+	}))
+	escpostest.Golden(t, "aztec-raster", record(t, adafruit, func(e *escpos.Escpos) {
+		e.Aztec(opt, "GOTP-1234")
+	}))
+}
+
+func TestGoldenGS1(t *testing.T) {
+	symbols := escpos.Profile{Name: "adafruit", Firmware: escpos.FirmwareDefault, Symbols: []string{"databar"}}
+	escpostest.Golden(t, "gs1", record(t, symbols, func(e *escpos.Escpos) {
+		e.PrintBarCode(models.BarCodeOption{Code: escpos.GS1128, Height: 60}, "(01)09501101530003(17)261231(10)AB12")
+		e.PrintBarCode(models.BarCodeOption{Code: escpos.DATABAR, Height: 60}, "(01)09501101530003")
+		e.PrintBarCode(models.BarCodeOption{Code: escpos.DATABAREXPANDED, Height: 60}, "(01)09501101530003(3103)000123")
+	}))
+	// DataBar as GS1-128 on printers without it
+	escpostest.Golden(t, "gs1-code128", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintBarCode(models.BarCodeOption{Code: escpos.DATABAR, Height: 60}, "(01)09501101530003")
+	}))
+}
+
+func TestGoldenITF14(t *testing.T) {
+	escpostest.Golden(t, "itf14", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintBarCode(models.BarCodeOption{Code: escpos.ITF14, Height: 60}, "1540014128876")
+	}))
+}
+
+func TestGoldenFormFeed(t *testing.T) {
+	form := escpos.Profile{Name: "adafruit", Firmware: escpos.FirmwareDefault, PageLength: "70mm"}
+	escpostest.Golden(t, "formfeed", record(t, form, func(e *escpos.Escpos) {
+		e.WriteText("page 1")
+		e.Linefeed()
+		e.Feed(2)
+		e.FormFeed()
+		// at the start of a page nothing is fed
+		e.FormFeed()
+		e.WriteText("page 2")
+		e.FormFeed()
+	}))
+}
+
+func TestModelJob(t *testing.T) {
+	m := loadModel(t)
+	want := record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintModel(m)
+	})
+	var p escpos.Progress
+	got := record(t, adafruit, func(e *escpos.Escpos) {
+		e.BeginJob(0)
+		if err := escpos.ModelJob(m).Print(e); err != nil {
+			t.Fatal(err)
+		}
+		p = e.Progress()
+	})
+	if !bytes.Equal(got, want) {
+		t.Errorf("ModelJob sent\n%s\nPrintModel\n%s", escpostest.Dump(got), escpostest.Dump(want))
+	}
+	if p.Total != int64(len(want)) || p.Sent != p.Total {
+		t.Errorf("ModelJob progress %+v, %d bytes", p, len(want))
+	}
+}
+
+func TestGoldenTwoUpEmptySide(t *testing.T) {
+	// side sections without the figure and the rows beside it
+	m, err := models.ReadPrintModel(strings.NewReader(`{"version": 2, "sections": [{"rows": [
+	  {"type": "section", "side": "left"},
+	  {"type": "section", "side": "right", "align": "center", "text": "no figure"}
+	]}]}`), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	escpostest.Golden(t, "twoup-empty", record(t, adafruit, func(e *escpos.Escpos) {
+		e.PrintModel(&m)
+	}))
+}
+
+func TestStreamFormFeed(t *testing.T) {
+	// FormFeed after a stream feeds the rest of the page as it does after
+	// the commands of the stream
+	form := escpos.Profile{Name: "adafruit", Firmware: escpos.FirmwareDefault, PageLength: "70mm"}
+	page := func(e *escpos.Escpos) {
+		e.WriteText("page 1")
+		e.Linefeed()
+		e.Feed(2)
+		e.FeedDots(10)
+	}
+	want := record(t, form, func(e *escpos.Escpos) {
+		page(e)
+		e.FormFeed()
+	})
+	stream := record(t, form, page)
+	got := record(t, form, func(e *escpos.Escpos) {
+		e.WriteStream(stream)
+		e.FormFeed()
+	})
+	if !bytes.Equal(got, want) {
+		t.Errorf("FormFeed after WriteStream\n%s\nafter the commands\n%s", escpostest.Dump(got), escpostest.Dump(want))
+	}
+}
@@ -0,0 +1,213 @@
+package escpos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GPIO - pins of a Raspberry Pi wired to the printer, BCM numbers (0 -
+// not wired), through the sysfs GPIO interface:
+//
+//	"gpio": {"door": 17, "led": 27, "dtr": 22}
+type GPIO struct {
+	// Door - input of the paper door microswitch, the door is open while
+	// it is high; jobs are refused and Status reports CoverOpen
+	Door int `json:"door,omitempty"`
+	// DoorLow - the door is open while Door is low
+	DoorLow bool `json:"door_low,omitempty"`
+	// LED - output lit while a job prints
+	LED int `json:"led,omitempty"`
+	// DTR - input wired to the DTR pin of the printer, high while it is
+	// busy: data waits for it instead of the estimated print time
+	DTR int `json:"dtr,omitempty"`
+}
+
+// gpioRoot - sysfs GPIO directory
+const gpioRoot = "/sys/class/gpio"
+
+// dtrTimeout - longest wait for the DTR pin, a printer which stays busy
+// longer is written to anyway
+const dtrTimeout = 5 * time.Second
+
+// gpioPins - open pins of GPIO, the ones not wired nil
+type gpioPins struct {
+	cfg            GPIO
+	door, led, dtr *gpioPin
+}
+
+// gpioPin - value file of an exported pin
+type gpioPin struct {
+	n     int
+	value *os.File
+}
+
+// openGPIO - export and set up the pins of cfg
+func openGPIO(cfg GPIO) (*gpioPins, error) {
+	res := &gpioPins{cfg: cfg}
+	var err error
+	if cfg.Door > 0 {
+		if res.door, err = openPin(cfg.Door, "in"); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.DTR > 0 {
+		if res.dtr, err = openPin(cfg.DTR, "in"); err != nil {
+			res.close()
+			return nil, err
+		}
+	}
+	if cfg.LED > 0 {
+		if res.led, err = openPin(cfg.LED, "low"); err != nil {
+			res.close()
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// gpioBase - number of BCM pin 0 in sysfs, the base of the pin
+// controller of the SoC (512 on newer kernels), 0 when it isn't found
+func gpioBase() int {
+	chips, _ := filepath.Glob(filepath.Join(gpioRoot, "gpiochip*"))
+	for _, chip := range chips {
+		label, err := ioutil.ReadFile(filepath.Join(chip, "label"))
+		if err != nil || !strings.HasPrefix(string(label), "pinctrl-") {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(chip, "base"))
+		if err != nil {
+			continue
+		}
+		if base, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+			return base
+		}
+	}
+	return 0
+}
+
+// openPin - export BCM pin n with direction "in" or "low" (an output
+// starting low)
+func openPin(n int, direction string) (*gpioPin, error) {
+	id := strconv.Itoa(gpioBase() + n)
+	dir := filepath.Join(gpioRoot, "gpio"+id)
+	if _, err := os.Stat(dir); err != nil {
+		if err := ioutil.WriteFile(filepath.Join(gpioRoot, "export"), []byte(id), 0200); err != nil {
+			return nil, fmt.Errorf("GPIO %d: %s", n, err.Error())
+		}
+	}
+	// udev makes the files of a new pin writable a moment later
+	var err error
+	for i := 0; i < 20; i++ {
+		if err = ioutil.WriteFile(filepath.Join(dir, "direction"), []byte(direction), 0200); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GPIO %d: %s", n, err.Error())
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "value"), os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("GPIO %d: %s", n, err.Error())
+	}
+	return &gpioPin{n: n, value: f}, nil
+}
+
+// read - the pin is high
+func (p *gpioPin) read() (bool, error) {
+	b := make([]byte, 1)
+	if _, err := p.value.ReadAt(b, 0); err != nil {
+		return false, fmt.Errorf("GPIO %d: %s", p.n, err.Error())
+	}
+	return b[0] == '1', nil
+}
+
+// write - set the output high or low
+func (p *gpioPin) write(high bool) error {
+	v := "0"
+	if high {
+		v = "1"
+	}
+	if _, err := p.value.WriteAt([]byte(v), 0); err != nil {
+		return fmt.Errorf("GPIO %d: %s", p.n, err.Error())
+	}
+	return nil
+}
+
+// doorOpen - the paper door switch reads open, false without one
+func (g *gpioPins) doorOpen() bool {
+	if g.door == nil {
+		return false
+	}
+	high, err := g.door.read()
+	return err == nil && high != g.cfg.DoorLow
+}
+
+// setLED - light the LED, nothing without one
+func (g *gpioPins) setLED(on bool) error {
+	if g.led == nil {
+		return nil
+	}
+	return g.led.write(on)
+}
+
+// waitReady - wait until the DTR pin is low, at most dtrTimeout or
+// until deadline
+func (g *gpioPins) waitReady(deadline time.Time) {
+	limit := time.Now().Add(dtrTimeout)
+	if !deadline.IsZero() && deadline.Before(limit) {
+		limit = deadline
+	}
+	for time.Now().Before(limit) {
+		if busy, err := g.dtr.read(); err != nil || !busy {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// hook - refuses jobs while the door is open, lights the LED while a
+// job prints
+func (g *gpioPins) hook() Hook {
+	return Hook{
+		Before: func(e *Escpos, job JobInfo) error {
+			if g.doorOpen() {
+				return fmt.Errorf("Paper door open")
+			}
+			return g.setLED(true)
+		},
+		After: func(e *Escpos, job JobInfo, err error) {
+			g.setLED(false)
+		},
+	}
+}
+
+// close - the LED off, the value files closed
+func (g *gpioPins) close() {
+	g.setLED(false)
+	for _, p := range []*gpioPin{g.door, g.led, g.dtr} {
+		if p != nil {
+			p.value.Close()
+		}
+	}
+}
+
+// readDoor - the paper door switch of Profile.GPIO into s
+func (e *Escpos) readDoor(s *Status) {
+	if e.gpio != nil && e.gpio.doorOpen() {
+		s.CoverOpen = true
+	}
+}
+
+// SetLED - light the status LED of Profile.GPIO, nothing without one
+func (e *Escpos) SetLED(on bool) error {
+	if e.gpio == nil {
+		return nil
+	}
+	return e.gpio.setLED(on)
+}
@@ -0,0 +1,338 @@
+package escpos
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// gs1Codes - bar code types with GS1 element strings like
+// "(01)09501101530003(17)250101(10)AB12"
+var gs1Codes = map[string]bool{
+	GS1128:          true,
+	DATABAR:         true,
+	DATABAREXPANDED: true,
+}
+
+// GS1Element - application identifier (AI) and its data
+type GS1Element struct {
+	AI   string
+	Data string
+}
+
+// gs1Format - data an AI takes
+type gs1Format struct {
+	min, max int
+	numeric  bool
+	// check - the last digit is a GS1 check digit
+	check bool
+	// date - YYMMDD, day 00 is the end of the month
+	date bool
+}
+
+// gs1 formats
+var (
+	gs1N6    = gs1Format{min: 6, max: 6, numeric: true}
+	gs1Date  = gs1Format{min: 6, max: 6, numeric: true, date: true}
+	gs1N8    = gs1Format{min: 1, max: 8, numeric: true}
+	gs1N13   = gs1Format{min: 13, max: 13, numeric: true, check: true}
+	gs1N14   = gs1Format{min: 14, max: 14, numeric: true, check: true}
+	gs1N15   = gs1Format{min: 1, max: 15, numeric: true}
+	gs1ISO   = gs1Format{min: 4, max: 18, numeric: true}
+	gs1AN20  = gs1Format{min: 1, max: 20}
+	gs1AN30  = gs1Format{min: 1, max: 30}
+	gs1AN90  = gs1Format{min: 1, max: 90}
+	gs1Count = gs1Format{min: 3, max: 3, numeric: true}
+)
+
+// gs1AIs - formats of the application identifiers of shipping and
+// retail labels
+var gs1AIs = map[string]gs1Format{
+	"00":   {min: 18, max: 18, numeric: true, check: true},
+	"01":   gs1N14,
+	"02":   gs1N14,
+	"10":   gs1AN20,
+	"11":   gs1Date,
+	"12":   gs1Date,
+	"13":   gs1Date,
+	"15":   gs1Date,
+	"16":   gs1Date,
+	"17":   gs1Date,
+	"20":   {min: 2, max: 2, numeric: true},
+	"21":   gs1AN20,
+	"22":   gs1AN20,
+	"240":  gs1AN30,
+	"241":  gs1AN30,
+	"242":  {min: 1, max: 6, numeric: true},
+	"243":  gs1AN20,
+	"250":  gs1AN30,
+	"251":  gs1AN30,
+	"254":  gs1AN20,
+	"30":   gs1N8,
+	"37":   gs1N8,
+	"400":  gs1AN30,
+	"401":  gs1AN30,
+	"402":  {min: 17, max: 17, numeric: true, check: true},
+	"403":  gs1AN30,
+	"410":  gs1N13,
+	"411":  gs1N13,
+	"412":  gs1N13,
+	"413":  gs1N13,
+	"414":  gs1N13,
+	"415":  gs1N13,
+	"416":  gs1N13,
+	"417":  gs1N13,
+	"420":  gs1AN20,
+	"421":  {min: 4, max: 12},
+	"422":  gs1Count,
+	"423":  {min: 3, max: 15, numeric: true},
+	"424":  gs1Count,
+	"425":  {min: 3, max: 15, numeric: true},
+	"426":  gs1Count,
+	"7001": {min: 13, max: 13, numeric: true},
+	"7003": {min: 10, max: 10, numeric: true},
+	"8004": gs1AN30,
+	"8005": gs1N6,
+	"8008": {min: 8, max: 12, numeric: true},
+	"8018": {min: 18, max: 18, numeric: true, check: true},
+	"8020": {min: 1, max: 25},
+	"90":   gs1AN30,
+}
+
+// gs1Fixed - first digits of the AIs with data of a predefined length,
+// the others end with FNC1 unless they are the last one
+var gs1Fixed = []string{"00", "01", "02", "03", "04", "11", "12", "13", "14", "15",
+	"16", "17", "18", "19", "20", "31", "32", "33", "34", "35", "36", "41"}
+
+// gs1Lookup - format of ai, the 4 digit measures with their decimal
+// point position included
+func gs1Lookup(ai string) (gs1Format, error) {
+	if f, ok := gs1AIs[ai]; ok {
+		return f, nil
+	}
+	if len(ai) == 2 && ai >= "91" && ai <= "99" {
+		return gs1AN90, nil
+	}
+	if len(ai) == 4 {
+		family, n := ai[:3], ai[3]
+		switch {
+		case family >= "310" && family <= "369" && family != "317" && family != "318" && family != "319":
+			// weights, lengths, areas and volumes of trade items and
+			// logistic units
+			if n > '5' {
+				return gs1Format{}, fmt.Errorf("GS1 (%s): decimal point position %c out of 0..5", ai, n)
+			}
+			return gs1N6, nil
+		case family == "390" || family == "392":
+			return gs1N15, nil
+		case family == "391" || family == "393":
+			// ISO 4217 currency and amount
+			return gs1ISO, nil
+		}
+	}
+	return gs1Format{}, fmt.Errorf("GS1: unknown application identifier (%s)", ai)
+}
+
+// gs1AIPattern - an AI of an element string
+var gs1AIPattern = regexp.MustCompile(`\((\d{2,4})\)`)
+
+// gs1Chars - characters of the GS1 character set 82
+const gs1Chars = "!\"%&'()*+,-./0123456789:;<=>?ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+
+// ParseGS1 - elements of text "(AI)data(AI)data...", validated against
+// their AI: length, character set, check digit and date
+func ParseGS1(text string) ([]GS1Element, error) {
+	text = strings.TrimSpace(text)
+	loc := gs1AIPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(loc) == 0 || loc[0][0] != 0 {
+		return nil, fmt.Errorf("GS1: %q does not start with an (AI)", text)
+	}
+	var res []GS1Element
+	for i, m := range loc {
+		end := len(text)
+		if i+1 < len(loc) {
+			end = loc[i+1][0]
+		}
+		el := GS1Element{AI: text[m[2]:m[3]], Data: strings.TrimSpace(text[m[1]:end])}
+		if err := el.Check(); err != nil {
+			return nil, err
+		}
+		res = append(res, el)
+	}
+	return res, nil
+}
+
+// Check - data of the element matches its AI
+func (el GS1Element) Check() error {
+	f, err := gs1Lookup(el.AI)
+	if err != nil {
+		return err
+	}
+	if len(el.Data) < f.min || len(el.Data) > f.max {
+		if f.min == f.max {
+			return fmt.Errorf("GS1 (%s): %q is not %d characters", el.AI, el.Data, f.min)
+		}
+		return fmt.Errorf("GS1 (%s): %q is not %d..%d characters", el.AI, el.Data, f.min, f.max)
+	}
+	for _, c := range el.Data {
+		if f.numeric && (c < '0' || c > '9') {
+			return fmt.Errorf("GS1 (%s): %q is not numeric", el.AI, el.Data)
+		}
+		if !strings.ContainsRune(gs1Chars, c) {
+			return fmt.Errorf("GS1 (%s): %q has the invalid character %q", el.AI, el.Data, c)
+		}
+	}
+	if f.check {
+		last := len(el.Data) - 1
+		if want := GS1CheckDigit(el.Data[:last]); el.Data[last] != want {
+			return fmt.Errorf("GS1 (%s): check digit of %s is %c, not %c", el.AI, el.Data, want, el.Data[last])
+		}
+	}
+	if f.date && !gs1ValidDate(el.Data) {
+		return fmt.Errorf("GS1 (%s): %s is not a YYMMDD date", el.AI, el.Data)
+	}
+	return nil
+}
+
+// fixed - the data of the AI has a predefined length, no FNC1 after it
+func (el GS1Element) fixed() bool {
+	for _, p := range gs1Fixed {
+		if strings.HasPrefix(el.AI, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// GS1CheckDigit - mod 10 check digit of digits (GTIN, GLN, SSCC), weights
+// 3 and 1 from the right
+func GS1CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// gs1ValidDate - YYMMDD with a day of the month or 00
+func gs1ValidDate(s string) bool {
+	year := 2000 + int(s[0]-'0')*10 + int(s[1]-'0')
+	month := int(s[2]-'0')*10 + int(s[3]-'0')
+	day := int(s[4]-'0')*10 + int(s[5]-'0')
+	if month < 1 || month > 12 {
+		return false
+	}
+	days := []int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}[month-1]
+	if month == 2 && year%4 == 0 {
+		days = 29
+	}
+	return day <= days
+}
+
+// GS1String - human readable element string, AIs in parentheses
+func GS1String(els []GS1Element) string {
+	var b strings.Builder
+	for _, el := range els {
+		b.WriteString("(" + el.AI + ")" + el.Data)
+	}
+	return b.String()
+}
+
+// gs1Code128 - Code 128 characters of els: FNC1, then the AIs and their
+// data, FNC1 after the ones of variable length but the last
+func gs1Code128(els []GS1Element) []int {
+	res := []int{code128FNC1}
+	for i, el := range els {
+		for _, c := range el.AI + el.Data {
+			res = append(res, int(c))
+		}
+		if !el.fixed() && i < len(els)-1 {
+			res = append(res, code128FNC1)
+		}
+	}
+	return res
+}
+
+// databarData - GS k data of a GS1 DataBar: the GTIN without the check
+// digit for the omnidirectional one, the AIs and data with {1 after the
+// ones of variable length for the expanded one
+func databarData(expanded bool, els []GS1Element) (string, error) {
+	if !expanded {
+		if len(els) != 1 || els[0].AI != "01" {
+			return "", fmt.Errorf("GS1 DataBar: %s is not a (01) GTIN only, use DATABAR_EXPANDED", GS1String(els))
+		}
+		return els[0].Data[:13], nil
+	}
+	var b strings.Builder
+	for i, el := range els {
+		b.WriteString(el.AI + el.Data)
+		if !el.fixed() && i < len(els)-1 {
+			b.WriteString("{1")
+		}
+	}
+	return b.String(), nil
+}
+
+// gs1BarCode - print the elements of text as a GS1 DataBar on printers
+// which draw it (Profile.Symbols "databar"), else as GS1-128; the element
+// string with the AIs in parentheses is the HRI
+func (e *Escpos) gs1BarCode(code, text string) error {
+	els, err := ParseGS1(text)
+	if err != nil {
+		return err
+	}
+	p := e.barCodeParams()
+	if sc, ok := e.symbols("databar"); ok && code != GS1128 {
+		data, err := databarData(code == DATABAREXPANDED, els)
+		if err != nil {
+			return err
+		}
+		e.WriteRaw(sc.DataBar(code == DATABAREXPANDED, data, p))
+		e.advance(int(e.barcodeHeight))
+		e.prevByte = ASCIILF
+		return nil
+	}
+	parts := code128Parts(gs1Code128(els))
+	widths := code128Widths(code128Values(parts))
+	modules := 0
+	for _, c := range widths {
+		modules += int(c - '0')
+	}
+	module := int(e.barcodeWidth)
+	for module > 1 && modules*module > e.dots {
+		module--
+	}
+	if e.cmd.Name() == "escpos" && e.recent() && module >= 2 {
+		if e.barcodeHRI&1 != 0 {
+			e.printHRI(gs1HRIText(els))
+		}
+		// HRI of the printer would show the data without parentheses
+		p.HRI = 0
+		p.Width = uint8(module)
+		e.WriteRaw(e.cmd.BarCode(CODE128, string(code128Escpos(parts)), p))
+		e.advance(int(e.barcodeHeight))
+		e.prevByte = ASCIILF
+		if e.barcodeHRI&2 != 0 {
+			e.printHRI(gs1HRIText(els))
+		}
+	} else {
+		// no code set selection or narrower modules than GS w takes,
+		// the bars as an image
+		e.rasterBarCode(barRaster(widths, module, int(e.barcodeHeight)), gs1HRIText(els))
+	}
+	return nil
+}
+
+// gs1HRIText - the element string with spaces between the elements for
+// the line breaks of the HRI
+func gs1HRIText(els []GS1Element) string {
+	var words []string
+	for _, el := range els {
+		words = append(words, GS1String([]GS1Element{el}))
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,333 @@
+package escpos
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// hexdumpWidth - bytes per hexdump line
+const hexdumpWidth = 16
+
+// Hexdump - writer printing an ESC/POS stream as offset, hex and ASCII
+// columns, one command per line with what it does:
+//
+//	00000000  1B 61 01                                          .a.               ESC a 1 → align center
+//
+// Text is grouped up to 16 bytes a line; a command split over writes is
+// printed when it is complete, Flush prints what is left
+type Hexdump struct {
+	w       io.Writer
+	offset  int
+	pending []byte
+}
+
+// NewHexdump - hexdump of the stream written to it on w
+func NewHexdump(w io.Writer) *Hexdump {
+	return &Hexdump{w: w}
+}
+
+// Write - dump the complete commands of p, text waits for a control or
+// a full line
+func (h *Hexdump) Write(p []byte) (int, error) {
+	h.pending = append(h.pending, p...)
+	h.dump(false)
+	return len(p), nil
+}
+
+// Flush - dump the pending bytes, an incomplete command as it is
+func (h *Hexdump) Flush() {
+	h.dump(true)
+}
+
+// dump - print the pending commands, all when flush
+func (h *Hexdump) dump(flush bool) {
+	data := h.pending
+	// start - first byte not printed, text runs from start to i
+	start, i := 0, 0
+	for i < len(data) {
+		l := CommandLen(data[i:])
+		if l == 1 && data[i] >= 32 && data[i] != 127 {
+			i++
+			if i-start == hexdumpWidth {
+				h.line(data[start:i], "text")
+				start = i
+			}
+			continue
+		}
+		if !flush && !complete(data[i:], l) {
+			break
+		}
+		if start < i {
+			h.line(data[start:i], "text")
+		}
+		if i+l > len(data) {
+			l = len(data) - i
+		}
+		h.command(data[i : i+l])
+		i += l
+		start = i
+	}
+	if flush && start < i {
+		h.line(data[start:i], "text")
+		start = i
+	}
+	h.pending = append(h.pending[:0], data[start:]...)
+}
+
+// headerLen - bytes of the commands with variable data before the length
+// of the data is known
+var headerLen = map[[2]byte]int{
+	{27, '*'}: 5, {29, 'v'}: 8, {29, '('}: 5, {29, '8'}: 5, {29, 'k'}: 4,
+	{29, 'V'}: 3, {18, '*'}: 4,
+}
+
+// complete - data starts with a whole command of length l
+func complete(data []byte, l int) bool {
+	if _, ok := prefixNames[data[0]]; !ok {
+		return true
+	}
+	if len(data) < 2 || len(data) < l {
+		return false
+	}
+	if n, ok := headerLen[[2]byte{data[0], data[1]}]; ok && len(data) < n {
+		return false
+	}
+	// NUL terminated: tab stops, bar codes with m < 65
+	if (data[0] == 27 && data[1] == 'D') || (data[0] == 29 && data[1] == 'k' && data[2] < 65) {
+		return data[l-1] == 0
+	}
+	return true
+}
+
+// command - hexdump of one command, the annotation on its first line
+func (h *Hexdump) command(c []byte) {
+	note := Annotate(c)
+	for len(c) > hexdumpWidth {
+		h.line(c[:hexdumpWidth], note)
+		note = ""
+		c = c[hexdumpWidth:]
+	}
+	h.line(c, note)
+}
+
+// line - one hexdump line of up to 16 bytes
+func (h *Hexdump) line(b []byte, note string) {
+	var hexs, ascii strings.Builder
+	for i, c := range b {
+		if i > 0 {
+			hexs.WriteByte(' ')
+		}
+		fmt.Fprintf(&hexs, "%02X", c)
+		if c >= 32 && c < 127 {
+			ascii.WriteByte(c)
+		} else {
+			ascii.WriteByte('.')
+		}
+	}
+	fmt.Fprintf(h.w, "%08x  %-47s  %-16s  %s\n", h.offset, hexs.String(), ascii.String(), note)
+	h.offset += len(b)
+}
+
+// controlNames - single byte controls
+var controlNames = map[byte]string{
+	9: "HT → tab", 10: "LF → line feed", 12: "FF → form feed",
+	13: "CR → carriage return", 24: "CAN → cancel page data",
+}
+
+// prefixNames - names of the command introducers
+var prefixNames = map[byte]string{16: "DLE", 18: "DC2", 27: "ESC", 28: "FS", 29: "GS"}
+
+// onOff - on for odd n, as the switch commands read it
+func onOff(n byte) string {
+	if n&1 == 1 {
+		return "on"
+	}
+	return "off"
+}
+
+// word - little endian parameter at i
+func word(c []byte, i int) int {
+	if len(c) < i+2 {
+		return 0
+	}
+	return int(c[i]) + int(c[i+1])*256
+}
+
+// param - parameter byte at i, 0 when the command is cut short
+func param(c []byte, i int) byte {
+	if len(c) <= i {
+		return 0
+	}
+	return c[i]
+}
+
+// annotations - what the known commands do, by prefix
+var annotations = map[[2]byte]func(c []byte) string{
+	{27, '@'}: func(c []byte) string { return "initialize" },
+	{27, 'a'}: func(c []byte) string {
+		switch param(c, 2) % 48 {
+		case 1:
+			return "align center"
+		case 2:
+			return "align right"
+		}
+		return "align left"
+	},
+	{27, 'E'}: func(c []byte) string { return "bold " + onOff(param(c, 2)) },
+	{27, 'G'}: func(c []byte) string { return "double strike " + onOff(param(c, 2)) },
+	{27, ' '}: func(c []byte) string { return fmt.Sprintf("right spacing %d dots", param(c, 2)) },
+	{27, '-'}: func(c []byte) string { return fmt.Sprintf("underline %d", param(c, 2)%48) },
+	{27, '!'}: func(c []byte) string {
+		n := param(c, 2)
+		var m []string
+		if n&1 == 1 {
+			m = append(m, "font B")
+		}
+		if n&8 != 0 {
+			m = append(m, "bold")
+		}
+		if n&16 != 0 {
+			m = append(m, "double height")
+		}
+		if n&32 != 0 {
+			m = append(m, "double width")
+		}
+		if n&128 != 0 {
+			m = append(m, "underline")
+		}
+		if len(m) == 0 {
+			m = append(m, "normal")
+		}
+		return "print mode " + strings.Join(m, ", ")
+	},
+	{27, 'M'}: func(c []byte) string {
+		if param(c, 2)%48 == 1 {
+			return "font B"
+		}
+		return "font A"
+	},
+	{27, '{'}:  func(c []byte) string { return "upside down " + onOff(param(c, 2)) },
+	{27, 'V'}:  func(c []byte) string { return "rotate 90° " + onOff(param(c, 2)) },
+	{27, 't'}:  func(c []byte) string { return fmt.Sprintf("code page %d", param(c, 2)) },
+	{27, 'R'}:  func(c []byte) string { return fmt.Sprintf("character set %d", param(c, 2)) },
+	{27, 'd'}:  func(c []byte) string { return fmt.Sprintf("feed %d lines", param(c, 2)) },
+	{27, 'J'}:  func(c []byte) string { return fmt.Sprintf("feed %d dots", param(c, 2)) },
+	{27, '2'}:  func(c []byte) string { return "default line spacing" },
+	{27, '3'}:  func(c []byte) string { return fmt.Sprintf("line spacing %d dots", param(c, 2)) },
+	{27, '$'}:  func(c []byte) string { return fmt.Sprintf("position x %d dots", word(c, 2)) },
+	{27, '\\'}: func(c []byte) string { return fmt.Sprintf("move x %d dots", int16(word(c, 2))) },
+	{27, 'D'}: func(c []byte) string {
+		return fmt.Sprintf("tab stops %d", []byte(strings.TrimRight(string(c[2:]), "\x00")))
+	},
+	{27, 'B'}: func(c []byte) string { return fmt.Sprintf("beep %d times", param(c, 2)) },
+	{27, 'p'}: func(c []byte) string { return fmt.Sprintf("drawer pulse pin %d", param(c, 2)%48) },
+	{27, 'c'}: func(c []byte) string {
+		if param(c, 2) == '5' && param(c, 3)&1 == 1 {
+			return "panel buttons locked"
+		}
+		if param(c, 2) == '5' {
+			return "panel buttons unlocked"
+		}
+		return "paper sensors"
+	},
+	{27, '*'}: func(c []byte) string {
+		return fmt.Sprintf("bit image mode %d, %d columns", param(c, 2), word(c, 3))
+	},
+	{27, 'L'}: func(c []byte) string { return "page mode" },
+	{27, 'S'}: func(c []byte) string { return "standard mode" },
+	{27, 'W'}: func(c []byte) string {
+		return fmt.Sprintf("page area %d,%d %dx%d", word(c, 2), word(c, 4), word(c, 6), word(c, 8))
+	},
+	{27, 'T'}: func(c []byte) string { return fmt.Sprintf("page direction %d", param(c, 2)%48) },
+	{27, 12}:  func(c []byte) string { return "print page" },
+	{29, 'V'}: func(c []byte) string {
+		if param(c, 2)%48 == 1 || param(c, 2) == 'B' {
+			return "partial cut"
+		}
+		return "full cut"
+	},
+	{29, '!'}: func(c []byte) string {
+		n := param(c, 2)
+		return fmt.Sprintf("character size %dx%d", n>>4+1, n&7+1)
+	},
+	{29, 'B'}: func(c []byte) string { return "reverse " + onOff(param(c, 2)) },
+	{29, 'h'}: func(c []byte) string { return fmt.Sprintf("bar code height %d dots", param(c, 2)) },
+	{29, 'w'}: func(c []byte) string { return fmt.Sprintf("bar code module %d dots", param(c, 2)) },
+	{29, 'H'}: func(c []byte) string { return fmt.Sprintf("bar code text position %d", param(c, 2)%48) },
+	{29, 'f'}: func(c []byte) string { return fmt.Sprintf("bar code text font %d", param(c, 2)%48) },
+	{29, 'k'}: func(c []byte) string { return fmt.Sprintf("bar code type %d", param(c, 2)) },
+	{29, 'v'}: func(c []byte) string {
+		return fmt.Sprintf("raster image %dx%d dots", word(c, 4)*8, word(c, 6))
+	},
+	{29, '('}: func(c []byte) string {
+		switch {
+		case param(c, 2) == 'k' && param(c, 5) == 49:
+			return fmt.Sprintf("2D code %c", param(c, 6))
+		case param(c, 2) == 'L':
+			return "graphics"
+		case param(c, 2) == 'F':
+			return "mark offset"
+		}
+		return fmt.Sprintf("function %c", param(c, 2))
+	},
+	{29, 'L'}:  func(c []byte) string { return fmt.Sprintf("left margin %d dots", word(c, 2)) },
+	{29, 'W'}:  func(c []byte) string { return fmt.Sprintf("print width %d dots", word(c, 2)) },
+	{29, '$'}:  func(c []byte) string { return fmt.Sprintf("position y %d dots", word(c, 2)) },
+	{29, '\\'}: func(c []byte) string { return fmt.Sprintf("move y %d dots", int16(word(c, 2))) },
+	{29, 'r'}:  func(c []byte) string { return fmt.Sprintf("status %d", param(c, 2)%48) },
+	{29, 'a'}:  func(c []byte) string { return fmt.Sprintf("automatic status %d", param(c, 2)) },
+	{29, 'I'}:  func(c []byte) string { return fmt.Sprintf("printer id %d", param(c, 2)) },
+	{29, 12}:   func(c []byte) string { return "feed to mark" },
+	{16, 4}:    func(c []byte) string { return fmt.Sprintf("real-time status %d", param(c, 2)) },
+	{16, 20}:   func(c []byte) string { return fmt.Sprintf("real-time request %d", param(c, 2)) },
+	{18, '*'}: func(c []byte) string {
+		return fmt.Sprintf("bit image %d rows of %d bytes", param(c, 2), param(c, 3))
+	},
+	{18, 'v'}: func(c []byte) string {
+		if param(c, 2) == 2 {
+			return "voltage query"
+		}
+		return "temperature query"
+	},
+	{28, 'p'}: func(c []byte) string { return fmt.Sprintf("NV image %d", param(c, 2)) },
+	{28, '.'}: func(c []byte) string { return "kanji off" },
+	{28, '&'}: func(c []byte) string { return "kanji on" },
+}
+
+// Annotate - command c by name with its parameters and what it does,
+// "ESC a 1 → align center"; text and unknown commands have no meaning
+func Annotate(c []byte) string {
+	if len(c) == 0 {
+		return ""
+	}
+	if name, ok := controlNames[c[0]]; ok && len(c) == 1 {
+		return name
+	}
+	prefix, ok := prefixNames[c[0]]
+	if !ok || len(c) == 1 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(prefix)
+	if c[1] > 32 && c[1] < 127 {
+		fmt.Fprintf(&b, " %c", c[1])
+	} else {
+		fmt.Fprintf(&b, " %d", c[1])
+	}
+	// parameters of commands with data are left in the hex column
+	params := c[2:]
+	if len(params) > 4 {
+		params = params[:4]
+	}
+	for _, p := range params {
+		fmt.Fprintf(&b, " %d", p)
+	}
+	if len(c) > 6 {
+		b.WriteString(" …")
+	}
+	if f, ok := annotations[[2]byte{c[0], c[1]}]; ok {
+		b.WriteString(" → " + f(c))
+	}
+	return b.String()
+}
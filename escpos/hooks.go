@@ -0,0 +1,176 @@
+package escpos
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// JobInfo - the job a hook is called for
+type JobInfo struct {
+	// Name - what prints: the command of gotp, "print" of the server
+	Name string
+	// Printer - name of the profile
+	Printer string
+}
+
+// Hook - calls around every job of a printer, see AddHook: Before runs
+// before anything of the job is sent and may refuse it (the till is
+// closed), After runs when the job is done with its error, also after a
+// later hook refused it
+type Hook struct {
+	Before func(e *Escpos, job JobInfo) error
+	After  func(e *Escpos, job JobInfo, err error)
+}
+
+// jobHooks - state of the job between StartJob and EndJob
+type jobHooks struct {
+	hooks []Hook
+	job   *JobInfo
+	// depth - StartJob calls inside the job, the hooks run for the
+	// outermost one
+	depth int
+	// started - hooks of the job whose Before passed
+	started []Hook
+	// refused - a Before failed, nothing of the job is sent
+	refused bool
+	// codePage - CodePage when the job started or of SetCodePage in it
+	// (--encode after Begin), SetDefault selects it
+	codePage string
+}
+
+// AddHook - call h around the jobs of the printer, hooks run in the order
+// they were added and their After in the reverse one; the BeforeJob and
+// AfterJob commands and the GPIO pins of the profile run outside of them
+func (e *Escpos) AddHook(h Hook) {
+	e.hooks.hooks = append(e.hooks.hooks, h)
+}
+
+// StartJob - begin the job name: the Before hooks in order, the first
+// error refuses it, it is the error of the printer and the job sends
+// nothing until EndJob; then SetDefault, the styles of the job before
+// don't leak into it (a printer before Begin is reset by it)
+func (e *Escpos) StartJob(name string) error {
+	if e.hooks.job != nil {
+		e.hooks.depth++
+		return e.refusedErr()
+	}
+	job := JobInfo{Name: name, Printer: e.profile.Name}
+	e.hooks.job = &job
+	e.hooks.started = nil
+	for _, h := range e.jobHooks() {
+		if h.Before != nil {
+			if err := h.Before(e, job); err != nil {
+				e.hooks.refused = true
+				e.err = fmt.Errorf("%w: %s", ErrRefused, err.Error())
+				return e.err
+			}
+		}
+		e.hooks.started = append(e.hooks.started, h)
+	}
+	e.hooks.codePage = e.CodePage()
+	if e.begun {
+		e.SetDefault()
+	}
+	return nil
+}
+
+// EndJob - end the job of StartJob: SetDefault, the After hooks of the
+// ones it started with the error of the printer, which is returned
+func (e *Escpos) EndJob() error {
+	if e.hooks.job == nil {
+		return e.err
+	}
+	if e.hooks.depth > 0 {
+		e.hooks.depth--
+		return e.err
+	}
+	job := *e.hooks.job
+	if !e.hooks.refused {
+		e.SetDefault()
+	}
+	for i := len(e.hooks.started) - 1; i >= 0; i-- {
+		if h := e.hooks.started[i]; h.After != nil {
+			h.After(e, job, e.err)
+		}
+	}
+	e.hooks.job, e.hooks.started, e.hooks.refused = nil, nil, false
+	return e.err
+}
+
+// RunJob - print job named name between StartJob and EndJob
+func (e *Escpos) RunJob(name string, job Job) error {
+	if err := e.StartJob(name); err != nil {
+		e.EndJob()
+		return err
+	}
+	if err := job.Print(e); err != nil && e.err == nil {
+		e.err = err
+	}
+	return e.EndJob()
+}
+
+// refusedErr - error of the refused job, nil while it prints
+func (e *Escpos) refusedErr() error {
+	if e.hooks.refused {
+		return e.err
+	}
+	return nil
+}
+
+// jobHooks - hooks of a job, the commands and the GPIO pins of the
+// profile first
+func (e *Escpos) jobHooks() []Hook {
+	var res []Hook
+	if p := e.profile; len(p.BeforeJob) > 0 || len(p.AfterJob) > 0 {
+		res = append(res, ShellHook(p.BeforeJob, p.AfterJob))
+	}
+	if e.gpio != nil {
+		res = append(res, e.gpio.hook())
+	}
+	return append(res, e.hooks.hooks...)
+}
+
+// ShellHook - hook running shell commands (empty - none), with the
+// variables GOTP_JOB, GOTP_PRINTER and for after GOTP_ERROR (not set
+// when printed); before exiting with an error refuses the job, its
+// output is the reason
+func ShellHook(before, after string) Hook {
+	var h Hook
+	if len(before) > 0 {
+		h.Before = func(e *Escpos, job JobInfo) error {
+			return runHook(before, job, nil)
+		}
+	}
+	if len(after) > 0 {
+		h.After = func(e *Escpos, job JobInfo, err error) {
+			if err := runHook(after, job, err); err != nil && e.Verbose {
+				fmt.Fprintln(e.logOut(), err)
+			}
+		}
+	}
+	return h
+}
+
+// runHook - run the shell command of a hook
+func runHook(command string, job JobInfo, jobErr error) error {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	env := []string{"GOTP_JOB=" + job.Name, "GOTP_PRINTER=" + job.Printer}
+	if jobErr != nil {
+		env = append(env, "GOTP_ERROR="+jobErr.Error())
+	}
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); len(msg) > 0 {
+			return fmt.Errorf("Hook %s: %s", command, msg)
+		}
+		return fmt.Errorf("Hook %s: %s", command, err.Error())
+	}
+	return nil
+}
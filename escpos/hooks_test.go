@@ -0,0 +1,31 @@
+package escpos_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/grengojbo/gotp/escpos/escpostest"
+)
+
+func TestEndJobCodePage(t *testing.T) {
+	// the cli selects --encode after StartJob and Begin
+	e, d := escpostest.NewPrinter()
+	if err := e.StartJob("text"); err != nil {
+		t.Fatal(err)
+	}
+	e.Begin()
+	if err := e.SetCodePage("PC866"); err != nil {
+		t.Fatal(err)
+	}
+	e.WriteText("x")
+	d.Reset()
+	if err := e.EndJob(); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Bytes(); !bytes.HasSuffix(got, []byte{27, 't', 7}) {
+		t.Errorf("EndJob sent\n%s\nwithout ESC t 7 of PC866", escpostest.Dump(got))
+	}
+	if cp := e.CodePage(); cp != "PC866" {
+		t.Errorf("CodePage after EndJob = %s, want PC866", cp)
+	}
+}
@@ -0,0 +1,323 @@
+package escpos
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register decoders for LoadImage
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+
+	"github.com/grengojbo/gotp/fetch"
+)
+
+const (
+	// MAXIMAGEWIDTH print head width in dots (384 pixels, 48 bytes)
+	MAXIMAGEWIDTH = 384
+)
+
+// Raster - 1 bit per pixel image, rows of (Width+7)/8 bytes, MSB left, 1 = black
+type Raster struct {
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// RowBytes - bytes per raster row
+func (r *Raster) RowBytes() int {
+	return (r.Width + 7) / 8
+}
+
+// LoadImage - open and decode png/jpeg/gif image file or http(s) URL
+func LoadImage(file string) (image.Image, error) {
+	if fetch.IsURL(file) {
+		b, err := fetch.Get(file)
+		if err != nil {
+			return nil, fmt.Errorf("Load image: %s", err.Error())
+		}
+		img, _, err := image.Decode(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("Decode image %s: %s", file, err.Error())
+		}
+		return img, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("Load image: %s", err.Error())
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("Decode image %s: %s", file, err.Error())
+	}
+	return img, nil
+}
+
+// DecodeImage - decode base64 encoded png/jpeg/gif image
+func DecodeImage(data string) (image.Image, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("Decode image data: %s", err.Error())
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("Decode image data: %s", err.Error())
+	}
+	return img, nil
+}
+
+// NewRaster - scale img to width dots (0 - original width, limited to
+// MAXIMAGEWIDTH) and convert to 1 bit with dither (floyd, threshold)
+func NewRaster(img image.Image, width int, dither string) *Raster {
+	return scaleRaster(img, width, MAXIMAGEWIDTH, dither)
+}
+
+// newRaster - NewRaster limited to the print head width
+func (e *Escpos) newRaster(img image.Image, width int, dither string) *Raster {
+	return scaleRaster(img, width, e.dots, dither)
+}
+
+// scaleRaster - NewRaster limited to max dots
+func scaleRaster(img image.Image, width, max int, dither string) *Raster {
+	b := img.Bounds()
+	if width <= 0 || width > max {
+		width = b.Dx()
+		if width > max {
+			width = max
+		}
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	// box filter scale to grayscale, 0 black .. 255 white
+	gray := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		sy0 := b.Min.Y + y*b.Dy()/height
+		sy1 := b.Min.Y + (y+1)*b.Dy()/height
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < width; x++ {
+			sx0 := b.Min.X + x*b.Dx()/width
+			sx1 := b.Min.X + (x+1)*b.Dx()/width
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+			sum, n := 0, 0
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					sum += grayLevel(img.At(sx, sy))
+					n++
+				}
+			}
+			gray[y*width+x] = sum / n
+		}
+	}
+
+	r := &Raster{Width: width, Height: height}
+	r.Data = make([]byte, r.RowBytes()*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			old := gray[y*width+x]
+			black := old < 128
+			if black {
+				r.Data[y*r.RowBytes()+x/8] |= 0x80 >> uint(x%8)
+			}
+			if dither == "threshold" || dither == "none" {
+				continue
+			}
+			// Floyd-Steinberg error diffusion
+			diff := old
+			if !black {
+				diff = old - 255
+			}
+			if x+1 < width {
+				gray[y*width+x+1] += diff * 7 / 16
+			}
+			if y+1 < height {
+				if x > 0 {
+					gray[(y+1)*width+x-1] += diff * 3 / 16
+				}
+				gray[(y+1)*width+x] += diff * 5 / 16
+				if x+1 < width {
+					gray[(y+1)*width+x+1] += diff / 16
+				}
+			}
+		}
+	}
+	return r
+}
+
+// grayLevel - luminance of c over a white background
+func grayLevel(c color.Color) int {
+	r, g, b, a := c.RGBA()
+	y := (299*r + 587*g + 114*b) / 1000
+	// transparent pixels are paper
+	return int((y*a/0xffff + (0xffff - a)) >> 8)
+}
+
+// Align - place raster on a width dots wide canvas (left, center, right)
+func (r *Raster) Align(align string, width int) *Raster {
+	if r.Width >= width {
+		return r
+	}
+	offset := 0
+	switch align {
+	case "center", "C":
+		offset = (width - r.Width) / 2
+	case "right", "R":
+		offset = width - r.Width
+	}
+	res := &Raster{Width: width, Height: r.Height}
+	res.Data = make([]byte, res.RowBytes()*r.Height)
+	for y := 0; y < r.Height; y++ {
+		for x := 0; x < r.Width; x++ {
+			if r.Data[y*r.RowBytes()+x/8]&(0x80>>uint(x%8)) != 0 {
+				nx := x + offset
+				res.Data[y*res.RowBytes()+nx/8] |= 0x80 >> uint(nx%8)
+			}
+		}
+	}
+	return res
+}
+
+// fill - black rectangle of w x h dots at x, y
+func (r *Raster) fill(x, y, w, h int) {
+	for dy := y; dy < y+h && dy < r.Height; dy++ {
+		for dx := x; dx < x+w && dx < r.Width; dx++ {
+			r.Data[dy*r.RowBytes()+dx/8] |= 0x80 >> uint(dx%8)
+		}
+	}
+}
+
+// Rotate - r turned 90 degrees clockwise
+func (r *Raster) Rotate() *Raster {
+	res := &Raster{Width: r.Height, Height: r.Width}
+	res.Data = make([]byte, res.RowBytes()*res.Height)
+	for y := 0; y < r.Height; y++ {
+		for x := 0; x < r.Width; x++ {
+			if r.Data[y*r.RowBytes()+x/8]&(0x80>>uint(x%8)) != 0 {
+				nx := r.Height - 1 - y
+				res.Data[x*res.RowBytes()+nx/8] |= 0x80 >> uint(nx%8)
+			}
+		}
+	}
+	return res
+}
+
+// PrintBitmap - print raster (DC2 * on ESC/POS) in chunks which fit in
+// the printer buffer (256 bytes without Profile.BufferSize), see bandWait
+func (e *Escpos) PrintBitmap(r *Raster) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func PrintBitmap()\n")
+	}
+	rowBytesClipped := r.RowBytes()
+	if rowBytesClipped >= 48 {
+		rowBytesClipped = 48 // 384 pixels max width
+	}
+
+	// rows to write at once, the ones the printer buffer takes
+	chunkHeightLimit := e.bandRows(rowBytesClipped, bitmapBuffer)
+	var cmd []byte
+
+	for rowStart := 0; rowStart < r.Height; rowStart += chunkHeightLimit {
+		// Issue up to chunkHeightLimit rows at a time:
+		chunkHeight := r.Height - rowStart
+		if chunkHeight > chunkHeightLimit {
+			chunkHeight = chunkHeightLimit
+		}
+		if rowStart > 0 {
+			e.bandWait(cmd)
+		}
+		cmd = e.cmd.BitImage(rowBytesClipped, chunkHeight, r.band(rowStart, chunkHeight, rowBytesClipped))
+		e.WriteRaw(cmd)
+		e.advance(chunkHeight)
+	}
+	e.prevByte = ASCIILF
+}
+
+// band - rows from start, rowBytes of each row
+func (r *Raster) band(start, rows, rowBytes int) []byte {
+	res := make([]byte, 0, rows*rowBytes)
+	for y := start; y < start+rows; y++ {
+		res = append(res, r.Data[y*r.RowBytes():y*r.RowBytes()+rowBytes]...)
+	}
+	return res
+}
+
+// PrintRaster - print raster with GS v 0 in bands which fit in the
+// printer buffer (4 KB without Profile.BufferSize) or of
+// Profile.BandHeight rows, see bandWait
+func (e *Escpos) PrintRaster(r *Raster) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func PrintRaster()\n")
+	}
+	rowBytes := r.RowBytes()
+	if rowBytes > 48 {
+		rowBytes = 48
+	}
+	bandHeight := e.bandRows(rowBytes, rasterBuffer)
+	var cmd []byte
+	for rowStart := 0; rowStart < r.Height; rowStart += bandHeight {
+		h := r.Height - rowStart
+		if h > bandHeight {
+			h = bandHeight
+		}
+		if rowStart > 0 {
+			e.bandWait(cmd)
+		}
+		cmd = e.cmd.Raster(rowBytes, h, r.band(rowStart, h, rowBytes))
+		e.WriteRaw(cmd)
+		e.advance(h)
+	}
+	e.prevByte = ASCIILF
+}
+
+// PrintColumns - print raster with ESC * in 24 dot lines, for firmware
+// without DC2 * and GS v 0, see bandWait
+func (e *Escpos) PrintColumns(r *Raster) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func PrintColumns()\n")
+	}
+	rowBytes := r.RowBytes()
+	if rowBytes > 48 {
+		rowBytes = 48
+	}
+	var cmd []byte
+	for rowStart := 0; rowStart < r.Height; rowStart += 24 {
+		h := r.Height - rowStart
+		if h > 24 {
+			h = 24
+		}
+		if rowStart > 0 {
+			e.bandWait(cmd)
+		}
+		cmd = e.cmd.ColumnImage(rowBytes, h, r.band(rowStart, h, rowBytes))
+		e.WriteRaw(cmd)
+		e.advance(24)
+	}
+	e.prevByte = ASCIILF
+}
+
+// PrintImage - print image scaled to width dots with dither and align,
+// with the image command of the profile
+func (e *Escpos) PrintImage(img image.Image, width int, dither string, align string) {
+	e.printRaster(e.newRaster(img, width, dither).Align(align, e.dots))
+}
+
+// printRaster - print r with the image command of the profile
+func (e *Escpos) printRaster(r *Raster) {
+	switch e.imageMode {
+	case "raster":
+		e.PrintRaster(r)
+	case "column":
+		e.PrintColumns(r)
+	default:
+		e.PrintBitmap(r)
+	}
+}
@@ -0,0 +1,112 @@
+package escpos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// itfPatterns - narrow (n) and wide (w) elements of the digits of
+// interleaved 2 of 5
+var itfPatterns = [10]string{
+	"nnwwn", "wnnnw", "nwnnw", "wwnnn", "nnwnw",
+	"wnwnn", "nwwnn", "nnnww", "wnnwn", "nwnwn",
+}
+
+// ITF14Digits - the 14 digits of an ITF-14 of data: 13 digits get their
+// check digit, the one of 14 digits is checked; spaces are left out
+func ITF14Digits(data string) (string, error) {
+	digits := strings.Replace(data, " ", "", -1)
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("ITF-14: %q is not numeric", data)
+		}
+	}
+	switch len(digits) {
+	case 13:
+		return digits + string(GS1CheckDigit(digits)), nil
+	case 14:
+		if want := GS1CheckDigit(digits[:13]); digits[13] != want {
+			return "", fmt.Errorf("ITF-14: check digit of %s is %c, not %c", digits, want, digits[13])
+		}
+		return digits, nil
+	}
+	return "", fmt.Errorf("ITF-14: %q is not 13 or 14 digits", data)
+}
+
+// itfWidths - bar and space widths in dots of the even number of
+// digits, start and stop included, wide elements 2.5 narrow ones (3 for
+// 1 dot)
+func itfWidths(digits string, narrow int) []int {
+	wide := (5*narrow + 1) / 2
+	res := []int{narrow, narrow, narrow, narrow}
+	for i := 0; i+1 < len(digits); i += 2 {
+		bars, spaces := itfPatterns[digits[i]-'0'], itfPatterns[digits[i+1]-'0']
+		for j := 0; j < 5; j++ {
+			for _, e := range []byte{bars[j], spaces[j]} {
+				if e == 'w' {
+					res = append(res, wide)
+				} else {
+					res = append(res, narrow)
+				}
+			}
+		}
+	}
+	return append(res, wide, narrow, narrow)
+}
+
+// ITF14Raster - ITF-14 of the 14 digits in a bearer bar box, narrow dots
+// wide elements, height dots high bars; the quiet zones of 10 narrow
+// elements are inside the box
+func ITF14Raster(digits string, narrow, height int) *Raster {
+	if narrow < 1 {
+		narrow = 1
+	}
+	bars := barsRaster(itfWidths(digits, narrow), height)
+	quiet := 10 * narrow
+	bearer := 4 * narrow
+	r := &Raster{Width: bars.Width + 2*quiet + 2*bearer, Height: height + 2*bearer}
+	r.Data = make([]byte, r.RowBytes()*r.Height)
+	r.fill(0, 0, r.Width, bearer)
+	r.fill(0, r.Height-bearer, r.Width, bearer)
+	r.fill(0, 0, bearer, r.Height)
+	r.fill(r.Width-bearer, 0, bearer, r.Height)
+	for y := 0; y < bars.Height; y++ {
+		for x := 0; x < bars.Width; x++ {
+			if bars.Data[y*bars.RowBytes()+x/8]&(0x80>>uint(x%8)) != 0 {
+				r.fill(bearer+quiet+x, bearer+y, 1, 1)
+			}
+		}
+	}
+	return r
+}
+
+// itf14Width - dots of an ITF-14 box of narrow dots narrow elements
+func itf14Width(narrow int) int {
+	w := 2*(10*narrow) + 2*(4*narrow)
+	for _, n := range itfWidths("00000000000000", narrow) {
+		w += n
+	}
+	return w
+}
+
+// itf14HRI - the digits grouped as on cartons: indicator, company prefix
+// and item, check digit
+func itf14HRI(digits string) string {
+	return digits[:1] + " " + digits[1:3] + " " + digits[3:8] + " " + digits[8:13] + " " + digits[13:]
+}
+
+// itf14 - print data as an ITF-14 in its bearer bar box, as an image
+// which keeps the box around the bars of every printer; the narrow
+// element is the module width or less until the box fits the paper
+func (e *Escpos) itf14(data string) error {
+	digits, err := ITF14Digits(data)
+	if err != nil {
+		return err
+	}
+	narrow := int(e.barcodeWidth)
+	for narrow > 1 && itf14Width(narrow) > e.dots {
+		narrow--
+	}
+	e.rasterBarCode(ITF14Raster(digits, narrow, int(e.barcodeHeight)), itf14HRI(digits))
+	return nil
+}
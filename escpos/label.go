@@ -0,0 +1,82 @@
+package escpos
+
+import (
+	"fmt"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// labelFeedDots - feed to the next mark assumed for pacing, a 50 mm label
+const labelFeedDots = 400
+
+// label - media of the profile is label or black mark stock
+func (e *Escpos) label() bool {
+	return e.media == "label"
+}
+
+// FeedLabel - feed to the print start of the next label or black mark
+// (GS FF on ESC/POS)
+func (e *Escpos) FeedLabel() {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func FeedLabel()\n")
+	}
+	e.WriteRaw(e.cmd.FeedMark())
+	e.paper = 0
+	e.prevByte = ASCIILF
+	e.column = 0
+}
+
+// SetMarkOffset - move the print start and the cut position start and cut
+// dots past the mark, negative values move them back (GS ( F)
+func (e *Escpos) SetMarkOffset(start, cut int) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetMarkOffset()\n")
+	}
+	s, ok := e.cmd.MarkOffset(false, start)
+	c, _ := e.cmd.MarkOffset(true, cut)
+	if !ok {
+		return fmt.Errorf("Black mark offsets are not supported by %s printers", e.cmd.Name())
+	}
+	e.WriteBytes(append(s, c...))
+	return e.err
+}
+
+// HasPageMode - the command set of the printer has page mode, labels and
+// pages are printed as one image otherwise
+func (e *Escpos) HasPageMode() bool {
+	_, err := e.pageCommands()
+	return err == nil
+}
+
+// LabelSize - dots of label, the print head width when it has none
+func (e *Escpos) LabelSize(label *models.Label) (width, height int, err error) {
+	width = e.dots
+	if len(label.Width) > 0 {
+		if width, err = e.ParseDots(label.Width); err != nil {
+			return 0, 0, fmt.Errorf("Label width: %s", err.Error())
+		}
+	}
+	if width <= 0 || width > e.dots {
+		return 0, 0, fmt.Errorf("Label width %d dots: 1..%d", width, e.dots)
+	}
+	if height, err = e.ParseDots(label.Height); err != nil || height <= 0 {
+		return 0, 0, fmt.Errorf("Label height: %q", label.Height)
+	}
+	return width, height, nil
+}
+
+// writeLabel - the rows of all sections of m on one page of the label
+// size
+func (e *Escpos) writeLabel(m *models.PrinterLine) {
+	width, height, err := e.LabelSize(m.Label)
+	if err != nil {
+		fmt.Fprintln(e.logOut(), err)
+		return
+	}
+	var rows []models.Printer
+	for _, s := range m.Sections {
+		rows = append(rows, s.Rows...)
+	}
+	e.setSection("label")
+	e.writePage(&models.Area{Width: uint16(width), Height: uint16(height)}, rows, &m.BarCode)
+}
@@ -0,0 +1,485 @@
+package escpos
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// PadBetween - left and right on one line of the current font width
+// (16 columns in double width) with fill in the gap, "Subtotal.....12.50".
+// When both don't fit, left is on a line of its own and right filled up
+// to the end of the next one
+func (e *Escpos) PadBetween(left, right string, fill byte) string {
+	return padBetween(left, right, fill, int(e.maxColumn))
+}
+
+// padBetween - PadBetween for width columns
+func padBetween(left, right string, fill byte, width int) string {
+	if fill == 0 {
+		fill = ' '
+	}
+	l, r := utf8.RuneCountInString(left), utf8.RuneCountInString(right)
+	if l+r >= width && l > 0 && r > 0 {
+		return left + "\n" + padBetween("", right, fill, width)
+	}
+	if n := width - l - r; n > 0 {
+		return left + strings.Repeat(string(fill), n) + right
+	}
+	return left + right
+}
+
+// WordWrap - text broken at spaces into lines of the current font width,
+// a word longer than the line is split
+func (e *Escpos) WordWrap(text string) string {
+	return wordWrap(text, int(e.maxColumn))
+}
+
+// wordWrap - WordWrap for width columns, the line breaks and the spaces
+// of text are kept but the ones a line is broken at
+func wordWrap(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		var line []rune
+		rest := []rune(para)
+		for len(rest) > 0 {
+			// next word with the spaces before it
+			i := 0
+			for i < len(rest) && rest[i] == ' ' {
+				i++
+			}
+			j := i
+			for j < len(rest) && rest[j] != ' ' {
+				j++
+			}
+			word := rest[:j]
+			rest = rest[j:]
+			if len(line) > 0 && len(line)+len(word) > width {
+				lines = append(lines, string(line))
+				line = nil
+				word = word[i:]
+			}
+			line = append(line, word...)
+			for len(line) > width {
+				lines = append(lines, string(line[:width]))
+				line = line[width:]
+			}
+		}
+		lines = append(lines, string(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Truncate - every line and field between tabs of text cut to width
+// characters, the last ones of a cut field an ellipsis when ellipsis is
+// set: "Chocolate cake w..."
+func (e *Escpos) Truncate(text string, width int, ellipsis bool) string {
+	mark := ""
+	if ellipsis {
+		mark = e.encodable("…", "...")
+	}
+	return truncate(text, width, mark)
+}
+
+// truncate - Truncate with mark at the end of a cut field, no mark when
+// it is as long as the field
+func truncate(text string, width int, mark string) string {
+	if width <= 0 {
+		return text
+	}
+	m := utf8.RuneCountInString(mark)
+	if m >= width {
+		mark, m = "", 0
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		fields := strings.Split(line, "\t")
+		for j, f := range fields {
+			if r := []rune(f); len(r) > width {
+				fields[j] = strings.TrimRight(string(r[:width-m]), " ") + mark
+			}
+		}
+		lines[i] = strings.Join(fields, "\t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rowText - Text of a text row with its tabs expanded, the fields cut to
+// MaxWidth; with Truncate and no MaxWidth cut to the cols of the line
+// less Right, which stays on it
+func (e *Escpos) rowText(row models.Printer, cols int) string {
+	ellipsis := row.Truncate != "cut"
+	if row.MaxWidth > 0 {
+		return e.ExpandTabs(e.Truncate(row.Text, int(row.MaxWidth), ellipsis))
+	}
+	text := e.ExpandTabs(row.Text)
+	if len(row.Truncate) > 0 {
+		if len(row.Right) > 0 {
+			cols -= utf8.RuneCountInString(row.Right) + 1
+		}
+		text = e.Truncate(text, cols, ellipsis)
+	}
+	return text
+}
+
+// ruleStyles - box drawing rules and their ASCII for code pages without
+// them, other styles are repeated as they are ("*", "-=", "~")
+var ruleStyles = map[string][2]string{
+	"":       {"-", "-"},
+	"single": {"─", "-"},
+	"double": {"═", "="},
+	"thick":  {"█", "#"},
+}
+
+// Rule - horizontal rule across the line of the current font width
+func (e *Escpos) Rule(style string) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func Rule() %s\n", style)
+	}
+	b, err := e.enc.String(e.ruleLine(style))
+	if err != nil {
+		return fmt.Errorf("%w (%s)", ErrEncode, err)
+	}
+	e.Write(b)
+	// a rule of SetColumns narrower than the print head doesn't end the
+	// line by itself
+	if w := e.charWidth(); e.dots-int(e.maxColumn)*w >= w {
+		e.Linefeed()
+	}
+	return e.err
+}
+
+// signatureSpace - paper to sign on above a signature line
+const signatureSpace = "15mm"
+
+// Space - blank paper of s ("30mm" or "24" dots) fed exactly with
+// ESC J; in a frame the lines which fill it
+func (e *Escpos) Space(s string) error {
+	dots, err := e.ParseDots(s)
+	if err != nil {
+		return err
+	}
+	if e.frame {
+		lines := dots / int(e.charHeight+e.lineSpacing)
+		if lines < 1 {
+			lines = 1
+		}
+		return e.FrameText(strings.Repeat("\n", lines-1), "left")
+	}
+	for ; dots > 0; dots -= 255 {
+		n := dots
+		if n > 255 {
+			n = 255
+		}
+		e.FeedDots(uint8(n))
+	}
+	return e.err
+}
+
+// Signature - space (empty is signatureSpace) to sign on, a line across
+// the paper and caption (empty is "Signature") centered under it
+func (e *Escpos) Signature(space, caption string) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func Signature()\n")
+	}
+	if len(space) == 0 {
+		space = signatureSpace
+	}
+	if len(caption) == 0 {
+		caption = "Signature"
+	}
+	if err := e.Space(space); err != nil {
+		return err
+	}
+	if e.frame {
+		if err := e.FrameText("x"+strings.Repeat("_", int(e.maxColumn)-5), "left"); err != nil {
+			return err
+		}
+		return e.FrameText(caption, "center")
+	}
+	st := e.Style()
+	e.SetAlign("left")
+	e.WriteText("x" + strings.Repeat("_", int(e.maxColumn)-1))
+	e.Linefeed()
+	e.SetAlign("center")
+	for _, line := range strings.Split(e.WordWrap(caption), "\n") {
+		e.WriteText(line)
+		e.Linefeed()
+	}
+	e.SetStyle(st)
+	return e.err
+}
+
+// ruleLine - maxColumn characters of the rule style
+func (e *Escpos) ruleLine(style string) string {
+	pattern := style
+	if s, ok := ruleStyles[style]; ok {
+		pattern = e.encodable(s[0], s[1])
+	}
+	runes := []rune(pattern)
+	line := make([]rune, e.maxColumn)
+	for i := range line {
+		line[i] = runes[i%len(runes)]
+	}
+	return string(line)
+}
+
+// encodable - s when the code page has its characters, else fallback
+func (e *Escpos) encodable(s, fallback string) string {
+	if _, err := e.enc.String(s); err != nil {
+		return fallback
+	}
+	return s
+}
+
+// boxChars - frame characters: horizontal, vertical, corners top left,
+// top right, bottom left, bottom right, then the left and right tees
+type boxChars [8]string
+
+var (
+	// boxDrawing - CP437 box drawing
+	boxDrawing = boxChars{"─", "│", "┌", "┐", "└", "┘", "├", "┤"}
+	// boxASCII - frame of code pages without box drawing
+	boxASCII = boxChars{"-", "|", "+", "+", "+", "+", "+", "+"}
+)
+
+// box - frame characters of the code page
+func (e *Escpos) box() boxChars {
+	if e.encodable(strings.Join(boxDrawing[:], ""), "") == "" {
+		return boxASCII
+	}
+	return boxDrawing
+}
+
+// frameLine - left, maxColumn-2 fill and right as one printed line
+func (e *Escpos) frameLine(left, fill, right string) error {
+	line := left + strings.Repeat(fill, int(e.maxColumn)-2) + right
+	b, err := e.enc.String(line)
+	if err != nil {
+		return fmt.Errorf("%w (%s)", ErrEncode, err)
+	}
+	e.SetAlign("left")
+	e.Write(b)
+	e.Linefeed()
+	return e.err
+}
+
+// FrameBegin - top of a box, FrameText lines are printed inside it until
+// FrameEnd
+func (e *Escpos) FrameBegin() error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func FrameBegin()\n")
+	}
+	b := e.box()
+	e.frame = true
+	return e.frameLine(b[2], b[0], b[3])
+}
+
+// FrameRule - separator line inside the box
+func (e *Escpos) FrameRule() error {
+	b := e.box()
+	return e.frameLine(b[6], b[0], b[7])
+}
+
+// FrameEnd - bottom of the box
+func (e *Escpos) FrameEnd() error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func FrameEnd()\n")
+	}
+	b := e.box()
+	e.frame = false
+	return e.frameLine(b[4], b[0], b[5])
+}
+
+// FrameText - text between the sides of the box, aligned (left, center,
+// right) and wrapped at the inner width; every "\n" starts a new line
+func (e *Escpos) FrameText(text, align string) error {
+	b := e.box()
+	width := int(e.maxColumn) - 4
+	if width < 1 {
+		width = 1
+	}
+	for _, l := range strings.Split(text, "\n") {
+		runes := []rune(l)
+		for {
+			n := len(runes)
+			if n > width {
+				n = width
+			}
+			left := 0
+			switch align {
+			case "center":
+				left = (width - n) / 2
+			case "right":
+				left = width - n
+			}
+			line := b[1] + " " + strings.Repeat(" ", left) + string(runes[:n]) +
+				strings.Repeat(" ", width-n-left) + " " + b[1]
+			enc, err := e.enc.String(line)
+			if err != nil {
+				return fmt.Errorf("%w (%s)", ErrEncode, err)
+			}
+			e.SetAlign("left")
+			e.Write(enc)
+			e.Linefeed()
+			runes = runes[n:]
+			if len(runes) == 0 {
+				break
+			}
+		}
+	}
+	return e.err
+}
+
+// Box - text in a box across the line, lines as FrameText
+func (e *Escpos) Box(text, align string) error {
+	if err := e.FrameBegin(); err != nil {
+		return err
+	}
+	if err := e.FrameText(text, align); err != nil {
+		e.FrameEnd()
+		return err
+	}
+	return e.FrameEnd()
+}
+
+// bannerSize - largest character size of Banner
+const bannerSize = 8
+
+// Banner - text centered in the largest characters that fit on one line,
+// 8 x 8 for a pickup number of up to 4 digits
+func (e *Escpos) Banner(text string) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func Banner()\n")
+	}
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return fmt.Errorf("Empty banner")
+	}
+	b, err := e.enc.String(text)
+	if err != nil {
+		return fmt.Errorf("%w (%s)", ErrEncode, err)
+	}
+	// columns of font A at size 1
+	size := e.lineDots() / 12 / n
+	if size > bannerSize {
+		size = bannerSize
+	}
+	if size < 1 {
+		size = 1
+	}
+	e.PushStyle()
+	e.SetAlign("center")
+	e.setSize(uint8(size), uint8(size))
+	e.Write(b)
+	e.Linefeed()
+	e.PopStyle()
+	return e.err
+}
+
+// twoUpGap - dots between the figure and the text of TwoUp
+const twoUpGap = 16
+
+// TwoUp - fig (a QR code or a logo) on the side (left or right) of rows,
+// both centered on each other and the text wrapped at the rest of the
+// paper width. Page mode prints them side by side; printers without it,
+// frames and pages print the figure above the rows
+func (e *Escpos) TwoUp(fig *Raster, side string, rows []models.Printer, set *models.BarCodeOption) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func TwoUp() %s\n", side)
+	}
+	if side != "left" && side != "right" {
+		return fmt.Errorf("Two-up: side left or right, not %q", side)
+	}
+	text := e.dots - fig.Width - twoUpGap
+	rows = wrapRows(rows)
+	if _, err := e.pageCommands(); err != nil || e.page || e.frame || 32*text/MAXIMAGEWIDTH < 8 {
+		e.printRaster(fig.Align("center", e.dots))
+		e.WriteNode(rows, set)
+		return e.err
+	}
+	textHeight := e.rowsHeight(rows, text)
+	height := fig.Height
+	if textHeight > height {
+		height = textHeight
+	}
+	figX, textX := 0, fig.Width+twoUpGap
+	if side == "right" {
+		figX, textX = text+twoUpGap, 0
+	}
+	if err := e.BeginPage(e.dots, height); err != nil {
+		return err
+	}
+	e.SetPageArea(figX, (height-fig.Height)/2, fig.Width, fig.Height, 0)
+	// in page mode an image ends on the base line, a band at a time
+	rb := fig.RowBytes()
+	for y := 0; y < fig.Height; y += 24 {
+		n := fig.Height - y
+		if n > 24 {
+			n = 24
+		}
+		e.PageMove(0, y+n)
+		e.printRaster(&Raster{Width: fig.Width, Height: n, Data: fig.Data[y*rb : (y+n)*rb]})
+	}
+	e.SetPageArea(textX, (height-textHeight)/2, text, textHeight, 0)
+	// the text lines follow the width of its area
+	dots, columns := e.dots, e.lineColumns
+	e.dots, e.lineColumns = text, 0
+	e.charMetrics()
+	e.WriteNode(rows, set)
+	e.dots, e.lineColumns = dots, columns
+	e.charMetrics()
+	return e.PrintPage()
+}
+
+// wrapRows - copy of rows with the text rows wrapped
+func wrapRows(rows []models.Printer) []models.Printer {
+	res := make([]models.Printer, len(rows))
+	for i, row := range rows {
+		row.Wrap = row.Kind() == "text"
+		res[i] = row
+	}
+	return res
+}
+
+// rowsHeight - dots the text and line rows take printed width dots wide,
+// a size change feeds a line of its own
+func (e *Escpos) rowsHeight(rows []models.Printer, width int) int {
+	line := 24 + int(e.lineSpacing)
+	height := 0
+	for _, row := range rows {
+		switch row.Kind() {
+		case "line":
+			height += line
+		case "feed":
+			height += int(row.Feed) * line
+		case "text":
+			w, h := 1, 1
+			switch row.Size {
+			case "large", "L":
+				w, h = 2, 2
+				height += line
+			case "medium", "M":
+				h = 2
+				height += line
+			}
+			cw, ch := 12, 24
+			if strings.Contains(row.Style, "small") {
+				cw, ch = 9, 17
+			}
+			cols := width / (cw * w)
+			text := e.rowText(row, cols)
+			if len(row.Right) > 0 || len(row.Fill) > 0 {
+				text = padBetween(text, row.Right, 0, cols)
+			}
+			n := len(strings.Split(wordWrap(text, cols), "\n"))
+			height += n * (ch*h + int(e.lineSpacing))
+		}
+	}
+	return height
+}
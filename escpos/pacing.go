@@ -0,0 +1,158 @@
+package escpos
+
+import (
+	"io"
+	"time"
+)
+
+// writerFunc - function as an io.Writer
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// symbolModules - GS ( k symbols by cn: the function setting the module
+// size and the modules of the largest symbol printed
+var symbolModules = map[byte]struct {
+	fn      byte
+	modules int64
+}{
+	49: {67, 57},  // QR code, version 10
+	53: {66, 151}, // Aztec
+	54: {66, 144}, // Data Matrix
+}
+
+// pacingWriter - writer of a printer which takes data faster than it
+// prints (the serial port): a write waits until the printer got through
+// the data before it, the time the bytes take on the port and the time
+// it needs for the feeds, lines, images and codes in them with the
+// metrics of e; with the DTR pin of Profile.GPIO the printer tells when
+// it is ready instead
+type pacingWriter struct {
+	e *Escpos
+	w io.Writer
+	// ready - the printer is done with the data written so far
+	ready time.Time
+	// text - characters of a line not printed yet, the next LF prints it
+	text bool
+	// off - writes go out at once, see Calibrate
+	off bool
+}
+
+// newPacer - pacingWriter of e writing to w
+func newPacer(e *Escpos, w io.Writer) *pacingWriter {
+	return &pacingWriter{e: e, w: w}
+}
+
+// Write - wait for the printer, write p and count the time it needs for
+// it
+func (p *pacingWriter) Write(b []byte) (int, error) {
+	if p.off {
+		return p.w.Write(b)
+	}
+	d := time.Duration(p.cost(b)) * time.Microsecond
+	p.wait()
+	n, err := p.w.Write(b)
+	p.ready = time.Now().Add(d)
+	return n, err
+}
+
+// wait - until the printer is done with the data written
+func (p *pacingWriter) wait() {
+	e := p.e
+	if e.gpio != nil && e.gpio.dtr != nil {
+		// the printer tells when it is ready
+		e.gpio.waitReady(e.deadline)
+		return
+	}
+	time.Sleep(time.Until(p.ready))
+}
+
+// hold - the printer takes d from now before it takes data (booting,
+// waking up), 0 - it takes it now
+func (p *pacingWriter) hold(d time.Duration) {
+	p.ready = time.Now().Add(d)
+}
+
+// cost - microseconds the printer needs for data: its bytes on the port
+// and the feeds, text lines, images, bar codes, 2D codes and pages in it
+func (p *pacingWriter) cost(data []byte) (t int64) {
+	e := p.e
+	t = int64(len(data)) * e.byteTime
+	module := int64(6)
+	for i := 0; i < len(data); {
+		l := CommandLen(data[i:])
+		c := data[i]
+		switch {
+		case c == ASCIILF:
+			if p.text {
+				t += e.charHeight*e.dotPrintTime + e.lineSpacing*e.dotFeedTime
+			} else {
+				// a blank line
+				t += (e.charHeight + e.lineSpacing) * e.dotFeedTime
+			}
+			p.text = false
+		case l == 1 && (c >= 32 || c == 9):
+			p.text = true
+		case c == 12 || (c == 27 && l == 2 && i+1 < len(data) && data[i+1] == 12):
+			// FF and ESC FF print the page of page mode
+			if e.page {
+				t += int64(e.pageHeight) * e.dotPrintTime
+			}
+			p.text = false
+		case c == 27 && l == 3 && i+2 < len(data) && data[i+1] == 'd':
+			t += int64(data[i+2]) * e.charHeight * e.dotFeedTime
+			p.text = false
+		case c == 27 && l == 3 && i+2 < len(data) && data[i+1] == 'J':
+			t += int64(data[i+2]) * e.dotFeedTime
+			p.text = false
+		case c == 27 && l == 4 && i+2 < len(data) && data[i+1] == 'B':
+			// the buzzer sounds n times
+			t += int64(data[i+2]) * 200000
+		case c == 27 && i+7 < len(data) && data[i+1] == 29 && data[i+2] == 'S':
+			t += (int64(data[i+6]) + int64(data[i+7])*256) * e.dotPrintTime
+			p.text = false
+		case c == 27 && i+1 < len(data) && data[i+1] == '*':
+			t += 24 * e.dotPrintTime
+		case c == 18 && i+2 < len(data) && data[i+1] == '*':
+			t += int64(data[i+2]) * e.dotPrintTime
+			p.text = false
+		case c == 29 && i+7 < len(data) && data[i+1] == 'v':
+			t += (int64(data[i+6]) + int64(data[i+7])*256) * e.dotPrintTime
+			p.text = false
+		case c == 29 && i+2 < len(data) && data[i+1] == 'k':
+			t += (int64(e.barcodeHeight) + 40) * e.dotPrintTime
+			p.text = false
+		case c == 29 && i+7 < len(data) && data[i+1] == '(' && data[i+2] == 'k':
+			if s, ok := symbolModules[data[i+5]]; ok {
+				switch data[i+6] {
+				case s.fn:
+					module = int64(data[i+7])
+				case 81:
+					t += module * s.modules * e.dotPrintTime
+					p.text = false
+				}
+			}
+		case c == 29 && i+1 < len(data) && data[i+1] == 12:
+			t += labelFeedDots * e.dotFeedTime
+			p.text = false
+		case c == 18 && i+1 < len(data) && data[i+1] == 'T':
+			t += e.dotPrintTime*24*26 + e.dotFeedTime*(6*26+30)
+		}
+		i += l
+	}
+	return t
+}
+
+// hold - pacingWriter hold of a paced printer
+func (e *Escpos) hold(d time.Duration) {
+	if e.pacer != nil {
+		e.pacer.hold(d)
+	}
+}
+
+// drain - wait until a paced printer is done with the data sent
+func (e *Escpos) drain() {
+	if e.pacer != nil && !e.pacer.off {
+		e.pacer.wait()
+	}
+}
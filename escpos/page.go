@@ -0,0 +1,160 @@
+package escpos
+
+import (
+	"fmt"
+)
+
+// PageCommands - page mode of command sets which have it, positions and
+// sizes are in dots
+type PageCommands interface {
+	// PageMode - enter page mode, or back to standard mode dropping the page
+	PageMode(on bool) []byte
+	PageArea(x, y, width, height int) []byte
+	// PageDirection - rotate the following data 0, 90, 180 or 270 degrees
+	PageDirection(rotate int) []byte
+	// PagePosition - x from the left of the area, y the base line from
+	// its top
+	PagePosition(x, y int) []byte
+	// PageY - base line y dots from the top of the area
+	PageY(y int) []byte
+	// PrintPage - print the page, stay in page mode when keep
+	PrintPage(keep bool) []byte
+	// CancelPage - drop the data of the area
+	CancelPage() []byte
+}
+
+// pageDirections - ESC T n of the rotations, print starts upper left (0),
+// upper right (90), lower right (180) or lower left (270)
+var pageDirections = map[int]byte{0: 0, 90: 3, 180: 2, 270: 1}
+
+// PageMode - ESC L, ESC S
+func (EscposCommands) PageMode(on bool) []byte {
+	if on {
+		return []byte{27, 'L'}
+	}
+	return []byte{27, 'S'}
+}
+
+// PageArea - ESC W xL xH yL yH dxL dxH dyL dyH
+func (EscposCommands) PageArea(x, y, width, height int) []byte {
+	return []byte{27, 'W', byte(x % 256), byte(x / 256), byte(y % 256), byte(y / 256),
+		byte(width % 256), byte(width / 256), byte(height % 256), byte(height / 256)}
+}
+
+// PageDirection - ESC T n
+func (EscposCommands) PageDirection(rotate int) []byte {
+	return []byte{27, 'T', pageDirections[rotate]}
+}
+
+// PagePosition - ESC $ nL nH, GS $ nL nH
+func (EscposCommands) PagePosition(x, y int) []byte {
+	return []byte{27, '$', byte(x % 256), byte(x / 256), 29, '$', byte(y % 256), byte(y / 256)}
+}
+
+// PageY - GS $ nL nH
+func (EscposCommands) PageY(y int) []byte {
+	return []byte{29, '$', byte(y % 256), byte(y / 256)}
+}
+
+// PrintPage - FF back to standard mode, ESC FF to keep the page mode
+func (EscposCommands) PrintPage(keep bool) []byte {
+	if keep {
+		return []byte{27, 12}
+	}
+	return []byte{12}
+}
+
+// CancelPage - CAN
+func (EscposCommands) CancelPage() []byte { return []byte{24} }
+
+// pageCommands - page mode of the command set or an error
+func (e *Escpos) pageCommands() (PageCommands, error) {
+	pc, ok := e.cmd.(PageCommands)
+	if !ok {
+		return nil, fmt.Errorf("Page mode is not supported by %s printers", e.cmd.Name())
+	}
+	return pc, nil
+}
+
+// BeginPage - enter page mode with a width x height dots page, text, bar
+// codes and images are kept until PrintPage
+func (e *Escpos) BeginPage(width, height int) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func BeginPage()\n")
+	}
+	pc, err := e.pageCommands()
+	if err != nil {
+		return err
+	}
+	if width <= 0 || width > e.dots {
+		width = e.dots
+	}
+	e.WriteRaw(pc.PageMode(true))
+	e.page = true
+	e.pageHeight = 0
+	return e.SetPageArea(0, 0, width, height, 0)
+}
+
+// SetPageArea - print area of the following data and its rotation (0, 90,
+// 180, 270 degrees clockwise), the position moves to its start
+func (e *Escpos) SetPageArea(x, y, width, height int, rotate int) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetPageArea()\n")
+	}
+	pc, err := e.pageCommands()
+	if err != nil {
+		return err
+	}
+	if !e.page {
+		return fmt.Errorf("Page area outside page mode")
+	}
+	if _, ok := pageDirections[rotate]; !ok {
+		return fmt.Errorf("Invalid page rotation: %d", rotate)
+	}
+	if y+height > e.pageHeight {
+		e.pageHeight = y + height
+	}
+	e.WriteRaw(append(pc.PageArea(x, y, width, height), pc.PageDirection(rotate)...))
+	e.column = 0
+	return e.err
+}
+
+// PageMove - move to x dots from the left of the area, y dots base line
+// from its top (bar codes and images end on it)
+func (e *Escpos) PageMove(x, y int) error {
+	pc, err := e.pageCommands()
+	if err != nil {
+		return err
+	}
+	e.WriteRaw(pc.PagePosition(x, y))
+	e.column = 0
+	return e.err
+}
+
+// PrintPage - print the page in one go and return to standard mode
+func (e *Escpos) PrintPage() error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func PrintPage()\n")
+	}
+	pc, err := e.pageCommands()
+	if err != nil {
+		return err
+	}
+	e.WriteRaw(pc.PrintPage(false))
+	e.advance(e.pageHeight)
+	e.page = false
+	e.prevByte = ASCIILF
+	e.column = 0
+	return e.err
+}
+
+// CancelPage - drop the page and return to standard mode
+func (e *Escpos) CancelPage() error {
+	pc, err := e.pageCommands()
+	if err != nil {
+		return err
+	}
+	e.WriteRaw(append(pc.CancelPage(), pc.PageMode(false)...))
+	e.page = false
+	return e.err
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package escpos
+
+// DefaultSerialPort - serial port of the printer by default, the UART of
+// the Raspberry Pi
+const DefaultSerialPort = "/dev/ttyAMA0"
@@ -0,0 +1,5 @@
+package escpos
+
+// DefaultSerialPort - serial port of the printer by default, USB-serial
+// adapters usually get a higher COM port
+const DefaultSerialPort = "COM1"
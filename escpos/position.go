@@ -0,0 +1,118 @@
+package escpos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultDPI - resolution of profiles without DPI, 8 dots per mm
+const DefaultDPI = 203
+
+// Dots - dots of mm millimeters at the profile resolution
+func (e *Escpos) Dots(mm float64) int {
+	dpi := e.dpi
+	if dpi <= 0 {
+		dpi = DefaultDPI
+	}
+	return int(mm*float64(dpi)/25.4 + 0.5)
+}
+
+// maxDots - largest position, the commands take 16 bits
+const maxDots = 0xFFFF
+
+// ParseDots - dots of a position: "24" or "24dots" dots, "3mm" or "3.5mm"
+// millimeters
+func (e *Escpos) ParseDots(s string) (int, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	if strings.HasSuffix(v, "mm") {
+		mm, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(v, "mm")), 64)
+		// also NaN and Inf
+		if err != nil || !(mm >= 0 && mm <= maxDots) || e.Dots(mm) > maxDots {
+			return 0, fmt.Errorf("Invalid position: %s", s)
+		}
+		return e.Dots(mm), nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(v, "dots")))
+	if err != nil || n < 0 || n > maxDots {
+		return 0, fmt.Errorf("Invalid position: %s", s)
+	}
+	return n, nil
+}
+
+// MoveX - print position x dots from the left margin, from the left of
+// the area in page mode
+func (e *Escpos) MoveX(x int) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func MoveX() %d\n", x)
+	}
+	e.WriteRaw(e.cmd.MoveX(x, false))
+	e.column = x
+	return e.err
+}
+
+// MoveBy - move the print position dx dots right, left when negative
+func (e *Escpos) MoveBy(dx int) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func MoveBy() %d\n", dx)
+	}
+	e.WriteRaw(e.cmd.MoveX(dx, true))
+	e.column += dx
+	if e.column < 0 {
+		e.column = 0
+	}
+	return e.err
+}
+
+// MoveY - base line y dots from the top of the area in page mode; in
+// standard mode the paper can only go forward, y dots are fed
+func (e *Escpos) MoveY(y int) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func MoveY() %d\n", y)
+	}
+	if e.page {
+		pc, err := e.pageCommands()
+		if err != nil {
+			return err
+		}
+		e.WriteRaw(pc.PageY(y))
+		return e.err
+	}
+	for ; y > 0; y -= 255 {
+		n := y
+		if n > 255 {
+			n = 255
+		}
+		e.FeedDots(uint8(n))
+	}
+	return e.err
+}
+
+// Position - move to x, y ("24" dots or "3mm"), empty x or y is left
+// unchanged; y goes first, in standard mode a feed would drop the x
+func (e *Escpos) Position(x, y string) error {
+	if len(y) > 0 {
+		dots, err := e.ParseDots(y)
+		if err != nil {
+			return err
+		}
+		if err := e.MoveY(dots); err != nil {
+			return err
+		}
+	}
+	if len(x) > 0 {
+		dots, err := e.ParseDots(x)
+		if err != nil {
+			return err
+		}
+		return e.MoveX(dots)
+	}
+	return nil
+}
+
+// Tab - n HT to the following tab stops
+func (e *Escpos) Tab(n int) {
+	for i := 0; i < n; i++ {
+		e.tab()
+	}
+}
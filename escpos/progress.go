@@ -0,0 +1,131 @@
+package escpos
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// Progress - how far a job is
+type Progress struct {
+	// Sent - bytes sent to the printer
+	Sent int64 `json:"sent"`
+	// Total - estimated bytes of the job, 0 when unknown
+	Total int64 `json:"total"`
+	// Section - name of the model section being printed
+	Section string `json:"section,omitempty"`
+}
+
+// Percent - Sent of Total, 0 when the total is unknown
+func (p Progress) Percent() int {
+	if p.Total <= 0 {
+		return 0
+	}
+	if p.Sent >= p.Total {
+		return 100
+	}
+	return int(p.Sent * 100 / p.Total)
+}
+
+// progress - Progress of the current job, read by other goroutines
+type progress struct {
+	mu sync.Mutex
+	p  Progress
+	// marks - sections started, kept for modelStream only
+	marks []sectionMark
+	keep  bool
+}
+
+// sectionMark - section of a model stream from offset on
+type sectionMark struct {
+	name   string
+	offset int64
+}
+
+// BeginJob - start the progress of a job of total bytes (0 - unknown),
+// see Estimate
+func (e *Escpos) BeginJob(total int64) {
+	e.prog.mu.Lock()
+	defer e.prog.mu.Unlock()
+	e.prog.p = Progress{Total: total}
+}
+
+// Progress - progress of the job since BeginJob
+func (e *Escpos) Progress() Progress {
+	e.prog.mu.Lock()
+	defer e.prog.mu.Unlock()
+	return e.prog.p
+}
+
+// sent - count n bytes sent
+func (e *Escpos) sent(n int) {
+	e.prog.mu.Lock()
+	defer e.prog.mu.Unlock()
+	e.prog.p.Sent += int64(n)
+}
+
+// setSection - name of the section being printed
+func (e *Escpos) setSection(name string) {
+	e.prog.mu.Lock()
+	defer e.prog.mu.Unlock()
+	e.prog.p.Section = name
+	if e.prog.keep {
+		e.prog.marks = append(e.prog.marks, sectionMark{name: name, offset: e.prog.p.Sent})
+	}
+}
+
+// setTotal - total of a job started without one
+func (e *Escpos) setTotal(total int64) {
+	e.prog.mu.Lock()
+	defer e.prog.mu.Unlock()
+	if e.prog.p.Total == 0 {
+		e.prog.p.Total = e.prog.p.Sent + total
+	}
+}
+
+// Estimate - bytes PrintModel sends for m, m is printed to a buffer
+func (e *Escpos) Estimate(m *models.PrinterLine) int64 {
+	return int64(len(e.ModelBytes(m)))
+}
+
+// ModelBytes - the stream PrintModel sends for m, e isn't written to;
+// WriteStream prints it again without rendering
+func (e *Escpos) ModelBytes(m *models.PrinterLine) []byte {
+	var buf bytes.Buffer
+	e.buffer(&buf).PrintModel(m)
+	return buf.Bytes()
+}
+
+// modelStream - ModelBytes of m, the sections in it and the paper
+// counted after it
+func (e *Escpos) modelStream(m *models.PrinterLine) ([]byte, []sectionMark, int) {
+	var buf bytes.Buffer
+	b := e.buffer(&buf)
+	b.prog.keep = true
+	b.PrintModel(m)
+	return buf.Bytes(), b.prog.marks, b.paper
+}
+
+// ModelJob - Job printing model m with its size as the total of the
+// progress: m is rendered once and its stream sent with WriteStream
+func ModelJob(m *models.PrinterLine) Job {
+	return PrintFunc(func(e *Escpos) error {
+		data, marks, paper := e.modelStream(m)
+		e.setTotal(int64(len(data)))
+		var off int64
+		for _, mark := range marks {
+			if _, err := e.WriteStream(data[off:mark.offset]); err != nil {
+				return err
+			}
+			e.setSection(mark.name)
+			off = mark.offset
+		}
+		if _, err := e.WriteStream(data[off:]); err != nil {
+			return err
+		}
+		// the paper of the render, not the one WriteStream counts
+		e.paper = paper
+		return e.Err()
+	})
+}
@@ -0,0 +1,464 @@
+package escpos
+
+import (
+	"fmt"
+)
+
+// qrEccCodewords - error correction codewords per block by level (L, M,
+// Q, H) and version
+var qrEccCodewords = [4][41]int{
+	{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// qrEccBlocks - error correction blocks by level and version
+var qrEccBlocks = [4][41]int{
+	{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// qrLevels - table index and format bits of the error correction levels
+var qrLevels = map[string][2]int{
+	"L": {0, 1},
+	"M": {1, 0},
+	"Q": {2, 3},
+	"H": {3, 2},
+}
+
+// qrSymbol - modules of a QR code, true is dark
+type qrSymbol struct {
+	size    int
+	modules [][]bool
+	// fixed - finder, timing, alignment, format and version modules
+	fixed [][]bool
+}
+
+// QrRaster - model 2 QR code of data in byte mode with error correction
+// ecc (L, M, Q, H), size dots per module. Printers without GS ( k print
+// it as an image
+func QrRaster(data string, size uint8, ecc string) (*Raster, error) {
+	q, err := qrEncode([]byte(data), ecc)
+	if err != nil {
+		return nil, err
+	}
+	return moduleRaster(q.modules, int(size)), nil
+}
+
+// qrRawModules - data and error correction modules of version ver
+func qrRawModules(ver int) int {
+	n := (16*ver+128)*ver + 64
+	if ver >= 2 {
+		align := ver/7 + 2
+		n -= (25*align-10)*align - 55
+		if ver >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// qrDataCodewords - data codewords of version ver at level l
+func qrDataCodewords(ver, l int) int {
+	return qrRawModules(ver)/8 - qrEccCodewords[l][ver]*qrEccBlocks[l][ver]
+}
+
+// qrEncode - smallest symbol holding data at level ecc (default M)
+func qrEncode(data []byte, ecc string) (*qrSymbol, error) {
+	level, ok := qrLevels[ecc]
+	if !ok {
+		level = qrLevels["M"]
+	}
+	l := level[0]
+	ver := 1
+	for ; ver <= 40; ver++ {
+		count := 8
+		if ver > 9 {
+			count = 16
+		}
+		if 4+count+8*len(data) <= qrDataCodewords(ver, l)*8 {
+			break
+		}
+	}
+	if ver > 40 {
+		return nil, fmt.Errorf("QR code: %d bytes do not fit in a symbol", len(data))
+	}
+
+	// mode, count, data, terminator and pad codewords
+	var bits []bool
+	put := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, v>>uint(i)&1 != 0)
+		}
+	}
+	put(4, 4)
+	if ver > 9 {
+		put(len(data), 16)
+	} else {
+		put(len(data), 8)
+	}
+	for _, b := range data {
+		put(int(b), 8)
+	}
+	capacity := qrDataCodewords(ver, l) * 8
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		put(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, b := range bits {
+		if b {
+			codewords[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+
+	q := newQrSymbol(ver)
+	q.drawCodewords(qrInterleave(codewords, ver, l))
+	best, penalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.mask(mask)
+		q.drawFormat(level[1], mask)
+		if p := q.penalty(); penalty < 0 || p < penalty {
+			best, penalty = mask, p
+		}
+		q.mask(mask)
+	}
+	q.mask(best)
+	q.drawFormat(level[1], best)
+	return q, nil
+}
+
+// qrMul - product in GF(256) modulo x^8 + x^4 + x^3 + x^2 + 1
+func qrMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ (z>>7)*0x11D
+		z ^= int(y>>uint(i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// qrGenerator - Reed-Solomon generator polynomial of degree n, highest
+// coefficient dropped
+func qrGenerator(n int) []byte {
+	res := make([]byte, n)
+	res[n-1] = 1
+	root := byte(1)
+	for i := 0; i < n; i++ {
+		for j := range res {
+			res[j] = qrMul(res[j], root)
+			if j+1 < n {
+				res[j] ^= res[j+1]
+			}
+		}
+		root = qrMul(root, 2)
+	}
+	return res
+}
+
+// qrRemainder - error correction codewords of data
+func qrRemainder(data, gen []byte) []byte {
+	res := make([]byte, len(gen))
+	for _, b := range data {
+		factor := b ^ res[0]
+		copy(res, res[1:])
+		res[len(res)-1] = 0
+		for i, c := range gen {
+			res[i] ^= qrMul(c, factor)
+		}
+	}
+	return res
+}
+
+// qrInterleave - split data into blocks, add their error correction and
+// interleave the codewords
+func qrInterleave(data []byte, ver, l int) []byte {
+	blocks := qrEccBlocks[l][ver]
+	eccLen := qrEccCodewords[l][ver]
+	raw := qrRawModules(ver) / 8
+	short := blocks - raw%blocks
+	shortLen := raw / blocks
+	gen := qrGenerator(eccLen)
+	var res [][]byte
+	k := 0
+	for i := 0; i < blocks; i++ {
+		n := shortLen - eccLen
+		if i >= short {
+			n++
+		}
+		block := append([]byte{}, data[k:k+n]...)
+		k += n
+		ecc := qrRemainder(block, gen)
+		if i < short {
+			// placeholder, skipped when interleaving
+			block = append(block, 0)
+		}
+		res = append(res, append(block, ecc...))
+	}
+	var out []byte
+	for i := 0; i < len(res[0]); i++ {
+		for j, block := range res {
+			if i != shortLen-eccLen || j >= short {
+				out = append(out, block[i])
+			}
+		}
+	}
+	return out
+}
+
+// newQrSymbol - symbol of version ver with the function patterns drawn
+func newQrSymbol(ver int) *qrSymbol {
+	size := ver*4 + 17
+	q := &qrSymbol{size: size}
+	q.modules = make([][]bool, size)
+	q.fixed = make([][]bool, size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.fixed[i] = make([]bool, size)
+	}
+	for i := 0; i < size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+	q.finder(3, 3)
+	q.finder(size-4, 3)
+	q.finder(3, size-4)
+	pos := qrAlignment(ver)
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(pos[i]+dx, pos[j]+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+				}
+			}
+		}
+	}
+	// reserve the format modules, drawn after masking
+	q.drawFormat(0, 0)
+	if ver >= 7 {
+		rem := ver
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ (rem>>11)*0x1F25
+		}
+		v := ver<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := v>>uint(i)&1 != 0
+			a, b := size-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+	return q
+}
+
+// qrAlignment - alignment pattern centers of version ver
+func qrAlignment(ver int) []int {
+	if ver == 1 {
+		return nil
+	}
+	n := ver/7 + 2
+	step := (ver*4 + n*2 + 1) / (n*2 - 2) * 2
+	if ver == 32 {
+		step = 26
+	}
+	res := make([]int, n)
+	res[0] = 6
+	for i, pos := n-1, ver*4+10; i > 0; i, pos = i-1, pos-step {
+		res[i] = pos
+	}
+	return res
+}
+
+func qrAbs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// set - function module x, y
+func (q *qrSymbol) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.fixed[y][x] = true
+}
+
+// finder - finder pattern and separator centered at x, y
+func (q *qrSymbol) finder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			d := qrMax(qrAbs(dx), qrAbs(dy))
+			if x+dx >= 0 && x+dx < q.size && y+dy >= 0 && y+dy < q.size {
+				q.set(x+dx, y+dy, d != 2 && d != 4)
+			}
+		}
+	}
+}
+
+// drawFormat - error correction level and mask bits, both copies
+func (q *qrSymbol) drawFormat(level, mask int) {
+	data := level<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>uint(i)&1 != 0 }
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+	q.set(8, q.size-8, true)
+}
+
+// drawCodewords - data in the zigzag order, two columns at a time from
+// the bottom right
+func (q *qrSymbol) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.fixed[y][x] && i < len(data)*8 {
+					q.modules[y][x] = data[i>>3]>>uint(7-i&7)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// mask - invert the data modules of the mask pattern, twice undoes it
+func (q *qrSymbol) mask(m int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			var inv bool
+			switch m {
+			case 0:
+				inv = (x+y)%2 == 0
+			case 1:
+				inv = y%2 == 0
+			case 2:
+				inv = x%3 == 0
+			case 3:
+				inv = (x+y)%3 == 0
+			case 4:
+				inv = (x/3+y/2)%2 == 0
+			case 5:
+				inv = x*y%2+x*y%3 == 0
+			case 6:
+				inv = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				inv = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if inv && !q.fixed[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty - mask evaluation score, lower is better
+func (q *qrSymbol) penalty() int {
+	n := q.size
+	at := func(x, y int, col bool) bool {
+		if col {
+			return q.modules[x][y]
+		}
+		return q.modules[y][x]
+	}
+	p, dark := 0, 0
+	// runs of 5 or more and finder like 1:1:3:1:1 patterns
+	finder := []bool{true, false, true, true, true, false, true}
+	for _, col := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			run := 0
+			for x := 0; x < n; x++ {
+				if x > 0 && at(x, y, col) == at(x-1, y, col) {
+					run++
+				} else {
+					run = 1
+				}
+				if run == 5 {
+					p += 3
+				} else if run > 5 {
+					p++
+				}
+				if x+7 > n {
+					continue
+				}
+				match := true
+				for i, f := range finder {
+					if at(x+i, y, col) != f {
+						match = false
+						break
+					}
+				}
+				if !match {
+					continue
+				}
+				light := func(from, to int) bool {
+					for i := from; i < to; i++ {
+						if i >= 0 && i < n && at(i, y, col) {
+							return false
+						}
+					}
+					return true
+				}
+				if light(x-4, x) || light(x+7, x+11) {
+					p += 40
+				}
+			}
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			c := q.modules[y][x]
+			if c {
+				dark++
+			}
+			if x+1 < n && y+1 < n && c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+				p += 3
+			}
+		}
+	}
+	// dark modules away from 50%
+	k := qrAbs(dark*20-n*n*10) / (n * n)
+	return p + k*10
+}
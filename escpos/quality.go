@@ -0,0 +1,93 @@
+package escpos
+
+import (
+	"fmt"
+)
+
+// Quality - print speed against darkness: the heating of ESC 7, the
+// density of DC2 # and the line spacing
+type Quality struct {
+	Name        string
+	Description string
+	// HeatDots - dots heated at once in units of 8 (minus 1), more is
+	// faster but draws more current
+	HeatDots uint8
+	// HeatTime - heating time in 10 us, more is darker and slower
+	HeatTime uint8
+	// HeatInterval - pause between heated groups in 10 us, more is
+	// clearer and slower
+	HeatInterval uint8
+	// Density - 50% + 5% * Density, 0..31
+	Density uint8
+	// BreakTime - 250 us * BreakTime, 0..7
+	BreakTime uint8
+	// LineHeight - dots from line to line, at least the 24 of a character
+	LineHeight uint8
+	// DotPrintTime - microseconds to print a dot row, paces the text;
+	// Profile.DotPrintTime replaces the estimate
+	DotPrintTime int64
+}
+
+// Qualities - presets --quality accepts
+var Qualities = []Quality{
+	{Name: "draft", Description: "fastest, light print and tight lines",
+		HeatDots: 15, HeatTime: 60, HeatInterval: 2, Density: 8, BreakTime: 2, LineHeight: 26, DotPrintTime: 22000},
+	{Name: "normal", Description: "default heating and line spacing",
+		HeatDots: 11, HeatTime: 80, HeatInterval: 40, Density: 10, BreakTime: 2, LineHeight: 30, DotPrintTime: 30000},
+	{Name: "dark", Description: "long heating for faded paper and bar codes, slowest",
+		HeatDots: 7, HeatTime: 160, HeatInterval: 60, Density: 15, BreakTime: 4, LineHeight: 32, DotPrintTime: 48000},
+}
+
+// FindQuality - quality preset by name, empty name is normal
+func FindQuality(name string) (Quality, bool) {
+	if len(name) == 0 {
+		name = "normal"
+	}
+	for _, q := range Qualities {
+		if q.Name == name {
+			return q, true
+		}
+	}
+	return Quality{}, false
+}
+
+// SetQuality - switch to the quality preset name (draft, normal, dark),
+// Begin sends it again after the reset
+func (e *Escpos) SetQuality(name string) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetQuality() %s\n", name)
+	}
+	q, ok := FindQuality(name)
+	if !ok {
+		return fmt.Errorf("Invalid quality: %s", name)
+	}
+	e.quality = q
+	e.applyQuality(true)
+	return e.err
+}
+
+// Quality - name of the current quality preset
+func (e *Escpos) Quality() string {
+	return e.quality.Name
+}
+
+// applyQuality - send the heating and density of the current preset
+// (Adafruit commands) and the line spacing when spacing
+func (e *Escpos) applyQuality(spacing bool) {
+	q := e.quality
+	if e.adafruit() {
+		e.WriteRaw([]byte{27, '7', q.HeatDots, q.HeatTime, q.HeatInterval})
+		e.printDensity = q.Density & 31
+		e.printBreakTime = q.BreakTime & 7
+		e.WriteRaw([]byte{18, '#', e.printBreakTime<<5 | e.printDensity})
+	}
+	height := q.LineHeight
+	if height < 24 {
+		height = 24
+	}
+	if spacing {
+		e.WriteRaw(e.cmd.LineSpacing(height))
+	}
+	e.lineSpacing = int64(height) - 24
+	e.dotPrintTime = e.printTime(q)
+}
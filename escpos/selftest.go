@@ -0,0 +1,115 @@
+package escpos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Check - result of one self test step
+type Check struct {
+	Name  string `json:"name"`
+	Pass  bool   `json:"pass"`
+	Error string `json:"error,omitempty"`
+}
+
+// SelfTestReport - summary of SelfTest
+type SelfTestReport struct {
+	Pass     bool    `json:"pass"`
+	Checks   []Check `json:"checks"`
+	Firmware string  `json:"firmware,omitempty"`
+	Status   *Status `json:"status,omitempty"`
+	// Throughput - bytes per second of text including print pacing
+	Throughput float64 `json:"throughput"`
+}
+
+// check - add step name failed by err
+func (r *SelfTestReport) check(name string, err error) bool {
+	c := Check{Name: name, Pass: err == nil}
+	if err != nil {
+		c.Error = err.Error()
+		r.Pass = false
+	}
+	r.Checks = append(r.Checks, c)
+	return c.Pass
+}
+
+// SelfTest - check the port, wake the printer, query status and
+// firmware, print a calibration block and measure text throughput
+func (e *Escpos) SelfTest() (r SelfTestReport) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SelfTest()\n")
+	}
+	r.Pass = true
+	if !e.Debug && e.dst == nil {
+		_, err := e.send(nil)
+		r.check("open", err)
+		return r
+	}
+	r.check("open", nil)
+
+	e.Begin()
+	if !r.check("wake", e.err) {
+		return r
+	}
+
+	s, err := e.Status()
+	if err == nil {
+		r.Status = &s
+		switch {
+		case !s.Online:
+			err = fmt.Errorf("Printer is offline")
+		case s.CoverOpen:
+			err = fmt.Errorf("Cover is open")
+		case s.PaperOut:
+			err = fmt.Errorf("Out of paper")
+		case s.Error || s.CutterError:
+			err = fmt.Errorf("Printer error")
+		}
+	}
+	r.check("status", err)
+
+	r.Firmware, err = e.FirmwareVersion()
+	r.check("firmware", err)
+
+	e.calibration()
+	r.check("calibration", e.err)
+
+	r.Throughput = e.throughput()
+	r.check("throughput", e.err)
+	e.Feed(3)
+	return r
+}
+
+// calibration - column ruler and a full width bar to check alignment,
+// print width and density
+func (e *Escpos) calibration() {
+	e.SetAlign("center")
+	e.SetBold(true)
+	e.WriteText("gotp selftest")
+	e.Linefeed()
+	e.SetBold(false)
+	e.SetAlign("left")
+	ruler := strings.Repeat("1234567890", int(e.maxColumn)/10+1)[:e.maxColumn]
+	e.WriteText(ruler)
+	bar := &Raster{Width: e.dots, Height: 16}
+	bar.Data = make([]byte, bar.RowBytes()*bar.Height)
+	for i := range bar.Data {
+		bar.Data[i] = 0xFF
+	}
+	e.printRaster(bar)
+	e.LinePrint()
+}
+
+// throughput - bytes per second sending full text lines
+func (e *Escpos) throughput() float64 {
+	line := strings.Repeat("#", int(e.maxColumn))
+	start := time.Now()
+	n := 0
+	for i := 0; i < 8; i++ {
+		e.WriteText(line)
+		n += len(line)
+	}
+	e.drain()
+	return float64(n) / time.Since(start).Seconds()
+}
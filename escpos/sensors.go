@@ -0,0 +1,42 @@
+package escpos
+
+// SensorCommands - head temperature and supply voltage queries of
+// command sets which have them; printers answer them only with the
+// vendor firmware, Profile.Sensors turns them on
+type SensorCommands interface {
+	// Temperature - query answered with one byte, the head temperature
+	// in °C
+	Temperature() []byte
+	// Voltage - query answered with one byte, the supply voltage in
+	// 0.1 V
+	Voltage() []byte
+}
+
+// Temperature - DC2 v 1, vendor transmit command of CSN-A2 firmwares
+func (EscposCommands) Temperature() []byte { return []byte{18, 'v', 1} }
+
+// Voltage - DC2 v 2, vendor transmit command of CSN-A2 firmwares
+func (EscposCommands) Voltage() []byte { return []byte{18, 'v', 2} }
+
+// lowVoltage - supply voltage below which prints fade, the heads need
+// 5-9 V at 1.5 A and more
+const lowVoltage = 5.0
+
+// readSensors - head temperature and supply voltage into s when the
+// profile has the sensors, a reading the printer doesn't answer is left
+// out
+func (e *Escpos) readSensors(s *Status) {
+	sc, ok := e.cmd.(SensorCommands)
+	if !e.sensors || !ok {
+		return
+	}
+	if b, err := e.queryByte(sc.Temperature()); err == nil {
+		t := float64(b)
+		s.Temperature = &t
+	}
+	if b, err := e.queryByte(sc.Voltage()); err == nil {
+		v := float64(b) / 10
+		s.Voltage = &v
+		s.LowVoltage = v < lowVoltage
+	}
+}
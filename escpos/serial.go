@@ -0,0 +1,124 @@
+package escpos
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// ParseFrame - data bits, parity and stop bits of a "8N1" style frame
+func ParseFrame(frame string) (size byte, parity serial.Parity, stop serial.StopBits, err error) {
+	if len(frame) == 0 {
+		return serial.DefaultSize, serial.ParityNone, serial.Stop1, nil
+	}
+	if len(frame) < 3 || frame[0] < '5' || frame[0] > '8' {
+		return size, parity, stop, fmt.Errorf("Invalid serial frame: %s", frame)
+	}
+	size = frame[0] - '0'
+	switch frame[1] {
+	case 'N', 'n':
+		parity = serial.ParityNone
+	case 'E', 'e':
+		parity = serial.ParityEven
+	case 'O', 'o':
+		parity = serial.ParityOdd
+	case 'M', 'm':
+		parity = serial.ParityMark
+	case 'S', 's':
+		parity = serial.ParitySpace
+	default:
+		return size, parity, stop, fmt.Errorf("Invalid serial frame: %s", frame)
+	}
+	switch frame[2:] {
+	case "1":
+		stop = serial.Stop1
+	case "1.5":
+		stop = serial.Stop1Half
+	case "2":
+		stop = serial.Stop2
+	default:
+		return size, parity, stop, fmt.Errorf("Invalid serial frame: %s", frame)
+	}
+	return size, parity, stop, nil
+}
+
+// SerialPort - name of port to open: "com3" and "COM3:" of the Windows
+// tools are COM3, tarm/serial opens it as \\.\COM3 which works past
+// COM9 too; other names are kept
+func SerialPort(port string) string {
+	name := strings.TrimSuffix(port, ":")
+	if len(name) > 3 && strings.EqualFold(name[:3], "COM") {
+		if _, err := strconv.Atoi(name[3:]); err == nil {
+			return "COM" + name[3:]
+		}
+	}
+	return port
+}
+
+// SerialConfig - config of port at baud with the frame and read timeout
+// of profile p
+func (p Profile) SerialConfig(port string, baud int) (*serial.Config, error) {
+	size, parity, stop, err := ParseFrame(p.Frame)
+	if err != nil {
+		return nil, err
+	}
+	return &serial.Config{
+		Name:        SerialPort(port),
+		Baud:        baud,
+		Size:        size,
+		Parity:      parity,
+		StopBits:    stop,
+		ReadTimeout: p.ReadTimeout,
+	}, nil
+}
+
+// BaudRates - rates ProbeBaud tries, most printers ship at 19200, rare
+// ones at 9600
+var BaudRates = []int{19200, 9600, 38400, 57600, 115200, 4800}
+
+// probeTimeout - wait for the status reply at each rate
+const probeTimeout = 500 * time.Millisecond
+
+// ProbeBaud - first of BaudRates the printer on config.Name answers
+// DLE EOT status queries at
+func ProbeBaud(config serial.Config) (int, error) {
+	config.ReadTimeout = probeTimeout
+	for _, baud := range BaudRates {
+		config.Baud = baud
+		s, err := serial.OpenPort(&config)
+		if err != nil {
+			return 0, fmt.Errorf("Probe baud: %s", err.Error())
+		}
+		ok := probeStatus(s, 1) && probeStatus(s, 4)
+		s.Close()
+		if ok {
+			return baud, nil
+		}
+	}
+	return 0, fmt.Errorf("Probe baud: no reply from %s", config.Name)
+}
+
+// probeStatus - DLE EOT n gets a valid status byte on s
+func probeStatus(s *serial.Port, n byte) bool {
+	s.Flush()
+	if _, err := s.Write([]byte{16, 4, n}); err != nil {
+		return false
+	}
+	buf := make([]byte, 16)
+	deadline := time.Now().Add(probeTimeout)
+	for time.Now().Before(deadline) {
+		l, err := s.Read(buf)
+		if l > 0 {
+			// only the status byte, bits 1 and 4 set, 0 and 7 clear
+			return l == 1 && buf[0]&0x93 == 0x12
+		}
+		if err != nil && err != io.EOF {
+			return false
+		}
+	}
+	return false
+}
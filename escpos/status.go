@@ -0,0 +1,305 @@
+package escpos
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// replyTimeout - how long to wait for the printer to answer a query when
+// no read timeout is configured
+const replyTimeout = time.Second
+
+// Status - printer state reported by DLE EOT (or GS r on printers
+// without real-time status)
+type Status struct {
+	Online bool `json:"online"`
+	// DrawerOpen - drawer kick-out connector pin 3 is high
+	DrawerOpen   bool `json:"drawerOpen"`
+	CoverOpen    bool `json:"coverOpen"`
+	FeedButton   bool `json:"feedButton"`
+	PaperOut     bool `json:"paperOut"`
+	PaperNearEnd bool `json:"paperNearEnd"`
+	CutterError  bool `json:"cutterError"`
+	Error        bool `json:"error"`
+	// Temperature - head temperature in °C, nil when not reported
+	Temperature *float64 `json:"temperature,omitempty"`
+	// Voltage - supply voltage in V, nil when not reported
+	Voltage *float64 `json:"voltage,omitempty"`
+	// LowVoltage - the supply is too weak, prints fade
+	LowVoltage bool `json:"lowVoltage,omitempty"`
+	// Model, Serial - reported by the SNMP agent of TCP printers
+	Model  string `json:"model,omitempty"`
+	Serial string `json:"serial,omitempty"`
+}
+
+// send - write data to the serial port (paced) or writer and the tee, debug
+// mode only writes the tee and a hexdump on stdout; the first error is
+// kept for Err
+func (e *Escpos) send(data []byte) (int, error) {
+	if e.hooks.refused {
+		return 0, e.err
+	}
+	if e.tee != nil {
+		e.tee.Write(data)
+	}
+	if e.Debug {
+		if e.hexdump == nil {
+			e.hexdump = NewHexdump(os.Stdout)
+		}
+		e.hexdump.Write(data)
+		e.sent(len(data))
+		return len(data), nil
+	}
+	if e.dst == nil {
+		if e.err == nil {
+			e.err = ErrNoDevice
+		}
+		return 0, e.err
+	}
+	w := io.Writer(writerFunc(e.write))
+	if e.pacer != nil {
+		w = e.pacer
+	}
+	n, err := w.Write(data)
+	e.sent(n)
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// startReader - copy bytes the printer sends into e.rx, the reader runs
+// until the port fails or a source other than the serial port ends
+func (e *Escpos) startReader() error {
+	if e.Debug {
+		return fmt.Errorf("No printer replies in debug mode")
+	}
+	if e.dst == nil {
+		_, err := e.send(nil)
+		return err
+	}
+	if e.src == nil {
+		return fmt.Errorf("Printer output can't send replies")
+	}
+	if e.rx != nil {
+		return nil
+	}
+	rx := make(chan byte, 256)
+	e.rx = rx
+	go func() {
+		defer close(rx)
+		buf := make([]byte, 64)
+		var packet []byte
+		for {
+			n, err := e.src.Read(buf)
+			for _, c := range buf[:n] {
+				// ASB packets start with bit 4 set, bits 0, 1 and 7 clear;
+				// the reply of a query can look like one
+				if len(packet) == 0 && atomic.LoadInt32(&e.asb) == 1 &&
+					atomic.LoadInt32(&e.querying) == 0 && c&0x93 == 0x10 {
+					packet = append(packet, c)
+				} else if len(packet) > 0 {
+					packet = append(packet, c)
+					if len(packet) == 4 {
+						if e.OnStatus != nil {
+							e.OnStatus(parseASB(packet))
+						}
+						packet = nil
+					}
+				} else {
+					rx <- c
+				}
+			}
+			if err == io.EOF && n == 0 && e.Serial == nil {
+				// the serial port reads EOF when its read times out,
+				// other sources are done
+				return
+			}
+			if err != nil && err != io.EOF {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// query - send cmd and read the reply until done returns true or the
+// reply times out
+func (e *Escpos) query(cmd []byte, done func([]byte) bool) ([]byte, error) {
+	if err := e.startReader(); err != nil {
+		return nil, err
+	}
+	atomic.StoreInt32(&e.querying, 1)
+	defer atomic.StoreInt32(&e.querying, 0)
+	// drop bytes left from an earlier query
+	for len(e.rx) > 0 {
+		<-e.rx
+	}
+	e.WriteBytes(cmd)
+	if e.err != nil {
+		return nil, e.err
+	}
+	var res []byte
+	wait := replyTimeout
+	if e.readTimeout > 0 {
+		wait = e.readTimeout
+	}
+	timeout := time.After(wait)
+	for {
+		select {
+		case c, ok := <-e.rx:
+			if !ok {
+				return res, fmt.Errorf("Printer port closed")
+			}
+			res = append(res, c)
+			if done(res) {
+				return res, nil
+			}
+		case <-timeout:
+			return res, fmt.Errorf("%w: no reply to %X", ErrTimeout, cmd)
+		}
+	}
+}
+
+// queryByte - one byte reply of cmd
+func (e *Escpos) queryByte(cmd []byte) (byte, error) {
+	res, err := e.query(cmd, func(b []byte) bool { return len(b) == 1 })
+	if err != nil {
+		return 0, err
+	}
+	return res[0], nil
+}
+
+// Status - read printer, offline, error and paper status with DLE EOT,
+// printers without real-time status only report paper with GS r; head
+// temperature and voltage are read on profiles with Sensors, TCP printers
+// with SetSNMP report the printer MIB
+func (e *Escpos) Status() (s Status, err error) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func Status()\n")
+	}
+	if len(e.snmpCommunity) > 0 {
+		return e.snmpStatus()
+	}
+	var st [5]byte
+	for n := byte(1); n <= 4; n++ {
+		b, err := e.queryByte([]byte{16, 4, n})
+		// bits 1 and 4 are always set, 0 and 7 always clear
+		if err == nil && b&0x93 != 0x12 {
+			err = fmt.Errorf("Invalid status reply: %X", b)
+		}
+		if err != nil {
+			if n == 1 {
+				return e.paperStatus()
+			}
+			return s, err
+		}
+		st[n] = b
+	}
+	s.Online = st[1]&0x08 == 0
+	s.DrawerOpen = st[1]&0x04 != 0
+	s.CoverOpen = st[2]&0x04 != 0
+	s.FeedButton = st[2]&0x08 != 0
+	s.Error = st[2]&0x40 != 0
+	s.CutterError = st[3]&0x08 != 0
+	s.PaperNearEnd = st[4]&0x0C != 0
+	s.PaperOut = st[4]&0x60 != 0
+	e.readSensors(&s)
+	e.readDoor(&s)
+	return s, nil
+}
+
+// Err - ErrPaperOut when the printer is out of paper
+func (s Status) Err() error {
+	if s.PaperOut {
+		return ErrPaperOut
+	}
+	return nil
+}
+
+// paperStatus - paper sensor status of GS r 0 (Adafruit hasPaper)
+func (e *Escpos) paperStatus() (s Status, err error) {
+	b, err := e.queryByte([]byte{29, 'r', 0})
+	if err != nil {
+		return s, err
+	}
+	s.Online = true
+	s.PaperOut = b&0x04 != 0
+	e.readSensors(&s)
+	e.readDoor(&s)
+	return s, nil
+}
+
+// FirmwareVersion - firmware version reported by GS I 65, the ROM
+// version byte of GS I 3 on printers without it
+func (e *Escpos) FirmwareVersion() (string, error) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func FirmwareVersion()\n")
+	}
+	if s, err := e.firmwareString(); err == nil {
+		return s, nil
+	}
+	b, err := e.queryByte([]byte{29, 'I', 3})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%02X", b), nil
+}
+
+// firmwareString - reply of GS I 65, "_" version NUL
+func (e *Escpos) firmwareString() (string, error) {
+	res, err := e.query([]byte{29, 'I', 65}, func(b []byte) bool { return b[len(b)-1] == 0 })
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(strings.TrimPrefix(string(res), "_"), "\x00"), nil
+}
+
+// EnableASB - turn automatic status back (GS a) on or off, with ASB on
+// the printer sends its status on every change and OnStatus is called
+// from the reader goroutine
+func (e *Escpos) EnableASB(enabled bool) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func EnableASB()\n")
+	}
+	if err := e.startReader(); err != nil {
+		return err
+	}
+	// drawer, online, error, paper sensor and panel switch changes
+	n := byte(0x2F)
+	if !enabled {
+		n = 0
+		atomic.StoreInt32(&e.asb, 0)
+	} else {
+		atomic.StoreInt32(&e.asb, 1)
+	}
+	// GS a also keeps the DTR busy signal of Begin
+	e.WriteBytes([]byte{29, 97, n | e.dtrBusy()})
+	return e.err
+}
+
+// dtrBusy - GS a bit 5 with the DTR pin of Profile.GPIO: the printer
+// raises it while busy, see pacingWriter
+func (e *Escpos) dtrBusy() byte {
+	if e.gpio != nil && e.gpio.dtr != nil && e.adafruit() {
+		return 1 << 5
+	}
+	return 0
+}
+
+// parseASB - status of a 4 byte ASB packet
+func parseASB(p []byte) (s Status) {
+	s.DrawerOpen = p[0]&0x04 != 0
+	s.Online = p[0]&0x08 == 0
+	s.CoverOpen = p[0]&0x20 != 0
+	s.FeedButton = p[0]&0x40 != 0
+	s.CutterError = p[1]&0x08 != 0
+	s.Error = p[1]&0x64 != 0
+	s.PaperNearEnd = p[2]&0x03 != 0
+	s.PaperOut = p[2]&0x0C != 0
+	return s
+}
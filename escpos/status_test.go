@@ -0,0 +1,75 @@
+package escpos
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// eofSource - printer replies of a reader which ends, counting the reads
+type eofSource struct {
+	r     io.Reader
+	reads int32
+}
+
+func (s *eofSource) Read(p []byte) (int, error) {
+	atomic.AddInt32(&s.reads, 1)
+	return s.r.Read(p)
+}
+
+func TestReaderEOF(t *testing.T) {
+	src := &eofSource{r: bytes.NewReader([]byte{0x12})}
+	e := NewReadWriter(struct {
+		io.Reader
+		io.Writer
+	}{src, ioutil.Discard})
+	b, err := e.queryByte([]byte{16, 4, 1})
+	if err != nil || b != 0x12 {
+		t.Fatalf("queryByte = %X, %v", b, err)
+	}
+	// the reader stops at the end of the source, queries fail at once
+	start := time.Now()
+	if _, err := e.queryByte([]byte{16, 4, 1}); err == nil || err.Error() != "Printer port closed" {
+		t.Errorf("queryByte after EOF: %v", err)
+	}
+	if d := time.Since(start); d >= replyTimeout {
+		t.Errorf("queryByte after EOF took %s", d)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&src.reads); n != 2 {
+		t.Errorf("%d reads of the source, want 2", n)
+	}
+}
+
+// replyPort - printer port sending reply after a write containing cmd
+type replyPort struct {
+	cmd, reply []byte
+	rx         chan []byte
+}
+
+func (p *replyPort) Write(b []byte) (int, error) {
+	if bytes.Contains(b, p.cmd) {
+		p.rx <- p.reply
+	}
+	return len(b), nil
+}
+
+func (p *replyPort) Read(b []byte) (int, error) {
+	return copy(b, <-p.rx), nil
+}
+
+func TestQueryWithASB(t *testing.T) {
+	// "8" and "0" of the firmware version look like ASB packet starts
+	port := &replyPort{cmd: []byte{29, 'I', 65}, reply: []byte("_2.80\x00"), rx: make(chan []byte, 4)}
+	e := NewReadWriter(port)
+	if err := e.EnableASB(true); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := e.firmwareString()
+	if err != nil || fw != "2.80" {
+		t.Errorf("firmwareString with ASB on = %q, %v", fw, err)
+	}
+}
@@ -0,0 +1,175 @@
+package escpos
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ParseHex - bytes from hex text ("1B 40", "1b40", "0x1B,0x40")
+func ParseHex(s string) ([]byte, error) {
+	s = strings.NewReplacer("0x", "", "0X", "", ",", " ", "\n", " ", "\t", " ").Replace(s)
+	s = strings.Join(strings.Fields(s), "")
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid hex: %s", err.Error())
+	}
+	return b, nil
+}
+
+// streamChunk - largest number of bytes sent without pacing
+const streamChunk = 64
+
+// WriteStream - write a ready ESC/POS byte stream, split in chunks ending
+// at line feeds, the pacing of the port waits for the printer between
+// them; images wait after every band as Profile.BandWait sets, the feeds
+// of the stream count for FormFeed
+func (e *Escpos) WriteStream(data []byte) (n int, err error) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func WriteStream() %d bytes\n", len(data))
+	}
+	for len(data) > 0 {
+		end := len(data)
+		if end > streamChunk {
+			end = streamChunk
+		}
+		for i := 0; i < end; i++ {
+			if data[i] == ASCIILF {
+				end = i + 1
+				break
+			}
+		}
+		// never split a command from its parameters
+		end = e.commandEnd(data, end)
+		w, err := e.send(data[:end])
+		n += w
+		if err != nil {
+			e.err = err
+			return n, err
+		}
+		e.streamed(data[:end])
+		if end < len(data) && hasImage(data[:end]) {
+			e.bandWait(data[:end])
+		}
+		data = data[end:]
+	}
+	return n, nil
+}
+
+// commandEnd - end moved past the parameters of a command starting
+// before end (raster and bitmap data included)
+func (e *Escpos) commandEnd(data []byte, end int) int {
+	for i := 0; i < end && i < len(data); {
+		l := CommandLen(data[i:])
+		if i+l > end {
+			end = i + l
+			if end > len(data) {
+				end = len(data)
+			}
+		}
+		i += l
+	}
+	return end
+}
+
+// fixedLen - length of commands without variable data, by prefix
+var fixedLen = map[[2]byte]int{
+	{27, '@'}: 2, {27, '<'}: 2, {27, '2'}: 2, {27, 'L'}: 2, {27, 'S'}: 2,
+	{27, 'i'}: 2, {27, 'm'}: 2, {27, 12}: 2,
+	{27, '$'}: 4, {27, '\\'}: 4, {27, '8'}: 4, {27, 'B'}: 4, {27, 'c'}: 4,
+	{27, '7'}: 5, {27, 'p'}: 5, {27, 'W'}: 10,
+	{29, 12}: 2, {29, '<'}: 2,
+	{29, '$'}: 4, {29, '\\'}: 4, {29, 'L'}: 4, {29, 'W'}: 4, {29, 'P'}: 4,
+	{18, 'T'}: 2,
+	{28, '.'}: 2, {28, '&'}: 2, {28, 'p'}: 4,
+	{16, 4}: 3, {16, 20}: 5,
+}
+
+// CommandLen - length of the command at the start of data, 1 for text
+// and single byte controls; ESC, GS, DC2 and FS commands not listed
+// take one parameter byte
+func CommandLen(data []byte) int {
+	if len(data) < 2 {
+		return 1
+	}
+	c := data[0]
+	if c != 27 && c != 29 && c != 18 && c != 28 && c != 16 {
+		return 1
+	}
+	if l, ok := fixedLen[[2]byte{c, data[1]}]; ok {
+		return l
+	}
+	switch {
+	case c == 16:
+		return 1
+	case c == 27 && data[1] == '*':
+		// ESC * m nL nH, 1 or 3 bytes per column
+		if len(data) >= 5 {
+			n := int(data[3]) + int(data[4])*256
+			if data[2] >= 32 {
+				n *= 3
+			}
+			return 5 + n
+		}
+	case c == 27 && data[1] == 29 && len(data) >= 9 && data[2] == 'S':
+		// star raster ESC GS S m xL xH yL yH n
+		return 9 + (int(data[4])+int(data[5])*256)*(int(data[6])+int(data[7])*256)
+	case c == 27 && data[1] == 29 && len(data) >= 3:
+		// star ESC GS commands
+		switch data[2] {
+		case 'a', 't':
+			return 4
+		case 'A', 'R':
+			return 5
+		case 7:
+			return 6
+		case 'y':
+			if len(data) >= 4 && data[3] == 'S' {
+				return 6
+			}
+			if len(data) >= 8 && data[3] == 'D' {
+				return 8 + int(data[6]) + int(data[7])*256
+			}
+			return 4
+		}
+	case c == 27 && data[1] == 'D':
+		// tab stops, NUL terminated
+		return nulEnd(data, 2)
+	case c == 29 && data[1] == 'v':
+		// GS v 0 m xL xH yL yH
+		if len(data) >= 8 {
+			return 8 + (int(data[4])+int(data[5])*256)*(int(data[6])+int(data[7])*256)
+		}
+	case c == 29 && (data[1] == '(' || data[1] == '8'):
+		// GS ( fn pL pH ...
+		if len(data) >= 5 {
+			return 5 + int(data[3]) + int(data[4])*256
+		}
+	case c == 29 && data[1] == 'k':
+		// GS k m n d1..dn (m >= 65) or GS k m d1..NUL
+		if len(data) >= 4 && data[2] >= 65 {
+			return 4 + int(data[3])
+		}
+		return nulEnd(data, 3)
+	case c == 29 && data[1] == 'V':
+		if len(data) >= 3 && data[2] >= 65 {
+			return 4
+		}
+	case c == 18 && data[1] == '*':
+		// DC2 * r n, r rows of n bytes
+		if len(data) >= 4 {
+			return 4 + int(data[2])*int(data[3])
+		}
+	}
+	return 3
+}
+
+// nulEnd - length up to and including the NUL after from
+func nulEnd(data []byte, from int) int {
+	for i := from; i < len(data); i++ {
+		if data[i] == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
@@ -0,0 +1,102 @@
+package escpos
+
+import (
+	"fmt"
+)
+
+// Style - character style and alignment, see PushStyle
+type Style struct {
+	Bold  bool
+	Small bool
+	// Width, Height - character size multiples 1..8
+	Width, Height uint8
+	Underline     uint8
+	Emphasize     uint8
+	Upsidedown    uint8
+	Rotate        uint8
+	Reverse       uint8
+	// Align - left, center or right
+	Align string
+}
+
+// Style - current style
+func (e *Escpos) Style() Style {
+	return Style{
+		Bold:       e.bold,
+		Small:      e.small,
+		Width:      e.width,
+		Height:     e.height,
+		Underline:  e.underline,
+		Emphasize:  e.emphasize,
+		Upsidedown: e.upsidedown,
+		Rotate:     e.rotate,
+		Reverse:    e.reverse,
+		Align:      e.align,
+	}
+}
+
+// SetStyle - send what differs from the current style
+func (e *Escpos) SetStyle(s Style) {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetStyle()\n")
+	}
+	if s.Width < 1 {
+		s.Width = 1
+	}
+	if s.Height < 1 {
+		s.Height = 1
+	}
+	if s.Small != e.small {
+		e.SetSmall(s.Small)
+	}
+	if s.Width != e.width || s.Height != e.height {
+		e.setSize(s.Width, s.Height)
+	}
+	if s.Bold != e.bold {
+		e.SetBold(s.Bold)
+	}
+	if s.Underline != e.underline {
+		e.SetUnderline(s.Underline)
+	}
+	if s.Emphasize != e.emphasize {
+		e.SetEmphasize(s.Emphasize)
+	}
+	if s.Upsidedown != e.upsidedown {
+		e.SetUpsidedown(s.Upsidedown)
+	}
+	if s.Rotate != e.rotate {
+		e.SetRotate(s.Rotate)
+	}
+	if s.Reverse != e.reverse {
+		e.SetReverse(s.Reverse)
+	}
+	if len(s.Align) > 0 && s.Align != e.align {
+		e.SetAlign(s.Align)
+	}
+}
+
+// PushStyle - save the current style, PopStyle restores it
+func (e *Escpos) PushStyle() {
+	e.styles = append(e.styles, e.Style())
+}
+
+// PopStyle - back to the style of the last PushStyle, false when there
+// is none
+func (e *Escpos) PopStyle() bool {
+	if len(e.styles) == 0 {
+		return false
+	}
+	s := e.styles[len(e.styles)-1]
+	e.styles = e.styles[:len(e.styles)-1]
+	e.SetStyle(s)
+	return true
+}
+
+// WithStyle - run fn with style s, the style before is restored after
+// it; s replaces the whole style, start from Style() to change a part
+func (e *Escpos) WithStyle(s Style, fn func()) {
+	e.PushStyle()
+	e.SetStyle(s)
+	fn()
+	e.PopStyle()
+}
@@ -0,0 +1,201 @@
+package escpos
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+)
+
+// Job - what Submit prints: a *Document, or a PrintFunc
+type Job interface {
+	Print(e *Escpos) error
+}
+
+// PrintFunc - printing code as a Job
+type PrintFunc func(e *Escpos) error
+
+// Print - run f
+func (f PrintFunc) Print(e *Escpos) error {
+	return f(e)
+}
+
+// Handle - job queued by Submit
+type Handle struct {
+	job Job
+	e   *Escpos
+	// priority - of SubmitPriority, seq - order of Submit
+	priority int
+	seq      int64
+	// started - closed when the writer starts the job
+	started chan struct{}
+	done    chan struct{}
+	err     error
+	// final - progress when it was done
+	final Progress
+}
+
+// Done - closed when the job is printed or failed
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Err - error of the job, nil before Done is closed
+func (h *Handle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
+	}
+}
+
+// Progress - progress of the job, zero while it waits in the queue
+func (h *Handle) Progress() Progress {
+	select {
+	case <-h.started:
+	default:
+		return Progress{}
+	}
+	p := h.e.Progress()
+	select {
+	case <-h.done:
+		// p may be of the next job
+		return h.final
+	default:
+		return p
+	}
+}
+
+// Wait - wait for the job, its error
+func (h *Handle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Then - call fn with the error of the job when it is done, on its own
+// goroutine
+func (h *Handle) Then(fn func(err error)) {
+	go func() {
+		<-h.done
+		fn(h.err)
+	}()
+}
+
+// queueSize - jobs Submit takes before it blocks
+const queueSize = 64
+
+// jobQueue - jobs waiting for the writer goroutine, highest priority
+// first
+type jobQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	jobs    handles
+	seq     int64
+	started bool
+	closed  bool
+	// drained - closed when the writer printed the last job
+	drained chan struct{}
+}
+
+// handles - heap of queued jobs, by priority then in submit order
+type handles []*Handle
+
+func (h handles) Len() int { return len(h) }
+func (h handles) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h handles) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *handles) Push(x interface{}) { *h = append(*h, x.(*Handle)) }
+func (h *handles) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Submit - queue job for the writer goroutine and return at once; jobs
+// are printed one at a time, those of the same priority (see
+// SubmitPriority) in the order they are submitted. Don't print on e
+// directly while jobs are queued
+func (e *Escpos) Submit(job Job) *Handle {
+	return e.SubmitPriority(job, 0)
+}
+
+// SubmitPriority - Submit printed before the queued jobs of lower
+// priority; the job printing is always finished first
+func (e *Escpos) SubmitPriority(job Job, priority int) *Handle {
+	h := &Handle{job: job, e: e, priority: priority, started: make(chan struct{}), done: make(chan struct{})}
+	q := e.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.cond == nil {
+		q.cond = sync.NewCond(&q.mu)
+	}
+	for !q.closed && len(q.jobs) >= queueSize {
+		q.cond.Wait()
+	}
+	if q.closed {
+		h.err = fmt.Errorf("Printer is closed")
+		close(h.started)
+		close(h.done)
+		return h
+	}
+	if !q.started {
+		q.started = true
+		q.drained = make(chan struct{})
+		go e.writer(q)
+	}
+	q.seq++
+	h.seq = q.seq
+	heap.Push(&q.jobs, h)
+	q.cond.Broadcast()
+	return h
+}
+
+// next - job of the highest priority, nil when the queue is closed and
+// empty
+func (q *jobQueue) next() *Handle {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for !q.closed && len(q.jobs) == 0 {
+		q.cond.Wait()
+	}
+	if len(q.jobs) == 0 {
+		return nil
+	}
+	h := heap.Pop(&q.jobs).(*Handle)
+	q.cond.Broadcast()
+	return h
+}
+
+// writer - print the submitted jobs, each starts without the error of
+// the one before
+func (e *Escpos) writer(q *jobQueue) {
+	defer close(q.drained)
+	for h := q.next(); h != nil; h = q.next() {
+		e.ClearErr()
+		e.BeginJob(0)
+		close(h.started)
+		h.err = e.RunJob("job", h.job)
+		h.final = e.Progress()
+		close(h.done)
+	}
+}
+
+// closeQueue - stop taking jobs and wait for the queued ones
+func (e *Escpos) closeQueue() {
+	q := e.queue
+	q.mu.Lock()
+	q.closed = true
+	if q.cond != nil {
+		q.cond.Broadcast()
+	}
+	started := q.started
+	q.mu.Unlock()
+	if started {
+		<-q.drained
+	}
+}
@@ -0,0 +1,101 @@
+package escpos
+
+// SymbolCommands - 2D codes besides QR and GS1 DataBar of command sets
+// which have them; printers draw them only with a recent firmware,
+// Profile.Symbols names the ones a printer has, the others print as
+// images (DataBar as GS1-128)
+type SymbolCommands interface {
+	// DataMatrix - ECC200 square symbol, size module dots
+	DataMatrix(data string, size uint8) []byte
+	// Aztec - full range symbol, size module dots, ecc error correction
+	// percent
+	Aztec(data string, size, ecc uint8) []byte
+	// DataBar - omnidirectional symbol of a GTIN without its check digit
+	// or expanded one of AIs and data, {1 for FNC1
+	DataBar(expanded bool, data string, p BarCodeParams) []byte
+}
+
+// DataMatrix - GS ( k symbol type, module size, store and print
+func (EscposCommands) DataMatrix(data string, size uint8) []byte {
+	// ECC200 square, columns and rows of the data
+	res := []byte{29, 40, 107, 5, 0, 54, 65, 0, 0, 0}
+	// module size
+	res = append(res, 29, 40, 107, 3, 0, 54, 66, size)
+	l := len(data) + 3
+	res = append(res, 29, 40, 107, byte(l%256), byte(l/256), 54, 80, 48)
+	res = append(res, data...)
+	return append(res, 29, 40, 107, 3, 0, 54, 81, 48)
+}
+
+// Aztec - GS ( k mode and layers, module size, error correction, store
+// and print
+func (EscposCommands) Aztec(data string, size, ecc uint8) []byte {
+	// full range, layers of the data
+	res := []byte{29, 40, 107, 4, 0, 53, 65, 0, 0}
+	// module size
+	res = append(res, 29, 40, 107, 3, 0, 53, 66, size)
+	// error correction percent
+	res = append(res, 29, 40, 107, 3, 0, 53, 67, ecc)
+	l := len(data) + 3
+	res = append(res, 29, 40, 107, byte(l%256), byte(l/256), 53, 80, 48)
+	res = append(res, data...)
+	return append(res, 29, 40, 107, 3, 0, 53, 81, 48)
+}
+
+// DataBar - GS H, GS h, GS w and GS k GS1 DataBar omnidirectional (75)
+// or expanded (78)
+func (EscposCommands) DataBar(expanded bool, data string, p BarCodeParams) []byte {
+	m := byte(75)
+	if expanded {
+		m = 78
+	}
+	if len(data) > 255 {
+		data = data[:255]
+	}
+	res := []byte{29, 0x48, p.HRI, 29, 0x68, p.Height, 29, 0x77, p.Width}
+	if p.Font > 0 {
+		res = append(res, 29, 'f', p.Font)
+	}
+	res = append(res, 29, 107, m, byte(len(data)))
+	res = append(res, data...)
+	if p.Font > 0 {
+		res = append(res, 29, 'f', 0)
+	}
+	return res
+}
+
+// symbols - the printer draws the 2D code name itself
+func (e *Escpos) symbols(name string) (SymbolCommands, bool) {
+	sc, ok := e.cmd.(SymbolCommands)
+	if !ok || e.imageMode == "column" {
+		return nil, false
+	}
+	for _, s := range e.symbolNames {
+		if s == name {
+			return sc, true
+		}
+	}
+	return nil, false
+}
+
+// moduleRaster - dark modules of a 2D code, size dots each, shrunk to
+// fit MAXIMAGEWIDTH
+func moduleRaster(modules [][]bool, size int) *Raster {
+	if size < 1 {
+		size = 1
+	}
+	n := len(modules[0])
+	for n*size > MAXIMAGEWIDTH && size > 1 {
+		size--
+	}
+	r := &Raster{Width: n * size, Height: len(modules) * size}
+	r.Data = make([]byte, r.RowBytes()*r.Height)
+	for y := 0; y < r.Height; y++ {
+		for x := 0; x < r.Width; x++ {
+			if modules[y/size][x/size] {
+				r.Data[y*r.RowBytes()+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,178 @@
+package escpos
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTabStops - stops ESC D takes
+const maxTabStops = 32
+
+// defaultTabStops - stops of reset, every 4 characters
+var defaultTabStops = []uint8{4, 8, 12, 16, 20, 24, 28}
+
+// SetTabStops - horizontal tab stops at the columns of stops, characters
+// of the current font and size from the left margin, ascending, at most
+// 32 and on the line; nil clears them. Printers before FirmwareRecent have
+// none, the HT of text goes to them as spaces up to the stops
+func (e *Escpos) SetTabStops(stops []uint8) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetTabStops() %v\n", stops)
+	}
+	if len(stops) > maxTabStops {
+		return fmt.Errorf("Tab stops: %d, at most %d", len(stops), maxTabStops)
+	}
+	w := e.charWidth()
+	dots := make([]int, len(stops))
+	for i, s := range stops {
+		if s == 0 || (i > 0 && s <= stops[i-1]) {
+			return fmt.Errorf("Invalid tab stops: %v, ascending columns from 1", stops)
+		}
+		if int(s)*w > e.lineDots() {
+			return fmt.Errorf("Tab stop %d past the %d columns of the line", s, e.maxColumn)
+		}
+		// the printer keeps the position of the font at the time
+		dots[i] = int(s) * w
+	}
+	e.tabStops = append([]uint8(nil), stops...)
+	e.tabDots = dots
+	e.tabAlign = nil
+	if e.recent() {
+		e.WriteRaw(append(append([]byte{27, 'D'}, stops...), 0))
+	}
+	return nil
+}
+
+// SetTabAlign - alignment of the fields at the tab stops of SetTabStops,
+// one per stop in order, the ones missing "left": "right" ends the field
+// at its stop (quantities, prices without decimals), "decimal" puts its
+// decimal separator, the last "." or "," of it, at the stop so the prices
+// of a column line up; a field is the text after an HT up to the next one
+// or the line end. The fields of aligned stops go to the printer as spaces
+func (e *Escpos) SetTabAlign(align []string) error {
+	if e.Verbose {
+		fmt.Fprintf(e.logOut(), "func SetTabAlign() %v\n", align)
+	}
+	if len(align) > len(e.tabStops) {
+		return fmt.Errorf("Tab align: %d, the tab stops are %d", len(align), len(e.tabStops))
+	}
+	res := make([]string, len(align))
+	for i, a := range align {
+		switch a {
+		case "", "left":
+			res[i] = "left"
+		case "right", "decimal":
+			res[i] = a
+		default:
+			return fmt.Errorf("Invalid tab align: %s", a)
+		}
+	}
+	e.tabAlign = res
+	return nil
+}
+
+// setModelTabs - SetTabStops and SetTabAlign of the tabs of a model
+func (e *Escpos) setModelTabs(tabs []int, align []string) error {
+	stops := make([]uint8, len(tabs))
+	for i, t := range tabs {
+		if t < 1 || t > 255 {
+			return fmt.Errorf("Invalid tab stop: %d", t)
+		}
+		stops[i] = uint8(t)
+	}
+	if err := e.SetTabStops(stops); err != nil {
+		return err
+	}
+	return e.SetTabAlign(align)
+}
+
+// TabStops - columns of SetTabStops
+func (e *Escpos) TabStops() []uint8 {
+	return append([]uint8(nil), e.tabStops...)
+}
+
+// TabAlign - alignments of SetTabAlign
+func (e *Escpos) TabAlign() []string {
+	return append([]string(nil), e.tabAlign...)
+}
+
+// nextTab - index of the first tab stop right of dots column, -1 past the
+// last one where the printer ignores HT
+func (e *Escpos) nextTab(column int) int {
+	for i, x := range e.tabDots {
+		if x > column {
+			return i
+		}
+	}
+	return -1
+}
+
+// tabAligned - a stop of SetTabAlign is not left, HT can't print its
+// fields
+func (e *Escpos) tabAligned() bool {
+	for _, a := range e.tabAlign {
+		if a != "left" {
+			return true
+		}
+	}
+	return false
+}
+
+// tabField - characters of field before its stop of align, the rest goes
+// right of it
+func tabField(field, align string) int {
+	switch align {
+	case "right":
+		return utf8.RuneCountInString(field)
+	case "decimal":
+		if i := strings.LastIndexAny(field, ".,"); i >= 0 {
+			return utf8.RuneCountInString(field[:i])
+		}
+		return utf8.RuneCountInString(field)
+	}
+	return 0
+}
+
+// ExpandTabs - text with every HT as the spaces of the current font up to
+// the next tab stop (its field aligned, see SetTabAlign), past the last
+// one or a field which doesn't fit as a space; wrapping, padding and
+// frames count them like any other character
+func (e *Escpos) ExpandTabs(text string) string {
+	return e.expandTabs(text, 0)
+}
+
+// expandTabs - ExpandTabs of text printed from dots column on
+func (e *Escpos) expandTabs(text string, column int) string {
+	if !strings.Contains(text, "\t") {
+		return text
+	}
+	w := e.charWidth()
+	var b strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+			column = 0
+		}
+		fields := strings.Split(line, "\t")
+		for j, f := range fields {
+			if j > 0 {
+				n := 1
+				if t := e.nextTab(column); t >= 0 {
+					x := e.tabDots[t]
+					if t < len(e.tabAlign) {
+						x -= tabField(f, e.tabAlign[t]) * w
+					}
+					if x >= column {
+						n = (x - column + w - 1) / w
+					}
+				}
+				b.WriteString(strings.Repeat(" ", n))
+				column += n * w
+			}
+			b.WriteString(f)
+			column += utf8.RuneCountInString(f) * w
+		}
+	}
+	return b.String()
+}
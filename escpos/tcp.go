@@ -0,0 +1,103 @@
+package escpos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/grengojbo/gotp/snmp"
+)
+
+// DefaultTCPPort - raw print port of Ethernet printers
+const DefaultTCPPort = "9100"
+
+// dialTimeout - wait for the TCP connection to the printer
+const dialTimeout = 5 * time.Second
+
+// NewTCP - create Escpos printer on an Ethernet printer at addr (host
+// or host:port, port 9100 by default); the printer buffers the stream,
+// it isn't paced
+func NewTCP(debug bool, addr string) (e *Escpos) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultTCPPort)
+	}
+	e = &Escpos{Debug: debug, byteTime: BYTETIME}
+	e.host, _, _ = net.SplitHostPort(addr)
+	if !e.Debug {
+		conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+		if err != nil {
+			e.err = fmt.Errorf("%w: %s", ErrNoDevice, err)
+		} else {
+			e.dst = conn
+			e.src = conn
+		}
+	}
+	e.init()
+	return
+}
+
+// OIDs of the Host Resources and Printer MIBs
+const (
+	oidDeviceDescr   = "1.3.6.1.2.1.25.3.2.1.3.1"
+	oidPrinterStatus = "1.3.6.1.2.1.25.3.5.1.1.1"
+	oidErrorState    = "1.3.6.1.2.1.25.3.5.1.2.1"
+	oidSerialNumber  = "1.3.6.1.2.1.43.5.1.1.17.1"
+)
+
+// hrPrinterDetectedErrorState bits of the first byte
+const (
+	snmpLowPaper = 0x80
+	snmpNoPaper  = 0x40
+	snmpDoorOpen = 0x08
+	snmpJammed   = 0x04
+	snmpOffline  = 0x02
+	snmpService  = 0x01
+)
+
+// SetSNMP - read the status of a TCP printer from its SNMP agent with
+// community (public), for printers which don't answer DLE EOT over the
+// data port; empty community stops it
+func (e *Escpos) SetSNMP(community string) {
+	e.snmpCommunity = community
+}
+
+// snmpStatus - status from the printer MIB
+func (e *Escpos) snmpStatus() (s Status, err error) {
+	if len(e.host) == 0 {
+		return s, fmt.Errorf("SNMP status needs a TCP printer")
+	}
+	wait := replyTimeout
+	if e.readTimeout > 0 {
+		wait = e.readTimeout
+	}
+	res, err := snmp.Get(e.host, e.snmpCommunity, wait,
+		oidDeviceDescr, oidPrinterStatus, oidErrorState, oidSerialNumber)
+	if err != nil {
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			return s, fmt.Errorf("%w: %s", ErrTimeout, err)
+		}
+		return s, err
+	}
+	// other(1), unknown(2), idle(3), printing(4), warmup(5)
+	st, _ := res[oidPrinterStatus].(int64)
+	s.Online = st >= 3
+	if b, ok := res[oidErrorState].([]byte); ok && len(b) > 0 {
+		s.PaperNearEnd = b[0]&snmpLowPaper != 0
+		s.PaperOut = b[0]&snmpNoPaper != 0
+		s.CoverOpen = b[0]&snmpDoorOpen != 0
+		s.Error = b[0]&(snmpJammed|snmpService) != 0
+		if b[0]&snmpOffline != 0 {
+			s.Online = false
+		}
+	}
+	if b, ok := res[oidDeviceDescr].([]byte); ok {
+		s.Model = string(bytes.TrimSpace(b))
+	}
+	if b, ok := res[oidSerialNumber].([]byte); ok {
+		s.Serial = string(bytes.TrimSpace(b))
+	}
+	return s, nil
+}
@@ -0,0 +1,187 @@
+// Package fetch - model templates and images from http(s) URLs, kept in
+// a local cache so a store keeps printing while the server is away
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache - downloads of URLs kept in Dir
+type Cache struct {
+	// Dir - cache directory, empty - the user cache directory
+	Dir string
+	// MaxSize - larger files fail (0 - 4 MiB)
+	MaxSize int64
+	// MaxAge - a cached file this old or newer is used without asking the
+	// server, an older one is revalidated (0 - 5 minutes)
+	MaxAge time.Duration
+	// Timeout - of a download (0 - 10 seconds)
+	Timeout time.Duration
+}
+
+// Default - cache of Get
+var Default = &Cache{}
+
+// defaults of Cache
+const (
+	maxSize = 4 << 20
+	maxAge  = 5 * time.Minute
+	timeout = 10 * time.Second
+)
+
+// entry - what the cache knows about a URL besides its body
+type entry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+// IsURL - name is an http or https URL
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// Get - body of url from the Default cache
+func Get(url string) ([]byte, error) {
+	return Default.Get(url)
+}
+
+// Get - body of url: the cached copy while it is fresh, else downloaded
+// (or revalidated) and cached; when the server fails a cached copy of
+// any age is returned
+func (c *Cache) Get(url string) ([]byte, error) {
+	dir, err := c.dir()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(url))
+	path := filepath.Join(dir, hex.EncodeToString(sum[:]))
+	var e entry
+	body, cerr := ioutil.ReadFile(path)
+	if cerr == nil {
+		if b, err := ioutil.ReadFile(path + ".json"); err == nil {
+			json.Unmarshal(b, &e)
+		}
+		age := c.MaxAge
+		if age <= 0 {
+			age = maxAge
+		}
+		if e.URL == url && time.Since(e.Fetched) <= age {
+			return body, nil
+		}
+	}
+	fresh, modified, err := c.download(url, &e, cerr == nil)
+	if err != nil {
+		if cerr == nil {
+			return body, nil
+		}
+		return nil, err
+	}
+	if modified {
+		body = fresh
+		if err := writeFile(path, body); err != nil {
+			return body, nil
+		}
+	}
+	e.URL = url
+	e.Fetched = time.Now()
+	if b, err := json.Marshal(e); err == nil {
+		writeFile(path+".json", b)
+	}
+	return body, nil
+}
+
+// writeFile - replace path with b at once, another goroutine reading it
+// gets the old or the new file
+func writeFile(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".fetch")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// download - body of url, false when the cached copy of e is still the
+// one of the server
+func (c *Cache) download(url string, e *entry, cached bool) ([]byte, bool, error) {
+	wait := c.Timeout
+	if wait <= 0 {
+		wait = timeout
+	}
+	limit := c.MaxSize
+	if limit <= 0 {
+		limit = maxSize
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("Fetch %s: %s", url, err.Error())
+	}
+	if cached && len(e.ETag) > 0 {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+	if cached && len(e.LastModified) > 0 {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+	client := &http.Client{Timeout: wait}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("Fetch %s: %s", url, err.Error())
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified && cached {
+		return nil, false, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("Fetch %s: %s", url, res.Status)
+	}
+	if res.ContentLength > limit {
+		return nil, false, fmt.Errorf("Fetch %s: %d bytes, more than %d", url, res.ContentLength, limit)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("Fetch %s: %s", url, err.Error())
+	}
+	if int64(len(body)) > limit {
+		return nil, false, fmt.Errorf("Fetch %s: more than %d bytes", url, limit)
+	}
+	e.ETag = res.Header.Get("ETag")
+	e.LastModified = res.Header.Get("Last-Modified")
+	return body, true, nil
+}
+
+// dir - the cache directory, created when missing
+func (c *Cache) dir() (string, error) {
+	dir := c.Dir
+	if len(dir) == 0 {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("Fetch: %s", err.Error())
+		}
+		dir = filepath.Join(base, "gotp")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("Fetch: %s", err.Error())
+	}
+	return dir, nil
+}
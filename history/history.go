@@ -0,0 +1,126 @@
+package history
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// Job - printed job in the history
+type Job struct {
+	ID    string    `json:"id"`
+	Time  time.Time `json:"time"`
+	Title string    `json:"title"`
+}
+
+// Store - last printed jobs, one rendered model file per job in dir
+type Store struct {
+	dir  string
+	keep int
+}
+
+// Open - history in dir keeping the last keep jobs
+func Open(dir string, keep int) *Store {
+	if keep < 1 {
+		keep = 1
+	}
+	return &Store{dir: dir, keep: keep}
+}
+
+func (s *Store) file(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Add - save rendered job, returns its id
+func (s *Store) Add(m models.PrinterLine) (string, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", fmt.Errorf("History: %s", err.Error())
+	}
+	now := time.Now()
+	id := fmt.Sprintf("%s-%03d", now.Format("20060102-150405"), now.Nanosecond()/1e6)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(s.file(id)); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%03d-%d", now.Format("20060102-150405"), now.Nanosecond()/1e6, i)
+	}
+	if err := models.SaveModel(s.file(id), m); err != nil {
+		return "", err
+	}
+	return id, s.prune()
+}
+
+// prune - remove all but the newest keep jobs
+func (s *Store) prune() error {
+	jobs, err := s.List()
+	if err != nil {
+		return err
+	}
+	for i := s.keep; i < len(jobs); i++ {
+		if err := os.Remove(s.file(jobs[i].ID)); err != nil {
+			return fmt.Errorf("History: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// List - jobs, newest first
+func (s *Store) List() (res []Job, err error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return res, nil
+	} else if err != nil {
+		return res, fmt.Errorf("History: %s", err.Error())
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		job := Job{ID: strings.TrimSuffix(f.Name(), ".json"), Time: f.ModTime()}
+		if m, err := models.LoadPrintModel(s.file(job.ID)); err == nil {
+			job.Title = title(m)
+		}
+		res = append(res, job)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID > res[j].ID })
+	return res, nil
+}
+
+// title - first text row of the job
+func title(m models.PrinterLine) string {
+	for _, s := range m.Sections {
+		for _, row := range s.Rows {
+			if row.Kind() == "text" && len(strings.TrimSpace(row.Text)) > 0 {
+				return row.Text
+			}
+		}
+	}
+	return ""
+}
+
+// Get - job by id, "last" is the newest job
+func (s *Store) Get(id string) (m models.PrinterLine, err error) {
+	if id == "last" {
+		jobs, err := s.List()
+		if err != nil {
+			return m, err
+		}
+		if len(jobs) == 0 {
+			return m, fmt.Errorf("History: no jobs")
+		}
+		id = jobs[0].ID
+	}
+	if strings.ContainsAny(id, `/\`) {
+		return m, fmt.Errorf("History: invalid job id %s", id)
+	}
+	if _, err := os.Stat(s.file(id)); err != nil {
+		return m, fmt.Errorf("History: no job %s", id)
+	}
+	return models.LoadPrintModel(s.file(id))
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// couponAlphabet - characters of coupon codes, Crockford's base 32: no I,
+// L, O and U which are read as 1, 0 or are rude
+const couponAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// couponRead - letters read as the digits they look like
+var couponRead = strings.NewReplacer("I", "1", "L", "1", "O", "0")
+
+// CouponCode - code of voucher n: prefix, n with at least digits digits
+// and the check character, "SUMMER" 42 6 -> "SUMMER000042" + check
+func CouponCode(prefix string, n int64, digits int) (string, error) {
+	return couponChecked(fmt.Sprintf("%s%0*d", prefix, digits, n))
+}
+
+// RandomCoupon - code of length random characters after prefix and the
+// check character, from crypto/rand
+func RandomCoupon(prefix string, length int) (string, error) {
+	b := []byte(prefix)
+	max := big.NewInt(int64(len(couponAlphabet)))
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("Coupon: %s", err.Error())
+		}
+		b = append(b, couponAlphabet[n.Int64()])
+	}
+	return couponChecked(string(b))
+}
+
+// RandomCoupons - count distinct random codes, see RandomCoupon
+func RandomCoupons(prefix string, length, count int) ([]string, error) {
+	// 32^length codes, a few draws more than count
+	if length < 16 && (int64(1)<<uint(5*length)) < int64(2*count) {
+		return nil, fmt.Errorf("Coupon: %d random characters are too few for %d codes", length, count)
+	}
+	seen := map[string]bool{}
+	res := make([]string, 0, count)
+	for len(res) < count {
+		code, err := RandomCoupon(prefix, length)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[code] {
+			seen[code] = true
+			res = append(res, code)
+		}
+	}
+	return res, nil
+}
+
+// couponChecked - code with its check character, code in upper case and
+// of the coupon alphabet
+func couponChecked(code string) (string, error) {
+	code = strings.ToUpper(code)
+	for _, c := range code {
+		if !strings.ContainsRune(couponAlphabet, c) {
+			return "", fmt.Errorf("Coupon: %q has %q, use 0-9 and A-Z without I, L, O and U", code, c)
+		}
+	}
+	return code + string(CouponCheck(code)), nil
+}
+
+// CouponCheck - Luhn mod 32 check character of code, one mistyped or two
+// swapped characters change it
+func CouponCheck(code string) byte {
+	n := len(couponAlphabet)
+	sum := 0
+	for i := 0; i < len(code); i++ {
+		v := strings.IndexByte(couponAlphabet, code[len(code)-1-i])
+		if i%2 == 0 {
+			v *= 2
+			v = v/n + v%n
+		}
+		sum += v
+	}
+	return couponAlphabet[(n-sum%n)%n]
+}
+
+// ValidCoupon - code typed by a customer has a right check character:
+// case, spaces and dashes don't matter, I, L and O are 1 and 0
+func ValidCoupon(code string) bool {
+	code = couponRead.Replace(strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(code)))
+	if len(code) < 2 {
+		return false
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(couponAlphabet, c) {
+			return false
+		}
+	}
+	return CouponCheck(code[:len(code)-1]) == code[len(code)-1]
+}
+
+// CouponGroups - code in groups of 4 characters for reading, "ABCD-EFGH-J"
+func CouponGroups(code string) string {
+	var groups []string
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
+
+// Coupon - text of the vouchers of CouponModel
+type Coupon struct {
+	Title string
+	Text  string
+	// Code - "qrcode" or a bar code type, CODE128 by default
+	Code string
+	// Expires - "valid until" line, empty - none
+	Expires string
+}
+
+// CouponModel - vouchers of codes, a section for each code: title, text,
+// the code as a QR or bar code and readable, cut after every voucher
+func CouponModel(c Coupon, codes []string) PrinterLine {
+	res := PrinterLine{Version: ModelVersion}
+	for i, code := range codes {
+		var rows []Printer
+		if len(c.Title) > 0 {
+			rows = append(rows, Printer{Align: "center", Size: "large", Style: "bold", Text: c.Title, Wrap: true})
+		}
+		if len(c.Text) > 0 {
+			rows = append(rows, Printer{Align: "center", Text: c.Text, Wrap: true})
+		}
+		rows = append(rows, Printer{Feed: 1})
+		if c.Code == "qrcode" {
+			rows = append(rows, Printer{Type: "qrcode", QrCode: true, Align: "center", Text: code, QrSize: 6},
+				Printer{Align: "center", Style: "bold", Text: CouponGroups(code)})
+		} else {
+			bc := c.Code
+			if len(bc) == 0 {
+				bc = "CODE128"
+			}
+			rows = append(rows, Printer{Type: "barcode", BarCode: true, Align: "center", Code: bc, Text: code,
+				Hri: "none", HriText: CouponGroups(code), HriStyle: "bold"})
+		}
+		if len(c.Expires) > 0 {
+			rows = append(rows, Printer{Align: "center", Text: c.Expires, Wrap: true})
+		}
+		rows = append(rows, Printer{Feed: 3}, Printer{Cut: "partial"})
+		res.Sections = append(res.Sections, Section{Name: "coupon " + strconv.Itoa(i+1), Rows: rows})
+	}
+	return res
+}
@@ -0,0 +1,282 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Eval - evaluate condition expression against data, e.g.
+//
+//	discount > 0
+//	taxable && vat.rate >= 20
+//	!(payment == "cash" || total < 10)
+//
+// names are dotted data paths, literals are numbers, 'strings', "strings",
+// true, false and null
+func Eval(expr string, data map[string]interface{}) (bool, error) {
+	p := &exprParser{data: data}
+	if err := p.tokenize(expr); err != nil {
+		return false, fmt.Errorf("Expression %q: %s", expr, err.Error())
+	}
+	v, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].val)
+	}
+	if err != nil {
+		return false, fmt.Errorf("Expression %q: %s", expr, err.Error())
+	}
+	return truth(v), nil
+}
+
+type tokenKind int
+
+const (
+	tokName tokenKind = iota
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+	data   map[string]interface{}
+}
+
+// operators, two char operators first
+var exprOps = []string{"&&", "||", "==", "!=", ">=", "<=", ">", "<", "!", "(", ")"}
+
+func (p *exprParser) tokenize(s string) error {
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			j := strings.IndexRune(s[i+1:], c)
+			if j < 0 {
+				return fmt.Errorf("unterminated string")
+			}
+			p.tokens = append(p.tokens, token{tokString, s[i+1 : i+1+j]})
+			i += j + 2
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			p.tokens = append(p.tokens, token{tokNumber, s[i:j]})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_' || s[j] == '.') {
+				j++
+			}
+			p.tokens = append(p.tokens, token{tokName, s[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, o := range exprOps {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if len(op) == 0 {
+				return fmt.Errorf("unexpected character %q", c)
+			}
+			p.tokens = append(p.tokens, token{tokOp, op})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) peekOp(ops ...string) string {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokOp {
+		for _, o := range ops {
+			if p.tokens[p.pos].val == o {
+				return o
+			}
+		}
+	}
+	return ""
+}
+
+func (p *exprParser) parseOr() (interface{}, error) {
+	l, err := p.parseAnd()
+	for err == nil && p.peekOp("||") != "" {
+		p.pos++
+		var r interface{}
+		if r, err = p.parseAnd(); err == nil {
+			l = truth(l) || truth(r)
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) parseAnd() (interface{}, error) {
+	l, err := p.parseNot()
+	for err == nil && p.peekOp("&&") != "" {
+		p.pos++
+		var r interface{}
+		if r, err = p.parseNot(); err == nil {
+			l = truth(l) && truth(r)
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) parseNot() (interface{}, error) {
+	if p.peekOp("!") != "" {
+		p.pos++
+		v, err := p.parseNot()
+		return !truth(v), err
+	}
+	return p.parseCmp()
+}
+
+func (p *exprParser) parseCmp() (interface{}, error) {
+	l, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peekOp("==", "!=", ">=", "<=", ">", "<")
+	if op == "" {
+		return l, nil
+	}
+	p.pos++
+	r, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return compare(l, op, r), nil
+}
+
+func (p *exprParser) parseOperand() (interface{}, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokNumber:
+		return strconv.ParseFloat(t.val, 64)
+	case tokString:
+		return t.val, nil
+	case tokName:
+		switch t.val {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null", "nil":
+			return nil, nil
+		}
+		return Lookup(p.data, t.val), nil
+	}
+	if t.val == "(" {
+		v, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peekOp(")") == "" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return v, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.val)
+}
+
+// number - numeric value of v, ok false when v is not a number
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func compare(l interface{}, op string, r interface{}) bool {
+	ln, lok := number(l)
+	rn, rok := number(r)
+	if lok && rok {
+		switch op {
+		case "==":
+			return ln == rn
+		case "!=":
+			return ln != rn
+		case ">":
+			return ln > rn
+		case ">=":
+			return ln >= rn
+		case "<":
+			return ln < rn
+		case "<=":
+			return ln <= rn
+		}
+	}
+	if l == nil || r == nil {
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		}
+		return false
+	}
+	ls, rs := fmt.Sprint(l), fmt.Sprint(r)
+	switch op {
+	case "==":
+		return ls == rs
+	case "!=":
+		return ls != rs
+	case ">":
+		return ls > rs
+	case ">=":
+		return ls >= rs
+	case "<":
+		return ls < rs
+	case "<=":
+		return ls <= rs
+	}
+	return false
+}
+
+// truth - false for nil, false, 0, "", empty arrays and objects
+func truth(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return len(t) > 0
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	}
+	if n, ok := number(v); ok {
+		return n != 0
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"text/template"
+)
+
+// modelSeeds - models of the fuzz targets: well formed ones, wrong types,
+// templates, conditions and truncated JSON
+var modelSeeds = []string{
+	`{"version": 2, "sections": [{"rows": [{"text": "Coffee", "right": "3.00"}]}]}`,
+	`{"version": 2, "sections": [{"rows": [{"barCode": {"code": "CODE128"}}]}]}`,
+	`{"version": 2, "sections": [{"rows": [{"text": "x", "font": "B"}]}]}`,
+	`{"version": 1, "lines": [{"text": "a"}]}`,
+	`{"version": 2, "sections": [{"rows": [{"qrCode": "x", "barCode": {}}]}]}`,
+	`{"version": 2, "sections": [{"rows": [{"text": "&amp;lt;\xff\xfe"}]}]}`,
+	`{"version": 2, "sections": [{"rows": [{"image": 5, "x": "1mm", "pos": []}]}]}`,
+	`{"version": 2, "sections": [{"rows": [null, 1, "a"]}]}`,
+	`{"version": 2, "sections": {"rows": 1}}`,
+	`{"version": 2, "data": {"items": [{"n": "a"}, {"n": "b"}], "total": 3},
+	  "sections": [{"rows": [{"repeat": "items", "rows": [{"text": "{{.n}} {{.index}}"}]},
+	  {"text": "{{.total}}", "if": "total > 2"}, {"text": "{{seq \"receipt\"}}", "unless": "!total"}]}]}`,
+	`{"version": 2, "data": {"items": 1}, "sections": [{"rows": [{"repeat": "items.x", "rows": [{"text": "{{.n"}]}]}]}`,
+	`{"version": 2, "sections": [{"rows": [{"include": "part.json"}, {"rows": [{"include": "model.json"}]}]}]}`,
+	`{"version": 2, "sections": [{"rows": [{"include": "missing.json"}, {"include": "../../.."}]}]}`,
+	`{"version": "2"`,
+	`[]`,
+	`null`,
+	"\xef\xbb\xbf{}",
+}
+
+func FuzzReadModel(f *testing.F) {
+	for _, seed := range modelSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// a malformed model is an error, never a panic or a hang
+		readModel(bytes.NewReader(data))
+	})
+}
+
+func FuzzRender(f *testing.F) {
+	for _, seed := range modelSeeds {
+		f.Add([]byte(seed))
+	}
+	funcs := template.FuncMap{"seq": func(name string) int { return 1 }}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := readModel(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		m.RenderFuncs(funcs)
+	})
+}
+
+func FuzzEval(f *testing.F) {
+	for _, seed := range []string{
+		"discount > 0",
+		"taxable && vat.rate >= 20",
+		`!(payment == "cash" || total < 10)`,
+		"name == 'Ann' && items",
+		"((((total",
+		"1e999 > -1e999",
+		`"unterminated`,
+		"a.b.c.d == null",
+		"!!!!!true",
+		"&& ||",
+		"",
+	} {
+		f.Add(seed)
+	}
+	data := map[string]interface{}{
+		"discount": 1.5, "taxable": true, "payment": "card", "total": 12.0,
+		"name": "Ann", "items": []interface{}{1.0, "a"},
+		"vat": map[string]interface{}{"rate": 20.0},
+	}
+	f.Fuzz(func(t *testing.T, expr string) {
+		Eval(expr, data)
+	})
+}
+
+func FuzzLoadPrintModel(f *testing.F) {
+	for _, seed := range modelSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// model.json includes part.json and may include itself
+		dir := t.TempDir()
+		file := filepath.Join(dir, "model.json")
+		part := []byte(`[{"text": "part"}, {"include": "model.json"}]`)
+		if err := ioutil.WriteFile(filepath.Join(dir, "part.json"), part, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		LoadPrintModel(file)
+	})
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/antonholmquist/jason"
+	"github.com/grengojbo/gotp/fetch"
+)
+
+// openFile - model or include file, a path or an http(s) URL fetched
+// through the fetch cache
+func openFile(file string) (io.ReadCloser, error) {
+	if !fetch.IsURL(file) {
+		return os.Open(file)
+	}
+	b, err := fetch.Get(file)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// joinPath - file relative to dir, a directory or the URL of the model
+// including it; absolute paths and URLs are kept
+func joinPath(dir, file string) string {
+	if fetch.IsURL(file) || filepath.IsAbs(file) {
+		return file
+	}
+	if fetch.IsURL(dir) {
+		base, err := url.Parse(dir)
+		if err != nil {
+			return file
+		}
+		ref, err := url.Parse(filepath.ToSlash(file))
+		if err != nil {
+			return file
+		}
+		return base.ResolveReference(ref).String()
+	}
+	if abs, err := filepath.Abs(filepath.Join(dir, file)); err == nil {
+		return abs
+	}
+	return filepath.Join(dir, file)
+}
+
+// dirOf - what the files of the model file are relative to, a URL is
+// kept for joinPath
+func dirOf(file string) string {
+	if fetch.IsURL(file) {
+		return file
+	}
+	return filepath.Dir(file)
+}
+
+// loadFragment - rows of an include file: a JSON array of rows or an
+// object with "rows" (or header/lines/footer, joined in that order)
+func loadFragment(file string) (res []Printer, err error) {
+	f, err := openFile(file)
+	if err != nil {
+		return res, fmt.Errorf("Include: %s", err.Error())
+	}
+	defer f.Close()
+	v, err := jason.NewValueFromReader(f)
+	if err != nil {
+		return res, fmt.Errorf("Include %s: %s", file, err.Error())
+	}
+	var rows []*jason.Object
+	if arr, err := v.ObjectArray(); err == nil {
+		rows = arr
+	} else if obj, err := v.Object(); err == nil {
+		for _, key := range []string{"rows", "header", "lines", "footer"} {
+			arr, _ := obj.GetObjectArray(key)
+			rows = append(rows, arr...)
+		}
+	} else {
+		return res, fmt.Errorf("Include %s: not an array or object", file)
+	}
+	for _, row := range rows {
+		r, err := parseRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("Include %s: %s", file, err.Error())
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// resolveIncludes - replace include rows with the rows of the included
+// file, paths are relative to dir, stack holds the files being included
+func resolveIncludes(rows []Printer, dir string, stack []string) (res []Printer, err error) {
+	for _, row := range rows {
+		if len(row.Include) == 0 {
+			if len(row.Rows) > 0 {
+				if row.Rows, err = resolveIncludes(row.Rows, dir, stack); err != nil {
+					return res, err
+				}
+			}
+			res = append(res, row)
+			continue
+		}
+		file := joinPath(dir, row.Include)
+		for _, f := range stack {
+			if f == file {
+				return res, fmt.Errorf("Include: %s includes itself", row.Include)
+			}
+		}
+		fragment, err := loadFragment(file)
+		if err != nil {
+			return res, err
+		}
+		fragment, err = resolveIncludes(fragment, dirOf(file), append(stack, file))
+		if err != nil {
+			return res, err
+		}
+		// if/unless/repeat and the page area of the include row apply to
+		// the whole fragment
+		if len(row.If) > 0 || len(row.Unless) > 0 || len(row.Repeat) > 0 || row.Area != nil {
+			res = append(res, Printer{If: row.If, Unless: row.Unless, Repeat: row.Repeat, Area: row.Area, Rows: fragment})
+		} else {
+			res = append(res, fragment...)
+		}
+	}
+	return res, nil
+}
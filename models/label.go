@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antonholmquist/jason"
+)
+
+// Label - fixed size of a label model: the rows of all sections are one
+// page of Width x Height ("50mm" or dots, width empty - the print head),
+// placed with their x and y; printers without page mode print an image of
+// it, see escpos PrintModel
+type Label struct {
+	Width  string `json:"width,omitempty"`
+	Height string `json:"height"`
+}
+
+// parseLabel - label object of the model, nil when missing
+func parseLabel(v *jason.Object) *Label {
+	o, err := v.GetObject("label")
+	if err != nil || o == nil {
+		return nil
+	}
+	return &Label{Width: position(o, "width"), Height: position(o, "height")}
+}
+
+// LabelKinds - layouts LabelModel builds
+var LabelKinds = []string{"shelf", "badge"}
+
+// labelHeights - label heights of the layouts when none is given
+var labelHeights = map[string]string{
+	"shelf": "30mm",
+	"badge": "40mm",
+}
+
+// LabelModel - label of kind from fields, width x height (empty - the print
+// head x the height of the kind):
+//
+//	shelf: name, price, unit ("per kg"), code (EAN13 unless type is set), type
+//	badge: name, company, role, qr (text of a QR code)
+func LabelModel(kind string, fields map[string]string, width, height string) (PrinterLine, error) {
+	if len(height) == 0 {
+		height = labelHeights[kind]
+	}
+	res := PrinterLine{Version: ModelVersion, Label: &Label{Width: width, Height: height}}
+	var rows []Printer
+	switch kind {
+	case "shelf":
+		if len(fields["name"]) == 0 || len(fields["price"]) == 0 {
+			return res, fmt.Errorf("Label shelf: name and price are required")
+		}
+		rows = append(rows,
+			Printer{X: "1mm", Y: "4mm", Style: "bold", Text: fields["name"]},
+			Printer{Y: "13mm", Align: "right", Size: "large", Style: "bold", Text: fields["price"]})
+		if len(fields["unit"]) > 0 {
+			rows = append(rows, Printer{Y: "17mm", Align: "right", Style: "small", Text: fields["unit"]})
+		}
+		if len(fields["code"]) > 0 {
+			code := strings.ToUpper(fields["type"])
+			if len(code) == 0 {
+				code = "EAN13"
+			}
+			rows = append(rows, Printer{Y: "26mm", X: "1mm", Type: "barcode", BarCode: true, Code: code,
+				Text: fields["code"], Height: 48, Width: 2, Hri: "below"})
+		}
+	case "badge":
+		if len(fields["name"]) == 0 {
+			return res, fmt.Errorf("Label badge: name is required")
+		}
+		rows = append(rows, Printer{Y: "9mm", Align: "center", Size: "large", Style: "bold", Text: fields["name"]})
+		if len(fields["company"]) > 0 {
+			rows = append(rows, Printer{Y: "16mm", Align: "center", Text: fields["company"]})
+		}
+		if len(fields["role"]) > 0 {
+			rows = append(rows, Printer{Y: "21mm", Align: "center", Style: "small", Text: fields["role"]})
+		}
+		if len(fields["qr"]) > 0 {
+			rows = append(rows, Printer{Y: "38mm", Align: "center", Type: "qrcode", QrCode: true,
+				Text: fields["qr"], QrSize: 3})
+		}
+	default:
+		return res, fmt.Errorf("Label %q, one of: %s", kind, strings.Join(LabelKinds, ", "))
+	}
+	res.Sections = []Section{{Name: "label " + kind, Rows: rows}}
+	return res, nil
+}
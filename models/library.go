@@ -0,0 +1,54 @@
+package models
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// templates - starter models shipped with gotp, see Templates
+//
+//go:embed templates/*.json
+var templates embed.FS
+
+// TemplateInfo - descriptions of the built-in templates
+var TemplateInfo = map[string]string{
+	"retail":   "retail receipt with items, totals, payment and a bar code",
+	"cafe":     "café order ticket for the kitchen or the bar",
+	"queue":    "queue number ticket",
+	"shipping": "shipping label with addresses, bar code and QR code",
+	"zreport":  "end of shift Z-report, totals by payment and category",
+}
+
+// Templates - names of the built-in templates, sorted
+func Templates() []string {
+	entries, _ := templates.ReadDir("templates")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Template - model file of the built-in template name, a version 2 model
+// with sample data to copy and customize
+func Template(name string) ([]byte, error) {
+	b, err := templates.ReadFile(path.Join("templates", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("Template %s not found, one of: %s", name, strings.Join(Templates(), ", "))
+	}
+	return b, nil
+}
+
+// ReadTemplate - parsed built-in template name
+func ReadTemplate(name string) (PrinterLine, error) {
+	b, err := Template(name)
+	if err != nil {
+		return PrinterLine{}, err
+	}
+	return readModel(bytes.NewReader(b))
+}
@@ -2,29 +2,228 @@ package models
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"strconv"
+	"strings"
 
 	"github.com/antonholmquist/jason"
 )
 
 // Printer - params line
 type Printer struct {
-	Line    bool   `json:"line"`
-	Align   string `json:"align"`
-	Style   string `json:"style"`
-	Size    string `json:"size"`
-	Text    string `json:"text"`
-	Image   bool   `json:"image"`
-	BarCode bool   `json:"barCode"`
-	QrCode  bool   `json:"qrCode"`
+	// Type - row kind in version 2 models, see Kind
+	Type string `json:"type,omitempty"`
+	// Name - section name for type "section" rows
+	Name string `json:"name,omitempty"`
+
+	Line bool `json:"line,omitempty"`
+	// LineStyle - rule of "line" rows, "line": {"style": "="} in models,
+	// see escpos Rule
+	LineStyle string `json:"lineStyle,omitempty"`
+	Align     string `json:"align,omitempty"`
+	// Style - "bold", "small" (font B) or both, "bold small"
+	Style string `json:"style,omitempty"`
+	Size  string `json:"size,omitempty"`
+	// Underline - underline of the text, 1 or 2 dots thick
+	Underline uint8 `json:"underline,omitempty"`
+	// Wrap - break Text at spaces into lines of the paper width
+	Wrap    bool   `json:"wrap,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Image   bool   `json:"image,omitempty"`
+	BarCode bool   `json:"barCode,omitempty"`
+	QrCode  bool   `json:"qrCode,omitempty"`
+	// Right - text at the end of the line, Fill (".", "_") fills the gap
+	// after Text, see escpos PadBetween
+	Right string `json:"right,omitempty"`
+	Fill  string `json:"fill,omitempty"`
+	// MaxWidth - characters of each field of Text (the parts between
+	// tabs) at most, longer ones are cut instead of wrapped; Truncate -
+	// "ellipsis" (default) ends a cut field with "...", "cut" doesn't.
+	// Truncate without MaxWidth cuts Text to the line beside Right
+	MaxWidth uint8  `json:"maxWidth,omitempty"`
+	Truncate string `json:"truncate,omitempty"`
+	// Encoding - code page of a text row (escpos CodePages name, "PC850"),
+	// selected for the row only: a Western footer of a Cyrillic receipt
+	Encoding string `json:"encoding,omitempty"`
+
+	// per row bar code / QR code options, empty values fall back to
+	// the global PrinterLine.BarCode settings
+	Code   string `json:"code,omitempty"`
+	Height uint8  `json:"height,omitempty"`
+	Width  uint16 `json:"width,omitempty"`
+	Hri    string `json:"hri,omitempty"`
+	// QrSize - module dots of QR codes and other 2D codes
+	QrSize uint8  `json:"qrSize,omitempty"`
+	QrEcc  string `json:"qrEcc,omitempty"`
+	// Rotate - ladder bar code, the bars along the paper
+	Rotate bool `json:"rotate,omitempty"`
+	// HriText - line under the bar code, after the HRI of "hri" (none
+	// replaces it); HriFormat - the data in it, # for each character
+	// ("#### #### #### #"); HriStyle - "small", "bold" or both
+	HriText   string `json:"hriText,omitempty"`
+	HriFormat string `json:"hriFormat,omitempty"`
+	HriStyle  string `json:"hriStyle,omitempty"`
+
+	// directives: "cut": "full"/"partial", "drawer": true, "beep": N, "feed": N
+	Cut    string `json:"cut,omitempty"`
+	Drawer bool   `json:"drawer,omitempty"`
+	Beep   uint8  `json:"beep,omitempty"`
+	Feed   uint8  `json:"feed,omitempty"`
+	// FormFeed - feed to the next page of the page length of the profile,
+	// pre-printed stock: "formFeed": true
+	FormFeed bool `json:"formFeed,omitempty"`
+	// Space - blank paper fed exactly, "30mm" or dots: "space": "30mm";
+	// above the line of a signature row
+	Space string `json:"space,omitempty"`
+	// Signature - line to sign on with Text (or "Signature") under it:
+	// "signature": true or "signature": "Cardholder signature"
+	Signature bool `json:"signature,omitempty"`
+
+	// image rows: file path or base64 data, width in dots and dither
+	// (floyd, threshold); Width is the module width for bar codes
+	Src    string `json:"src,omitempty"`
+	Data   string `json:"data,omitempty"`
+	Dither string `json:"dither,omitempty"`
+
+	// repeat: data array path, Rows rendered for each element
+	Repeat string    `json:"repeat,omitempty"`
+	Rows   []Printer `json:"rows,omitempty"`
+
+	// conditions evaluated against the model data, see Eval
+	If     string `json:"if,omitempty"`
+	Unless string `json:"unless,omitempty"`
+
+	// include: rows of another model file, relative to this one
+	Include string `json:"include,omitempty"`
+
+	// page mode: area of a section row
+	Area *Area `json:"area,omitempty"`
+	// Frame - box around the rows of a section row
+	Frame bool `json:"frame,omitempty"`
+	// Side - "left" or "right": the first of the rows of a section row (a
+	// QR code or an image) on that side of the other rows, see escpos TwoUp
+	Side string `json:"side,omitempty"`
+	// X, Y - position of the row in dots ("24") or millimeters ("3mm"); x
+	// from the left margin (of the area in page mode), y the base line
+	// from the area top in page mode, a feed before the row otherwise
+	X string `json:"x,omitempty"`
+	Y string `json:"y,omitempty"`
 }
 
 // PrinterLine - print collection
 type PrinterLine struct {
-	Header  []Printer     `json:"header"`
-	Lines   []Printer     `json:"lines"`
-	Footer  []Printer     `json:"footer"`
-	BarCode BarCodeOption `json:"barCode"`
+	// Version - model schema version, 1 for header/lines/footer files
+	Version  int           `json:"version"`
+	BarCode  BarCodeOption `json:"barCode"`
+	Sections []Section     `json:"sections"`
+	// Header, Lines, Footer - rows of a version 1 model as read, before
+	// includes and Render; they aren't printed or saved.
+	//
+	// Deprecated: use Sections, the migrated rows are its header, lines
+	// and footer sections.
+	Header []Printer `json:"-"`
+	Lines  []Printer `json:"-"`
+	Footer []Printer `json:"-"`
+	// Data - values for templates ({{.total}}) and repeat blocks
+	Data map[string]interface{} `json:"data,omitempty"`
+	// Quality - print quality preset of the job: draft, normal or dark
+	Quality string `json:"quality,omitempty"`
+	// Priority - the print server prints queued jobs of higher priority
+	// first (customer receipt 10, end of day report -10), default 0
+	Priority int `json:"priority,omitempty"`
+	// IdempotencyKey - the print server prints one job of the models with
+	// the same key in its window, replays get the status of the first
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// Cache - the print server keeps the job printed, the next jobs of the
+	// same model and data replay it without rendering; not for templates
+	// printing a {{seq}} or the time
+	Cache bool `json:"cache,omitempty"`
+	// Report - totals of the transactions of the data, see Report
+	Report *Report `json:"report,omitempty"`
+	// Label - the sections are one page of a fixed size, see Label
+	Label *Label `json:"label,omitempty"`
+	// Tabs - tab stops of the job in columns, "\t" in the text of a row
+	// goes to the next one (empty - every 4 columns)
+	Tabs []int `json:"tabs,omitempty"`
+	// TabAlign - alignment of the fields at Tabs, one per stop: "left"
+	// (default), "right" or "decimal" lining up the decimal separators of
+	// a price column
+	TabAlign []string `json:"tabAlign,omitempty"`
+	// Columns - characters per line of font A of the job, see
+	// escpos.SetColumns (0 - the ones of the printer)
+	Columns int `json:"columns,omitempty"`
+}
+
+// Section - named block of rows, Feed lines are fed after the section
+type Section struct {
+	Name string    `json:"name"`
+	Feed uint8     `json:"feed,omitempty"`
+	Rows []Printer `json:"rows"`
+	// Page - print the rows in page mode as one page of this size
+	Page *Area `json:"page,omitempty"`
+	// Frame - draw a box around the rows
+	Frame bool `json:"frame,omitempty"`
+}
+
+// Area - page mode area in dots, the data in it is rotated 0, 90, 180 or
+// 270 degrees clockwise
+type Area struct {
+	X      uint16 `json:"x,omitempty"`
+	Y      uint16 `json:"y,omitempty"`
+	Width  uint16 `json:"width"`
+	Height uint16 `json:"height"`
+	Rotate uint16 `json:"rotate,omitempty"`
+}
+
+// parseArea - page or area object of v, nil when missing
+func parseArea(v *jason.Object, name string) (*Area, error) {
+	o, err := v.GetObject(name)
+	if err != nil || o == nil {
+		return nil, nil
+	}
+	f := numbers{v: o}
+	a := &Area{
+		X:      uint16(f.get("x", 65535)),
+		Y:      uint16(f.get("y", 65535)),
+		Width:  uint16(f.get("width", 65535)),
+		Height: uint16(f.get("height", 65535)),
+		Rotate: uint16(f.get("rotate", 65535)),
+	}
+	if f.err != nil {
+		return nil, fmt.Errorf("%s: %s", name, f.err.Error())
+	}
+	return a, nil
+}
+
+// numbers - integers of a model object for fields of a fixed size, err
+// is the first one out of their range
+type numbers struct {
+	v   *jason.Object
+	err error
+}
+
+// get - value of name in 0..max, 0 when missing or not a number
+func (f *numbers) get(name string, max int64) int64 {
+	n, err := f.v.GetInt64(name)
+	if err != nil {
+		return 0
+	}
+	if n < 0 || n > max {
+		if f.err == nil {
+			f.err = fmt.Errorf("Invalid %s: %d, 0..%d", name, n, max)
+		}
+		return 0
+	}
+	return n
+}
+
+// position - x or y of a row, a number of dots or a string with a unit
+func position(v *jason.Object, name string) string {
+	if n, err := v.GetInt64(name); err == nil {
+		return strconv.FormatInt(n, 10)
+	}
+	s, _ := v.GetString(name)
+	return s
 }
 
 // BarCodeOption - print option for bar code
@@ -32,89 +231,455 @@ type BarCodeOption struct {
 	Height uint8  `json:"height"`
 	Chr    uint8  `json:"chr"`
 	Code   string `json:"code"`
+	Width  uint8  `json:"width,omitempty"`
+	QrSize uint8  `json:"qrSize,omitempty"`
+	QrEcc  string `json:"qrEcc,omitempty"`
+	Rotate bool   `json:"rotate,omitempty"`
+	// HriText - of a row only, see Printer
+	HriText   string `json:"hriText,omitempty"`
+	HriFormat string `json:"hriFormat,omitempty"`
+	HriStyle  string `json:"hriStyle,omitempty"`
+}
+
+// hriPosition - HRI names accepted in a row, value for GS H
+var hriPosition = map[string]uint8{
+	"none":  0,
+	"above": 1,
+	"below": 2,
+	"both":  3,
+}
+
+// BarCodeOptions - bar code settings for row, row values override def
+func (p Printer) BarCodeOptions(def BarCodeOption) BarCodeOption {
+	opt := def
+	if len(p.Code) > 0 {
+		opt.Code = p.Code
+	}
+	if p.Height > 0 {
+		opt.Height = p.Height
+	}
+	if p.Width > 0 && p.Width <= 255 {
+		opt.Width = uint8(p.Width)
+	}
+	if chr, ok := hriPosition[p.Hri]; ok {
+		opt.Chr = chr
+	}
+	if p.QrSize > 0 {
+		opt.QrSize = p.QrSize
+	}
+	if len(p.QrEcc) > 0 {
+		opt.QrEcc = p.QrEcc
+	}
+	if p.Rotate {
+		opt.Rotate = true
+	}
+	opt.HriText = p.HriText
+	if len(p.HriFormat) > 0 {
+		opt.HriFormat = p.HriFormat
+	}
+	if len(p.HriStyle) > 0 {
+		opt.HriStyle = p.HriStyle
+	}
+	return opt
+}
+
+// Kind - row type: text, line, barcode, qrcode, datamatrix, aztec, image,
+// cut, drawer, beep, feed, space, signature, repeat, include or section
+func (p Printer) Kind() string {
+	switch {
+	case len(p.Include) > 0:
+		return "include"
+	case len(p.Repeat) > 0:
+		return "repeat"
+	case len(p.Rows) > 0 || p.Type == "section":
+		return "section"
+	case len(p.Cut) > 0:
+		return "cut"
+	case p.Drawer:
+		return "drawer"
+	case p.Beep > 0:
+		return "beep"
+	case p.Feed > 0:
+		return "feed"
+	case p.FormFeed:
+		return "formfeed"
+	case p.Signature:
+		return "signature"
+	case len(p.Space) > 0:
+		return "space"
+	case p.Line && len(p.Text) == 0:
+		return "line"
+	case p.Image:
+		return "image"
+	case p.BarCode:
+		return "barcode"
+	case p.QrCode:
+		return "qrcode"
+	case p.Type == "datamatrix", p.Type == "aztec":
+		return p.Type
+	}
+	return "text"
+}
+
+// setType - set the flags for a version 2 typed row
+func (p *Printer) setType() {
+	switch p.Type {
+	case "line":
+		p.Line = true
+		p.Text = ""
+	case "image":
+		p.Image = true
+	case "barcode":
+		p.BarCode = true
+	case "qrcode":
+		p.QrCode = true
+	case "cut":
+		if len(p.Cut) == 0 {
+			p.Cut = "full"
+		}
+	case "drawer":
+		p.Drawer = true
+	case "beep":
+		if p.Beep == 0 {
+			p.Beep = 1
+		}
+	case "feed":
+		if p.Feed == 0 {
+			p.Feed = 1
+		}
+	case "space":
+		if len(p.Space) == 0 {
+			p.Space = "5mm"
+		}
+	case "signature":
+		p.Signature = true
+	case "formfeed":
+		p.FormFeed = true
+	}
+}
+
+// parseRow - read one model row, an error for numbers out of the range
+// of their field
+func parseRow(row *jason.Object) (Printer, error) {
+	f := numbers{v: row}
+	kind, _ := row.GetString("type")
+	name, _ := row.GetString("name")
+	line, _ := row.GetBoolean("line")
+	lineStyle, _ := row.GetString("lineStyle")
+	if o, err := row.GetObject("line"); err == nil && o != nil {
+		line = true
+		lineStyle, _ = o.GetString("style")
+	}
+	image, _ := row.GetBoolean("image")
+	barCode, _ := row.GetBoolean("barCode")
+	qrCode, _ := row.GetBoolean("qrCode")
+	align, _ := row.GetString("align")
+	style, _ := row.GetString("style")
+	size, _ := row.GetString("size")
+	underline := f.get("underline", 255)
+	wrap, _ := row.GetBoolean("wrap")
+	text, _ := row.GetString("text")
+	right, _ := row.GetString("right")
+	fill, _ := row.GetString("fill")
+	maxWidth := f.get("maxWidth", 255)
+	truncate, _ := row.GetString("truncate")
+	encoding, _ := row.GetString("encoding")
+	code, _ := row.GetString("code")
+	height := f.get("height", 255)
+	width := f.get("width", 65535)
+	hri, _ := row.GetString("hri")
+	qrSize := f.get("qrSize", 255)
+	qrEcc, _ := row.GetString("qrEcc")
+	rotate, _ := row.GetBoolean("rotate")
+	hriText, _ := row.GetString("hriText")
+	hriFormat, _ := row.GetString("hriFormat")
+	hriStyle, _ := row.GetString("hriStyle")
+	cut, err := row.GetString("cut")
+	if err != nil {
+		if ok, _ := row.GetBoolean("cut"); ok {
+			cut = "full"
+		}
+	}
+	drawer, _ := row.GetBoolean("drawer")
+	beep := f.get("beep", 255)
+	feed := f.get("feed", 255)
+	formFeed, _ := row.GetBoolean("formFeed")
+	space := position(row, "space")
+	signature, _ := row.GetBoolean("signature")
+	if caption, err := row.GetString("signature"); err == nil {
+		signature = true
+		if len(text) == 0 {
+			text = caption
+		}
+	}
+	src, _ := row.GetString("src")
+	data, _ := row.GetString("data")
+	dither, _ := row.GetString("dither")
+	repeat, _ := row.GetString("repeat")
+	cond, _ := row.GetString("if")
+	unless, _ := row.GetString("unless")
+	include, _ := row.GetString("include")
+	frame, _ := row.GetBoolean("frame")
+	side, _ := row.GetString("side")
+	x := position(row, "x")
+	y := position(row, "y")
+	area, err := parseArea(row, "area")
+	if f.err == nil {
+		f.err = err
+	}
+	if f.err != nil {
+		return Printer{}, f.err
+	}
+	var rows []Printer
+	children, _ := row.GetObjectArray("rows")
+	for _, child := range children {
+		r, err := parseRow(child)
+		if err != nil {
+			return Printer{}, err
+		}
+		rows = append(rows, r)
+	}
+	p := Printer{
+		Type:      kind,
+		Name:      name,
+		Line:      line,
+		LineStyle: lineStyle,
+		Image:     image,
+		BarCode:   barCode,
+		QrCode:    qrCode,
+		Align:     align,
+		Style:     style,
+		Size:      size,
+		Underline: uint8(underline),
+		Wrap:      wrap,
+		Text:      text,
+		Right:     right,
+		Fill:      fill,
+		MaxWidth:  uint8(maxWidth),
+		Truncate:  truncate,
+		Encoding:  encoding,
+		Code:      code,
+		Height:    uint8(height),
+		Width:     uint16(width),
+		Hri:       hri,
+		QrSize:    uint8(qrSize),
+		QrEcc:     qrEcc,
+		Rotate:    rotate,
+		HriText:   hriText,
+		HriFormat: hriFormat,
+		HriStyle:  hriStyle,
+		Cut:       cut,
+		Drawer:    drawer,
+		Beep:      uint8(beep),
+		Feed:      uint8(feed),
+		FormFeed:  formFeed,
+		Space:     space,
+		Signature: signature,
+		Src:       src,
+		Data:      data,
+		Dither:    dither,
+		Repeat:    repeat,
+		Rows:      rows,
+		If:        cond,
+		Unless:    unless,
+		Include:   include,
+		Area:      area,
+		Frame:     frame,
+		Side:      side,
+		X:         x,
+		Y:         y,
+	}
+	p.setType()
+	return p, nil
 }
 
-// LoadPrintModel - lading model
+// LoadPrintModel - lading model from a file or an http(s) URL, version 1
+// models are migrated to sections and include rows are resolved
 func LoadPrintModel(file string) (res PrinterLine, err error) {
-	f, err := os.Open(file)
+	if res, err = loadModel(file); err != nil {
+		return res, err
+	}
+	stack := []string{joinPath("", file)}
+	return res, res.includes(dirOf(file), stack)
+}
+
+// ReadPrintModel - read model from r, includes are resolved against dir
+func ReadPrintModel(r io.Reader, dir string) (res PrinterLine, err error) {
+	if res, err = readModel(r); err != nil {
+		return res, err
+	}
+	return res, res.includes(dir, nil)
+}
+
+// includes - resolve includes of all sections
+func (p *PrinterLine) includes(dir string, stack []string) (err error) {
+	for i := range p.Sections {
+		if p.Sections[i].Rows, err = resolveIncludes(p.Sections[i].Rows, dir, stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadModel - read model file without resolving includes
+func loadModel(file string) (res PrinterLine, err error) {
+	f, err := openFile(file)
 	if err != nil {
 		return res, fmt.Errorf("Load file: %s", err.Error())
 	}
-	v, _ := jason.NewObjectFromReader(f)
-	header, _ := v.GetObjectArray("header")
-	lines, _ := v.GetObjectArray("lines")
-	footer, _ := v.GetObjectArray("footer")
-	b, _ := v.GetObject("barCode")
-	height, _ := b.GetInt64("height")
-	chr, _ := b.GetInt64("chr")
-	code, _ := b.GetString("code")
-	res.BarCode.Height = uint8(height)
-	res.BarCode.Chr = uint8(chr)
-	res.BarCode.Code = code
-
-	for _, row := range header {
-		line, _ := row.GetBoolean("line")
-		image, _ := row.GetBoolean("image")
-		barCode, _ := row.GetBoolean("barCode")
-		qrCode, _ := row.GetBoolean("qrCode")
-		align, _ := row.GetString("align")
-		style, _ := row.GetString("style")
-		size, _ := row.GetString("size")
-		text, _ := row.GetString("text")
-		r := Printer{
-			Line:    line,
-			Image:   image,
-			BarCode: barCode,
-			QrCode:  qrCode,
-			Align:   align,
-			Style:   style,
-			Size:    size,
-			Text:    text,
-		}
-		res.Header = append(res.Header, r)
-	}
-	for _, row := range lines {
-		// fmt.Println(row)
-		line, _ := row.GetBoolean("line")
-		image, _ := row.GetBoolean("image")
-		barCode, _ := row.GetBoolean("barCode")
-		qrCode, _ := row.GetBoolean("qrCode")
-		align, _ := row.GetString("align")
-		style, _ := row.GetString("style")
-		size, _ := row.GetString("size")
-		text, _ := row.GetString("text")
-		r := Printer{
-			Line:    line,
-			Image:   image,
-			BarCode: barCode,
-			QrCode:  qrCode,
-			Align:   align,
-			Style:   style,
-			Size:    size,
-			Text:    text,
-		}
-		res.Lines = append(res.Lines, r)
-	}
-	for _, row := range footer {
-		line, _ := row.GetBoolean("line")
-		image, _ := row.GetBoolean("image")
-		barCode, _ := row.GetBoolean("barCode")
-		qrCode, _ := row.GetBoolean("qrCode")
-		align, _ := row.GetString("align")
-		style, _ := row.GetString("style")
-		size, _ := row.GetString("size")
-		text, _ := row.GetString("text")
-		r := Printer{
-			Line:    line,
-			Image:   image,
-			BarCode: barCode,
-			QrCode:  qrCode,
-			Align:   align,
-			Style:   style,
-			Size:    size,
-			Text:    text,
-		}
-		res.Footer = append(res.Footer, r)
-	}
-	return res, err
+	defer f.Close()
+	return readModel(f)
+}
+
+// readModel - parse model JSON without resolving includes
+func readModel(r io.Reader) (res PrinterLine, err error) {
+	v, err := jason.NewObjectFromReader(r)
+	if err != nil {
+		return res, fmt.Errorf("Load file: %s", err.Error())
+	}
+	version, _ := v.GetInt64("version")
+	if version == 0 {
+		version = 1
+	}
+	if version > ModelVersion {
+		return res, fmt.Errorf("Load file: unsupported model version %d", version)
+	}
+	// barCode is optional
+	if b, err := v.GetObject("barCode"); err == nil && b != nil {
+		f := numbers{v: b}
+		height := f.get("height", 255)
+		chr := f.get("chr", 255)
+		code, _ := b.GetString("code")
+		width := f.get("width", 255)
+		qrSize := f.get("qrSize", 255)
+		qrEcc, _ := b.GetString("qrEcc")
+		rotate, _ := b.GetBoolean("rotate")
+		hriFormat, _ := b.GetString("hriFormat")
+		hriStyle, _ := b.GetString("hriStyle")
+		res.BarCode.Height = uint8(height)
+		res.BarCode.Chr = uint8(chr)
+		res.BarCode.Code = code
+		res.BarCode.Width = uint8(width)
+		res.BarCode.QrSize = uint8(qrSize)
+		res.BarCode.QrEcc = qrEcc
+		res.BarCode.Rotate = rotate
+		res.BarCode.HriFormat = hriFormat
+		res.BarCode.HriStyle = hriStyle
+		if f.err != nil {
+			return res, fmt.Errorf("Load file: barCode: %s", f.err.Error())
+		}
+	}
+	if data, err := v.GetObject("data"); err == nil {
+		res.Data, _ = data.Interface().(map[string]interface{})
+	}
+	res.Quality, _ = v.GetString("quality")
+	priority, _ := v.GetInt64("priority")
+	res.Priority = int(priority)
+	res.IdempotencyKey, _ = v.GetString("idempotencyKey")
+	res.Cache, _ = v.GetBoolean("cache")
+	res.Report = parseReport(v)
+	res.Label = parseLabel(v)
+	if tabs, err := v.GetInt64Array("tabs"); err == nil {
+		for _, t := range tabs {
+			res.Tabs = append(res.Tabs, int(t))
+		}
+	}
+	res.TabAlign, _ = v.GetStringArray("tabAlign")
+	columns, _ := v.GetInt64("columns")
+	res.Columns = int(columns)
+
+	if version == 1 {
+		if err := migrateV1(&res, v); err != nil {
+			return res, fmt.Errorf("Load file: %s", err.Error())
+		}
+		return res, nil
+	}
+	res.Version = int(version)
+	sections, _ := v.GetObjectArray("sections")
+	for _, sec := range sections {
+		name, _ := sec.GetString("name")
+		f := numbers{v: sec}
+		feed := f.get("feed", 255)
+		frame, _ := sec.GetBoolean("frame")
+		page, err := parseArea(sec, "page")
+		if f.err == nil {
+			f.err = err
+		}
+		if f.err != nil {
+			return res, fmt.Errorf("Load file: section %q: %s", name, f.err.Error())
+		}
+		s := Section{Name: name, Feed: uint8(feed), Page: page, Frame: frame}
+		rows, _ := sec.GetObjectArray("rows")
+		for _, row := range rows {
+			r, err := parseRow(row)
+			if err != nil {
+				return res, fmt.Errorf("Load file: section %q: %s", name, err.Error())
+			}
+			s.Rows = append(s.Rows, r)
+		}
+		res.Sections = append(res.Sections, s)
+	}
+	return res, nil
+}
+
+// TextModel - model printing each line as a text row with align
+func TextModel(lines []string, align string) PrinterLine {
+	s := Section{Name: "lines", Feed: 2}
+	for _, line := range lines {
+		s.Rows = append(s.Rows, Printer{Align: align, Text: line})
+	}
+	return PrinterLine{Version: ModelVersion, Sections: []Section{s}}
+}
+
+// tabWidth - columns of a tab in CatModel, the printer tab stops of reset
+const tabWidth = 4
+
+// CatModel - model printing the lines of a text file wrapped to the paper
+// width, numbered when numbers is set; with page > 0 every page lines
+// start with a header of title and the page number
+func CatModel(title string, lines []string, numbers bool, page int) PrinterLine {
+	m := PrinterLine{Version: ModelVersion}
+	digits := len(strconv.Itoa(len(lines)))
+	var s *Section
+	for i, line := range lines {
+		if s == nil || (page > 0 && i%page == 0) {
+			n := len(m.Sections) + 1
+			m.Sections = append(m.Sections, Section{Name: fmt.Sprintf("page %d", n)})
+			s = &m.Sections[len(m.Sections)-1]
+			if page > 0 {
+				s.Rows = append(s.Rows,
+					Printer{Style: "bold", Text: title, Right: fmt.Sprintf("%d", n)},
+					Printer{Type: "line", Line: true})
+			}
+		}
+		line = expandTabs(strings.TrimRight(line, "\r"))
+		if numbers {
+			line = fmt.Sprintf("%*d %s", digits, i+1, line)
+		}
+		s.Rows = append(s.Rows, Printer{Text: line, Wrap: true})
+	}
+	if s != nil {
+		s.Feed = 2
+	}
+	return m
+}
+
+// expandTabs - tabs of line as spaces to the next tab stop
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
 }
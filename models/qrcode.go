@@ -0,0 +1,260 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QrKinds - payloads QrPayload builds
+var QrKinds = []string{"wifi", "vcard", "url", "epc", "crypto"}
+
+// QrPayload - QR code text of kind from fields:
+//
+//	wifi:   ssid, password, auth (WPA, WEP, nopass), hidden
+//	vcard:  name, org, title, phone, mobile, email, url, address, note
+//	url:    url, other fields are added as query parameters
+//	epc:    name, iban, bic, amount, purpose, reference, text, info (SEPA credit transfer)
+//	crypto: scheme (bitcoin, litecoin...), address, amount, label, message...
+func QrPayload(kind string, fields map[string]string) (string, error) {
+	switch kind {
+	case "wifi":
+		return WiFiPayload(fields["ssid"], fields["password"], fields["auth"], fields["hidden"] == "true")
+	case "vcard":
+		return VCardPayload(fields)
+	case "url":
+		return URLPayload(fields["url"], without(fields, "url"))
+	case "epc":
+		return EPCPayload(fields)
+	case "crypto":
+		return CryptoPayload(fields["scheme"], fields["address"], without(fields, "scheme", "address"))
+	}
+	return "", fmt.Errorf("QR payload %q, one of: %s", kind, strings.Join(QrKinds, ", "))
+}
+
+// qrFunc - {{qr "wifi" "ssid" .ssid "password" .password}} of templates
+func qrFunc(kind string, pairs ...interface{}) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("qr %s: key value pairs expected", kind)
+	}
+	fields := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		fields[fmt.Sprint(pairs[i])] = fmt.Sprint(pairs[i+1])
+	}
+	return QrPayload(kind, fields)
+}
+
+// without - fields except keys
+func without(fields map[string]string, keys ...string) map[string]string {
+	res := make(map[string]string, len(fields))
+	for k, v := range fields {
+		res[k] = v
+	}
+	for _, k := range keys {
+		delete(res, k)
+	}
+	return res
+}
+
+// WiFiPayload - WIFI: network credentials phones join by scanning, auth
+// WPA (default with a password), WEP or nopass
+func WiFiPayload(ssid, password, auth string, hidden bool) (string, error) {
+	if len(ssid) == 0 {
+		return "", fmt.Errorf("QR wifi: ssid is required")
+	}
+	switch strings.ToUpper(auth) {
+	case "":
+		auth = "WPA"
+		if len(password) == 0 {
+			auth = "nopass"
+		}
+	case "WPA", "WPA2", "WPA3":
+		auth = "WPA"
+	case "WEP":
+		auth = "WEP"
+	case "NOPASS", "NONE", "OPEN":
+		auth = "nopass"
+	default:
+		return "", fmt.Errorf("QR wifi: auth WPA, WEP or nopass")
+	}
+	if auth != "nopass" && len(password) == 0 {
+		return "", fmt.Errorf("QR wifi: %s needs a password", auth)
+	}
+	var b strings.Builder
+	b.WriteString("WIFI:T:" + auth + ";S:" + wifiEscape(ssid) + ";")
+	if auth != "nopass" {
+		b.WriteString("P:" + wifiEscape(password) + ";")
+	}
+	if hidden {
+		b.WriteString("H:true;")
+	}
+	b.WriteString(";")
+	return b.String(), nil
+}
+
+// wifiEscape - backslash before the special characters of WIFI: fields
+func wifiEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`).Replace(s)
+}
+
+// VCardPayload - vCard 3.0 contact of fields, name ("First Last") is
+// required
+func VCardPayload(fields map[string]string) (string, error) {
+	name := strings.TrimSpace(fields["name"])
+	if len(name) == 0 {
+		return "", fmt.Errorf("QR vcard: name is required")
+	}
+	first, last := name, ""
+	if i := strings.LastIndex(name, " "); i > 0 {
+		first, last = name[:i], name[i+1:]
+	}
+	lines := []string{"BEGIN:VCARD", "VERSION:3.0",
+		"N:" + vcardEscape(last) + ";" + vcardEscape(first) + ";;;",
+		"FN:" + vcardEscape(name)}
+	for _, f := range []struct{ key, prop string }{
+		{"org", "ORG"},
+		{"title", "TITLE"},
+		{"phone", "TEL;TYPE=WORK,VOICE"},
+		{"mobile", "TEL;TYPE=CELL"},
+		{"email", "EMAIL"},
+		{"url", "URL"},
+		{"address", "ADR;TYPE=WORK"},
+		{"note", "NOTE"},
+	} {
+		v := fields[f.key]
+		if len(v) == 0 {
+			continue
+		}
+		if f.key == "address" {
+			// street address of the ADR components
+			lines = append(lines, f.prop+":;;"+vcardEscape(v)+";;;;")
+			continue
+		}
+		lines = append(lines, f.prop+":"+vcardEscape(v))
+	}
+	lines = append(lines, "END:VCARD")
+	return strings.Join(lines, "\r\n"), nil
+}
+
+// vcardEscape - escape the text value separators of vCard
+func vcardEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `,`, `\,`, `;`, `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// URLPayload - http(s) link with query added, https:// when raw has no
+// scheme
+func URLPayload(raw string, query map[string]string) (string, error) {
+	if len(raw) == 0 {
+		return "", fmt.Errorf("QR url: url is required")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("QR url: %s", err.Error())
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return "", fmt.Errorf("QR url: %s is not an http(s) URL", raw)
+	}
+	if len(query) > 0 {
+		q := u.Query()
+		for k, v := range query {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
+
+// EPCPayload - EPC069-12 SEPA credit transfer ("GiroCode") of fields:
+// name and iban are required, amount in euro, a structured reference or
+// a text for the beneficiary
+func EPCPayload(fields map[string]string) (string, error) {
+	name := strings.TrimSpace(fields["name"])
+	if len(name) == 0 || len([]rune(name)) > 70 {
+		return "", fmt.Errorf("QR epc: name of 1..70 characters is required")
+	}
+	iban := strings.ToUpper(strings.ReplaceAll(fields["iban"], " ", ""))
+	if !validIBAN(iban) {
+		return "", fmt.Errorf("QR epc: invalid iban %q", fields["iban"])
+	}
+	bic := strings.ToUpper(strings.ReplaceAll(fields["bic"], " ", ""))
+	if len(bic) != 0 && len(bic) != 8 && len(bic) != 11 {
+		return "", fmt.Errorf("QR epc: invalid bic %q", fields["bic"])
+	}
+	amount := ""
+	if v := strings.TrimSpace(fields["amount"]); len(v) > 0 {
+		f, err := strconv.ParseFloat(strings.TrimPrefix(strings.ToUpper(v), "EUR"), 64)
+		if err != nil || f < 0.01 || f > 999999999.99 {
+			return "", fmt.Errorf("QR epc: amount 0.01..999999999.99 euro, not %q", v)
+		}
+		amount = "EUR" + strconv.FormatFloat(f, 'f', 2, 64)
+	}
+	ref, text := fields["reference"], fields["text"]
+	if len(ref) > 0 && len(text) > 0 {
+		return "", fmt.Errorf("QR epc: reference or text, not both")
+	}
+	if len(ref) > 35 || len([]rune(text)) > 140 || len([]rune(fields["info"])) > 70 || len(fields["purpose"]) > 4 {
+		return "", fmt.Errorf("QR epc: reference up to 35, text 140, info 70 and purpose 4 characters")
+	}
+	lines := []string{"BCD", "002", "1", "SCT", bic, name, iban, amount,
+		strings.ToUpper(fields["purpose"]), ref, text, fields["info"]}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n"), nil
+}
+
+// validIBAN - iban (without spaces) passes the ISO 13616 mod 97 check
+func validIBAN(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			digits.WriteString(strconv.Itoa(int(c-'A') + 10))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// CryptoPayload - BIP 21 style payment URI (bitcoin:address?amount=0.01),
+// params (amount, label, message...) in key order
+func CryptoPayload(scheme, address string, params map[string]string) (string, error) {
+	scheme = strings.ToLower(strings.TrimSuffix(scheme, ":"))
+	if len(scheme) == 0 {
+		scheme = "bitcoin"
+	}
+	if len(address) == 0 || strings.ContainsAny(address, " ?&") {
+		return "", fmt.Errorf("QR crypto: address is required")
+	}
+	if v, ok := params["amount"]; ok {
+		if f, err := strconv.ParseFloat(v, 64); err != nil || f <= 0 {
+			return "", fmt.Errorf("QR crypto: invalid amount %q", v)
+		}
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var q []string
+	for _, k := range keys {
+		if len(params[k]) > 0 {
+			q = append(q, url.QueryEscape(k)+"="+strings.ReplaceAll(url.QueryEscape(params[k]), "+", "%20"))
+		}
+	}
+	res := scheme + ":" + address
+	if len(q) > 0 {
+		res += "?" + strings.Join(q, "&")
+	}
+	return res, nil
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/antonholmquist/jason"
+)
+
+// Report - totals of the transactions of a shift or Z-report model,
+// computed into the data before the rows are rendered:
+//
+//	"report": {"transactions": "transactions", "amount": "amount",
+//	  "groupBy": ["payment", "category"], "where": "status != 'void'"}
+//
+// gives report.count, report.total and for each field of groupBy the
+// array report.groups.<field> of {key, count, total} sorted by key, for
+// "repeat": "report.groups.payment" rows
+type Report struct {
+	// Transactions - data path of the transactions array, "transactions"
+	Transactions string `json:"transactions,omitempty"`
+	// Amount - field of the amount of a transaction, "amount"
+	Amount string `json:"amount,omitempty"`
+	// GroupBy - fields of the subtotals, a transaction without one is in
+	// the group ""
+	GroupBy []string `json:"groupBy,omitempty"`
+	// Where - condition a transaction is counted on, see Eval
+	Where string `json:"where,omitempty"`
+	// Decimals - digits of the totals after the point, 2 when not set
+	Decimals *int `json:"decimals,omitempty"`
+}
+
+// reportGroup - count and total of the transactions of one key
+type reportGroup struct {
+	count int64
+	// total - in units of the last decimal, no rounding errors add up
+	total int64
+}
+
+// parseReport - report object of the model, nil when missing
+func parseReport(v *jason.Object) *Report {
+	o, err := v.GetObject("report")
+	if err != nil || o == nil {
+		return nil
+	}
+	r := &Report{}
+	r.Transactions, _ = o.GetString("transactions")
+	r.Amount, _ = o.GetString("amount")
+	r.Where, _ = o.GetString("where")
+	r.GroupBy, _ = o.GetStringArray("groupBy")
+	if n, err := o.GetInt64("decimals"); err == nil {
+		d := int(n)
+		r.Decimals = &d
+	}
+	return r
+}
+
+// Aggregate - the report of the transactions of data, see Report
+func (r *Report) Aggregate(data map[string]interface{}) (map[string]interface{}, error) {
+	path, field, decimals := "transactions", "amount", 2
+	if len(r.Transactions) > 0 {
+		path = r.Transactions
+	}
+	if len(r.Amount) > 0 {
+		field = r.Amount
+	}
+	if r.Decimals != nil && *r.Decimals >= 0 {
+		decimals = *r.Decimals
+	}
+	items, ok := Lookup(data, path).([]interface{})
+	if !ok && Lookup(data, path) != nil {
+		return nil, fmt.Errorf("Report: %s is not an array", path)
+	}
+	scale := math.Pow10(decimals)
+	var all reportGroup
+	groups := make([]map[string]*reportGroup, len(r.GroupBy))
+	for i := range groups {
+		groups[i] = map[string]*reportGroup{}
+	}
+	for i, item := range items {
+		scope := itemScope(data, item, i)
+		if len(r.Where) > 0 {
+			if ok, err := Eval(r.Where, scope); err != nil {
+				return nil, fmt.Errorf("Report: %s", err.Error())
+			} else if !ok {
+				continue
+			}
+		}
+		var amount int64
+		if v := Lookup(scope, field); v != nil {
+			f, ok := number(v)
+			if !ok {
+				return nil, fmt.Errorf("Report: %s %v of transaction %d is not a number", field, v, i+1)
+			}
+			amount = int64(math.Round(f * scale))
+		}
+		all.count++
+		all.total += amount
+		for j, by := range r.GroupBy {
+			key := ""
+			if v := Lookup(scope, by); v != nil {
+				key = fmt.Sprint(v)
+			}
+			g, ok := groups[j][key]
+			if !ok {
+				g = &reportGroup{}
+				groups[j][key] = g
+			}
+			g.count++
+			g.total += amount
+		}
+	}
+	byField := map[string]interface{}{}
+	for j, by := range r.GroupBy {
+		keys := make([]string, 0, len(groups[j]))
+		for key := range groups[j] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		list := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			g := groups[j][key]
+			list = append(list, map[string]interface{}{
+				"key":   key,
+				"count": g.count,
+				"total": reportAmount(g.total, decimals),
+			})
+		}
+		byField[by] = list
+	}
+	return map[string]interface{}{
+		"count":  all.count,
+		"total":  reportAmount(all.total, decimals),
+		"groups": byField,
+	}, nil
+}
+
+// reportAmount - units of the last decimal as a decimal string, "-12.50"
+func reportAmount(units int64, decimals int) string {
+	if decimals <= 0 {
+		return strconv.FormatInt(units, 10)
+	}
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	s := fmt.Sprintf("%0*d", decimals+1, units)
+	return sign + s[:len(s)-decimals] + "." + s[len(s)-decimals:]
+}
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Render - expand repeat blocks and substitute template values with the
+// model data in all sections, the totals of a report first
+func (p *PrinterLine) Render() error {
+	return p.RenderFuncs(nil)
+}
+
+// RenderFuncs - Render with extra template functions, e.g. {{seq "receipt"}}
+func (p *PrinterLine) RenderFuncs(funcs template.FuncMap) (err error) {
+	if p.Report != nil {
+		report, err := p.Report.Aggregate(p.Data)
+		if err != nil {
+			return err
+		}
+		p.SetVar("report", report)
+	}
+	for i := range p.Sections {
+		if p.Sections[i].Rows, err = renderRows(p.Sections[i].Rows, p.Data, funcs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hash - hex SHA-256 of the template and data of the model before Render,
+// the same for the jobs which print the same
+func (p PrinterLine) Hash() string {
+	// not printed
+	p.Priority, p.IdempotencyKey = 0, ""
+	b, _ := json.Marshal(p)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// RenderRows - expand rows with data, rows failing their if/unless
+// condition are dropped, a repeat row renders its child rows for each
+// element of the data array repeat points to, any other row with child
+// rows is a group rendered in place; groups with a page area, a frame or
+// a side keep their row
+func RenderRows(rows []Printer, data map[string]interface{}) ([]Printer, error) {
+	return renderRows(rows, data, nil)
+}
+
+func renderRows(rows []Printer, data map[string]interface{}, funcs template.FuncMap) (res []Printer, err error) {
+	for _, row := range rows {
+		if ok, err := row.Visible(data); err != nil {
+			return res, err
+		} else if !ok {
+			continue
+		}
+		if len(row.Repeat) > 0 {
+			items, ok := Lookup(data, row.Repeat).([]interface{})
+			if !ok {
+				return res, fmt.Errorf("Repeat: %s is not an array", row.Repeat)
+			}
+			for i, item := range items {
+				sub, err := renderRows(row.Rows, itemScope(data, item, i), funcs)
+				if err != nil {
+					return res, err
+				}
+				res = append(res, sub...)
+			}
+			continue
+		}
+		if len(row.Rows) > 0 {
+			sub, err := renderRows(row.Rows, data, funcs)
+			if err != nil {
+				return res, err
+			}
+			if row.Area != nil || row.Frame || len(row.Side) > 0 {
+				row.If, row.Unless, row.Rows = "", "", sub
+				res = append(res, row)
+			} else {
+				res = append(res, sub...)
+			}
+			continue
+		}
+		if row.Text, err = renderText(row.Text, data, funcs); err != nil {
+			return res, err
+		}
+		if row.Right, err = renderText(row.Right, data, funcs); err != nil {
+			return res, err
+		}
+		if row.Src, err = renderText(row.Src, data, funcs); err != nil {
+			return res, err
+		}
+		if row.HriText, err = renderText(row.HriText, data, funcs); err != nil {
+			return res, err
+		}
+		res = append(res, row)
+	}
+	return res, nil
+}
+
+// Visible - row if/unless conditions hold for data
+func (p Printer) Visible(data map[string]interface{}) (bool, error) {
+	if len(p.If) > 0 {
+		if ok, err := Eval(p.If, data); err != nil || !ok {
+			return false, err
+		}
+	}
+	if len(p.Unless) > 0 {
+		if ok, err := Eval(p.Unless, data); err != nil || ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// itemScope - data for one repeat element: parent values, fields of the
+// element, the element as "item" and its position as "index"
+func itemScope(parent map[string]interface{}, item interface{}, index int) map[string]interface{} {
+	scope := make(map[string]interface{}, len(parent)+2)
+	for k, v := range parent {
+		scope[k] = v
+	}
+	if m, ok := item.(map[string]interface{}); ok {
+		for k, v := range m {
+			scope[k] = v
+		}
+	}
+	scope["item"] = item
+	scope["index"] = index
+	return scope
+}
+
+// Lookup - value for dotted path (order.items) in data, nil if not found
+func Lookup(data map[string]interface{}, path string) interface{} {
+	var cur interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if cur, ok = m[key]; !ok {
+			return nil
+		}
+	}
+	return cur
+}
+
+// LoadData - template data of the JSON object in file (path, http(s) URL
+// or - for stdin), see MergeData
+func LoadData(file string) (map[string]interface{}, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := openFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("Load data: %s", err.Error())
+		}
+		defer f.Close()
+		r = f
+	}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var data map[string]interface{}
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("Load data %s: %s", file, err.Error())
+	}
+	return data, nil
+}
+
+// MergeData - data over the data of the model: objects are merged key by
+// key, other values replace the ones of the model
+func (p *PrinterLine) MergeData(data map[string]interface{}) {
+	if p.Data == nil {
+		p.Data = map[string]interface{}{}
+	}
+	mergeData(p.Data, data)
+}
+
+func mergeData(dst, src map[string]interface{}) {
+	for k, v := range src {
+		sub, ok := v.(map[string]interface{})
+		if cur, isMap := dst[k].(map[string]interface{}); ok && isMap {
+			mergeData(cur, sub)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// SetVar - set the dotted path (customer.name) of the model data to
+// value, the maps on the way are created; a value which isn't a map is
+// replaced
+func (p *PrinterLine) SetVar(path string, value interface{}) {
+	if p.Data == nil {
+		p.Data = map[string]interface{}{}
+	}
+	keys := strings.Split(path, ".")
+	cur := p.Data
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := cur[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			cur[key] = next
+		}
+		cur = next
+	}
+	cur[keys[len(keys)-1]] = value
+}
+
+// builtinFuncs - template functions of every model, e.g.
+// {{qr "wifi" "ssid" .ssid "password" .password}}, see QrPayload
+var builtinFuncs = template.FuncMap{"qr": qrFunc}
+
+// renderText - execute text as text/template when it has {{ }} actions
+func renderText(text string, data map[string]interface{}, funcs template.FuncMap) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	t, err := template.New("row").Funcs(builtinFuncs).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return text, fmt.Errorf("Template %q: %s", text, err.Error())
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return text, fmt.Errorf("Template %q: %s", text, err.Error())
+	}
+	return buf.String(), nil
+}
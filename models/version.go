@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/antonholmquist/jason"
+)
+
+const (
+	// ModelVersion - current model schema version
+	ModelVersion = 2
+)
+
+// migrateV1 - version 1 header/lines/footer arrays to sections, with the
+// feeds the cli always printed after the header and the footer
+func migrateV1(res *PrinterLine, v *jason.Object) error {
+	res.Version = ModelVersion
+	for _, name := range []string{"header", "lines", "footer"} {
+		s := Section{Name: name}
+		rows, _ := v.GetObjectArray(name)
+		for _, row := range rows {
+			r, err := parseRow(row)
+			if err != nil {
+				return fmt.Errorf("%s: %s", name, err.Error())
+			}
+			s.Rows = append(s.Rows, r)
+		}
+		switch name {
+		case "header":
+			res.Header = append([]Printer(nil), s.Rows...)
+		case "lines":
+			res.Lines = append([]Printer(nil), s.Rows...)
+		case "footer":
+			res.Footer = append([]Printer(nil), s.Rows...)
+		}
+		if len(s.Rows) > 0 {
+			switch name {
+			case "header":
+				s.Feed = 1
+			case "footer":
+				s.Feed = 3
+			}
+		}
+		res.Sections = append(res.Sections, s)
+	}
+	return nil
+}
+
+// typed - rows with Type set and the version 1 flags cleared
+func typed(rows []Printer) []Printer {
+	res := make([]Printer, 0, len(rows))
+	for _, row := range rows {
+		row.Type = row.Kind()
+		row.Image, row.BarCode, row.QrCode, row.Drawer, row.FormFeed = false, false, false, false, false
+		if row.Type == "line" {
+			row.Line = false
+		}
+		if row.Type == "cut" && row.Cut == "full" {
+			row.Cut = ""
+		}
+		if (row.Type == "beep" && row.Beep == 1) || (row.Type == "feed" && row.Feed == 1) {
+			row.Beep, row.Feed = 0, 0
+		}
+		row.Rows = typed(row.Rows)
+		if len(row.Rows) == 0 {
+			row.Rows = nil
+		}
+		res = append(res, row)
+	}
+	return res
+}
+
+// Marshal - model as current version JSON
+func (p PrinterLine) Marshal() ([]byte, error) {
+	p.Version = ModelVersion
+	sections := make([]Section, len(p.Sections))
+	for i, s := range p.Sections {
+		s.Rows = typed(s.Rows)
+		sections[i] = s
+	}
+	p.Sections = sections
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// UpgradeModel - rewrite model file src in the current version format to
+// dst, includes are kept as include rows
+func UpgradeModel(src, dst string) error {
+	res, err := loadModel(src)
+	if err != nil {
+		return err
+	}
+	return SaveModel(dst, res)
+}
+
+// SaveModel - write model in the current version format to file
+func SaveModel(file string, m PrinterLine) error {
+	b, err := m.Marshal()
+	if err != nil {
+		return fmt.Errorf("Save model: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(file, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("Save model: %s", err.Error())
+	}
+	return nil
+}
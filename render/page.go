@@ -0,0 +1,149 @@
+package render
+
+import (
+	"image"
+)
+
+// pageStart - ESC L, an empty page as wide as the print head
+func (r *renderer) pageStart() {
+	r.flush(false)
+	r.pageMode = true
+	r.pg = blank(Width, PageHeight)
+	r.area = r.pg.Bounds()
+	r.dir = 0
+	r.pageH = 0
+	r.pline, r.plineW = nil, 0
+	r.pageHome()
+}
+
+// pageHome - cursor to the start of the area, the base line of the
+// first line
+func (r *renderer) pageHome() {
+	r.px = 0
+	r.py = r.cellSize().Y
+}
+
+// pageArea - ESC W x y dx dy, clipped to the page
+func (r *renderer) pageArea(c []byte) {
+	r.pageFlush()
+	x, y := word(c, 2), word(c, 4)
+	r.area = image.Rect(x, y, x+word(c, 6), y+word(c, 8)).Intersect(r.pg.Bounds())
+	r.pageHome()
+}
+
+// pageWidth - width of the area in the print direction
+func (r *renderer) pageWidth() int {
+	if r.dir == 1 || r.dir == 3 {
+		return r.area.Dy()
+	}
+	return r.area.Dx()
+}
+
+// pageLine - LF and feeds, next line of the area
+func (r *renderer) pageLine(n int) {
+	r.pageFlush()
+	r.px = 0
+	r.py += n
+}
+
+// pageAdd - draw img with its bottom on the base line, wrapping at the
+// end of the area; centered and right aligned lines wait for their end,
+// see pageFlush
+func (r *renderer) pageAdd(img *image.Gray) {
+	b := img.Bounds()
+	if r.px+r.plineW+b.Dx() > r.pageWidth() && r.px+r.plineW > 0 {
+		h := r.spacing
+		if b.Dy() > h {
+			h = b.Dy()
+		}
+		r.pageLine(h)
+	}
+	if r.align != 0 {
+		r.pline = append(r.pline, img)
+		r.plineW += b.Dx()
+		return
+	}
+	r.pageDraw(img)
+}
+
+// pageDraw - img at the position, its bottom on the base line
+func (r *renderer) pageDraw(img *image.Gray) {
+	b := img.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if img.Pix[y*img.Stride+x] == 0 {
+				r.plot(r.px+x, r.py-b.Dy()+y)
+			}
+		}
+	}
+	r.px += b.Dx()
+}
+
+// pageFlush - draw the waiting line justified in the rest of the area
+// after the position, before the position changes
+func (r *renderer) pageFlush() {
+	if len(r.pline) == 0 {
+		return
+	}
+	rest := r.pageWidth() - r.px - r.plineW
+	if r.align == 1 {
+		rest /= 2
+	}
+	if rest > 0 {
+		r.px += rest
+	}
+	for _, img := range r.pline {
+		r.pageDraw(img)
+	}
+	r.pline = nil
+	r.plineW = 0
+}
+
+// plot - black dot at x, y of the area in the print direction set by
+// ESC T, dots outside the area are dropped
+func (r *renderer) plot(x, y int) {
+	a := r.area
+	var px, py int
+	switch r.dir {
+	case 1:
+		px, py = a.Min.X+y, a.Max.Y-1-x
+	case 2:
+		px, py = a.Max.X-1-x, a.Max.Y-1-y
+	case 3:
+		px, py = a.Max.X-1-y, a.Min.Y+x
+	default:
+		px, py = a.Min.X+x, a.Min.Y+y
+	}
+	if !image.Pt(px, py).In(a) {
+		return
+	}
+	r.pg.Pix[py*r.pg.Stride+px] = 0
+	if py >= r.pageH {
+		r.pageH = py + 1
+	}
+}
+
+// pagePrint - FF and ESC FF, print the page down to its lowest dot; FF
+// goes back to standard mode, ESC FF keeps the page and its settings
+func (r *renderer) pagePrint(keep bool) {
+	r.pageFlush()
+	r.pageMode = false
+	if r.pageH > 0 {
+		r.block(r.pg.SubImage(image.Rect(0, 0, Width, r.pageH)).(*image.Gray), 0)
+	}
+	if keep {
+		r.pageMode = true
+		return
+	}
+	r.pg = nil
+}
+
+// pageClear - CAN, clear the area
+func (r *renderer) pageClear() {
+	r.pline, r.plineW = nil, 0
+	for y := r.area.Min.Y; y < r.area.Max.Y; y++ {
+		for x := r.area.Min.X; x < r.area.Max.X; x++ {
+			r.pg.Pix[y*r.pg.Stride+x] = 255
+		}
+	}
+}
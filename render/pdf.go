@@ -0,0 +1,117 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+)
+
+// paperWidth - 58 mm roll in PDF points, pages are centered on it
+const paperWidth = 58 * 72 / 25.4
+
+// points - PDF points of n dots
+func points(n int) float64 {
+	return float64(n) * 72 / DPI
+}
+
+// pdfWriter - PDF objects with their offsets for the xref table
+type pdfWriter struct {
+	bytes.Buffer
+	offsets []int
+}
+
+// object - start object n (numbered from 1 in order)
+func (w *pdfWriter) object(format string, a ...interface{}) {
+	w.offsets = append(w.offsets, w.Len())
+	fmt.Fprintf(w, "%d 0 obj\n", len(w.offsets))
+	fmt.Fprintf(w, format, a...)
+	w.WriteString("\nendobj\n")
+}
+
+// stream - object with dict entries and the data stream
+func (w *pdfWriter) stream(dict string, data []byte) {
+	w.object("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// bits - 1 bit DeviceGray rows of page, 0 black
+func bits(page *image.Gray) []byte {
+	b := page.Bounds()
+	rowBytes := (b.Dx() + 7) / 8
+	res := make([]byte, rowBytes*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if page.Pix[y*page.Stride+x] >= 128 {
+				res[y*rowBytes+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return res
+}
+
+// WritePDF - pages as a PDF, one page per receipt page on a 58 mm wide
+// roll at print head size
+func WritePDF(w io.Writer, pages []*image.Gray) error {
+	pdf := &pdfWriter{}
+	pdf.WriteString("%PDF-1.4\n")
+	// catalog 1, pages 2, then page, contents and image of every page
+	kids := ""
+	for i := range pages {
+		kids += fmt.Sprintf("%d 0 R ", 3+i*3)
+	}
+	pdf.object("<< /Type /Catalog /Pages 2 0 R >>")
+	pdf.object("<< /Type /Pages /Kids [ %s] /Count %d >>", kids, len(pages))
+	for i, page := range pages {
+		b := page.Bounds()
+		iw, ih := points(b.Dx()), points(b.Dy())
+		margin := (paperWidth - iw) / 2
+		pw, ph := paperWidth, ih+2*margin
+		n := 3 + i*3
+		pdf.object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R /Resources << /XObject << /Im%d %d 0 R >> >> >>",
+			pw, ph, n+1, i, n+2)
+		pdf.stream("", []byte(fmt.Sprintf("q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q", iw, ih, margin, margin, i)))
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(bits(page))
+		zw.Close()
+		pdf.stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 1 /Filter /FlateDecode",
+			b.Dx(), b.Dy()), z.Bytes())
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(pdf, "xref\n0 %d\n0000000000 65535 f \n", len(pdf.offsets)+1)
+	for _, o := range pdf.offsets {
+		fmt.Fprintf(pdf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pdf.offsets)+1, xref)
+	if _, err := w.Write(pdf.Bytes()); err != nil {
+		return fmt.Errorf("Write PDF: %s", err.Error())
+	}
+	return nil
+}
+
+// WritePNG - pages one under the other as a PNG, a gray line between
+// pages
+func WritePNG(w io.Writer, pages []*image.Gray) error {
+	h := 0
+	for _, p := range pages {
+		h += p.Bounds().Dy() + 1
+	}
+	img := image.NewGray(image.Rect(0, 0, Width, h))
+	y := 0
+	for _, p := range pages {
+		for py := 0; py < p.Bounds().Dy(); py++ {
+			copy(img.Pix[y*img.Stride:y*img.Stride+Width], p.Pix[py*p.Stride:py*p.Stride+Width])
+			y++
+		}
+		for x := 0; x < Width; x++ {
+			img.Pix[y*img.Stride+x] = 128
+		}
+		y++
+	}
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("Write PNG: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,769 @@
+package render
+
+import (
+	"image"
+
+	"github.com/grengojbo/gotp/escpos"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// Width - print head width in dots
+	Width = escpos.MAXIMAGEWIDTH
+	// DPI - print head resolution, 8 dots per mm
+	DPI = 203
+	// PageHeight - longest page in dots, longer receipts continue on the
+	// next page
+	PageHeight = 1600
+
+	// lineHeight - default line feed, 24 dot characters and 6 dots spacing
+	lineHeight = 30
+	// barcodeHeight - bar code height until GS h sets it (Adafruit default)
+	barcodeHeight = 50
+)
+
+// font cell sizes in dots, font A 12x24 and font B (small) 9x17
+var (
+	fontA = image.Pt(12, 24)
+	fontB = image.Pt(9, 17)
+)
+
+// renderer - printer state while the stream is replayed
+type renderer struct {
+	pages []*image.Gray
+	page  *image.Gray
+	// y - next dot row of page
+	y int
+
+	// line - characters and column images waiting for the line feed
+	line  []*image.Gray
+	lineW int
+
+	charmap   *charmap.Charmap
+	align     byte
+	small     bool
+	bold      bool
+	underline byte
+	reverse   bool
+	width     int
+	height    int
+	spacing   int
+	// charSpacing - ESC SP, dots after every character
+	charSpacing int
+	// tabs - dots of the tab stops from the left margin
+	tabs []int
+
+	barcodeHeight int
+	barcodeWidth  int
+	barcodeHRI    byte
+	qrSize        int
+	qrEcc         string
+	qrData        []byte
+	// dmSize, dmData - GS ( k DataMatrix (cn 54)
+	dmSize int
+	dmData []byte
+	// azSize, azEcc, azData - GS ( k Aztec (cn 53)
+	azSize int
+	azEcc  int
+	azData []byte
+
+	// page mode, see page.go
+	pageMode bool
+	pg       *image.Gray
+	area     image.Rectangle
+	dir      byte
+	px, py   int
+	pageH    int
+	// pline - images of a centered or right aligned line, see pageFlush
+	pline  []*image.Gray
+	plineW int
+}
+
+// Render - lay out the ESC/POS stream data the way the printer prints it,
+// the pages are split at cuts and at PageHeight.
+//
+// Text, styles, line spacing, feeds, tabs, DC2 *, GS v 0 and ESC * images,
+// QR codes, page mode and cuts are emulated. Bar codes are drawn as
+// placeholders of their size with the bar code text, they are not
+// scannable. Star line mode and commands the emulator doesn't know are
+// skipped.
+func Render(data []byte) []*image.Gray {
+	r := &renderer{}
+	r.init()
+	r.newPage()
+	for i := 0; i < len(data); {
+		l := escpos.CommandLen(data[i:])
+		if i+l > len(data) {
+			l = len(data) - i
+		}
+		r.command(data[i : i+l])
+		i += l
+	}
+	r.flush(false)
+	r.endPage()
+	return r.pages
+}
+
+// init - ESC @, settings after power on
+func (r *renderer) init() {
+	r.charmap = charmap.CodePage437
+	r.align = 0
+	r.small = false
+	r.bold = false
+	r.underline = 0
+	r.reverse = false
+	r.width = 1
+	r.height = 1
+	r.spacing = lineHeight
+	r.charSpacing = 0
+	r.tabs = []int{8 * fontA.X, 16 * fontA.X, 24 * fontA.X}
+	r.barcodeHeight = barcodeHeight
+	r.barcodeWidth = 3
+	r.barcodeHRI = 0
+	r.qrSize = 3
+	r.qrEcc = "M"
+	r.dmSize = 3
+	r.azSize = 3
+	r.azEcc = 23
+	r.pageMode = false
+	r.pg = nil
+}
+
+// param - parameter byte n of command c, 0 when it is missing
+func param(c []byte, n int) byte {
+	if n < len(c) {
+		return c[n]
+	}
+	return 0
+}
+
+// tail - bytes of command c after n
+func tail(c []byte, n int) []byte {
+	if n < len(c) {
+		return c[n:]
+	}
+	return nil
+}
+
+// word - little endian parameter of command c at n
+func word(c []byte, n int) int {
+	return int(param(c, n)) + int(param(c, n+1))*256
+}
+
+// command - apply one command (or text byte) c
+func (r *renderer) command(c []byte) {
+	if len(c) == 1 {
+		r.char(c[0])
+		return
+	}
+	switch c[0] {
+	case 27:
+		r.esc(c)
+	case 29:
+		r.gs(c)
+	case 18:
+		// DC2 * r n, raster at the left margin
+		if c[1] == '*' {
+			r.flush(false)
+			r.block(rasterImage(int(param(c, 3)), int(param(c, 2)), tail(c, 4)), 0)
+		}
+	}
+}
+
+// char - text byte or single byte control
+func (r *renderer) char(b byte) {
+	switch {
+	case b == 10 && r.pageMode:
+		r.pageLine(r.spacing)
+	case b == 10:
+		r.flush(true)
+	case b == 9:
+		r.tab()
+	case b == 12 && r.pageMode:
+		r.pagePrint(false)
+	case b == 24 && r.pageMode:
+		r.pageClear()
+	case b == 12:
+		// FF, end of page
+		r.flush(true)
+		r.cut()
+	case b == 255:
+		// Wake, the firmware takes it as a NOP
+	case b >= 32:
+		r.add(r.cell(rune(r.charmap.DecodeByte(b))))
+	}
+}
+
+// esc - ESC commands
+func (r *renderer) esc(c []byte) {
+	n := param(c, 2)
+	switch c[1] {
+	case '@':
+		r.flush(false)
+		r.init()
+	case '!':
+		r.small = n&0x01 != 0
+		r.bold = n&0x08 != 0
+		r.height = 1 + int(n>>4&1)
+		r.width = 1 + int(n>>5&1)
+		r.underline = n >> 7
+	case 'E':
+		r.bold = n&1 != 0
+	case ' ':
+		r.charSpacing = int(n)
+	case '-':
+		if n > 2 && n < 48 {
+			n = 1
+		}
+		r.underline = n % 48
+		if r.underline > 2 {
+			r.underline = 1
+		}
+	case 'M':
+		r.small = n%48 == 1
+	case 'a':
+		r.align = n % 48
+		if r.align > 2 {
+			r.align = 0
+		}
+	case '2':
+		r.spacing = lineHeight
+	case '3':
+		r.spacing = int(n)
+	case 'd':
+		if r.pageMode {
+			r.pageLine(int(n) * r.spacing)
+			return
+		}
+		r.flush(true)
+		for i := 1; i < int(n); i++ {
+			r.feed(r.spacing)
+		}
+	case 'J':
+		if r.pageMode {
+			r.pageLine(int(n))
+			return
+		}
+		r.flush(false)
+		r.feed(int(n))
+	case 't':
+		for _, cp := range escpos.CodePages {
+			if cp.Number == n {
+				r.charmap = cp.Charmap
+			}
+		}
+	case 'D':
+		// the stops are positions in the character width of the time
+		cw := r.cellSize().X + r.charSpacing*r.width
+		r.tabs = nil
+		for _, t := range c[2:] {
+			if t == 0 {
+				break
+			}
+			r.tabs = append(r.tabs, int(t)*cw)
+		}
+	case '*':
+		r.columns(c)
+	case 'L':
+		r.pageStart()
+	case 'S':
+		r.pageMode = false
+		r.pg = nil
+	case 'W':
+		if r.pageMode {
+			r.pageArea(c)
+		}
+	case 'T':
+		if r.pageMode {
+			r.pageFlush()
+			r.dir = n % 48 & 3
+			r.pageHome()
+		}
+	case '$':
+		// ESC $ nL nH, x from the left margin or the start of the area
+		if x := word(c, 2); r.pageMode {
+			r.pageFlush()
+			r.px = x
+		} else if x > r.lineW {
+			r.add(blank(x-r.lineW, 1))
+		}
+	case '\\':
+		// ESC \ nL nH, relative x, negative moves left
+		dx := int(int16(word(c, 2)))
+		if r.pageMode {
+			r.pageFlush()
+			r.px += dx
+		} else if dx > 0 {
+			r.add(blank(dx, 1))
+		}
+	case 12:
+		if r.pageMode {
+			r.pagePrint(true)
+		}
+	}
+}
+
+// gs - GS commands
+func (r *renderer) gs(c []byte) {
+	n := param(c, 2)
+	switch c[1] {
+	case '!':
+		r.width = 1 + int(n>>4&7)
+		r.height = 1 + int(n&7)
+	case 'B':
+		r.reverse = n&1 != 0
+	case '$':
+		if r.pageMode {
+			r.pageFlush()
+			r.py = word(c, 2)
+		}
+	case 'V':
+		if r.pageMode {
+			return
+		}
+		r.flush(false)
+		r.cut()
+	case 12:
+		// GS FF, next label on a new page
+		r.flush(false)
+		if r.y > 0 {
+			r.endPage()
+			r.newPage()
+		}
+	case 'v':
+		// GS v 0 m xL xH yL yH
+		if n == '0' || n == 0 {
+			r.flush(false)
+			r.block(rasterImage(word(c, 4), word(c, 6), tail(c, 8)), r.align)
+		}
+	case 'h':
+		r.barcodeHeight = int(n)
+	case 'w':
+		r.barcodeWidth = int(n)
+	case 'H':
+		r.barcodeHRI = n % 48
+	case 'k':
+		r.flush(false)
+		if n >= 65 {
+			r.barcode(tail(c, 4))
+		} else {
+			d := tail(c, 3)
+			if len(d) > 0 && d[len(d)-1] == 0 {
+				d = d[:len(d)-1]
+			}
+			r.barcode(d)
+		}
+	case '(':
+		// GS ( k cn fn, QR code model 2 (cn 49), Aztec (cn 53) and
+		// DataMatrix (cn 54)
+		if param(c, 2) != 'k' {
+			return
+		}
+		if param(c, 5) == 53 {
+			switch param(c, 6) {
+			case 66:
+				r.azSize = int(param(c, 7))
+			case 67:
+				r.azEcc = int(param(c, 7))
+			case 80:
+				r.azData = append([]byte{}, tail(c, 8)...)
+			case 81:
+				r.flush(false)
+				if a, err := escpos.AztecRaster(string(r.azData), uint8(r.azSize), azLevel(r.azEcc)); err == nil {
+					r.block(rasterImage(a.RowBytes(), a.Height, a.Data), r.align)
+				}
+			}
+			return
+		}
+		if param(c, 5) == 54 {
+			switch param(c, 6) {
+			case 66:
+				r.dmSize = int(param(c, 7))
+			case 80:
+				r.dmData = append([]byte{}, tail(c, 8)...)
+			case 81:
+				r.flush(false)
+				if d, err := escpos.DataMatrixRaster(string(r.dmData), uint8(r.dmSize)); err == nil {
+					r.block(rasterImage(d.RowBytes(), d.Height, d.Data), r.align)
+				}
+			}
+			return
+		}
+		if param(c, 5) != 49 {
+			return
+		}
+		switch param(c, 6) {
+		case 67:
+			r.qrSize = int(param(c, 7))
+		case 69:
+			if e := int(param(c, 7)) - 48; e >= 0 && e < 4 {
+				r.qrEcc = string("LMQH"[e])
+			}
+		case 80:
+			r.qrData = append([]byte{}, tail(c, 8)...)
+		case 81:
+			r.flush(false)
+			r.qr()
+		}
+	}
+}
+
+// newPage - start an empty page
+func (r *renderer) newPage() {
+	r.page = image.NewGray(image.Rect(0, 0, Width, PageHeight))
+	for i := range r.page.Pix {
+		r.page.Pix[i] = 255
+	}
+	r.y = 0
+}
+
+// endPage - keep the printed part of the page
+func (r *renderer) endPage() {
+	if r.y == 0 && len(r.pages) > 0 {
+		return
+	}
+	h := r.y
+	if h == 0 {
+		h = 1
+	}
+	r.pages = append(r.pages, r.page.SubImage(image.Rect(0, 0, Width, h)).(*image.Gray))
+}
+
+// room - make sure h dots fit on the page
+func (r *renderer) room(h int) {
+	if r.y+h > PageHeight && r.y > 0 {
+		r.endPage()
+		r.newPage()
+	}
+}
+
+// feed - advance the paper n dots
+func (r *renderer) feed(n int) {
+	r.room(n)
+	r.y += n
+	if r.y > PageHeight {
+		r.y = PageHeight
+	}
+}
+
+// cut - dashed cut line, the receipt continues on a new page
+func (r *renderer) cut() {
+	if r.y == 0 {
+		return
+	}
+	for x := 0; x < Width; x++ {
+		if x%8 < 4 {
+			r.page.Pix[(r.y-1)*r.page.Stride+x] = 0
+		}
+	}
+	r.endPage()
+	r.newPage()
+}
+
+// blit - draw the black dots of img at x, y
+func (r *renderer) blit(img *image.Gray, x, y int) {
+	b := img.Bounds()
+	for iy := 0; iy < b.Dy(); iy++ {
+		for ix := 0; ix < b.Dx() && x+ix < Width; ix++ {
+			if x+ix >= 0 && img.Pix[iy*img.Stride+ix] == 0 {
+				r.page.Pix[(y+iy)*r.page.Stride+x+ix] = 0
+			}
+		}
+	}
+}
+
+// offset - left margin of a w dots wide line with align
+func offset(w int, align byte) int {
+	switch align {
+	case 1:
+		return (Width - w) / 2
+	case 2:
+		return Width - w
+	}
+	return 0
+}
+
+// flush - print the waiting line, feed (LF) prints an empty line too
+func (r *renderer) flush(feed bool) {
+	if len(r.line) == 0 {
+		if feed {
+			r.feed(r.spacing)
+		}
+		return
+	}
+	top := 0
+	for _, img := range r.line {
+		if img.Bounds().Dy() > top {
+			top = img.Bounds().Dy()
+		}
+	}
+	h := top
+	if r.spacing > h {
+		h = r.spacing
+	}
+	r.room(top)
+	x := offset(r.lineW, r.align)
+	for _, img := range r.line {
+		// characters of a line sit on the same base line
+		r.blit(img, x, r.y+top-img.Bounds().Dy())
+		x += img.Bounds().Dx()
+	}
+	r.y += h
+	if r.y > PageHeight {
+		r.y = PageHeight
+	}
+	r.line = nil
+	r.lineW = 0
+}
+
+// add - append img to the line, wrapping at the print head width
+func (r *renderer) add(img *image.Gray) {
+	if r.pageMode {
+		r.pageAdd(img)
+		return
+	}
+	if r.lineW+img.Bounds().Dx() > Width && len(r.line) > 0 {
+		r.flush(false)
+	}
+	r.line = append(r.line, img)
+	r.lineW += img.Bounds().Dx()
+}
+
+// blank - white image
+func blank(w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// cellSize - font cell of the current style
+func (r *renderer) cellSize() image.Point {
+	f := fontA
+	if r.small {
+		f = fontB
+	}
+	return image.Pt(f.X*r.width, f.Y*r.height)
+}
+
+// tab - HT, blank up to the next tab stop
+func (r *renderer) tab() {
+	w, lw := r.lineW, Width
+	if r.pageMode {
+		w, lw = r.px, r.pageWidth()
+	}
+	for _, x := range r.tabs {
+		if x > w {
+			if x > lw {
+				break
+			}
+			r.add(blank(x-w, 1))
+			return
+		}
+	}
+}
+
+// cell - character c in the current style
+func (r *renderer) cell(c rune) *image.Gray {
+	s := r.cellSize()
+	w := s.X + r.charSpacing*r.width
+	img := blank(w, s.Y)
+	g := glyph(c)
+	// 5 x 7 glyphs in a 6 x 8 cell, box drawing fills it
+	cols, rows := 6, 8
+	if _, ok := boxGlyphs[c]; ok {
+		cols, rows = 5, 7
+	}
+	for y := 0; y < s.Y; y++ {
+		row := y * rows / s.Y
+		for x := 0; x < s.X; x++ {
+			col := x * cols / s.X
+			on := row < 7 && col < 5 && g[row]&(0x10>>uint(col)) != 0
+			if !on && r.bold && x >= r.width {
+				// emphasized characters are one dot wider
+				pc := (x - r.width) * cols / s.X
+				on = row < 7 && pc < 5 && g[row]&(0x10>>uint(pc)) != 0
+			}
+			if on {
+				img.Pix[y*img.Stride+x] = 0
+			}
+		}
+	}
+	for y := s.Y - int(r.underline); y < s.Y; y++ {
+		for x := 0; x < w; x++ {
+			img.Pix[y*img.Stride+x] = 0
+		}
+	}
+	if r.reverse {
+		for i := range img.Pix {
+			img.Pix[i] = 255 - img.Pix[i]
+		}
+	}
+	return img
+}
+
+// rasterImage - rows of rowBytes bytes, MSB left, 1 = black
+func rasterImage(rowBytes, rows int, data []byte) *image.Gray {
+	img := blank(rowBytes*8, rows)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < rowBytes*8; x++ {
+			i := y*rowBytes + x/8
+			if i < len(data) && data[i]&(0x80>>uint(x%8)) != 0 {
+				img.Pix[y*img.Stride+x] = 0
+			}
+		}
+	}
+	return img
+}
+
+// block - print img with align, in line mode it may continue on the next
+// page
+func (r *renderer) block(img *image.Gray, align byte) {
+	if r.pageMode {
+		r.pageAdd(img)
+		return
+	}
+	w := img.Bounds().Dx()
+	x := offset(w, align)
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		r.room(1)
+		for bx := 0; bx < w; bx++ {
+			if x+bx >= 0 && x+bx < Width && img.Pix[y*img.Stride+bx] == 0 {
+				r.page.Pix[r.y*r.page.Stride+x+bx] = 0
+			}
+		}
+		r.y++
+	}
+}
+
+// columns - ESC * m nL nH, columns of 8 or 24 dots placed in the line
+// like a character, single density modes are twice as wide
+func (r *renderer) columns(c []byte) {
+	m := param(c, 2)
+	n := word(c, 3)
+	dots, xs := 8, 1
+	if m >= 32 {
+		dots = 24
+	}
+	if m == 0 || m == 32 {
+		xs = 2
+	}
+	bpc := dots / 8
+	img := blank(n*xs, dots)
+	data := tail(c, 5)
+	for col := 0; col < n; col++ {
+		for y := 0; y < dots; y++ {
+			i := col*bpc + y/8
+			if i < len(data) && data[i]&(0x80>>uint(y%8)) != 0 {
+				for s := 0; s < xs; s++ {
+					img.Pix[y*img.Stride+col*xs+s] = 0
+				}
+			}
+		}
+	}
+	r.add(img)
+}
+
+// textImage - a line of small characters with 2 dots above and below
+func (r *renderer) textImage(s string) *image.Gray {
+	small, w, h, bold, u, rev := r.small, r.width, r.height, r.bold, r.underline, r.reverse
+	r.small, r.width, r.height, r.bold, r.underline, r.reverse = true, 1, 1, false, 0, false
+	n := len(s)
+	if n*fontB.X > Width {
+		n = Width / fontB.X
+	}
+	img := blank(n*fontB.X, fontB.Y+4)
+	for i, c := range []rune(s) {
+		if i >= n {
+			break
+		}
+		cell := r.cell(c)
+		for y := 0; y < fontB.Y; y++ {
+			copy(img.Pix[(y+2)*img.Stride+i*fontB.X:], cell.Pix[y*cell.Stride:y*cell.Stride+fontB.X])
+		}
+	}
+	r.small, r.width, r.height, r.bold, r.underline, r.reverse = small, w, h, bold, u, rev
+	return img
+}
+
+// stack - images one under the other, centered
+func stack(imgs ...*image.Gray) *image.Gray {
+	w, h := 0, 0
+	for _, img := range imgs {
+		if img.Bounds().Dx() > w {
+			w = img.Bounds().Dx()
+		}
+		h += img.Bounds().Dy()
+	}
+	res := blank(w, h)
+	y := 0
+	for _, img := range imgs {
+		x := (w - img.Bounds().Dx()) / 2
+		for iy := 0; iy < img.Bounds().Dy(); iy++ {
+			copy(res.Pix[(y+iy)*res.Stride+x:], img.Pix[iy*img.Stride:iy*img.Stride+img.Bounds().Dx()])
+		}
+		y += img.Bounds().Dy()
+	}
+	return res
+}
+
+// barcode - placeholder bars of data, the HRI text above and below
+func (r *renderer) barcode(data []byte) {
+	mw := r.barcodeWidth
+	if mw < 1 {
+		mw = 1
+	}
+	// start, 11 modules per character, stop
+	var bars []bool
+	bars = append(bars, true, true, false, true, false, false)
+	for _, b := range data {
+		for i := 7; i >= 0; i-- {
+			bars = append(bars, b>>uint(i)&1 != 0)
+		}
+		bars = append(bars, true, false, false)
+	}
+	bars = append(bars, true, true, false, false, true, true)
+	w := len(bars) * mw
+	if w > Width {
+		w = Width
+	}
+	img := blank(w, r.barcodeHeight)
+	for y := 0; y < r.barcodeHeight; y++ {
+		for x := 0; x < w; x++ {
+			if bars[x/mw] {
+				img.Pix[y*img.Stride+x] = 0
+			}
+		}
+	}
+	parts := []*image.Gray{img}
+	if r.barcodeHRI&1 != 0 {
+		parts = append([]*image.Gray{r.textImage(string(data))}, parts...)
+	}
+	if r.barcodeHRI&2 != 0 {
+		parts = append(parts, r.textImage(string(data)))
+	}
+	r.block(stack(parts...), r.align)
+}
+
+// azLevel - error correction level of AztecRaster closest to percent
+func azLevel(percent int) string {
+	switch {
+	case percent < 17:
+		return "L"
+	case percent < 30:
+		return "M"
+	case percent < 43:
+		return "Q"
+	}
+	return "H"
+}
+
+// qr - the symbol of the stored data
+func (r *renderer) qr() {
+	q, err := escpos.QrRaster(string(r.qrData), uint8(r.qrSize), r.qrEcc)
+	if err != nil {
+		return
+	}
+	r.block(rasterImage(q.RowBytes(), q.Height, q.Data), r.align)
+}
@@ -0,0 +1,71 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"strings"
+)
+
+// halfBlocks - characters of two pixels one above the other, by top and
+// bottom dark
+var halfBlocks = [4]string{" ", "▄", "▀", "█"}
+
+// WriteText - pages as text of half block characters columns wide (0 -
+// 96), each character two dots of a downscaled page; a dashed line
+// between pages
+func WriteText(w io.Writer, pages []*image.Gray, columns int) error {
+	if columns <= 0 {
+		columns = 96
+	}
+	if columns > Width {
+		columns = Width
+	}
+	scale := (Width + columns - 1) / columns
+	for n, p := range pages {
+		if n > 0 {
+			if _, err := fmt.Fprintln(w, strings.Repeat("- ", columns/2)); err != nil {
+				return fmt.Errorf("Write text: %s", err.Error())
+			}
+		}
+		h := p.Bounds().Dy()
+		var line strings.Builder
+		for y := 0; y < h; y += 2 * scale {
+			line.Reset()
+			for x := 0; x < columns; x++ {
+				c := 0
+				if dark(p, x*scale, y, scale) {
+					c |= 2
+				}
+				if dark(p, x*scale, y+scale, scale) {
+					c |= 1
+				}
+				line.WriteString(halfBlocks[c])
+			}
+			if _, err := fmt.Fprintln(w, strings.TrimRight(line.String(), " ")); err != nil {
+				return fmt.Errorf("Write text: %s", err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// dark - a quarter of the scale x scale dots at x, y is black, thin
+// strokes of text stay visible
+func dark(p *image.Gray, x, y, scale int) bool {
+	b := p.Bounds()
+	black, all := 0, 0
+	for dy := 0; dy < scale; dy++ {
+		for dx := 0; dx < scale; dx++ {
+			px, py := b.Min.X+x+dx, b.Min.Y+y+dy
+			if px >= b.Max.X || py >= b.Max.Y {
+				continue
+			}
+			all++
+			if p.GrayAt(px, py).Y < 128 {
+				black++
+			}
+		}
+	}
+	return black > 0 && black*4 >= all
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+	"text/template"
+
+	"github.com/grengojbo/gotp/escpos"
+	"github.com/grengojbo/gotp/models"
+)
+
+// rendered - job of a model with Cache set: the model rendered and the
+// stream it printed by printer of the pool
+type rendered struct {
+	key    string
+	model  models.PrinterLine
+	stream map[*escpos.Escpos][]byte
+}
+
+// renderCache - rendered jobs by models.PrinterLine Hash, the least
+// recently used go past Cache
+type renderCache struct {
+	mu sync.Mutex
+	l  *list.List
+	m  map[string]*list.Element
+}
+
+// get - the job of key, nil when it isn't kept
+func (c *renderCache) get(key string) *rendered {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.m[key]
+	if !ok {
+		return nil
+	}
+	c.l.MoveToFront(el)
+	return el.Value.(*rendered)
+}
+
+// put - keep the model of key rendered, at most size jobs
+func (c *renderCache) put(key string, m models.PrinterLine, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.l, c.m = list.New(), map[string]*list.Element{}
+	}
+	if el, ok := c.m[key]; ok {
+		c.l.MoveToFront(el)
+		return
+	}
+	c.m[key] = c.l.PushFront(&rendered{key: key, model: m, stream: map[*escpos.Escpos][]byte{}})
+	for c.l.Len() > size {
+		old := c.l.Back()
+		c.l.Remove(old)
+		delete(c.m, old.Value.(*rendered).key)
+	}
+}
+
+// stream - bytes the job of key printed on p, nil before it did
+func (c *renderCache) stream(key string, p *escpos.Escpos) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.m[key]; ok {
+		return el.Value.(*rendered).stream[p]
+	}
+	return nil
+}
+
+// setStream - the bytes of the job of key on p, nothing when the job went
+func (c *renderCache) setStream(key string, p *escpos.Escpos, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.m[key]; ok {
+		el.Value.(*rendered).stream[p] = data
+	}
+}
+
+// render - render the model of j, the one kept for its model and data
+// when it has Cache set
+func (s *Server) render(j *job) error {
+	if s.Cache > 0 && j.model.Cache {
+		j.key = j.model.Hash()
+		if r := s.cache.get(j.key); r != nil {
+			j.model = r.model
+			return nil
+		}
+	}
+	funcs := s.Funcs
+	if s.JobFuncs != nil {
+		var own template.FuncMap
+		own, j.finished = s.JobFuncs()
+		funcs = template.FuncMap{}
+		for name, fn := range s.Funcs {
+			funcs[name] = fn
+		}
+		for name, fn := range own {
+			funcs[name] = fn
+		}
+	}
+	if err := j.model.RenderFuncs(funcs); err != nil {
+		return err
+	}
+	if len(j.key) > 0 {
+		s.cache.put(j.key, j.model, s.Cache)
+	}
+	return nil
+}
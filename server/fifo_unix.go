@@ -0,0 +1,22 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// mkfifo - create the named pipe path of serveFifo when it is missing
+func mkfifo(path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return nil
+	}
+	return syscall.Mkfifo(path, 0660)
+}
+
+// openFifoWriter - open the pipe path for writing without waiting for
+// a reader
+func openFifoWriter(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+}
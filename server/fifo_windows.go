@@ -0,0 +1,20 @@
+package server
+
+import (
+	"errors"
+	"os"
+)
+
+// errNoFifo - Windows named pipes aren't files, fifo:// needs a unix
+var errNoFifo = errors.New("fifo:// is not supported on Windows, use host:port")
+
+// mkfifo - create the named pipe path of serveFifo when it is missing
+func mkfifo(path string) error {
+	return errNoFifo
+}
+
+// openFifoWriter - open the pipe path for writing without waiting for
+// a reader
+func openFifoWriter(path string) (*os.File, error) {
+	return nil, errNoFifo
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// idempotencyWindow - Idempotency when it is 0
+const idempotencyWindow = 10 * time.Minute
+
+// keyed - job submitted with an idempotency key
+type keyed struct {
+	// done - closed when err is the result of the job
+	done chan struct{}
+	err  error
+	at   time.Time
+}
+
+// keys - keyed jobs by idempotency key
+type keys struct {
+	mu sync.Mutex
+	m  map[string]*keyed
+}
+
+// submit - queue m and wait for it to print; a model with the key of one
+// submitted within Idempotency is not printed again, it waits for the
+// first one and gets its error with replayed set. The key of a failed
+// job is forgotten, it may be sent again
+func (s *Server) submit(m models.PrinterLine, key string) (replayed bool, err error) {
+	if len(key) == 0 {
+		return false, s.queue(m, true)
+	}
+	window := s.Idempotency
+	if window <= 0 {
+		window = idempotencyWindow
+	}
+	now := time.Now()
+	s.keys.mu.Lock()
+	if s.keys.m == nil {
+		s.keys.m = map[string]*keyed{}
+	}
+	for k, old := range s.keys.m {
+		if now.Sub(old.at) > window {
+			delete(s.keys.m, k)
+		}
+	}
+	if old, ok := s.keys.m[key]; ok {
+		s.keys.mu.Unlock()
+		<-old.done
+		return true, old.err
+	}
+	k := &keyed{done: make(chan struct{}), at: now}
+	s.keys.m[key] = k
+	s.keys.mu.Unlock()
+	k.err = s.queue(m, true)
+	close(k.done)
+	if k.err != nil {
+		s.keys.mu.Lock()
+		delete(s.keys.m, key)
+		s.keys.mu.Unlock()
+	}
+	return false, k.err
+}
+
+// queue - print m (a template to render when render) with the jobs of
+// the worker, its error
+func (s *Server) queue(m models.PrinterLine, render bool) error {
+	j := job{model: m, render: render, done: make(chan error, 1)}
+	if !s.send(j) {
+		return ErrShutdown
+	}
+	return <-j.done
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/grengojbo/gotp/models"
+)
+
+// IPP operations, status codes and tags (RFC 8010, RFC 8011)
+const (
+	ippPrintJob         = 0x0002
+	ippValidateJob      = 0x0004
+	ippGetJobs          = 0x000A
+	ippGetPrinterAttrs  = 0x000B
+	ippOK               = 0x0000
+	ippBadRequest       = 0x0400
+	ippFormatNotSupport = 0x040A
+	ippNotSupported     = 0x0501
+	ippInternalError    = 0x0500
+
+	tagOperation = 0x01
+	tagJob       = 0x02
+	tagEnd       = 0x03
+	tagPrinter   = 0x04
+	tagInteger   = 0x21
+	tagBoolean   = 0x22
+	tagEnum      = 0x23
+	tagText      = 0x41
+	tagName      = 0x42
+	tagKeyword   = 0x44
+	tagURI       = 0x45
+	tagCharset   = 0x47
+	tagLanguage  = 0x48
+	tagMimeType  = 0x49
+)
+
+// ippFormats - document formats of Print-Job
+var ippFormats = []string{"text/plain", "image/png", "image/jpeg", "image/gif", "application/octet-stream"}
+
+// ippRequest - operation and operation attributes of an IPP request,
+// the document follows in body
+type ippRequest struct {
+	version [2]byte
+	op      uint16
+	id      uint32
+	attrs   map[string]string
+	body    io.Reader
+}
+
+// parseIPP - read the IPP header and attribute groups of r
+func parseIPP(r io.Reader) (req ippRequest, err error) {
+	br := bufio.NewReader(r)
+	var head [8]byte
+	if _, err = io.ReadFull(br, head[:]); err != nil {
+		return req, fmt.Errorf("IPP: %s", err.Error())
+	}
+	copy(req.version[:], head[:2])
+	req.op = binary.BigEndian.Uint16(head[2:4])
+	req.id = binary.BigEndian.Uint32(head[4:8])
+	req.attrs = map[string]string{}
+	group, name := byte(0), ""
+	for {
+		tag, err := br.ReadByte()
+		if err != nil {
+			return req, fmt.Errorf("IPP: %s", err.Error())
+		}
+		if tag == tagEnd {
+			break
+		}
+		if tag < 0x10 {
+			group = tag
+			continue
+		}
+		n, err := readIPPString(br)
+		if err != nil {
+			return req, err
+		}
+		v, err := readIPPString(br)
+		if err != nil {
+			return req, err
+		}
+		// additional values have no name, keep the first one
+		if len(n) > 0 {
+			name = n
+			if group == tagOperation {
+				req.attrs[name] = v
+			}
+		}
+	}
+	req.body = br
+	return req, nil
+}
+
+// readIPPString - 2 byte length and value
+func readIPPString(r io.Reader) (string, error) {
+	var l uint16
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return "", fmt.Errorf("IPP: %s", err.Error())
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", fmt.Errorf("IPP: %s", err.Error())
+	}
+	return string(b), nil
+}
+
+// ippResponse - IPP response encoder
+type ippResponse struct {
+	bytes.Buffer
+}
+
+// newIPPResponse - response to req with status and the operation group
+func newIPPResponse(req ippRequest, status uint16) *ippResponse {
+	w := &ippResponse{}
+	w.Write([]byte{1, 1})
+	binary.Write(w, binary.BigEndian, status)
+	binary.Write(w, binary.BigEndian, req.id)
+	w.WriteByte(tagOperation)
+	w.str(tagCharset, "attributes-charset", "utf-8")
+	w.str(tagLanguage, "attributes-natural-language", "en")
+	return w
+}
+
+// attr - attribute (an additional value when name is empty)
+func (w *ippResponse) attr(tag byte, name string, value []byte) {
+	w.WriteByte(tag)
+	binary.Write(w, binary.BigEndian, uint16(len(name)))
+	w.WriteString(name)
+	binary.Write(w, binary.BigEndian, uint16(len(value)))
+	w.Write(value)
+}
+
+func (w *ippResponse) str(tag byte, name string, values ...string) {
+	for i, v := range values {
+		if i > 0 {
+			name = ""
+		}
+		w.attr(tag, name, []byte(v))
+	}
+}
+
+func (w *ippResponse) integer(tag byte, name string, values ...int32) {
+	for i, v := range values {
+		if i > 0 {
+			name = ""
+		}
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(v))
+		w.attr(tag, name, b)
+	}
+}
+
+// printerAttrs - printer description of Get-Printer-Attributes
+func (s *Server) printerAttrs(w *ippResponse, uri string) {
+	w.WriteByte(tagPrinter)
+	w.str(tagURI, "printer-uri-supported", uri)
+	w.str(tagKeyword, "uri-security-supported", "none")
+	w.str(tagKeyword, "uri-authentication-supported", "none")
+	w.str(tagName, "printer-name", "gotp")
+	w.str(tagText, "printer-make-and-model", "gotp ESC/POS thermal printer")
+	w.integer(tagEnum, "printer-state", 3)
+	w.str(tagKeyword, "printer-state-reasons", "none")
+	w.attr(tagBoolean, "printer-is-accepting-jobs", []byte{1})
+	w.str(tagKeyword, "ipp-versions-supported", "1.1")
+	w.integer(tagEnum, "operations-supported", ippPrintJob, ippValidateJob, ippGetJobs, ippGetPrinterAttrs)
+	w.str(tagCharset, "charset-configured", "utf-8")
+	w.str(tagCharset, "charset-supported", "utf-8")
+	w.str(tagLanguage, "natural-language-configured", "en")
+	w.str(tagLanguage, "generated-natural-language-supported", "en")
+	w.str(tagMimeType, "document-format-default", "text/plain")
+	w.str(tagMimeType, "document-format-supported", ippFormats...)
+	w.str(tagKeyword, "compression-supported", "none")
+	w.str(tagKeyword, "pdl-override-supported", "not-attempted")
+	w.integer(tagInteger, "queued-job-count", 0)
+}
+
+// ippModel - model printing document of format
+func ippModel(format string, doc []byte) (m models.PrinterLine, err error) {
+	if format == "application/octet-stream" || len(format) == 0 {
+		format = http.DetectContentType(doc)
+	}
+	switch {
+	case strings.HasPrefix(format, "text/plain"):
+		text := strings.Replace(string(doc), "\r\n", "\n", -1)
+		return models.TextModel(strings.Split(strings.TrimRight(text, "\n"), "\n"), "left"), nil
+	case format == "image/png" || format == "image/jpeg" || format == "image/gif":
+		row := models.Printer{Image: true, Data: base64.StdEncoding.EncodeToString(doc), Dither: "floyd", Align: "center"}
+		return models.PrinterLine{
+			Version:  models.ModelVersion,
+			Sections: []models.Section{{Name: "lines", Feed: 3, Rows: []models.Printer{row}}},
+		}, nil
+	}
+	return m, fmt.Errorf("IPP: document format %s not supported", format)
+}
+
+// ipp - POST /ipp/print, Print-Job of text and image documents
+func (s *Server) ipp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "IPP requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := parseIPP(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	uri := "ipp://" + r.Host + "/ipp/print"
+	var res *ippResponse
+	switch req.op {
+	case ippGetPrinterAttrs:
+		res = newIPPResponse(req, ippOK)
+		s.printerAttrs(res, uri)
+	case ippGetJobs:
+		res = newIPPResponse(req, ippOK)
+	case ippValidateJob, ippPrintJob:
+		format := req.attrs["document-format"]
+		doc, err := ioutil.ReadAll(req.body)
+		if err != nil {
+			res = newIPPResponse(req, ippBadRequest)
+			break
+		}
+		m, err := ippModel(format, doc)
+		if err == nil && req.op == ippPrintJob {
+			// images are decoded by the worker, report their errors too
+			if err = s.queue(m, false); err != nil {
+				res = newIPPResponse(req, ippInternalError)
+				res.str(tagText, "status-message", err.Error())
+				break
+			}
+		} else if err != nil {
+			res = newIPPResponse(req, ippFormatNotSupport)
+			res.str(tagText, "status-message", err.Error())
+			break
+		}
+		res = newIPPResponse(req, ippOK)
+		if req.op == ippPrintJob {
+			id := atomic.AddInt32(&s.ippJob, 1)
+			res.WriteByte(tagJob)
+			res.integer(tagInteger, "job-id", id)
+			res.str(tagURI, "job-uri", fmt.Sprintf("%s/%d", uri, id))
+			// completed
+			res.integer(tagEnum, "job-state", 9)
+			res.str(tagKeyword, "job-state-reasons", "job-completed-successfully")
+		}
+	default:
+		res = newIPPResponse(req, ippNotSupported)
+	}
+	res.WriteByte(tagEnd)
+	w.Header().Set("Content-Type", "application/ipp")
+	w.Write(res.Bytes())
+}
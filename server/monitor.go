@@ -0,0 +1,62 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grengojbo/gotp/escpos"
+)
+
+// recentErrors - failed jobs GET /status lists
+const recentErrors = 10
+
+// jobError - failed job of GET /status
+type jobError struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// errorLog - last recentErrors failed jobs, the newest first
+type errorLog struct {
+	mu   sync.Mutex
+	list []jobError
+}
+
+// logError - keep err of a failed job for GET /status
+func (s *Server) logError(err error) {
+	s.failures.mu.Lock()
+	defer s.failures.mu.Unlock()
+	s.failures.list = append([]jobError{{Time: time.Now(), Error: err.Error()}}, s.failures.list...)
+	if len(s.failures.list) > recentErrors {
+		s.failures.list = s.failures.list[:recentErrors]
+	}
+}
+
+// recent - copy of the error log
+func (s *Server) recent() []jobError {
+	s.failures.mu.Lock()
+	defer s.failures.mu.Unlock()
+	return append([]jobError(nil), s.failures.list...)
+}
+
+// unitState - last status read from the printer of a unit
+type unitState struct {
+	mu     sync.Mutex
+	status *escpos.Status
+	err    string
+}
+
+// pollStatus - read the status of the idle printer of u for GET /status,
+// only the worker of u talks to the printer
+func (s *Server) pollStatus(u *unit) {
+	st, err := u.p.Status()
+	u.state.mu.Lock()
+	defer u.state.mu.Unlock()
+	if err != nil {
+		u.state.status = nil
+		u.state.err = err.Error()
+		return
+	}
+	u.state.status = &st
+	u.state.err = ""
+}
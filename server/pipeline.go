@@ -0,0 +1,66 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/grengojbo/gotp/escpos"
+)
+
+// pipelineDepth - jobs a printer of the pool takes at a time: one
+// streaming to the port, the next one rendered meanwhile
+const pipelineDepth = 2
+
+// snapshot - settings of the printer of a unit between its jobs, the
+// renderer of the unit renders the next job with them
+type snapshot struct {
+	mu sync.Mutex
+	e  *escpos.Escpos
+}
+
+// get - the last snapshot
+func (sn *snapshot) get() *escpos.Escpos {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	return sn.e
+}
+
+// take - snapshot of p, only by the goroutine printing on it
+func (sn *snapshot) take(p *escpos.Escpos) {
+	e := p.Snapshot()
+	sn.mu.Lock()
+	sn.e = e
+	sn.mu.Unlock()
+}
+
+// renderer - first stage of the pipeline of u: the stream of every job
+// for its printer, rendered while the worker streams the job before; the
+// jobs left after the dispatcher stopped are kept
+func (s *Server) renderer(u *unit) {
+	defer s.workers.Done()
+	defer close(u.ready)
+	for j := range u.jobs {
+		select {
+		case <-s.dispatched:
+			s.keep(j)
+			continue
+		default:
+		}
+		j.stream = s.jobStream(u, &j)
+		u.ready <- j
+	}
+}
+
+// jobStream - bytes of the model of j on the printer of u, the ones of
+// the cache when the job printed there before
+func (s *Server) jobStream(u *unit, j *job) []byte {
+	if len(j.key) > 0 {
+		if data := s.cache.stream(j.key, u.p); data != nil {
+			return data
+		}
+	}
+	data := u.snap.get().ModelBytes(&j.model)
+	if len(j.key) > 0 {
+		s.cache.setStream(j.key, u.p, data)
+	}
+	return data
+}
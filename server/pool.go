@@ -0,0 +1,208 @@
+package server
+
+import (
+	"container/heap"
+	"errors"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/grengojbo/gotp/escpos"
+)
+
+// failoverTime - Failover when it is 0
+const failoverTime = 30 * time.Second
+
+// unit - printer of the pool with its worker
+type unit struct {
+	p *escpos.Escpos
+	// jobs - to render, ready - rendered, see renderer
+	jobs, ready chan job
+	// snap - settings of p the jobs are rendered with
+	snap snapshot
+	// busy - jobs of the unit rendering or printing, see pipelineDepth
+	busy int32
+	// printing - number of the job being printed, 0 - none
+	printing int32
+	// down - unix nanoseconds until which the unit gets no jobs
+	down int64
+	// state - status of the printer, see StatusInterval
+	state unitState
+}
+
+// NewPool - server spreading its jobs over the printers ps, see Balance
+func NewPool(ps ...*escpos.Escpos) *Server {
+	s := New(ps[0])
+	s.Pool = ps
+	return s
+}
+
+// start - a worker for every printer of the pool, the dispatcher and
+// the scheduler, then queue the jobs of the Spool
+func (s *Server) start() {
+	s.mu.Lock()
+	s.started = true
+	s.mu.Unlock()
+	if len(s.Pool) == 0 {
+		s.Pool = []*escpos.Escpos{s.Printer}
+	}
+	s.free = make(chan struct{}, 1)
+	for _, p := range s.Pool {
+		u := &unit{p: p, jobs: make(chan job, 1), ready: make(chan job)}
+		u.snap.take(p)
+		s.units = append(s.units, u)
+		s.workers.Add(2)
+		go s.renderer(u)
+		go s.worker(u)
+	}
+	go s.dispatch()
+	go s.scheduler()
+	s.restore()
+}
+
+// queue - jobs waiting for a printer, by priority of the model then in
+// the order they came
+type queue []job
+
+func (q queue) Len() int { return len(q) }
+func (q queue) Less(i, j int) bool {
+	if q[i].model.Priority != q[j].model.Priority {
+		return q[i].model.Priority > q[j].model.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(job)) }
+func (q *queue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// dispatch - render the jobs and hand them to idle printers, the highest
+// priority first, or to a printing one to render its next job; a job
+// printing is never interrupted. On Shutdown the waiting jobs are kept
+func (s *Server) dispatch() {
+	defer func() {
+		close(s.dispatched)
+		// the renderers keep the jobs they have
+		for _, u := range s.units {
+			close(u.jobs)
+		}
+	}()
+	var waiting queue
+	var seq int64
+	for {
+		atomic.StoreInt32(&s.queued, int32(len(waiting)))
+		select {
+		case <-s.stop:
+			for len(waiting) > 0 {
+				s.keep(heap.Pop(&waiting).(job))
+			}
+			return
+		default:
+		}
+		if len(waiting) > 0 {
+			if u := s.pick(); u != nil {
+				j := heap.Pop(&waiting).(job)
+				atomic.AddInt32(&u.busy, 1)
+				u.jobs <- j
+				continue
+			}
+		}
+		select {
+		case j := <-s.jobs:
+			if j.render {
+				if err := s.render(&j); err != nil {
+					s.finish(j, err)
+					continue
+				}
+				j.render = false
+			}
+			// requeued jobs keep their place
+			if j.seq == 0 {
+				seq++
+				j.seq = seq
+			}
+			heap.Push(&waiting, j)
+		case <-s.free:
+		case <-s.stop:
+		}
+	}
+}
+
+// pick - printer for the next job, nil when there is none: the next one
+// in turn (round-robin) or any idle one after the last picked, without
+// one a printing one with no job rendering (least-busy); failed printers
+// are skipped while another one is up
+func (s *Server) pick() *unit {
+	now := time.Now().UnixNano()
+	up := 0
+	for _, u := range s.units {
+		if atomic.LoadInt64(&u.down) <= now {
+			up++
+		}
+	}
+	n := len(s.units)
+	for depth := int32(1); depth <= pipelineDepth; depth++ {
+		for i := 0; i < n; i++ {
+			u := s.units[(s.next+i)%n]
+			if up > 0 && atomic.LoadInt64(&u.down) > now {
+				continue
+			}
+			busy := atomic.LoadInt32(&u.busy)
+			if s.Balance == "round-robin" && busy >= pipelineDepth {
+				return nil
+			}
+			if s.Balance != "round-robin" && busy >= depth {
+				continue
+			}
+			s.next = (s.next + i + 1) % n
+			return u
+		}
+	}
+	return nil
+}
+
+// down - u failed a job lately and another printer is up to take its
+// jobs
+func (s *Server) down(u *unit) bool {
+	now := time.Now().UnixNano()
+	if atomic.LoadInt64(&u.down) <= now {
+		return false
+	}
+	for _, o := range s.units {
+		if o != u && atomic.LoadInt64(&o.down) <= now {
+			return true
+		}
+	}
+	return false
+}
+
+// fail - keep jobs off u for Failover, true when another printer is up
+// to take its job
+func (s *Server) fail(u *unit) bool {
+	wait := s.Failover
+	if wait <= 0 {
+		wait = failoverTime
+	}
+	now := time.Now()
+	atomic.StoreInt64(&u.down, now.Add(wait).UnixNano())
+	for _, o := range s.units {
+		if o != u && atomic.LoadInt64(&o.down) <= now.UnixNano() {
+			return true
+		}
+	}
+	return false
+}
+
+// failed - err is one of the printer (port, paper, timeout) and not of
+// the job, another printer may print it
+func failed(err error) bool {
+	var pe *os.PathError
+	var ne net.Error
+	return errors.Is(err, escpos.ErrNoDevice) || errors.Is(err, escpos.ErrTimeout) ||
+		errors.Is(err, escpos.ErrPaperOut) || errors.As(err, &pe) || errors.As(err, &ne)
+}
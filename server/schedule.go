@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grengojbo/gotp/fetch"
+	"github.com/grengojbo/gotp/models"
+)
+
+// Schedule - model template printed on a cron expression
+type Schedule struct {
+	Name string `json:"name"`
+	// Cron - "minute hour day month weekday" ("0 23 * * *", "*/15 9-17 *
+	// * 1-5") or @hourly, @daily, @weekly, @monthly, @yearly
+	Cron string `json:"cron"`
+	// Model - model file or http(s) URL, a file is relative to Dir of the
+	// server (to the config file of LoadSchedule)
+	Model string `json:"model"`
+	// Disabled - not printed until enabled with POST /schedule
+	Disabled bool `json:"disabled,omitempty"`
+	// Next - next time it prints, set by GET /schedule
+	Next *time.Time `json:"next,omitempty"`
+
+	cron *cron
+}
+
+// schedules - Schedules of the server and the lock of their Disabled
+type schedules struct {
+	mu   sync.Mutex
+	list []*Schedule
+}
+
+// LoadSchedule - the "schedule" list of the JSON config file path (see
+// escpos.LoadConfig):
+//
+//	{"schedule": [
+//	  {"name": "z-header", "cron": "0 23 * * *", "model": "zreport.json"},
+//	  {"name": "heartbeat", "cron": "@hourly", "model": "heartbeat.json", "disabled": true}
+//	]}
+func LoadSchedule(path string) ([]*Schedule, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg struct {
+		Schedule []*Schedule `json:"schedule"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	for _, sc := range cfg.Schedule {
+		if !filepath.IsAbs(sc.Model) && !fetch.IsURL(sc.Model) {
+			sc.Model = filepath.Join(filepath.Dir(path), sc.Model)
+		}
+		if sc.cron, err = parseCron(sc.Cron); err != nil {
+			return nil, fmt.Errorf("Schedule %s: %s", sc.Name, err)
+		}
+	}
+	return cfg.Schedule, nil
+}
+
+// AddSchedule - print sc on its cron expression once the server runs
+func (s *Server) AddSchedule(sc *Schedule) error {
+	c, err := parseCron(sc.Cron)
+	if err != nil {
+		return fmt.Errorf("Schedule %s: %s", sc.Name, err)
+	}
+	sc.cron = c
+	s.schedules.mu.Lock()
+	defer s.schedules.mu.Unlock()
+	s.schedules.list = append(s.schedules.list, sc)
+	return nil
+}
+
+// scheduler - queue the scheduled models at the start of every minute
+// until Shutdown
+func (s *Server) scheduler() {
+	for {
+		now := time.Now()
+		t := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-time.After(t.Sub(now)):
+		case <-s.stop:
+			return
+		}
+		s.schedules.mu.Lock()
+		var due []*Schedule
+		for _, sc := range s.schedules.list {
+			if !sc.Disabled && sc.cron.match(t) {
+				due = append(due, sc)
+			}
+		}
+		s.schedules.mu.Unlock()
+		for _, sc := range due {
+			go s.printSchedule(sc)
+		}
+	}
+}
+
+// printSchedule - queue the model of sc as a job, Printed gets its errors
+func (s *Server) printSchedule(sc *Schedule) {
+	path := sc.Model
+	if !filepath.IsAbs(path) && !fetch.IsURL(path) {
+		path = filepath.Join(s.Dir, path)
+	}
+	m, err := models.LoadPrintModel(path)
+	if err != nil {
+		if s.Printed != nil {
+			s.Printed(m, fmt.Errorf("Schedule %s: %s", sc.Name, err))
+		}
+		return
+	}
+	s.queue(m, true)
+}
+
+// schedule - GET /schedule lists the schedule with the next times, POST
+// /schedule?name=<name>&enabled=true|false enables or disables one
+func (s *Server) schedule(w http.ResponseWriter, r *http.Request) {
+	s.schedules.mu.Lock()
+	defer s.schedules.mu.Unlock()
+	switch r.Method {
+	case "GET":
+		res := make([]Schedule, 0, len(s.schedules.list))
+		now := time.Now()
+		for _, sc := range s.schedules.list {
+			item := *sc
+			if next := sc.cron.next(now); !sc.Disabled && !next.IsZero() {
+				item.Next = &next
+			}
+			res = append(res, item)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	case "POST":
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			reply(w, http.StatusBadRequest, fmt.Errorf("Invalid enabled: %s", r.FormValue("enabled")))
+			return
+		}
+		for _, sc := range s.schedules.list {
+			if sc.Name == r.FormValue("name") {
+				sc.Disabled = !enabled
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(sc)
+				return
+			}
+		}
+		reply(w, http.StatusNotFound, fmt.Errorf("No schedule %s", r.FormValue("name")))
+	default:
+		reply(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+	}
+}
@@ -0,0 +1,488 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/grengojbo/gotp/escpos"
+	"github.com/grengojbo/gotp/models"
+)
+
+// Server - HTTP print server, jobs are printed one at a time by a
+// worker which owns the printer, one worker for every printer of Pool
+type Server struct {
+	Printer *escpos.Escpos
+	// Pool - printers sharing the jobs, New makes it Printer alone
+	Pool []*escpos.Escpos
+	// Balance - printer of the pool a job goes to: "least-busy" (default),
+	// any idle one, or "round-robin", the next one in turn
+	Balance string
+	// Failover - a printer of the pool which failed a job gets no jobs for
+	// this long, the job prints on another one (0 - 30s)
+	Failover time.Duration
+	// Dir - directory includes of posted models are resolved against
+	Dir string
+	// Funcs - template functions of the model
+	Funcs template.FuncMap
+	// JobFuncs - template functions of one job over Funcs, called when
+	// the job renders, e.g. a {{seq}} keeping the numbers of the job;
+	// done gets the error the job finished with
+	JobFuncs func() (funcs template.FuncMap, done func(err error))
+	// IdleSleep - put the printer to sleep after this long without jobs,
+	// the next job wakes it up (0 - never)
+	IdleSleep time.Duration
+	// Printed - called by the worker after every job
+	Printed func(m models.PrinterLine, err error)
+	// Timeout - deadline of a job, a job still printing after it fails
+	// with escpos.ErrTimeout (0 - none)
+	Timeout time.Duration
+	// Requeue - times a timed out job goes back to the queue before it
+	// fails
+	Requeue int
+	// Idempotency - window of idempotency keys: a job with the key of one
+	// submitted this long ago or less isn't printed again (0 - 10 minutes)
+	Idempotency time.Duration
+	// Spool - directory Shutdown saves the queued jobs to, the next start
+	// prints them (empty - they are dropped)
+	Spool string
+	// StatusInterval - read the status of the idle printers this often
+	// for GET /status (0 - never)
+	StatusInterval time.Duration
+	// Cache - jobs of models with Cache set kept rendered, a job of the
+	// same model and data prints the stream of the first one (0 - none)
+	Cache int
+
+	jobs  chan job
+	units []*unit
+	// free - a printer of the pool finished its job
+	free chan struct{}
+	// cache - see Cache
+	cache renderCache
+	// schedules - see AddSchedule
+	schedules schedules
+	// failures - recent failed jobs, see logError
+	failures errorLog
+	// queued - jobs waiting for a printer
+	queued int32
+	// keys - jobs by idempotency key, see submit
+	keys keys
+	// next - unit after the last one picked
+	next int
+	// ippJob - last IPP job id
+	ippJob int32
+	// jobNumber - number of the last job started
+	jobNumber int32
+
+	// stop - closed by Shutdown, dispatched - closed when the dispatcher
+	// stopped handing out jobs, fifoDone - when serveFifo returned, done -
+	// when Shutdown is done
+	stop, dispatched, fifoDone, done chan struct{}
+	stopOnce, doneOnce               sync.Once
+	workers, requeues                sync.WaitGroup
+	// mu - guards http, fifo, started and kept, the jobs for the Spool
+	mu      sync.Mutex
+	http    *http.Server
+	fifo    string
+	started bool
+	kept    []models.PrinterLine
+}
+
+// job - model waiting for the worker
+type job struct {
+	model models.PrinterLine
+	// render - model is a template to render before printing
+	render bool
+	done   chan error
+	// tries - times the job timed out, moves - times it went to another
+	// printer of the pool
+	tries int
+	moves int
+	// seq - order the job was queued in
+	seq int64
+	// key - Hash of the model in the cache, empty when it isn't kept
+	key string
+	// stream - the model rendered for the printer of the unit it went to
+	stream []byte
+	// finished - done of the JobFuncs the job rendered with
+	finished func(err error)
+}
+
+// New - server printing on p
+func New(p *escpos.Escpos) *Server {
+	return &Server{Printer: p, Dir: ".", jobs: make(chan job),
+		stop: make(chan struct{}), dispatched: make(chan struct{}),
+		fifoDone: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Handler - HTTP routes of the server, POST /print prints the model in
+// the request body, GET /status is the progress of the job printing,
+// /schedule lists and enables the recurring jobs, /ipp/print is an IPP
+// printer for text and images
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/print", s.print)
+	mux.HandleFunc("/status", s.status)
+	mux.HandleFunc("/schedule", s.schedule)
+	mux.HandleFunc("/ipp/print", s.ipp)
+	return mux
+}
+
+// ListenAndServe - start the worker and serve on addr: "host:port" is
+// HTTP over TCP, "unix:///run/gotp.sock" HTTP over a unix socket,
+// "systemd://" (or "systemd://<FileDescriptorName>") HTTP over the socket
+// of socket activation and "fifo:///run/gotp.fifo" reads one model per
+// write to a named pipe. Under systemd it notifies READY=1 once it
+// listens and keeps the WatchdogSec= watchdog alive. After Shutdown it
+// returns nil once the shutdown is done
+func (s *Server) ListenAndServe(addr string) error {
+	s.start()
+	if strings.HasPrefix(addr, "fifo://") {
+		path := strings.TrimPrefix(addr, "fifo://")
+		s.mu.Lock()
+		s.fifo = path
+		s.mu.Unlock()
+		err := s.serveFifo(path)
+		close(s.fifoDone)
+		if err == nil {
+			<-s.done
+		}
+		return err
+	}
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	s.mu.Lock()
+	s.http = srv
+	s.mu.Unlock()
+	var l net.Listener
+	var err error
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		l, err = listenUnix(strings.TrimPrefix(addr, "unix://"))
+	case strings.HasPrefix(addr, "systemd://"):
+		l, err = listenSystemd(strings.TrimPrefix(addr, "systemd://"))
+	default:
+		if l, err = net.Listen("tcp", addr); err != nil {
+			err = fmt.Errorf("Listen: %s", err.Error())
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	s.ready()
+	err = srv.Serve(l)
+	if err == http.ErrServerClosed {
+		<-s.done
+		return nil
+	}
+	return err
+}
+
+// listenUnix - listen on socket path, a stale socket file is removed and
+// the socket is readable and writable by owner and group
+func listenUnix(path string) (net.Listener, error) {
+	if st, err := os.Stat(path); err == nil && st.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("Listen: %s", err.Error())
+	}
+	if err := os.Chmod(path, 0660); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("Listen: %s", err.Error())
+	}
+	return l, nil
+}
+
+// serveFifo - print models written to the named pipe path, it is created
+// when missing; nil after Shutdown
+func (s *Server) serveFifo(path string) error {
+	if err := mkfifo(path); err != nil {
+		return fmt.Errorf("Listen: %s", err.Error())
+	}
+	s.ready()
+	for {
+		// open blocks until a writer opens the pipe, read ends when it closes
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("Listen: %s", err.Error())
+		}
+		select {
+		case <-s.stop:
+			f.Close()
+			return nil
+		default:
+		}
+		m, err := models.ReadPrintModel(f, s.Dir)
+		f.Close()
+		if err != nil {
+			if s.Printed != nil {
+				s.Printed(m, err)
+			}
+			continue
+		}
+		s.submit(m, m.IdempotencyKey)
+	}
+}
+
+// stopFifo - open the pipe path for writing until serveFifo returns from
+// its open, sees the shutdown and stops
+func (s *Server) stopFifo(ctx context.Context, path string) {
+	for {
+		if f, err := openFifoWriter(path); err == nil {
+			f.Close()
+		}
+		select {
+		case <-s.fifoDone:
+			return
+		case <-ctx.Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// worker - second stage of the pipeline of u: stream the rendered jobs
+// to its printer, sleep it when idle; after the dispatcher stopped the
+// jobs rendered are kept
+func (s *Server) worker(u *unit) {
+	defer s.workers.Done()
+	asleep := false
+	var idle <-chan time.Time
+	var poll <-chan time.Time
+	if s.StatusInterval > 0 {
+		t := time.NewTicker(s.StatusInterval)
+		defer t.Stop()
+		poll = t.C
+		s.pollStatus(u)
+	}
+	for {
+		if s.IdleSleep > 0 && !asleep {
+			idle = time.After(s.IdleSleep)
+		}
+		select {
+		case j, ok := <-u.ready:
+			if !ok {
+				return
+			}
+			select {
+			case <-s.dispatched:
+				s.keep(j)
+				continue
+			default:
+			}
+			if s.down(u) {
+				// rendered before the printer failed the job before, the
+				// stream is of this printer
+				s.release(u)
+				j.stream = nil
+				s.requeue(j, 0)
+				continue
+			}
+			if asleep {
+				u.p.Wake()
+				asleep = false
+			}
+			err := s.printJob(u, &j)
+			u.snap.take(u.p)
+			s.release(u)
+			j.stream = nil
+			if failed(err) && j.moves < len(s.units)-1 && s.fail(u) {
+				j.moves++
+				s.requeue(j, 0)
+				continue
+			}
+			if errors.Is(err, escpos.ErrTimeout) && j.tries < s.Requeue {
+				// back in the queue after another timeout, the port may
+				// be unblocked by then
+				j.tries++
+				s.requeue(j, s.Timeout)
+				continue
+			}
+			s.finish(j, err)
+		case <-idle:
+			u.p.Sleep()
+			asleep = true
+			idle = nil
+		case <-poll:
+			// a sleeping printer is woken by the next job only
+			if !asleep {
+				s.pollStatus(u)
+			}
+		}
+	}
+}
+
+// release - u is done with a job, the dispatcher may hand it another
+func (s *Server) release(u *unit) {
+	atomic.AddInt32(&u.busy, -1)
+	select {
+	case s.free <- struct{}{}:
+	default:
+	}
+}
+
+// requeue - queue j again after wait, it is kept when the server shuts
+// down first
+func (s *Server) requeue(j job, wait time.Duration) {
+	s.requeues.Add(1)
+	go func() {
+		defer s.requeues.Done()
+		select {
+		case <-time.After(wait):
+		case <-s.stop:
+			s.keep(j)
+			return
+		}
+		if !s.send(j) {
+			s.keep(j)
+		}
+	}()
+}
+
+// finish - report the job printed or failed
+func (s *Server) finish(j job, err error) {
+	if err != nil {
+		s.logError(err)
+	}
+	if j.finished != nil {
+		j.finished(err)
+	}
+	if s.Printed != nil {
+		s.Printed(j.model, err)
+	}
+	j.done <- err
+}
+
+// printJob - stream the job rendered for the printer of u to it
+func (s *Server) printJob(u *unit, j *job) error {
+	p := u.p
+	p.ClearErr()
+	p.BeginJob(int64(len(j.stream)))
+	atomic.StoreInt32(&u.printing, atomic.AddInt32(&s.jobNumber, 1))
+	defer atomic.StoreInt32(&u.printing, 0)
+	if s.Timeout > 0 {
+		p.SetDeadline(time.Now().Add(s.Timeout))
+		defer p.SetDeadline(time.Time{})
+	}
+	return p.RunJob("print", escpos.PrintFunc(func(e *escpos.Escpos) error {
+		e.WriteStream(j.stream)
+		return e.Err()
+	}))
+}
+
+// print - POST /print
+func (s *Server) print(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		reply(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+		return
+	}
+	m, err := models.ReadPrintModel(r.Body, s.Dir)
+	if err != nil {
+		reply(w, http.StatusBadRequest, err)
+		return
+	}
+	key := r.Header.Get("Idempotency-Key")
+	if len(key) == 0 {
+		key = m.IdempotencyKey
+	}
+	replayed, err := s.submit(m, key)
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	if errors.Is(err, escpos.ErrTimeout) {
+		reply(w, http.StatusGatewayTimeout, err)
+		return
+	} else if errors.Is(err, ErrShutdown) {
+		reply(w, http.StatusServiceUnavailable, err)
+		return
+	} else if err != nil {
+		reply(w, http.StatusInternalServerError, err)
+		return
+	}
+	reply(w, http.StatusOK, nil)
+}
+
+// jobStatus - reply of GET /status
+type jobStatus struct {
+	Printing bool `json:"printing"`
+	// Job - number of the job since the server started
+	Job int32 `json:"job,omitempty"`
+	escpos.Progress
+	Percent int `json:"percent"`
+	// Printer - profile name of the printer in a pool
+	Printer string `json:"printer,omitempty"`
+	// Down - the printer failed a job and gets none for now
+	Down bool `json:"down,omitempty"`
+	// Status - last status read from the printer, see StatusInterval;
+	// StatusError - why it could not be read
+	Status      *escpos.Status `json:"status,omitempty"`
+	StatusError string         `json:"statusError,omitempty"`
+	// Printers - every printer of a pool, the job above is the first one
+	// printing
+	Printers []jobStatus `json:"printers,omitempty"`
+	// Queued - jobs waiting for a printer, Errors - the last failed jobs
+	Queued int        `json:"queued"`
+	Errors []jobError `json:"errors,omitempty"`
+}
+
+// unitStatus - progress of the job printing on u
+func unitStatus(u *unit) jobStatus {
+	res := jobStatus{Job: atomic.LoadInt32(&u.printing)}
+	if res.Job > 0 {
+		res.Printing = true
+		res.Progress = u.p.Progress()
+		res.Percent = res.Progress.Percent()
+	}
+	u.state.mu.Lock()
+	res.Status, res.StatusError = u.state.status, u.state.err
+	u.state.mu.Unlock()
+	return res
+}
+
+// status - GET /status, progress of the job printing, the queue, the
+// printer status and the recent errors
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	var res jobStatus
+	if len(s.units) == 1 {
+		res = unitStatus(s.units[0])
+	} else {
+		now := time.Now().UnixNano()
+		var all []jobStatus
+		for _, u := range s.units {
+			st := unitStatus(u)
+			st.Printer = u.p.Profile().Name
+			st.Down = atomic.LoadInt64(&u.down) > now
+			if st.Printing && !res.Printing {
+				res = st
+			}
+			all = append(all, st)
+		}
+		res.Printers = all
+	}
+	res.Queued = int(atomic.LoadInt32(&s.queued))
+	res.Errors = s.recent()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// reply - JSON status of a request
+func reply(w http.ResponseWriter, code int, err error) {
+	res := map[string]string{"status": "printed"}
+	if err != nil {
+		res = map[string]string{"status": "error", "error": err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/grengojbo/gotp/models"
+	"github.com/grengojbo/gotp/systemd"
+)
+
+// ErrShutdown - the server stopped before the job printed, it is kept in
+// the Spool
+var ErrShutdown = errors.New("Server is shutting down")
+
+// Shutdown - stop taking jobs, wait for the jobs printing and save the
+// queued ones to Spool, they print when the server starts again; the
+// requests waiting for them get ErrShutdown. When ctx ends first the
+// jobs kept so far are saved and its error returned
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		systemd.Notify("STOPPING=1")
+	})
+	s.mu.Lock()
+	srv, fifo, started := s.http, s.fifo, s.started
+	s.mu.Unlock()
+	if len(fifo) > 0 {
+		s.stopFifo(ctx, fifo)
+	}
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+	if started {
+		idle := make(chan struct{})
+		go func() {
+			<-s.dispatched
+			s.workers.Wait()
+			s.requeues.Wait()
+			close(idle)
+		}()
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if serr := s.saveSpool(); serr != nil && err == nil {
+		err = serr
+	}
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+	return err
+}
+
+// send - hand j to the dispatcher, false once the server is shutting
+// down
+func (s *Server) send(j job) bool {
+	select {
+	case s.jobs <- j:
+		return true
+	case <-s.stop:
+		return false
+	}
+}
+
+// keep - j, taken but not printed, goes to the Spool
+func (s *Server) keep(j job) {
+	s.mu.Lock()
+	s.kept = append(s.kept, j.model)
+	s.mu.Unlock()
+	j.done <- ErrShutdown
+}
+
+// saveSpool - write the kept jobs to Spool, in the order they would
+// have printed
+func (s *Server) saveSpool() error {
+	s.mu.Lock()
+	kept := s.kept
+	s.kept = nil
+	s.mu.Unlock()
+	if len(kept) == 0 || len(s.Spool) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(s.Spool, 0755); err != nil {
+		return fmt.Errorf("Spool: %s", err.Error())
+	}
+	for i, m := range kept {
+		if err := models.SaveModel(filepath.Join(s.Spool, fmt.Sprintf("%04d.json", i)), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSpool - jobs saved by the last Shutdown, the files are removed
+func (s *Server) loadSpool() ([]models.PrinterLine, error) {
+	if len(s.Spool) == 0 {
+		return nil, nil
+	}
+	files, err := ioutil.ReadDir(s.Spool)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Spool: %s", err.Error())
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+	var res []models.PrinterLine
+	for _, name := range names {
+		path := filepath.Join(s.Spool, name)
+		m, err := models.LoadPrintModel(path)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, m)
+		if err := os.Remove(path); err != nil {
+			return res, fmt.Errorf("Spool: %s", err.Error())
+		}
+	}
+	return res, nil
+}
+
+// restore - queue the jobs of the Spool, they are rendered already
+func (s *Server) restore() {
+	spooled, err := s.loadSpool()
+	if err != nil && s.Printed != nil {
+		s.Printed(models.PrinterLine{}, err)
+	}
+	if len(spooled) == 0 {
+		return
+	}
+	s.requeues.Add(1)
+	go func() {
+		defer s.requeues.Done()
+		for _, m := range spooled {
+			j := job{model: m, done: make(chan error, 1)}
+			if !s.send(j) {
+				s.keep(j)
+			}
+		}
+	}()
+}
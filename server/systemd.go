@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/grengojbo/gotp/models"
+	"github.com/grengojbo/gotp/systemd"
+)
+
+// listenSystemd - socket name passed by socket activation, any name when
+// systemd passed one socket
+func listenSystemd(name string) (net.Listener, error) {
+	ls, err := systemd.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("Listen: %s", err.Error())
+	}
+	if l, ok := ls[name]; ok {
+		return l, nil
+	}
+	if len(name) == 0 && len(ls) == 1 {
+		for _, l := range ls {
+			return l, nil
+		}
+	}
+	for _, l := range ls {
+		l.Close()
+	}
+	if len(ls) == 0 {
+		return nil, fmt.Errorf("Listen: no socket passed by systemd")
+	}
+	return nil, fmt.Errorf("Listen: no systemd socket %s", name)
+}
+
+// ready - tell systemd the server takes jobs and send the watchdog
+// keep-alive until Shutdown, nothing when not run by systemd
+func (s *Server) ready() {
+	if _, err := systemd.Notify("READY=1"); err != nil && s.Printed != nil {
+		s.Printed(models.PrinterLine{}, err)
+	}
+	interval, err := systemd.WatchdogInterval()
+	if err != nil && s.Printed != nil {
+		s.Printed(models.PrinterLine{}, err)
+	}
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(interval / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				systemd.Notify("WATCHDOG=1")
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,268 @@
+// Package snmp - SNMP v2c GET requests, enough to read the printer MIB of
+// network printers
+package snmp
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort - SNMP agent port
+const DefaultPort = "161"
+
+// BER tags
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+	tagCounter32   = 0x41
+	tagGauge32     = 0x42
+	tagTimeTicks   = 0x43
+	tagGetRequest  = 0xA0
+	tagResponse    = 0xA2
+)
+
+// Get - values of oids ("1.3.6.1.2.1.1.1.0") from the agent at addr
+// (host or host:port): int64 for numbers, []byte for strings, missing
+// objects are left out
+func Get(addr, community string, timeout time.Duration, oids ...string) (map[string]interface{}, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultPort)
+	}
+	id := rand.Int31()
+	req, err := getRequest(community, id, oids)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("SNMP: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(req); err != nil {
+		return nil, fmt.Errorf("SNMP: %w", err)
+	}
+	buf := make([]byte, 65536)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("SNMP: %w", err)
+		}
+		res, rid, err := parseResponse(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		// a late answer to an earlier request
+		if rid != id {
+			continue
+		}
+		return res, nil
+	}
+}
+
+// getRequest - GetRequest message for oids
+func getRequest(community string, id int32, oids []string) ([]byte, error) {
+	var binds []byte
+	for _, oid := range oids {
+		o, err := encodeOID(oid)
+		if err != nil {
+			return nil, err
+		}
+		binds = append(binds, tlv(tagSequence, append(tlv(tagOID, o), tagNull, 0))...)
+	}
+	pdu := integer(int64(id))
+	pdu = append(pdu, integer(0)...)
+	pdu = append(pdu, integer(0)...)
+	pdu = append(pdu, tlv(tagSequence, binds)...)
+	// version 1 is v2c
+	msg := integer(1)
+	msg = append(msg, tlv(tagOctetString, []byte(community))...)
+	msg = append(msg, tlv(tagGetRequest, pdu)...)
+	return tlv(tagSequence, msg), nil
+}
+
+// tlv - tag, length and value
+func tlv(tag byte, value []byte) []byte {
+	res := []byte{tag}
+	switch l := len(value); {
+	case l < 128:
+		res = append(res, byte(l))
+	case l < 256:
+		res = append(res, 0x81, byte(l))
+	default:
+		res = append(res, 0x82, byte(l>>8), byte(l))
+	}
+	return append(res, value...)
+}
+
+// integer - INTEGER n, shortest two's complement
+func integer(n int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(n)}, b...)
+		if (n < 128 && n >= -128) || len(b) == 8 {
+			break
+		}
+		n >>= 8
+	}
+	return tlv(tagInteger, b)
+}
+
+// encodeOID - contents of an OBJECT IDENTIFIER
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("SNMP: invalid OID %s", oid)
+	}
+	ids := make([]uint64, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("SNMP: invalid OID %s", oid)
+		}
+		ids[i] = n
+	}
+	if ids[0] > 2 || ids[1] > 39 {
+		return nil, fmt.Errorf("SNMP: invalid OID %s", oid)
+	}
+	res := []byte{byte(ids[0]*40 + ids[1])}
+	for _, n := range ids[2:] {
+		// base 128, high bit on all but the last byte
+		enc := []byte{byte(n & 0x7F)}
+		for n >>= 7; n > 0; n >>= 7 {
+			enc = append([]byte{byte(n&0x7F) | 0x80}, enc...)
+		}
+		res = append(res, enc...)
+	}
+	return res, nil
+}
+
+// decodeOID - dotted form of OBJECT IDENTIFIER contents
+func decodeOID(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	parts := []string{strconv.Itoa(int(b[0]) / 40), strconv.Itoa(int(b[0]) % 40)}
+	var n uint64
+	for _, c := range b[1:] {
+		n = n<<7 | uint64(c&0x7F)
+		if c&0x80 == 0 {
+			parts = append(parts, strconv.FormatUint(n, 10))
+			n = 0
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// next - the first TLV of b and the rest of b
+func next(b []byte) (tag byte, value, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, fmt.Errorf("SNMP: short reply")
+	}
+	tag, l, i := b[0], int(b[1]), 2
+	if l&0x80 != 0 {
+		n := l & 0x7F
+		if n > 3 || len(b) < 2+n {
+			return 0, nil, nil, fmt.Errorf("SNMP: invalid length")
+		}
+		l = 0
+		for _, c := range b[2 : 2+n] {
+			l = l<<8 | int(c)
+		}
+		i += n
+	}
+	if len(b) < i+l {
+		return 0, nil, nil, fmt.Errorf("SNMP: short reply")
+	}
+	return tag, b[i : i+l], b[i+l:], nil
+}
+
+// expect - value of the first TLV of b, which must have tag
+func expect(b []byte, tag byte) (value, rest []byte, err error) {
+	t, value, rest, err := next(b)
+	if err == nil && t != tag {
+		err = fmt.Errorf("SNMP: unexpected tag %X", t)
+	}
+	return value, rest, err
+}
+
+// number - contents of an INTEGER or unsigned type
+func number(b []byte, signed bool) int64 {
+	var n int64
+	if signed && len(b) > 0 && b[0]&0x80 != 0 {
+		n = -1
+	}
+	for _, c := range b {
+		n = n<<8 | int64(c)
+	}
+	return n
+}
+
+// parseResponse - values and request id of a Response message
+func parseResponse(b []byte) (map[string]interface{}, int32, error) {
+	msg, _, err := expect(b, tagSequence)
+	if err != nil {
+		return nil, 0, err
+	}
+	// version, community
+	if _, msg, err = expect(msg, tagInteger); err != nil {
+		return nil, 0, err
+	}
+	if _, msg, err = expect(msg, tagOctetString); err != nil {
+		return nil, 0, err
+	}
+	pdu, _, err := expect(msg, tagResponse)
+	if err != nil {
+		return nil, 0, err
+	}
+	var v []byte
+	if v, pdu, err = expect(pdu, tagInteger); err != nil {
+		return nil, 0, err
+	}
+	id := int32(number(v, true))
+	if v, pdu, err = expect(pdu, tagInteger); err != nil {
+		return nil, 0, err
+	}
+	if status := number(v, true); status != 0 {
+		return nil, id, fmt.Errorf("SNMP: error status %d", status)
+	}
+	if _, pdu, err = expect(pdu, tagInteger); err != nil {
+		return nil, 0, err
+	}
+	binds, _, err := expect(pdu, tagSequence)
+	if err != nil {
+		return nil, 0, err
+	}
+	res := map[string]interface{}{}
+	for len(binds) > 0 {
+		var bind []byte
+		if bind, binds, err = expect(binds, tagSequence); err != nil {
+			return nil, 0, err
+		}
+		oid, bind, err := expect(bind, tagOID)
+		if err != nil {
+			return nil, 0, err
+		}
+		tag, value, _, err := next(bind)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch tag {
+		case tagInteger:
+			res[decodeOID(oid)] = number(value, true)
+		case tagCounter32, tagGauge32, tagTimeTicks:
+			res[decodeOID(oid)] = number(value, false)
+		case tagOctetString:
+			res[decodeOID(oid)] = value
+		}
+		// NULL, noSuchObject, noSuchInstance: left out
+	}
+	return res, id, nil
+}
@@ -0,0 +1,93 @@
+// Package systemd - sd_notify and socket activation of services run by
+// systemd, without libsystemd
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenFdsStart - first file descriptor passed by socket activation
+const listenFdsStart = 3
+
+// Notify - send state ("READY=1", "WATCHDOG=1", "STOPPING=1",
+// "STATUS=...") to the service manager; false without NOTIFY_SOCKET,
+// when the service isn't run by systemd with Type=notify
+func Notify(state string) (bool, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if len(path) == 0 {
+		return false, nil
+	}
+	// @name is a socket in the abstract namespace
+	if path[0] == '@' {
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("Notify: %s", err.Error())
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("Notify: %s", err.Error())
+	}
+	return true, nil
+}
+
+// WatchdogInterval - WatchdogSec= of the service, the service sends
+// WATCHDOG=1 more often or is restarted; 0 when the watchdog is off or
+// it is for another process
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if len(usec) == 0 {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); len(pid) > 0 && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("Invalid WATCHDOG_USEC: %s", usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+// Listeners - sockets passed by socket activation (a .socket unit) by
+// FileDescriptorName= of the socket, the socket unit name by default;
+// empty when the service wasn't activated by a socket
+func Listeners() (map[string]net.Listener, error) {
+	res := map[string]net.Listener{}
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return res, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return res, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	// the sockets aren't passed on to children
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(names) && len(names[i]) > 0 {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(listenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return res, fmt.Errorf("Socket %s: %s", name, err.Error())
+		}
+		if _, ok := res[name]; !ok {
+			res[name] = l
+		} else {
+			l.Close()
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,337 @@
+// Package zpl - experimental translation of simple Zebra ZPL labels into
+// label models, so label jobs of Zebra printers print on ESC/POS ones
+package zpl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grengojbo/gotp/escpos"
+	"github.com/grengojbo/gotp/models"
+)
+
+// Label - one ^XA..^XZ format as a label model
+type Label struct {
+	Model models.PrinterLine
+	// Copies - ^PQ quantity, 1 without it
+	Copies int
+	// Ignored - commands of the label which are not translated, once each
+	Ignored []string
+}
+
+// zplBarCodes - bar code commands and the row code they print as
+var zplBarCodes = map[string]string{
+	"BC": "CODE128",
+	"B3": "CODE39",
+	"BE": "EAN13",
+	"B8": "EAN8",
+	"BU": "UPC_A",
+	"B2": "I25",
+	"BA": "CODE93",
+	"BK": "CODEBAR",
+}
+
+// field - state of the field up to ^FS
+type field struct {
+	x, y int
+	// base - ^FT, y is the base line and not the top
+	base     bool
+	height   int
+	width    int
+	barCode  string
+	barH     int
+	hri      bool
+	hriAbove bool
+	qr       bool
+	qrSize   int
+	data     string
+	hasData  bool
+}
+
+// format - state of one ^XA..^XZ
+type format struct {
+	label   Label
+	rows    []models.Printer
+	width   int
+	length  int
+	homeX   int
+	homeY   int
+	font    [2]int
+	module  int
+	barH    int
+	f       field
+	ignored map[string]bool
+	bottom  int
+}
+
+// Parse - labels of the ZPL text, commands outside ^XA..^XZ are dropped
+func Parse(text string) ([]Label, error) {
+	var res []Label
+	var cur *format
+	for _, cmd := range split(text) {
+		name, params := command(cmd)
+		switch {
+		case name == "XA":
+			cur = &format{font: [2]int{30, 0}, module: 2, barH: 10, ignored: map[string]bool{}}
+			cur.label.Copies = 1
+			continue
+		case cur == nil:
+			continue
+		case name == "XZ":
+			l, err := cur.end()
+			if err != nil {
+				return res, err
+			}
+			res = append(res, l)
+			cur = nil
+			continue
+		}
+		cur.apply(name, params)
+	}
+	if cur != nil {
+		return res, fmt.Errorf("ZPL: ^XA without ^XZ")
+	}
+	return res, nil
+}
+
+// split - the commands of text without their ^ or ~, line breaks between
+// commands dropped
+func split(text string) []string {
+	var res []string
+	start := -1
+	for i := 0; i < len(text); i++ {
+		if text[i] == '^' || text[i] == '~' {
+			if start >= 0 {
+				res = append(res, text[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start >= 0 {
+		res = append(res, text[start:])
+	}
+	for i, c := range res {
+		// line breaks between commands, not in field data
+		if !strings.HasPrefix(c, "FD") {
+			res[i] = strings.TrimRight(c, "\r\n")
+		}
+	}
+	return res
+}
+
+// command - name and parameters of cmd, ^A takes the font as a name
+// character: ^A0N,30,20 is A with "0N,30,20"
+func command(cmd string) (string, string) {
+	if strings.HasPrefix(cmd, "A") && !strings.HasPrefix(cmd, "A@") {
+		return "A", cmd[1:]
+	}
+	if len(cmd) < 2 {
+		return cmd, ""
+	}
+	return strings.ToUpper(cmd[:2]), cmd[2:]
+}
+
+// ints - comma separated numbers of params, def for the empty ones
+func ints(params string, def ...int) []int {
+	res := append([]int{}, def...)
+	for i, p := range strings.Split(params, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			continue
+		}
+		if i < len(res) {
+			res[i] = n
+		} else {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// args - comma separated parameters, at least n of them
+func args(params string, n int) []string {
+	res := strings.Split(params, ",")
+	for len(res) < n {
+		res = append(res, "")
+	}
+	return res
+}
+
+// apply - one command of the format
+func (f *format) apply(name, params string) {
+	switch name {
+	case "PW":
+		f.width = ints(params, 0)[0]
+	case "LL":
+		f.length = ints(params, 0)[0]
+	case "LH":
+		v := ints(params, 0, 0)
+		f.homeX, f.homeY = v[0], v[1]
+	case "PQ":
+		if n := ints(params, 1)[0]; n > 0 {
+			f.label.Copies = n
+		}
+	case "CF":
+		// ^CFf,h,w, the font f is the one of the printer
+		a := args(params, 3)
+		f.font = [2]int{ints(a[1], f.font[0])[0], ints(a[2], f.font[1])[0]}
+	case "BY":
+		v := ints(params, f.module, 3, f.barH)
+		f.module, f.barH = v[0], v[2]
+	case "FO", "FT":
+		v := ints(params, 0, 0)
+		f.f = field{x: f.homeX + v[0], y: f.homeY + v[1], base: name == "FT",
+			height: f.font[0], width: f.font[1]}
+	case "A":
+		// ^AfO,h,w: font f, orientation O
+		a := args(params, 3)
+		if len(a[0]) > 1 && a[0][1:] != "N" {
+			f.ignore("^A rotated")
+		}
+		f.f.height, f.f.width = ints(a[1], f.font[0])[0], ints(a[2], 0)[0]
+	case "BQ":
+		a := args(params, 3)
+		f.f.qr = true
+		f.f.qrSize = ints(a[2], 3)[0]
+	case "FD":
+		f.f.data = params
+		f.f.hasData = true
+	case "FS":
+		f.fieldEnd()
+	case "CI", "FX", "MM", "MN", "MT", "PR", "MD", "JM", "LR", "PO", "LS":
+		// code page, comment, media and darkness settings of the Zebra
+	default:
+		if code, ok := zplBarCodes[name]; ok {
+			a := args(params, 5)
+			if o := strings.TrimSpace(a[0]); len(o) > 0 && o != "N" {
+				f.ignore("^" + name + " rotated")
+			}
+			f.f.barCode = code
+			f.f.barH = f.barH
+			if h := ints(a[1], 0)[0]; h > 0 {
+				f.f.barH = h
+			}
+			f.f.hri = strings.TrimSpace(a[2]) != "N"
+			f.f.hriAbove = strings.TrimSpace(a[3]) == "Y"
+			return
+		}
+		f.ignore("^" + name)
+	}
+}
+
+// ignore - record a command which is not translated
+func (f *format) ignore(name string) {
+	if !f.ignored[name] {
+		f.ignored[name] = true
+		f.label.Ignored = append(f.label.Ignored, name)
+	}
+}
+
+// hriHeight - dots of the HRI line under bar codes
+const hriHeight = 26
+
+// fieldEnd - ^FS, the field as a row
+func (f *format) fieldEnd() {
+	fd := f.f
+	f.f = field{x: fd.x, y: fd.y, height: f.font[0], width: f.font[1]}
+	if !fd.hasData {
+		return
+	}
+	row := models.Printer{X: strconv.Itoa(fd.x)}
+	base := fd.y
+	switch {
+	case fd.qr:
+		// ^FDQA,data: the error correction level and the input mode first
+		ecc, data := "M", fd.data
+		if i := strings.Index(data, ","); i >= 0 && i <= 3 {
+			if l := strings.ToUpper(data[:1]); strings.Contains("LMQH", l) && i > 0 {
+				ecc = l
+			}
+			data = data[i+1:]
+		}
+		size := fd.qrSize
+		if size < 1 || size > 10 {
+			size = 3
+		}
+		row.Type, row.QrCode, row.Text, row.QrSize, row.QrEcc = "qrcode", true, data, uint8(size), ecc
+		if r, err := escpos.QrRaster(data, uint8(size), ecc); err == nil && !fd.base {
+			base += r.Height
+		}
+	case len(fd.barCode) > 0:
+		row.Type, row.BarCode, row.Code = "barcode", true, fd.barCode
+		row.Text = barCodeData(fd.barCode, fd.data)
+		row.Height = 255
+		if fd.barH < 255 {
+			row.Height = uint8(fd.barH)
+		}
+		row.Width = uint16(f.module)
+		row.Hri = "none"
+		if fd.hri {
+			row.Hri = "below"
+			if fd.hriAbove {
+				row.Hri = "above"
+			}
+		}
+		if !fd.base {
+			base += fd.barH
+			if fd.hri {
+				base += hriHeight
+			}
+		}
+	default:
+		row.Text = strings.Replace(fd.data, "\\&", "\n", -1)
+		row.Size, row.Style = fontSize(fd.height, fd.width)
+		if !fd.base {
+			base += fd.height
+		}
+	}
+	row.Y = strconv.Itoa(base)
+	if base > f.bottom {
+		f.bottom = base
+	}
+	f.rows = append(f.rows, row)
+}
+
+// barCodeData - data of a ZPL bar code for the row: the subset
+// invocation codes of ^BC (>: >; >9) are dropped, the printer selects
+func barCodeData(code, data string) string {
+	if code != "CODE128" {
+		return data
+	}
+	r := strings.NewReplacer(">:", "", ">;", "", ">9", "", ">8", "", ">>", ">")
+	return r.Replace(data)
+}
+
+// fontSize - row size and style of a ZPL font height x width in dots:
+// font B under 20 dots, double height from 36, double size from 44
+func fontSize(height, width int) (string, string) {
+	switch {
+	case height >= 44:
+		return "large", "bold"
+	case height >= 36:
+		return "medium", ""
+	case height > 0 && height < 20:
+		return "", "small"
+	}
+	return "", ""
+}
+
+// end - ^XZ, the label model of the format
+func (f *format) end() (Label, error) {
+	if len(f.rows) == 0 {
+		return f.label, fmt.Errorf("ZPL: label without fields")
+	}
+	length := f.length
+	if length <= 0 {
+		// the lowest field and a margin
+		length = f.bottom + 16
+	}
+	l := &models.Label{Height: strconv.Itoa(length)}
+	if f.width > 0 {
+		l.Width = strconv.Itoa(f.width)
+	}
+	f.label.Model = models.PrinterLine{Version: models.ModelVersion, Label: l,
+		Sections: []models.Section{{Name: "zpl", Rows: f.rows}}}
+	return f.label, nil
+}